@@ -0,0 +1,91 @@
+package centrifuge
+
+import (
+	"sync"
+)
+
+// ackTracker keeps offsets of publications delivered to handler but not
+// acknowledged yet. Stored position advances only over acknowledged prefix, so
+// publication acknowledged out of order does not skip earlier unacknowledged ones.
+type ackTracker struct {
+	mu      sync.Mutex
+	epoch   string
+	pending []uint64
+	acked   map[uint64]struct{}
+}
+
+func newAckTracker() *ackTracker {
+	return &ackTracker{acked: make(map[uint64]struct{})}
+}
+
+// deliver registers publication passed to handler.
+func (t *ackTracker) deliver(offset uint64, epoch string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if epoch != t.epoch {
+		// Stream was reset, old offsets can't be recovered anyway.
+		t.epoch = epoch
+		t.pending = nil
+		clear(t.acked)
+	}
+	if n := len(t.pending); n > 0 && t.pending[n-1] >= offset {
+		// Redelivered after recovery, already pending.
+		return
+	}
+	t.pending = append(t.pending, offset)
+}
+
+// ack marks publication acknowledged. Returns the new position to store and true
+// if acknowledged prefix advanced.
+func (t *ackTracker) ack(offset uint64, epoch string) (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if epoch != t.epoch || len(t.pending) == 0 || offset < t.pending[0] {
+		return 0, false
+	}
+	t.acked[offset] = struct{}{}
+	var position uint64
+	for len(t.pending) > 0 {
+		if _, ok := t.acked[t.pending[0]]; !ok {
+			break
+		}
+		position = t.pending[0]
+		delete(t.acked, position)
+		t.pending = t.pending[1:]
+	}
+	return position, position > 0
+}
+
+// hasPending tells whether there are unacknowledged publications.
+func (t *ackTracker) hasPending() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending) > 0
+}
+
+// Ack acknowledges publication when SubscriptionConfig.ManualAck enabled, so
+// its position may be saved to Config.PositionStore. May be called from any
+// goroutine after handler returned. Does nothing if ManualAck is not enabled.
+func (e PublicationEvent) Ack() {
+	if e.ack != nil {
+		e.ack()
+	}
+}
+
+// withAck sets acknowledgement function of publication event if ManualAck enabled.
+func (s *Subscription) withAck(event PublicationEvent) PublicationEvent {
+	if s.acks == nil || event.StreamPosition == nil {
+		return event
+	}
+	sp := *event.StreamPosition
+	s.acks.deliver(sp.Offset, sp.Epoch)
+	var once sync.Once
+	event.ack = func() {
+		once.Do(func() {
+			if position, ok := s.acks.ack(sp.Offset, sp.Epoch); ok {
+				s.storePosition(StreamPosition{Offset: position, Epoch: sp.Epoch})
+			}
+		})
+	}
+	return event
+}
@@ -0,0 +1,78 @@
+package centrifuge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+func TestAckTracker(t *testing.T) {
+	tracker := newAckTracker()
+	for _, offset := range []uint64{1, 2, 3} {
+		tracker.deliver(offset, "e")
+	}
+	// Redelivery does not add pending publication.
+	tracker.deliver(2, "e")
+	if _, ok := tracker.ack(2, "e"); ok {
+		t.Fatal("position must not advance over unacknowledged publication")
+	}
+	if position, ok := tracker.ack(1, "e"); !ok || position != 2 {
+		t.Fatalf("expected position 2, got %d %v", position, ok)
+	}
+	if !tracker.hasPending() {
+		t.Fatal("expected pending publication")
+	}
+	if position, ok := tracker.ack(3, "e"); !ok || position != 3 {
+		t.Fatalf("expected position 3, got %d %v", position, ok)
+	}
+	if tracker.hasPending() {
+		t.Fatal("unexpected pending publication")
+	}
+	tracker.deliver(4, "e")
+	if _, ok := tracker.ack(4, "other"); ok {
+		t.Fatal("ack of other epoch must be ignored")
+	}
+	tracker.deliver(1, "new")
+	if position, ok := tracker.ack(1, "new"); !ok || position != 1 {
+		t.Fatalf("expected position 1 in new epoch, got %d %v", position, ok)
+	}
+}
+
+func TestSubscription_ManualAck(t *testing.T) {
+	subCh := make(chan *protocol.SubscribeRequest, 1)
+	u := startPositionServer(t, subCh)
+	store := NewMemoryPositionStore()
+	client := NewJsonClient(u, Config{PositionStore: store})
+	defer client.Close()
+	sub, err := client.NewSubscription("test", SubscriptionConfig{Recoverable: true, ManualAck: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan PublicationEvent, 1)
+	sub.OnPublication(func(e PublicationEvent) {
+		events <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	var event PublicationEvent
+	select {
+	case event = <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for publication")
+	}
+	waitFor(t, func() bool {
+		return sub.Stats().ProcessedOffset == 6
+	})
+	if sp, _, _ := store.Load("test"); sp.Offset != 5 {
+		t.Fatalf("position must not advance before ack, got %d", sp.Offset)
+	}
+	event.Ack()
+	if sp, _, _ := store.Load("test"); sp != (StreamPosition{Offset: 6, Epoch: "e"}) {
+		t.Fatalf("expected position 6 after ack, got %v", sp)
+	}
+}
@@ -0,0 +1,92 @@
+package centrifuge
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+// BroadcastResult is a result of publishing into one channel by Client.Broadcast.
+type BroadcastResult struct {
+	Channel string
+	// Error is nil if publication into Channel succeeded.
+	Error error
+}
+
+// Broadcast publishes the same data into many channels. Publish commands are sent
+// to server in one frame and replies are awaited concurrently. Results are
+// returned in order of channels. Returned error is set only if publications were
+// not sent at all. Broadcast waits for connect up to ReadTimeout, offline queue
+// is not used.
+func (c *Client) Broadcast(ctx context.Context, channels []string, data []byte) ([]BroadcastResult, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+	if c.config.MaxPublishSize > 0 && len(data) > c.config.MaxPublishSize {
+		return nil, ErrPublishTooLarge
+	}
+	connectCh := make(chan error, 1)
+	c.onConnect(func(err error) {
+		connectCh <- err
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err := <-connectCh:
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]BroadcastResult, len(channels))
+	batch := &commandBatch{}
+	var wg sync.WaitGroup
+	started := time.Now()
+	for i, channel := range channels {
+		results[i].Channel = channel
+		if !c.allowCommand(ThrottledEvent{Command: "publish", Channel: channel}) {
+			results[i].Error = ErrRateLimited
+			continue
+		}
+		cmd := &protocol.Command{
+			Id: c.nextCmdID(),
+			Publish: &protocol.PublishRequest{
+				Channel: channel,
+				Data:    protocol.Raw(data),
+			},
+		}
+		wg.Add(1)
+		var once sync.Once
+		batch.add(cmd, func(r *protocol.Reply, err error) {
+			once.Do(func() {
+				defer wg.Done()
+				if err == nil {
+					c.metrics.ObservePublishDuration(time.Since(started))
+					if r.Error != nil {
+						err = errorFromProto(r.Error)
+					}
+				}
+				results[i].Error = err
+			})
+		})
+	}
+	if len(batch.cmds) == 0 {
+		return results, nil
+	}
+	if err := c.sendAsyncBatch(batch); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-done:
+		return results, nil
+	}
+}
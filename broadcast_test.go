@@ -0,0 +1,94 @@
+package centrifuge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// startBroadcastServer rejects publications into "denied" channel and counts
+// frames with publish commands.
+func startBroadcastServer(t *testing.T) (string, *atomic.Int32) {
+	numFrames := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var replies []string
+			for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+				var cmd struct {
+					ID      uint32          `json:"id"`
+					Connect json.RawMessage `json:"connect"`
+					Publish struct {
+						Channel string `json:"channel"`
+					} `json:"publish"`
+				}
+				if err := json.Unmarshal(line, &cmd); err != nil {
+					return
+				}
+				switch {
+				case cmd.Connect != nil:
+					replies = append(replies, fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.ID))
+				case cmd.Publish.Channel == "denied":
+					replies = append(replies, fmt.Sprintf(`{"id":%d,"error":{"code":103,"message":"permission denied"}}`, cmd.ID))
+				default:
+					replies = append(replies, fmt.Sprintf(`{"id":%d,"publish":{}}`, cmd.ID))
+				}
+			}
+			if !strings.Contains(replies[0], "connect") {
+				numFrames.Add(1)
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(strings.Join(replies, "\n"))); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http"), numFrames
+}
+
+func TestClient_Broadcast(t *testing.T) {
+	u, numFrames := startBroadcastServer(t)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	channels := []string{"a", "denied", "b"}
+	results, err := client.Broadcast(ctx, channels, []byte(`{"status":"ok"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(channels) {
+		t.Fatalf("expected %d results, got %d", len(channels), len(results))
+	}
+	for i, res := range results {
+		if res.Channel != channels[i] {
+			t.Fatalf("expected channel %s, got %s", channels[i], res.Channel)
+		}
+		if (res.Error != nil) != (res.Channel == "denied") {
+			t.Fatalf("unexpected result for %s: %v", res.Channel, res.Error)
+		}
+	}
+	if n := numFrames.Load(); n != 1 {
+		t.Fatalf("expected publications in 1 frame, got %d", n)
+	}
+}
@@ -0,0 +1,84 @@
+package centrifuge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"github.com/centrifugal/protocol"
+)
+
+// maxPooledBufferSize is a maximum capacity of buffer which will be returned
+// back to the pool. Larger buffers are left for GC to not keep rare huge frames
+// in memory forever.
+const maxPooledBufferSize = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		atomic.AddUint64(&bufferPoolNews, 1)
+		return new(bytes.Buffer)
+	},
+}
+
+var (
+	bufferPoolGets     uint64
+	bufferPoolNews     uint64
+	bufferPoolDiscards uint64
+)
+
+// BufferPoolStats contains counters of internal buffer pool used by Protobuf
+// protocol path to marshal commands and read replies. Counters are cumulative
+// and shared by all clients in a process.
+type BufferPoolStats struct {
+	// Gets is a number of buffers acquired from the pool.
+	Gets uint64
+	// News is a number of buffers allocated since the pool had no free buffer
+	// to reuse. The closer it to zero compared to Gets – the better.
+	News uint64
+	// Discards is a number of buffers not returned to the pool because they
+	// grew larger than the pooled size limit.
+	Discards uint64
+}
+
+// GetBufferPoolStats returns current BufferPoolStats.
+func GetBufferPoolStats() BufferPoolStats {
+	return BufferPoolStats{
+		Gets:     atomic.LoadUint64(&bufferPoolGets),
+		News:     atomic.LoadUint64(&bufferPoolNews),
+		Discards: atomic.LoadUint64(&bufferPoolDiscards),
+	}
+}
+
+func getBuffer() *bytes.Buffer {
+	atomic.AddUint64(&bufferPoolGets, 1)
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		atomic.AddUint64(&bufferPoolDiscards, 1)
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// encodeProtobufCommand appends varint length-delimited Protobuf representation
+// of Command to buf – the same format as protocol.NewProtobufCommandEncoder
+// produces, but without allocating a new slice for every frame.
+func encodeProtobufCommand(buf *bytes.Buffer, cmd *protocol.Command) error {
+	size := cmd.SizeVT()
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(size))
+	buf.Grow(n + size)
+	buf.Write(lenBuf[:n])
+	data := buf.AvailableBuffer()[:size]
+	if _, err := cmd.MarshalToSizedBufferVT(data); err != nil {
+		return err
+	}
+	// Data is already in place, Write only advances buffer length here.
+	buf.Write(data)
+	return nil
+}
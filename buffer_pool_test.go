@@ -0,0 +1,73 @@
+package centrifuge
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/centrifugal/protocol"
+)
+
+func TestEncodeProtobufCommand(t *testing.T) {
+	cmd := &protocol.Command{
+		Id: 1,
+		Publish: &protocol.PublishRequest{
+			Channel: "test",
+			Data:    []byte("boom"),
+		},
+	}
+	expected, err := protocol.NewProtobufCommandEncoder().Encode(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeProtobufCommand(buf, cmd); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("encoded command mismatch: %v != %v", expected, buf.Bytes())
+	}
+	// Decoder returns the only command of a frame together with io.EOF.
+	decoded, err := protocol.NewProtobufCommandDecoder(buf.Bytes()).Decode()
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if decoded == nil || decoded.Id != 1 || decoded.Publish.Channel != "test" {
+		t.Fatalf("unexpected decoded command: %v", decoded)
+	}
+}
+
+func TestBufferPoolStats(t *testing.T) {
+	before := GetBufferPoolStats()
+	buf := getBuffer()
+	putBuffer(buf)
+	large := getBuffer()
+	large.Grow(maxPooledBufferSize + 1)
+	putBuffer(large)
+	after := GetBufferPoolStats()
+	if after.Gets-before.Gets != 2 {
+		t.Fatalf("expected 2 gets, got %d", after.Gets-before.Gets)
+	}
+	if after.Discards-before.Discards != 1 {
+		t.Fatalf("expected 1 discard, got %d", after.Discards-before.Discards)
+	}
+}
+
+func BenchmarkEncodeProtobufCommand(b *testing.B) {
+	cmd := &protocol.Command{
+		Id: 1,
+		Publish: &protocol.PublishRequest{
+			Channel: "test",
+			Data:    []byte(`{"input": "test"}`),
+		},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := getBuffer()
+		if err := encodeProtobufCommand(buf, cmd); err != nil {
+			b.Fatal(err)
+		}
+		putBuffer(buf)
+	}
+}
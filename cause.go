@@ -0,0 +1,105 @@
+package centrifuge
+
+// DisconnectCause is a category of disconnect reason. It's computed from disconnect
+// codes and internal errors, so application may aggregate disconnect causes without
+// matching codes or reason strings.
+type DisconnectCause string
+
+// Known disconnect causes.
+const (
+	// DisconnectCauseClientRequest means state changed due to Client method call
+	// (Connect, Disconnect, Reconnect, Suspend).
+	DisconnectCauseClientRequest DisconnectCause = "client-request"
+	// DisconnectCauseNetworkError means connection lost due to network or transport
+	// error.
+	DisconnectCauseNetworkError DisconnectCause = "network-error"
+	// DisconnectCauseProxyClose means connection closed with WebSocket close code not
+	// used by Centrifuge protocol – usually by a proxy or load balancer.
+	DisconnectCauseProxyClose DisconnectCause = "proxy-close"
+	// DisconnectCauseServerShutdown means server is shutting down.
+	DisconnectCauseServerShutdown DisconnectCause = "server-shutdown"
+	// DisconnectCauseTokenExpired means connection token expired.
+	DisconnectCauseTokenExpired DisconnectCause = "token-expired"
+	// DisconnectCausePingTimeout means ping or pong not received in time.
+	DisconnectCausePingTimeout DisconnectCause = "ping-timeout"
+	// DisconnectCauseUnauthorized means connection credentials were rejected.
+	DisconnectCauseUnauthorized DisconnectCause = "unauthorized"
+	// DisconnectCauseLimitExceeded means server limit (rate, connection or channel
+	// limit) exceeded.
+	DisconnectCauseLimitExceeded DisconnectCause = "limit-exceeded"
+	// DisconnectCauseProtocolError means client and server failed to understand
+	// each other.
+	DisconnectCauseProtocolError DisconnectCause = "protocol-error"
+	// DisconnectCauseMessageSizeLimit means message exceeded size limit.
+	DisconnectCauseMessageSizeLimit DisconnectCause = "message-size-limit"
+	// DisconnectCauseSubscriptionError means subscribe timed out or unsubscribe failed.
+	DisconnectCauseSubscriptionError DisconnectCause = "subscription-error"
+	// DisconnectCauseRefreshFailed means connection token refresh failed.
+	DisconnectCauseRefreshFailed DisconnectCause = "refresh-failed"
+	// DisconnectCauseReconnectFailed means reconnect budget exhausted.
+	DisconnectCauseReconnectFailed DisconnectCause = "reconnect-failed"
+	// DisconnectCauseSlowConsumer means event handlers could not keep up with
+	// events, see Config.EventQueuePolicy.
+	DisconnectCauseSlowConsumer DisconnectCause = "slow-consumer"
+	// DisconnectCauseServerDisconnect means server disconnected client for other
+	// reason, including application-specific disconnect codes.
+	DisconnectCauseServerDisconnect DisconnectCause = "server-disconnect"
+)
+
+// connectingCause returns cause for ConnectingEvent code.
+func connectingCause(code uint32) DisconnectCause {
+	switch code {
+	case connectingConnectCalled, connectingReconnectCalled, connectingSuspended:
+		return DisconnectCauseClientRequest
+	case connectingTransportClosed:
+		return DisconnectCauseNetworkError
+	case connectingNoPing:
+		return DisconnectCausePingTimeout
+	case connectingSubscribeTimeout, connectingUnsubscribeError:
+		return DisconnectCauseSubscriptionError
+	case connectingEventQueueFull:
+		return DisconnectCauseSlowConsumer
+	}
+	return serverDisconnectCause(code)
+}
+
+// disconnectedCause returns cause for DisconnectedEvent code.
+func disconnectedCause(code uint32) DisconnectCause {
+	switch code {
+	case disconnectedDisconnectCalled:
+		return DisconnectCauseClientRequest
+	case disconnectedUnauthorized:
+		return DisconnectCauseUnauthorized
+	case disconnectBadProtocol:
+		return DisconnectCauseProtocolError
+	case disconnectMessageSizeLimit:
+		return DisconnectCauseMessageSizeLimit
+	case disconnectedRefreshFailed:
+		return DisconnectCauseRefreshFailed
+	case disconnectedReconnectFailed:
+		return DisconnectCauseReconnectFailed
+	}
+	return serverDisconnectCause(code)
+}
+
+// serverDisconnectCause returns cause for disconnect codes and error codes sent by
+// server.
+func serverDisconnectCause(code uint32) DisconnectCause {
+	switch code {
+	case 3001:
+		return DisconnectCauseServerShutdown
+	case 3005, 109:
+		return DisconnectCauseTokenExpired
+	case 3009:
+		return DisconnectCauseNetworkError
+	case 3012:
+		return DisconnectCausePingTimeout
+	case 3013, 3504, 3505:
+		return DisconnectCauseLimitExceeded
+	case 3500, 3507, 101, 103:
+		return DisconnectCauseUnauthorized
+	case 3501, 3506:
+		return DisconnectCauseProtocolError
+	}
+	return DisconnectCauseServerDisconnect
+}
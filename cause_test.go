@@ -0,0 +1,66 @@
+package centrifuge
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDisconnectCause(t *testing.T) {
+	testCases := []struct {
+		cause    DisconnectCause
+		expected DisconnectCause
+	}{
+		{connectingCause(connectingConnectCalled), DisconnectCauseClientRequest},
+		{connectingCause(connectingTransportClosed), DisconnectCauseNetworkError},
+		{connectingCause(connectingNoPing), DisconnectCausePingTimeout},
+		{connectingCause(connectingEventQueueFull), DisconnectCauseSlowConsumer},
+		{connectingCause(3001), DisconnectCauseServerShutdown},
+		{connectingCause(3005), DisconnectCauseTokenExpired},
+		{connectingCause(4000), DisconnectCauseServerDisconnect},
+		{disconnectedCause(disconnectedUnauthorized), DisconnectCauseUnauthorized},
+		{disconnectedCause(disconnectedReconnectFailed), DisconnectCauseReconnectFailed},
+		{disconnectedCause(3500), DisconnectCauseUnauthorized},
+		{disconnectedCause(3504), DisconnectCauseLimitExceeded},
+	}
+	for i, tc := range testCases {
+		if tc.cause != tc.expected {
+			t.Errorf("case %d: expected %s, got %s", i, tc.expected, tc.cause)
+		}
+	}
+}
+
+func TestExtractDisconnectWebsocket_Cause(t *testing.T) {
+	testCases := []struct {
+		code     int
+		expected DisconnectCause
+	}{
+		{websocket.CloseGoingAway, DisconnectCauseProxyClose},
+		{websocket.CloseAbnormalClosure, DisconnectCauseNetworkError},
+		{websocket.CloseMessageTooBig, DisconnectCauseMessageSizeLimit},
+	}
+	for _, tc := range testCases {
+		d := extractDisconnectWebsocket(&websocket.CloseError{Code: tc.code})
+		if d == nil || d.Cause != tc.expected {
+			t.Fatalf("code %d: unexpected disconnect: %#v", tc.code, d)
+		}
+	}
+}
+
+func TestClient_DisconnectCause(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+	connecting := make(chan ConnectingEvent, 1)
+	client.OnConnecting(func(e ConnectingEvent) {
+		connecting <- e
+	})
+	client.mu.Lock()
+	client.state = StateConnected
+	client.mu.Unlock()
+
+	client.handleDisconnect(extractDisconnectWebsocket(&websocket.CloseError{Code: websocket.CloseGoingAway}))
+	e := <-connecting
+	if e.Code != connectingTransportClosed || e.Cause != DisconnectCauseProxyClose {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+}
@@ -0,0 +1,228 @@
+package centrifuge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrPublishTooLarge returned when publication data exceeds
+	// Config.MaxPublishSize. See Client.PublishChunked.
+	ErrPublishTooLarge = errors.New("publication too large")
+	// ErrNotChunk returned by ChunkAssembler.Add for data which is not a chunk
+	// published with Client.PublishChunked.
+	ErrNotChunk = errors.New("not a chunk")
+)
+
+// defaultChunkSize is a chunk size used by Client.PublishChunked if
+// Config.MaxPublishSize is not set.
+const defaultChunkSize = 64 * 1024
+
+const (
+	defaultMaxPendingChunked = 16
+	defaultMaxChunks         = 1024
+	defaultMaxChunkedSize    = 64 << 20
+)
+
+// Chunk is a part of large payload published with Client.PublishChunked. Chunk is
+// published as JSON object, so it's valid for both JSON and Protobuf protocols.
+type Chunk struct {
+	// ID is the same for all chunks of payload.
+	ID string `json:"chunk_id"`
+	// Seq is a chunk index starting from 0.
+	Seq int `json:"seq"`
+	// Total is the number of chunks of payload.
+	Total int `json:"total"`
+	// Data is a part of payload.
+	Data []byte `json:"data"`
+}
+
+// SplitChunks splits data into encoded chunks, every chunk is not larger than
+// maxSize bytes.
+func SplitChunks(data []byte, maxSize int) ([][]byte, error) {
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	// Estimate envelope size with the largest possible numbers.
+	overhead, err := json.Marshal(Chunk{ID: id, Seq: len(data), Total: len(data) + 1, Data: []byte{}})
+	if err != nil {
+		return nil, err
+	}
+	// Data is base64 encoded – 4 bytes for every 3 bytes of payload.
+	partSize := (maxSize - len(overhead)) / 4 * 3
+	if partSize <= 0 {
+		return nil, fmt.Errorf("chunk size %d is too small", maxSize)
+	}
+	total := (len(data) + partSize - 1) / partSize
+	if total == 0 {
+		total = 1
+	}
+	chunks := make([][]byte, 0, total)
+	for seq := 0; seq < total; seq++ {
+		end := min((seq+1)*partSize, len(data))
+		chunk, err := json.Marshal(Chunk{ID: id, Seq: seq, Total: total, Data: data[seq*partSize : end]})
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+func newRandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// PublishChunked publishes data into channel in chunks not larger than
+// Config.MaxPublishSize (64KB if not set). Chunks are published in order one by
+// one, subscribers restore data with ChunkAssembler. Subscribers may receive only
+// part of chunks if publish failed in the middle.
+func (c *Client) PublishChunked(ctx context.Context, channel string, data []byte, opts ...PublishOption) (PublishResult, error) {
+	chunkSize := c.config.MaxPublishSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	chunks, err := SplitChunks(data, chunkSize)
+	if err != nil {
+		return PublishResult{}, err
+	}
+	for _, chunk := range chunks {
+		if _, err := c.Publish(ctx, channel, chunk, opts...); err != nil {
+			return PublishResult{}, err
+		}
+	}
+	return PublishResult{}, nil
+}
+
+// ChunkAssembler restores payloads published with Client.PublishChunked from
+// chunks. Chunks of different payloads may interleave. It's safe for concurrent
+// use.
+type ChunkAssembler struct {
+	mu         sync.Mutex
+	maxPending int
+	maxChunks  int
+	maxSize    int
+	pending    map[string]*pendingChunks
+	// order keeps IDs of pending payloads from the oldest one.
+	order []string
+}
+
+type pendingChunks struct {
+	parts    [][]byte
+	received int
+	size     int
+}
+
+// ChunkAssemblerConfig configures ChunkAssembler. Chunks come from other
+// clients, so limits protect from crafted chunks allocating too much memory.
+type ChunkAssemblerConfig struct {
+	// MaxPending is the maximum number of incomplete payloads kept, the oldest
+	// incomplete payload is dropped when limit reached.
+	// Zero value means 16.
+	MaxPending int
+	// MaxChunks is the maximum number of chunks of one payload, chunks with
+	// larger total are rejected with error.
+	// Zero value means 1024.
+	MaxChunks int
+	// MaxSize is the maximum size of assembled payload, payload is dropped with
+	// error when its chunks exceed it.
+	// Zero value means 64 MiB.
+	MaxSize int
+}
+
+// NewChunkAssembler creates ChunkAssembler.
+func NewChunkAssembler(config ChunkAssemblerConfig) *ChunkAssembler {
+	a := &ChunkAssembler{
+		maxPending: config.MaxPending,
+		maxChunks:  config.MaxChunks,
+		maxSize:    config.MaxSize,
+		pending:    make(map[string]*pendingChunks),
+	}
+	if a.maxPending <= 0 {
+		a.maxPending = defaultMaxPendingChunked
+	}
+	if a.maxChunks <= 0 {
+		a.maxChunks = defaultMaxChunks
+	}
+	if a.maxSize <= 0 {
+		a.maxSize = defaultMaxChunkedSize
+	}
+	return a
+}
+
+// Add adds publication data. Returns payload and true when the last missing chunk
+// of payload added. Returns ErrNotChunk if data is not a chunk, so the caller may
+// handle it as a usual publication.
+func (a *ChunkAssembler) Add(data []byte) ([]byte, bool, error) {
+	var chunk Chunk
+	if err := json.Unmarshal(data, &chunk); err != nil || chunk.ID == "" || chunk.Total <= 0 {
+		return nil, false, ErrNotChunk
+	}
+	if chunk.Seq < 0 || chunk.Seq >= chunk.Total {
+		return nil, false, fmt.Errorf("chunk seq %d out of range [0, %d)", chunk.Seq, chunk.Total)
+	}
+	if chunk.Total > a.maxChunks {
+		return nil, false, fmt.Errorf("chunk total %d exceeds %d", chunk.Total, a.maxChunks)
+	}
+	if len(chunk.Data) > a.maxSize {
+		return nil, false, fmt.Errorf("chunked payload exceeds %d bytes", a.maxSize)
+	}
+	if chunk.Total == 1 {
+		return chunk.Data, true, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p, ok := a.pending[chunk.ID]
+	if !ok {
+		if len(a.order) >= a.maxPending {
+			delete(a.pending, a.order[0])
+			a.order = a.order[1:]
+		}
+		p = &pendingChunks{parts: make([][]byte, chunk.Total)}
+		a.pending[chunk.ID] = p
+		a.order = append(a.order, chunk.ID)
+	}
+	if len(p.parts) != chunk.Total {
+		return nil, false, fmt.Errorf("chunk total %d does not match %d", chunk.Total, len(p.parts))
+	}
+	if p.parts[chunk.Seq] == nil {
+		if p.size+len(chunk.Data) > a.maxSize {
+			a.removeLocked(chunk.ID)
+			return nil, false, fmt.Errorf("chunked payload exceeds %d bytes", a.maxSize)
+		}
+		// Keep empty part distinguishable from missing one.
+		p.parts[chunk.Seq] = append([]byte{}, chunk.Data...)
+		p.received++
+		p.size += len(chunk.Data)
+	}
+	if p.received < chunk.Total {
+		return nil, false, nil
+	}
+	a.removeLocked(chunk.ID)
+	payload := make([]byte, 0, p.size)
+	for _, part := range p.parts {
+		payload = append(payload, part...)
+	}
+	return payload, true, nil
+}
+
+// removeLocked drops pending payload. Lock must be held outside.
+func (a *ChunkAssembler) removeLocked(id string) {
+	delete(a.pending, id)
+	for i, pendingID := range a.order {
+		if pendingID == id {
+			a.order = append(a.order[:i], a.order[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package centrifuge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSplitChunks(t *testing.T) {
+	data := make([]byte, 10000)
+	_, _ = rand.Read(data)
+	chunks, err := SplitChunks(data, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 10 {
+		t.Fatalf("expected at least 10 chunks, got %d", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if len(chunk) > 1000 {
+			t.Fatalf("chunk size %d exceeds limit", len(chunk))
+		}
+	}
+	// Chunks arrive in any order.
+	rand.Shuffle(len(chunks), func(i, j int) { chunks[i], chunks[j] = chunks[j], chunks[i] })
+	a := NewChunkAssembler(ChunkAssemblerConfig{})
+	for i, chunk := range chunks {
+		payload, ok, err := a.Add(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != (i == len(chunks)-1) {
+			t.Fatalf("unexpected completion on chunk %d", i)
+		}
+		if ok && !bytes.Equal(payload, data) {
+			t.Fatal("payload mismatch")
+		}
+	}
+}
+
+func TestSplitChunks_Small(t *testing.T) {
+	chunks, err := SplitChunks([]byte("hello"), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	payload, ok, err := NewChunkAssembler(ChunkAssemblerConfig{}).Add(chunks[0])
+	if err != nil || !ok || string(payload) != "hello" {
+		t.Fatalf("unexpected result: %q %v %v", payload, ok, err)
+	}
+	if _, err := SplitChunks([]byte("hello"), 10); err == nil {
+		t.Fatal("expected error for too small chunk size")
+	}
+}
+
+func TestChunkAssembler_Interleaved(t *testing.T) {
+	first, _ := SplitChunks(bytes.Repeat([]byte("a"), 500), 200)
+	second, _ := SplitChunks(bytes.Repeat([]byte("b"), 500), 200)
+	a := NewChunkAssembler(ChunkAssemblerConfig{})
+	var payloads [][]byte
+	for i := range first {
+		for _, chunk := range [][]byte{first[i], second[i]} {
+			payload, ok, err := a.Add(chunk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				payloads = append(payloads, payload)
+			}
+		}
+	}
+	if len(payloads) != 2 || payloads[0][0] != 'a' || payloads[1][0] != 'b' || len(payloads[0]) != 500 || len(payloads[1]) != 500 {
+		t.Fatalf("unexpected payloads: %q", payloads)
+	}
+	if _, _, err := a.Add([]byte(`{"input": 1}`)); !errors.Is(err, ErrNotChunk) {
+		t.Fatalf("expected ErrNotChunk, got %v", err)
+	}
+}
+
+func TestChunkAssembler_MaxPending(t *testing.T) {
+	a := NewChunkAssembler(ChunkAssemblerConfig{MaxPending: 1})
+	first, _ := SplitChunks(bytes.Repeat([]byte("a"), 500), 200)
+	second, _ := SplitChunks(bytes.Repeat([]byte("b"), 500), 200)
+	_, _, _ = a.Add(first[0])
+	_, _, _ = a.Add(second[0])
+	for _, chunk := range first[1:] {
+		if _, ok, _ := a.Add(chunk); ok {
+			t.Fatal("dropped payload must not complete")
+		}
+	}
+}
+
+func TestChunkAssembler_Limits(t *testing.T) {
+	a := NewChunkAssembler(ChunkAssemblerConfig{MaxChunks: 4, MaxSize: 100})
+	// Total is checked before allocating parts.
+	if _, _, err := a.Add([]byte(`{"chunk_id":"x","seq":0,"total":1000000000000,"data":""}`)); err == nil {
+		t.Fatal("expected error for too many chunks")
+	}
+	if len(a.pending) != 0 {
+		t.Fatal("rejected chunk must not be kept")
+	}
+	chunks, err := SplitChunks(bytes.Repeat([]byte("a"), 120), 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) > 4 {
+		t.Fatalf("unexpected number of chunks %d", len(chunks))
+	}
+	var addErr error
+	for _, chunk := range chunks {
+		if _, _, err := a.Add(chunk); err != nil {
+			addErr = err
+			break
+		}
+	}
+	if addErr == nil {
+		t.Fatal("expected error for too large payload")
+	}
+	if len(a.pending) != 0 {
+		t.Fatal("too large payload must be dropped")
+	}
+}
+
+func TestClient_MaxPublishSize(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{MaxPublishSize: 300})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	data := []byte(`"` + string(bytes.Repeat([]byte("x"), 1000)) + `"`)
+	if _, err := client.Publish(ctx, "test", data); !errors.Is(err, ErrPublishTooLarge) {
+		t.Fatalf("expected ErrPublishTooLarge, got %v", err)
+	}
+	if _, err := client.PublishChunked(ctx, "test", data); err != nil {
+		t.Fatal(err)
+	}
+}
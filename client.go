@@ -2,6 +2,7 @@ package centrifuge
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/centrifugal/centrifuge-go/internal/lists"
+	"github.com/centrifugal/centrifuge-go/internal/locks"
+	"github.com/centrifugal/centrifuge-go/internal/maps"
 	"github.com/centrifugal/centrifuge-go/internal/queues"
 	"github.com/centrifugal/protocol"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -39,7 +43,7 @@ const (
 type Client struct {
 	futureID          uint64
 	cmdID             uint32
-	mu                sync.RWMutex
+	mu                locks.RWMutex
 	endpoints         []string
 	round             int
 	protocolType      protocol.Type
@@ -49,25 +53,80 @@ type Client struct {
 	transport         transport
 	disconnectedCh    chan struct{}
 	state             State
-	subs              map[string]*Subscription
+	subs              *maps.ShardedMap[*Subscription]
 	serverSubs        map[string]*serverSub
 	requestsMu        sync.RWMutex
 	requests          map[uint32]request
 	receive           chan []byte
 	reconnectAttempts int
+	reconnectHistory  []ReconnectAttempt
+	reconnectFailures int
 	reconnectStrategy reconnectStrategy
+	jitterRand        *lockedRand
 	events            *eventHub
 	sendPong          bool
-	delayPing         chan struct{}
+	pingTimer         Timer
+	pingTimeout       time.Duration
+	pingGen           uint64
 	closeCh           chan struct{}
 	connectFutures    map[uint64]connectFuture
+	closedCh          chan struct{}
+	dispatcherGoID    atomic.Uint64
+	closeCtx          context.Context
+	closeCancel       context.CancelFunc
+	serverRPCSem      chan struct{}
 	cbQueue           *queues.CallBackQueue
-	reconnectTimer    *time.Timer
-	refreshTimer      *time.Timer
+	reconnectSignal   chan struct{}
+	reconnectGen      uint64
+	reconnectPending  bool
+	reconnectDelay    time.Duration
+	retryAfter        time.Duration
+	connectionLostAt  time.Time
+	suspended         bool
+	metrics           *statsMetrics
+	clientID          string
+	connectedAt       time.Time
+	rtt               time.Duration
+	protocolDump      *protocolDumper
+	failedAttempts    []ReconnectAttempt
+	offlineQueue      *offlineQueue
+	publishAsync      *publishAsyncQueue
+	publishAsyncOnce  sync.Once
+	sendQueue         *lists.List[*protocol.Command]
+	sendQueueOnce     sync.Once
+	rateLimiter       *tokenBucket
+	refreshTimer      Timer
 	refreshRequired   bool
+	refreshAttempts   int
+	tokenFromStore    bool
 	logCh             chan LogEntry
 	logCloseCh        chan struct{}
 	logCloseOnce      sync.Once
+
+	// Concurrent identical history and presence requests share one command.
+	historyCalls       inflightGroup[historyKey, HistoryResult]
+	presenceCalls      inflightGroup[string, PresenceResult]
+	presenceStatsCalls inflightGroup[string, PresenceStatsResult]
+
+	// serverInfo is set from the last connect result.
+	serverInfo ServerInfo
+	// watchdog is nil unless Config.StallTimeout set.
+	watchdog *watchdog
+	// goroutines are long-running goroutines, see Client.Goroutines.
+	goroutines    *goroutineRegistry
+	dispatcherID  uint64
+	leakCheckOnce sync.Once
+	clock         Clock
+	// scheduler runs token refresh, resubscribe, reconnect and server ping
+	// timers.
+	scheduler *scheduler
+	// queuedFor is the time currently dispatched event waited in cbQueue. Only
+	// accessed from event dispatching goroutine.
+	queuedFor time.Duration
+	// Lock contention of client and subscriptions, nil unless
+	// Config.MeasureLockContention set.
+	lockStats    *locks.Stats
+	subLockStats *locks.Stats
 }
 
 // NewJsonClient initializes Client which uses JSON-based protocol internally.
@@ -89,10 +148,17 @@ func NewProtobufClient(endpoint string, config Config) *Client {
 }
 
 func (c *Client) logLevelEnabled(level LogLevel) bool {
+	if c.config.Logger != nil {
+		return c.config.Logger.Enabled(context.Background(), level.slogLevel())
+	}
 	return level >= c.config.LogLevel
 }
 
 func (c *Client) log(level LogLevel, message string, fields map[string]string) {
+	if c.config.Logger != nil {
+		c.logSlog(level, message, fields)
+		return
+	}
 	logEntry := LogEntry{
 		Level:   level,
 		Message: message,
@@ -159,6 +225,15 @@ func newClient(endpoint string, isProtobuf bool, config Config) *Client {
 	if config.Name == "" {
 		config.Name = "go"
 	}
+	if config.RefreshRetryMinDelay == 0 {
+		config.RefreshRetryMinDelay = 10 * time.Second
+	}
+	if config.RefreshRetryMaxDelay == 0 {
+		config.RefreshRetryMaxDelay = 60 * time.Second
+	}
+	if config.ResubscribeBatchSize <= 0 {
+		config.ResubscribeBatchSize = 100
+	}
 
 	// We support setting multiple endpoints to try in round-robin fashion. But
 	// for now this feature is not documented and used for internal tests. In most
@@ -181,28 +256,85 @@ func newClient(endpoint string, isProtobuf bool, config Config) *Client {
 		protocolType = protocol.TypeProtobuf
 	}
 
+	var jitterRand *lockedRand
+	if config.ReconnectRandSource != nil {
+		jitterRand = newLockedRand(config.ReconnectRandSource)
+	}
+
+	metrics := &statsMetrics{Metrics: noopMetrics{}}
+	if config.Metrics != nil {
+		metrics.Metrics = config.Metrics
+	}
+
 	client := &Client{
 		endpoints:         endpoints,
 		config:            config,
 		state:             StateDisconnected,
 		protocolType:      protocolType,
-		subs:              make(map[string]*Subscription),
+		subs:              maps.NewShardedMap[*Subscription](),
 		serverSubs:        make(map[string]*serverSub),
 		requests:          make(map[uint32]request),
-		reconnectStrategy: defaultBackoffReconnect,
-		delayPing:         make(chan struct{}, 32),
-		events:            newEventHub(),
+		reconnectStrategy: newBackoff(config, jitterRand, defaultBackoffReconnect.MinDelay, defaultBackoffReconnect.MaxDelay),
+		jitterRand:        jitterRand,
+		events:            newEventHub(config.EventReplaySize),
 		connectFutures:    make(map[uint64]connectFuture),
+		closedCh:          make(chan struct{}),
+		reconnectSignal:   make(chan struct{}, 1),
 		token:             config.Token,
 		data:              config.Data,
 		logCh:             make(chan LogEntry, 256),
 		logCloseCh:        make(chan struct{}),
+		metrics:           metrics,
+		goroutines:        newGoroutineRegistry(),
+		clock:             config.Clock,
+	}
+	if client.clock == nil {
+		client.clock = realClock{}
+	}
+	client.scheduler = newScheduler(client.clock, client.spawn)
+
+	client.mu.SetClass("client")
+	if config.MeasureLockContention {
+		client.lockStats = &locks.Stats{}
+		client.subLockStats = &locks.Stats{}
+		client.mu.SetStats(client.lockStats)
+	}
+	if config.OfflineQueue != nil {
+		client.offlineQueue = newOfflineQueue(*config.OfflineQueue)
+	}
+	client.publishAsync = newPublishAsyncQueue(config.PublishAsyncQueueSize)
+	client.sendQueue = lists.NewList[*protocol.Command]()
+	client.closeCtx, client.closeCancel = context.WithCancel(context.Background())
+	maxServerRPC := config.MaxConcurrentServerRPC
+	if maxServerRPC <= 0 {
+		maxServerRPC = defaultMaxConcurrentServerRPC
+	}
+	client.serverRPCSem = make(chan struct{}, maxServerRPC)
+	if config.CommandRateLimit != nil {
+		client.rateLimiter = newCommandRateLimiter(*config.CommandRateLimit)
+	}
+	if config.ProtocolDump != nil {
+		client.protocolDump = newProtocolDumper(config.ProtocolDump, protocolType, config.ProtocolDumpRedactTokens)
 	}
 
 	// Queue to run callbacks on.
-	client.cbQueue = queues.OpenCallBackQueue()
+	client.cbQueue = openEventQueue(config)
+	client.dispatcherID = client.goroutines.add("dispatcher")
+	if config.StallTimeout > 0 {
+		client.watchdog = newWatchdog(client, config.StallTimeout)
+		client.spawn("watchdog", client.watchdog.run)
+	}
+	client.spawn("reconnect_loop", client.reconnectLoop)
+	if config.NetworkMonitor != nil {
+		client.watchNetwork(config.NetworkMonitor)
+	}
 	if client.config.LogLevel > 0 {
-		go client.handleLogs()
+		client.spawn("logs", client.handleLogs)
+	}
+	if client.offlineQueue != nil {
+		if err := client.offlineQueue.restore(); err != nil && client.logLevelEnabled(LogLevelDebug) {
+			client.log(LogLevelDebug, "error loading offline queue from spool", map[string]string{"error": err.Error()})
+		}
 	}
 	return client
 }
@@ -224,17 +356,100 @@ func (c *Client) Disconnect() error {
 	return nil
 }
 
+// Reconnect establishes a new connection with a server. If client is connected then
+// current connection is closed first, if client is already connecting then the next
+// attempt is used. Connection token is requested again using Config.GetToken or
+// Config.TokenProvider (if set) – so Reconnect is useful after user re-authenticated
+// in response to OnAuthRequired event. Reconnect attempts counter is reset.
+func (c *Client) Reconnect() error {
+	c.mu.Lock()
+	if c.state == StateClosed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+	if c.config.GetToken != nil || c.config.TokenProvider != nil {
+		c.refreshRequired = true
+	}
+	c.reconnectAttempts = 0
+	state := c.state
+	c.mu.Unlock()
+	if state == StateDisconnected {
+		return c.startConnecting()
+	}
+	c.moveToConnecting(connectingReconnectCalled, "reconnect called")
+	return nil
+}
+
+// Suspend pauses connection for planned network transitions (for example, device
+// going to sleep or VPN switch). Connection is closed and client moves to connecting
+// state – subscriptions move to subscribing state and keep their positions – but
+// no reconnect attempts are made until Client.Resume called. Disconnect or Close
+// may be called while suspended as usual.
+func (c *Client) Suspend() error {
+	c.mu.Lock()
+	if c.state == StateClosed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+	if c.state == StateDisconnected || c.suspended {
+		c.mu.Unlock()
+		return nil
+	}
+	c.suspended = true
+	if c.state == StateConnecting {
+		// Stop reconnect timer and connection attempt in progress.
+		if c.transport != nil {
+			_ = c.transport.Close()
+			c.transport = nil
+		}
+		c.clearConnectedState()
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+	c.moveToConnecting(connectingSuspended, "suspended")
+	return nil
+}
+
+// Resume connects suspended client immediately, skipping reconnect backoff, and
+// restores subscriptions. Does nothing if client is not suspended.
+func (c *Client) Resume() error {
+	c.mu.Lock()
+	if c.state == StateClosed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+	if !c.suspended {
+		c.mu.Unlock()
+		return nil
+	}
+	c.suspended = false
+	c.reconnectAttempts = 0
+	c.mu.Unlock()
+	return c.startReconnecting()
+}
+
 // Close closes Client and cleanups resources. Client is unusable after this. Use this
 // method if you don't need client anymore, otherwise look at Client.Disconnect.
+//
+// Close has strict ordering guarantees: all client-side subscriptions receive
+// OnUnsubscribed event with unsubscribedClientClosed code, then server-side
+// subscriptions receive OnUnsubscribed, then OnDisconnected called (if client was not
+// already disconnected). All event handlers are completed by the time Close returns,
+// including when Close called concurrently from several goroutines. Close blocks
+// until event handlers complete. When called from an event handler Close does not
+// wait: remaining events are delivered after the handler returns.
 func (c *Client) Close() {
-	if c.isClosed() {
-		return
-	}
-	c.moveToDisconnected(disconnectedDisconnectCalled, "disconnect called")
 	c.moveToClosed()
+	c.flushPositions()
 	c.logCloseOnce.Do(func() {
 		close(c.logCloseCh)
 	})
+	c.watchdog.stop()
+	c.scheduler.stop()
+	c.leakCheckOnce.Do(func() {
+		go c.checkGoroutineLeaks()
+	})
 }
 
 // State returns current Client state. Note that while you are processing
@@ -260,14 +475,13 @@ func (c *Client) SetToken(token string) {
 // you can remove it from the internal registry by calling Client.RemoveSubscription
 // method.
 func (c *Client) NewSubscription(channel string, config ...SubscriptionConfig) (*Subscription, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	var sub *Subscription
-	if _, ok := c.subs[channel]; ok {
+	sub := newSubscription(c, channel, config...)
+	if err := sub.restorePosition(); err != nil {
+		return nil, PositionStoreError{err}
+	}
+	if !c.subs.StoreIfAbsent(channel, sub) {
 		return nil, ErrDuplicateSubscription
 	}
-	sub = newSubscription(c, channel, config...)
-	c.subs[channel] = sub
 	return sub, nil
 }
 
@@ -277,37 +491,41 @@ func (c *Client) RemoveSubscription(sub *Subscription) error {
 	if sub.State() != SubStateUnsubscribed {
 		return errors.New("subscription must be unsubscribed to be removed")
 	}
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	delete(c.subs, sub.Channel)
+	c.subs.Delete(sub.Channel)
 	return nil
 }
 
 // GetSubscription allows getting Subscription from the internal client registry.
 func (c *Client) GetSubscription(channel string) (*Subscription, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	s, ok := c.subs[channel]
-	return s, ok
+	return c.subs.Load(channel)
 }
 
 // Subscriptions returns a map with all currently registered client-side subscriptions.
 func (c *Client) Subscriptions() map[string]*Subscription {
 	subs := make(map[string]*Subscription)
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	for k, v := range c.subs {
+	c.subs.Range(func(k string, v *Subscription) bool {
 		subs[k] = v
-	}
+		return true
+	})
 	return subs
 }
 
 // Send message to server without waiting for response.
-// Message handler must be registered on server.
-func (c *Client) Send(ctx context.Context, data []byte) error {
+// Message handler must be registered on server. Send waits until client
+// connected and message written to connection, so returned nil error confirms
+// message reached the socket. Use WithSendNoWait to return immediately.
+func (c *Client) Send(ctx context.Context, data []byte, opts ...SendOption) error {
 	if c.isClosed() {
 		return ErrClientClosed
 	}
+	sendOpts := SendOptions{}
+	for _, opt := range opts {
+		opt(&sendOpts)
+	}
+	if sendOpts.NoWait {
+		c.sendNoWait(&protocol.Command{Send: &protocol.SendRequest{Data: data}})
+		return nil
+	}
 	errCh := make(chan error, 1)
 	c.onConnect(func(err error) {
 		if err != nil {
@@ -343,13 +561,17 @@ type RPCResult struct {
 
 // RPC allows sending data to a server and waiting for a response.
 // RPC handler must be registered on server.
-func (c *Client) RPC(ctx context.Context, method string, data []byte) (RPCResult, error) {
+func (c *Client) RPC(ctx context.Context, method string, data []byte, opts ...RPCOption) (RPCResult, error) {
 	if c.isClosed() {
 		return RPCResult{}, ErrClientClosed
 	}
+	rpcOpts := &RPCOptions{}
+	for _, opt := range opts {
+		opt(rpcOpts)
+	}
 	resCh := make(chan RPCResult, 1)
 	errCh := make(chan error, 1)
-	c.sendRPC(ctx, method, data, func(result RPCResult, err error) {
+	c.sendRPC(ctx, method, data, *rpcOpts, func(result RPCResult, err error) {
 		resCh <- result
 		errCh <- err
 	})
@@ -379,13 +601,49 @@ func (c *Client) isClosed() bool {
 }
 
 func (c *Client) isSubscribed(channel string) bool {
-	c.mu.RLock()
-	_, ok := c.subs[channel]
-	c.mu.RUnlock()
+	_, ok := c.subs.Load(channel)
 	return ok
 }
 
-func (c *Client) sendRPC(ctx context.Context, method string, data []byte, fn func(RPCResult, error)) {
+type SendOptions struct {
+	// NoWait makes Send return without waiting for connect and write. Message is
+	// queued and written in order of Send calls by a separate goroutine, write
+	// errors are not reported then.
+	NoWait bool
+}
+
+type SendOption func(options *SendOptions)
+
+// WithSendNoWait makes Send return immediately without confirmation that message
+// was written to connection. Useful for low-value telemetry.
+func WithSendNoWait() SendOption {
+	return func(options *SendOptions) {
+		options.NoWait = true
+	}
+}
+
+type RPCOptions struct {
+	// Priority of RPC command, see Priority.
+	Priority Priority
+	// Codec encodes and decodes RPCCall and RegisterRPC payloads, JSONCodec by
+	// default.
+	Codec RPCCodec
+	// PropagateDeadline wraps RPC data into RPCEnvelope with remaining time of
+	// context deadline, see WithRPCDeadlinePropagation.
+	PropagateDeadline bool
+}
+
+type RPCOption func(options *RPCOptions)
+
+// WithRPCPriority sets priority of RPC command. Commands with higher priority are
+// written first when connection is slow.
+func WithRPCPriority(priority Priority) RPCOption {
+	return func(options *RPCOptions) {
+		options.Priority = priority
+	}
+}
+
+func (c *Client) sendRPC(ctx context.Context, method string, data []byte, opts RPCOptions, fn func(RPCResult, error)) {
 	c.onConnect(func(err error) {
 		select {
 		case <-ctx.Done():
@@ -397,6 +655,17 @@ func (c *Client) sendRPC(ctx context.Context, method string, data []byte, fn fun
 			fn(RPCResult{}, err)
 			return
 		}
+		if !c.allowCommand(ThrottledEvent{Command: "rpc", Method: method}) {
+			fn(RPCResult{}, ErrRateLimited)
+			return
+		}
+		if opts.PropagateDeadline {
+			data, err = deadlineEnvelope(ctx, data)
+			if err != nil {
+				fn(RPCResult{}, err)
+				return
+			}
+		}
 		cmd := &protocol.Command{
 			Id: c.nextCmdID(),
 		}
@@ -408,11 +677,15 @@ func (c *Client) sendRPC(ctx context.Context, method string, data []byte, fn fun
 
 		cmd.Rpc = params
 
-		err = c.sendAsync(cmd, func(r *protocol.Reply, err error) {
+		started := time.Now()
+		replied := c.watchRPCCancel(ctx, method, cmd.Id)
+		err = c.sendAsyncPriority(cmd, opts.Priority, func(r *protocol.Reply, err error) {
+			replied()
 			if err != nil {
 				fn(RPCResult{}, err)
 				return
 			}
+			c.metrics.ObserveRPCDuration(method, time.Since(started))
 			if r.Error != nil {
 				fn(RPCResult{}, errorFromProto(r.Error))
 				return
@@ -420,6 +693,7 @@ func (c *Client) sendRPC(ctx context.Context, method string, data []byte, fn fun
 			fn(RPCResult{Data: r.Rpc.Data}, nil)
 		})
 		if err != nil {
+			replied()
 			fn(RPCResult{}, err)
 			return
 		}
@@ -432,6 +706,15 @@ func (c *Client) moveToDisconnected(code uint32, reason string) {
 		c.mu.Unlock()
 		return
 	}
+	emitEvents := c.moveToDisconnectedLocked(code, reason)
+	c.mu.Unlock()
+	emitEvents()
+}
+
+// moveToDisconnectedLocked changes client state to disconnected and returns a
+// function to run event handlers – it must be called without lock held.
+// Lock must be held outside.
+func (c *Client) moveToDisconnectedLocked(code uint32, reason string) func() {
 	if c.transport != nil {
 		_ = c.transport.Close()
 		c.transport = nil
@@ -439,25 +722,26 @@ func (c *Client) moveToDisconnected(code uint32, reason string) {
 
 	prevState := c.state
 	c.state = StateDisconnected
+	c.metrics.IncDisconnects(code)
 	c.clearConnectedState()
 	c.resolveConnectFutures(ErrClientDisconnected)
+	c.reconnectHistory = nil
+	c.connectionLostAt = time.Time{}
+	c.suspended = false
+	event := DisconnectedEvent{Code: code, Reason: reason, Cause: disconnectedCause(code), RetryAfter: c.retryAfter}
+	c.retryAfter = 0
 
-	subsToUnsubscribe := make([]*Subscription, 0, len(c.subs))
-	for _, s := range c.subs {
-		s.mu.Lock()
-		if s.state == SubStateUnsubscribed {
-			s.mu.Unlock()
-			continue
-		}
-		s.mu.Unlock()
-		subsToUnsubscribe = append(subsToUnsubscribe, s)
-	}
+	subsToUnsubscribe := c.activeSubs()
 	serverSubsToUnsubscribe := make([]string, 0, len(c.serverSubs))
 	for ch := range c.serverSubs {
 		serverSubsToUnsubscribe = append(serverSubsToUnsubscribe, ch)
 	}
-	c.mu.Unlock()
+	return func() {
+		c.emitDisconnectedEvents(prevState, subsToUnsubscribe, serverSubsToUnsubscribe, event)
+	}
+}
 
+func (c *Client) emitDisconnectedEvents(prevState State, subsToUnsubscribe []*Subscription, serverSubsToUnsubscribe []string, event DisconnectedEvent) {
 	for _, s := range subsToUnsubscribe {
 		s.moveToSubscribing(subscribingTransportClosed, "transport closed")
 	}
@@ -476,19 +760,34 @@ func (c *Client) moveToDisconnected(code uint32, reason string) {
 		}
 	}
 
-	var handler DisconnectHandler
-	if c.events != nil && c.events.onDisconnected != nil {
-		handler = c.events.onDisconnected
-	}
-	if handler != nil {
+	if handler := c.disconnectedHandler(event); handler != nil {
 		c.runHandlerAsync(func() {
-			event := DisconnectedEvent{Code: code, Reason: reason}
 			handler(event)
 		})
 	}
+
+	if required, reconnectAllowed := authRequired(event.Code); required {
+		var authRequiredHandler AuthRequiredHandler
+		if c.events != nil && c.events.onAuthRequired != nil {
+			authRequiredHandler = c.events.onAuthRequired
+		}
+		if authRequiredHandler != nil {
+			c.runHandlerAsync(func() {
+				authRequiredHandler(AuthRequiredEvent{
+					Code:             event.Code,
+					Reason:           event.Reason,
+					ReconnectAllowed: reconnectAllowed,
+				})
+			})
+		}
+	}
 }
 
 func (c *Client) moveToConnecting(code uint32, reason string) {
+	c.moveToConnectingWithCause(code, reason, connectingCause(code))
+}
+
+func (c *Client) moveToConnectingWithCause(code uint32, reason string, cause DisconnectCause) {
 	if c.logLevelEnabled(LogLevelDebug) {
 		c.log(LogLevelDebug, "moving client to connecting state", map[string]string{
 			"code":   strconv.Itoa(int(code)),
@@ -514,6 +813,9 @@ func (c *Client) moveToConnecting(code uint32, reason string) {
 	}
 
 	c.state = StateConnecting
+	c.connectionLostAt = time.Now()
+	c.metrics.IncDisconnects(code)
+	retryAfter := c.retryAfter
 	if c.logLevelEnabled(LogLevelDebug) {
 		c.log(LogLevelDebug, "client moved to connecting state", nil)
 	}
@@ -526,16 +828,7 @@ func (c *Client) moveToConnecting(code uint32, reason string) {
 		c.log(LogLevelDebug, "resolved connect futures", nil)
 	}
 
-	subsToUnsubscribe := make([]*Subscription, 0, len(c.subs))
-	for _, s := range c.subs {
-		s.mu.Lock()
-		if s.state == SubStateUnsubscribed {
-			s.mu.Unlock()
-			continue
-		}
-		s.mu.Unlock()
-		subsToUnsubscribe = append(subsToUnsubscribe, s)
-	}
+	subsToUnsubscribe := c.activeSubs()
 	serverSubsToUnsubscribe := make([]string, 0, len(c.serverSubs))
 	for ch := range c.serverSubs {
 		serverSubsToUnsubscribe = append(serverSubsToUnsubscribe, ch)
@@ -568,13 +861,9 @@ func (c *Client) moveToConnecting(code uint32, reason string) {
 		})
 	}
 
-	var handler ConnectingHandler
-	if c.events != nil && c.events.onConnecting != nil {
-		handler = c.events.onConnecting
-	}
-	if handler != nil {
+	event := ConnectingEvent{Code: code, Reason: reason, Cause: cause, RetryAfter: retryAfter}
+	if handler := c.connectingHandler(event); handler != nil {
 		c.runHandlerSync(func() {
-			event := ConnectingEvent{Code: code, Reason: reason}
 			handler(event)
 		})
 	}
@@ -592,44 +881,80 @@ func (c *Client) moveToConnecting(code uint32, reason string) {
 		c.mu.Unlock()
 		return
 	}
-	c.scheduleReconnectLocked()
+	c.scheduleReconnectLocked(nil)
 	c.mu.Unlock()
 }
 
-func (c *Client) scheduleReconnectLocked() {
+// scheduleReconnectLocked schedules the next connection attempt. Error is a reason
+// of failed attempt, nil if connection was just lost. If reconnect budget (see
+// Config.MaxReconnectAttempts and Config.ReconnectWindow) is exhausted then client
+// moves to disconnected state instead.
+// Lock must be held outside.
+func (c *Client) scheduleReconnectLocked(err error) {
+	if c.suspended {
+		if c.logLevelEnabled(LogLevelDebug) {
+			c.log(LogLevelDebug, "client suspended, no need to reconnect", nil)
+		}
+		return
+	}
+	if err != nil {
+		c.recordFailedAttemptLocked(err)
+		if c.reconnectBudgetExhaustedLocked(err) {
+			c.failReconnectLocked()
+			return
+		}
+	}
 	c.reconnectAttempts++
+	c.metrics.IncReconnectAttempts()
 	reconnectDelay := c.getReconnectDelay()
+	// Delay requested by a server takes precedence over backoff.
+	if retryAfter, ok := retryAfterFromError(err); ok {
+		reconnectDelay = retryAfter
+	} else if c.retryAfter > 0 {
+		reconnectDelay = c.retryAfter
+	}
+	c.retryAfter = 0
+	if c.config.ReconnectCoordinator != nil {
+		if wait := c.config.ReconnectCoordinator.reserve(time.Now()); wait > reconnectDelay {
+			reconnectDelay = wait
+		}
+	}
 	if c.logLevelEnabled(LogLevelDebug) {
 		c.log(LogLevelDebug, "reconnect with delay", map[string]string{
 			"delay": reconnectDelay.String(),
 		})
 	}
-	c.reconnectTimer = time.AfterFunc(reconnectDelay, func() {
-		if c.logLevelEnabled(LogLevelDebug) {
-			c.log(LogLevelDebug, "reconnect timer fired, start reconnecting", nil)
-		}
-		_ = c.startReconnecting()
-	})
+	c.reconnectGen++
+	c.reconnectPending = true
+	c.reconnectDelay = reconnectDelay
+	c.signalReconnectLoop()
 }
 
 func (c *Client) moveToClosed() {
 	c.mu.Lock()
 	if c.state == StateClosed {
+		closedCh := c.closedCh
 		c.mu.Unlock()
+		if c.onDispatcher() {
+			// Events can't be delivered until handler returns.
+			return
+		}
+		// Concurrent Close may still be in progress.
+		<-closedCh
 		return
 	}
+	c.closeCancel()
+	if c.transport != nil {
+		_ = c.transport.Close()
+		c.transport = nil
+	}
+
+	prevState := c.state
 	c.state = StateClosed
+	c.clearConnectedState()
+	c.resolveConnectFutures(ErrClientDisconnected)
 
-	subsToUnsubscribe := make([]*Subscription, 0, len(c.subs))
-	for _, s := range c.subs {
-		s.mu.Lock()
-		if s.state == SubStateUnsubscribed {
-			s.mu.Unlock()
-			continue
-		}
-		s.mu.Unlock()
-		subsToUnsubscribe = append(subsToUnsubscribe, s)
-	}
+	subsToUnsubscribe := c.activeSubs()
 	serverSubsToUnsubscribe := make([]string, 0, len(c.serverSubs))
 	for ch := range c.serverSubs {
 		serverSubsToUnsubscribe = append(serverSubsToUnsubscribe, ch)
@@ -640,6 +965,10 @@ func (c *Client) moveToClosed() {
 		s.moveToUnsubscribed(unsubscribedClientClosed, "client closed")
 	}
 
+	if c.offlineQueue != nil {
+		c.offlineQueue.close()
+	}
+
 	var serverUnsubscribedHandler ServerUnsubscribedHandler
 	if c.events != nil && c.events.onServerUnsubscribed != nil {
 		serverUnsubscribedHandler = c.events.onServerUnsubscribed
@@ -652,6 +981,28 @@ func (c *Client) moveToClosed() {
 		})
 	}
 
+	if prevState != StateDisconnected {
+		event := DisconnectedEvent{Code: disconnectedDisconnectCalled, Reason: "disconnect called", Cause: DisconnectCauseClientRequest}
+		if handler := c.disconnectedHandler(event); handler != nil {
+			c.runHandlerAsync(func() {
+				handler(event)
+			})
+		}
+	}
+
+	if c.onDispatcher() {
+		// Called from event handler: the reader goroutine and the queued events
+		// wait for it to return, so the queue is closed in background.
+		c.spawn("close_events", c.closeEventQueue)
+		return
+	}
+	c.closeEventQueue()
+}
+
+// closeEventQueue waits for events emitted before close to be handled and closes
+// event queue.
+func (c *Client) closeEventQueue() {
+	defer close(c.closedCh)
 	c.mu.RLock()
 	disconnectedCh := c.disconnectedCh
 	c.mu.RUnlock()
@@ -660,12 +1011,23 @@ func (c *Client) moveToClosed() {
 	if disconnectedCh != nil {
 		<-disconnectedCh
 	}
+	// Wait for all callbacks pushed so far to complete.
+	c.runHandlerSync(func() {})
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.disconnectedCh = nil
+	// Queue is not set to nil: handlers pushed by goroutines which are still
+	// running are rejected by closed queue.
 	c.cbQueue.Close()
-	c.cbQueue = nil
+	c.goroutines.remove(c.dispatcherID)
+}
+
+// onDispatcher reports whether it's called from event handler running on event
+// queue goroutine.
+func (c *Client) onDispatcher() bool {
+	id := c.dispatcherGoID.Load()
+	return id != 0 && id == curGoroutineID()
 }
 
 func (c *Client) handleError(err error) {
@@ -682,14 +1044,22 @@ func (c *Client) handleError(err error) {
 
 // Lock must be held outside.
 func (c *Client) clearConnectedState() {
-	if c.reconnectTimer != nil {
-		c.reconnectTimer.Stop()
-		c.reconnectTimer = nil
+	c.clientID = ""
+	c.connectedAt = time.Time{}
+	c.rtt = 0
+	if c.reconnectPending {
+		c.reconnectGen++
+		c.reconnectPending = false
+		c.signalReconnectLoop()
 	}
 	if c.refreshTimer != nil {
 		c.refreshTimer.Stop()
 		c.refreshTimer = nil
 	}
+	if c.pingTimer != nil {
+		c.pingTimer.Stop()
+		c.pingTimer = nil
+	}
 	if c.closeCh != nil {
 		close(c.closeCh)
 		c.closeCh = nil
@@ -718,24 +1088,33 @@ func (c *Client) handleDisconnect(d *disconnect) {
 			Reconnect: true,
 		}
 	}
+	if d.RetryAfter > 0 && c.config.CloseRetryAfter {
+		c.mu.Lock()
+		c.retryAfter = time.Duration(d.RetryAfter) * time.Second
+		c.mu.Unlock()
+	}
 	if d.Reconnect {
-		c.moveToConnecting(d.Code, d.Reason)
+		if d.Cause != "" {
+			c.moveToConnectingWithCause(d.Code, d.Reason, d.Cause)
+		} else {
+			c.moveToConnecting(d.Code, d.Reason)
+		}
 	} else {
 		c.moveToDisconnected(d.Code, d.Reason)
 	}
 }
 
-func (c *Client) waitServerPing(disconnectCh chan struct{}, pingInterval uint32) {
-	timeout := c.config.MaxServerPingDelay + time.Duration(pingInterval)*time.Second
-	for {
-		select {
-		case <-c.delayPing:
-		case <-time.After(timeout):
-			go c.handleDisconnect(&disconnect{Code: connectingNoPing, Reason: "no ping", Reconnect: true})
-		case <-disconnectCh:
-			return
-		}
+// handleNoPing reconnects when server has not sent ping in time. Timer of
+// previous connection may fire concurrently with disconnect, gen protects from
+// it.
+func (c *Client) handleNoPing(gen uint64) {
+	c.mu.RLock()
+	stale := c.pingTimer == nil || c.pingGen != gen
+	c.mu.RUnlock()
+	if stale {
+		return
 	}
+	c.handleDisconnect(&disconnect{Code: connectingNoPing, Reason: "no ping", Reconnect: true})
 }
 
 func (c *Client) readOnce(t transport) error {
@@ -744,7 +1123,9 @@ func (c *Client) readOnce(t transport) error {
 		go c.handleDisconnect(disconnect)
 		return err
 	}
+	c.watchdog.begin(componentReader)
 	c.handle(reply)
+	c.watchdog.end(componentReader)
 	return nil
 }
 
@@ -758,26 +1139,161 @@ func (c *Client) reader(t transport, disconnectCh chan struct{}) {
 	}
 }
 
+// runHandlerSync runs handler of state event and waits for it. State events
+// ignore event queue size limit: they are never dropped and never wait for free
+// space. Called from event handler it does not wait – handler can't wait for
+// itself.
 func (c *Client) runHandlerSync(fn func()) {
+	if c.config.SyncHandlers {
+		c.runCallback(fn, 0)
+		return
+	}
+	if c.onDispatcher() {
+		c.runHandlerAsync(fn)
+		return
+	}
 	waitCh := make(chan struct{})
 	c.mu.RLock()
-	cb := func(_ context.Context, _ time.Duration) {
+	cb := func(ctx context.Context, delay time.Duration) {
 		defer close(waitCh)
-		fn()
+		if ctx.Err() != nil {
+			return
+		}
+		c.runCallback(fn, delay)
 	}
-	if err := c.cbQueue.Push(cb); err != nil {
+	if err := c.cbQueue.ForcePush(cb); err != nil {
 		c.log(LogLevelDebug, "runHandlerSync failed to push callback to queue", map[string]string{"reason": err.Error()})
+		c.mu.RUnlock()
+		return
 	}
 	c.mu.RUnlock()
+	c.reportQueueDepth()
 	<-waitCh
 }
 
+// runHandlerAsync runs handler of state event asynchronously ignoring event queue
+// size limit, so it's safe to call from event handlers.
 func (c *Client) runHandlerAsync(fn func()) {
-	cb := func(_ context.Context, _ time.Duration) {
-		fn()
+	if c.config.SyncHandlers {
+		c.runCallback(fn, 0)
+		return
+	}
+	cb := func(ctx context.Context, delay time.Duration) {
+		if ctx.Err() != nil {
+			return
+		}
+		c.runCallback(fn, delay)
 	}
-	if err := c.cbQueue.Push(cb); err != nil {
+	if err := c.cbQueue.ForcePush(cb); err != nil {
 		c.log(LogLevelDebug, "runHandlerAsync failed to push callback to queue", map[string]string{"reason": err.Error()})
+		return
+	}
+	c.reportQueueDepth()
+}
+
+// runDataHandler runs handler of publication, join, leave or message event and
+// waits for it. Unlike state events these are subject to Config.EventQueuePolicy
+// when event queue is full.
+func (c *Client) runDataHandler(fn func()) {
+	if c.config.SyncHandlers {
+		c.runCallback(fn, 0)
+		return
+	}
+	if c.onDispatcher() {
+		// Waiting for free space would block queue forever.
+		c.runHandlerAsync(fn)
+		return
+	}
+	waitCh := make(chan struct{})
+	// Wait for space in full event queue before taking lock, so that event
+	// handlers are not blocked by the waiting producer.
+	if err := c.cbQueue.WaitSpace(); err != nil {
+		c.log(LogLevelDebug, "runDataHandler failed to push callback to queue", map[string]string{"reason": err.Error()})
+		return
+	}
+	c.mu.RLock()
+	cb := func(ctx context.Context, delay time.Duration) {
+		defer close(waitCh)
+		if ctx.Err() != nil {
+			return
+		}
+		c.runCallback(fn, delay)
+	}
+	if err := c.cbQueue.PushNoWait(cb); err != nil {
+		c.log(LogLevelDebug, "runDataHandler failed to push callback to queue", map[string]string{"reason": err.Error()})
+		c.mu.RUnlock()
+		if errors.Is(err, queues.ErrQueueFull) {
+			c.handleEventQueueFull()
+		}
+		return
+	}
+	c.mu.RUnlock()
+	c.reportQueueDepth()
+	<-waitCh
+}
+
+// runCallback runs event handler from callback queue, measures its execution time
+// and reports slow handlers. The delay is the time handler waited in queue.
+func (c *Client) runCallback(fn func(), delay time.Duration) {
+	started := time.Now()
+	if c.config.SyncHandlers {
+		// Called inline, possibly concurrently from different goroutines.
+		c.callHandler(fn)
+	} else {
+		if c.dispatcherGoID.Load() == 0 {
+			c.dispatcherGoID.Store(curGoroutineID())
+		}
+		c.watchdog.begin(componentDispatcher)
+		c.queuedFor = delay
+		c.callHandler(fn)
+		c.watchdog.end(componentDispatcher)
+	}
+	duration := time.Since(started)
+	c.metrics.ObserveCallbackDelay(delay)
+	c.metrics.ObserveCallbackDuration(duration)
+	c.reportQueueDepth()
+	if c.config.SlowHandlerThreshold <= 0 || duration < c.config.SlowHandlerThreshold {
+		return
+	}
+	if c.logLevelEnabled(LogLevelDebug) {
+		c.log(LogLevelDebug, "slow event handler", map[string]string{
+			"duration": duration.String(),
+			"delay":    delay.String(),
+		})
+	}
+	var handler SlowHandlerHandler
+	if c.events != nil && c.events.onSlowHandler != nil {
+		handler = c.events.onSlowHandler
+	}
+	if handler != nil {
+		event := SlowHandlerEvent{Duration: duration, Delay: delay, QueueDepth: c.cbQueue.Len()}
+		if c.config.SyncHandlers {
+			c.callHandler(func() {
+				handler(event)
+			})
+			return
+		}
+		// Pushed directly to queue, so slow OnSlowHandler itself is not reported.
+		_ = c.cbQueue.ForcePush(func(ctx context.Context, _ time.Duration) {
+			// Dropped or discarded on close, handler must not run on pushing
+			// goroutine.
+			if ctx.Err() != nil {
+				return
+			}
+			defer c.recoverCallback("OnSlowHandler")
+			handler(event)
+		})
+	}
+}
+
+func (c *Client) callHandler(fn func()) {
+	defer c.recoverCallback("event handler")
+	fn()
+}
+
+func (c *Client) reportQueueDepth() {
+	if c.config.Metrics != nil {
+		c.metrics.SetCallbackQueueDepth(c.cbQueue.Len())
 	}
 }
 
@@ -786,55 +1302,77 @@ func (c *Client) handle(reply *protocol.Reply) {
 		if c.logLevelEnabled(LogLevelTrace) {
 			c.traceInReply(reply)
 		}
-		c.requestsMu.RLock()
-		req, ok := c.requests[reply.Id]
-		c.requestsMu.RUnlock()
+		// Remove request before calling callback, so it's not called again with
+		// an error if client disconnects or request times out concurrently.
+		req, ok := c.takeRequest(reply.Id)
 		if ok {
 			if req.cb != nil {
 				req.cb(reply, nil)
 			}
+		} else if c.logLevelEnabled(LogLevelDebug) {
+			c.log(LogLevelDebug, "reply for unknown request dropped", map[string]string{
+				"id": strconv.FormatUint(uint64(reply.Id), 10),
+			})
 		}
-		c.removeRequest(reply.Id)
 	} else {
 		if reply.Push == nil {
 			if c.logLevelEnabled(LogLevelTrace) {
 				c.traceInReply(reply)
 			}
 			// Ping from server, send pong if needed.
-			select {
-			case c.delayPing <- struct{}{}:
-			default:
-			}
 			c.mu.RLock()
+			if c.pingTimer != nil {
+				c.pingTimer.Reset(c.pingTimeout)
+			}
 			sendPong := c.sendPong
 			c.mu.RUnlock()
 			if sendPong {
 				cmd := &protocol.Command{}
 				_ = c.send(cmd)
 			}
+			if c.config.MeasureRTT {
+				go c.measureRTT()
+			}
 			return
 		}
 		if c.logLevelEnabled(LogLevelTrace) {
 			c.traceInPush(reply.Push)
 		}
-		c.mu.Lock()
-		if c.state != StateConnected {
-			c.mu.Unlock()
+		if !c.isConnected() {
+			if c.logLevelEnabled(LogLevelDebug) {
+				c.log(LogLevelDebug, "push dropped, client not connected", map[string]string{
+					"channel": reply.Push.Channel,
+				})
+			}
 			return
 		}
-		c.mu.Unlock()
 		c.handlePush(reply.Push)
 	}
 }
 
 func (c *Client) handleMessage(msg *protocol.Message) error {
+	if c.events != nil && c.events.rpcMux != nil {
+		if req, ok := decodeServerRPC(msg.Data); ok {
+			select {
+			case c.serverRPCSem <- struct{}{}:
+				c.spawn("server RPC", func() {
+					defer func() { <-c.serverRPCSem }()
+					c.handleServerRPC(req)
+				})
+			default:
+				c.rejectServerRPC(req)
+			}
+			return nil
+		}
+	}
 	var handler MessageHandler
 	if c.events != nil && c.events.onMessage != nil {
 		handler = c.events.onMessage
 	}
 	if handler != nil {
 		event := MessageEvent{Data: msg.Data}
-		c.runHandlerSync(func() {
+		c.runDataHandler(func() {
+			event.QueuedFor = c.queuedFor
 			handler(event)
 		})
 	}
@@ -843,9 +1381,7 @@ func (c *Client) handleMessage(msg *protocol.Message) error {
 
 func (c *Client) handlePush(push *protocol.Push) {
 	channel := push.Channel
-	c.mu.RLock()
-	sub, ok := c.subs[channel]
-	c.mu.RUnlock()
+	sub, ok := c.subs.Load(channel)
 	switch {
 	case push.Message != nil:
 		_ = c.handleMessage(push.Message)
@@ -856,6 +1392,7 @@ func (c *Client) handlePush(push *protocol.Push) {
 		}
 		sub.handleUnsubscribe(push.Unsubscribe)
 	case push.Pub != nil:
+		c.metrics.IncPublications(channel)
 		if !ok {
 			c.handleServerPublication(channel, push.Pub)
 			return
@@ -889,6 +1426,7 @@ func (c *Client) handlePush(push *protocol.Push) {
 			c.moveToDisconnected(code, push.Disconnect.Reason)
 		}
 	default:
+		c.handleUnknownPush(push)
 	}
 }
 
@@ -909,8 +1447,8 @@ func (c *Client) handleServerPublication(channel string, pub *protocol.Publicati
 		handler = c.events.onServerPublication
 	}
 	if handler != nil {
-		c.runHandlerSync(func() {
-			handler(ServerPublicationEvent{Channel: channel, Publication: pubFromProto(pub)})
+		c.runDataHandler(func() {
+			handler(ServerPublicationEvent{Channel: channel, Publication: pubFromProto(pub), QueuedFor: c.queuedFor})
 		})
 	}
 }
@@ -929,8 +1467,8 @@ func (c *Client) handleServerJoin(channel string, join *protocol.Join) {
 		handler = c.events.onServerJoin
 	}
 	if handler != nil {
-		c.runHandlerSync(func() {
-			handler(ServerJoinEvent{Channel: channel, ClientInfo: infoFromProto(join.Info)})
+		c.runDataHandler(func() {
+			handler(ServerJoinEvent{Channel: channel, ClientInfo: infoFromProto(join.Info), QueuedFor: c.queuedFor})
 		})
 	}
 }
@@ -949,8 +1487,8 @@ func (c *Client) handleServerLeave(channel string, leave *protocol.Leave) {
 		handler = c.events.onServerLeave
 	}
 	if handler != nil {
-		c.runHandlerSync(func() {
-			handler(ServerLeaveEvent{Channel: channel, ClientInfo: infoFromProto(leave.Info)})
+		c.runDataHandler(func() {
+			handler(ServerLeaveEvent{Channel: channel, ClientInfo: infoFromProto(leave.Info), QueuedFor: c.queuedFor})
 		})
 	}
 }
@@ -1018,35 +1556,174 @@ func (c *Client) getReconnectDelay() time.Duration {
 	return c.reconnectStrategy.timeBeforeNextAttempt(c.reconnectAttempts)
 }
 
-func (c *Client) startReconnecting() error {
-	c.mu.Lock()
-	c.round++
-	round := c.round
-	if c.state != StateConnecting {
+// tlsConfig returns TLS configuration for dialing a server.
+func (c *Client) tlsConfig() *tls.Config {
+	if c.config.GetClientCertificate == nil {
+		return c.config.TLSConfig
+	}
+	var tlsConfig *tls.Config
+	if c.config.TLSConfig != nil {
+		tlsConfig = c.config.TLSConfig.Clone()
+	} else {
+		tlsConfig = &tls.Config{}
+	}
+	getClientCertificate := c.config.GetClientCertificate
+	tlsConfig.Certificates = nil
+	tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return callSafe(c, "GetClientCertificate", getClientCertificate)
+	}
+	return tlsConfig
+}
+
+func (c *Client) startReconnecting() error {
+	c.mu.Lock()
+	c.round++
+	round := c.round
+	if c.state != StateConnecting || c.suspended {
+		if c.logLevelEnabled(LogLevelDebug) {
+			c.log(LogLevelDebug, "not in connecting state, no need to reconnect", map[string]string{
+				"state": string(c.state),
+			})
+		}
+		c.mu.Unlock()
+		return nil
+	}
+	refreshRequired := c.refreshRequired
+	token := c.token
+	hasTokenGetter := c.config.GetToken != nil || c.config.TokenProvider != nil
+	attempt := c.reconnectAttempts + 1
+	c.mu.Unlock()
+
+	if token == "" && !refreshRequired && c.config.CredentialStore != nil {
+		if storedToken, ok := c.loadStoredToken(); ok {
+			if c.logLevelEnabled(LogLevelDebug) {
+				c.log(LogLevelDebug, "using token from credential store", nil)
+			}
+			token = storedToken
+			c.mu.Lock()
+			c.token = storedToken
+			c.tokenFromStore = true
+			c.mu.Unlock()
+		}
+	}
+
+	if refreshRequired || (token == "" && hasTokenGetter) {
+		// Try to refresh token.
+		if c.logLevelEnabled(LogLevelDebug) {
+			c.log(LogLevelDebug, "refreshing token", nil)
+		}
+		if refreshRequired {
+			c.invalidateToken("")
+		}
+		newToken, err := c.refreshToken()
+		if err != nil {
+			if errors.Is(err, ErrUnauthorized) {
+				if c.logLevelEnabled(LogLevelDebug) {
+					c.log(LogLevelDebug, "unauthorized error, move to disconnected", nil)
+				}
+				c.moveToDisconnected(disconnectedUnauthorized, "unauthorized")
+				return nil
+			}
+			if c.logLevelEnabled(LogLevelDebug) {
+				c.log(LogLevelDebug, "error refreshing token", map[string]string{
+					"error": err.Error(),
+				})
+			}
+			c.handleError(RefreshError{err})
+			c.mu.Lock()
+			if c.state != StateConnecting {
+				if c.logLevelEnabled(LogLevelDebug) {
+					c.log(LogLevelDebug, "not in connecting state, no need to continue", map[string]string{
+						"state": string(c.state),
+					})
+				}
+				c.mu.Unlock()
+				return nil
+			}
+			c.scheduleReconnectLocked(err)
+			c.mu.Unlock()
+			return err
+		} else {
+			c.saveToken(newToken)
+			c.mu.Lock()
+			c.token = newToken
+			c.tokenFromStore = false
+			token = newToken
+			if c.state != StateConnecting {
+				if c.logLevelEnabled(LogLevelDebug) {
+					c.log(LogLevelDebug, "got token, but not in connecting state anymore", map[string]string{
+						"state": string(c.state),
+					})
+				}
+				c.mu.Unlock()
+				return nil
+			}
+			c.mu.Unlock()
+		}
+	}
+
+	var data []byte
+	if c.config.GetData != nil {
+		var err error
+		data, err = callSafe(c, "GetData", func() ([]byte, error) {
+			return c.config.GetData(ConnectDataEvent{Attempt: attempt})
+		})
+		if err != nil {
+			if c.logLevelEnabled(LogLevelDebug) {
+				c.log(LogLevelDebug, "error getting connect data", map[string]string{
+					"error": err.Error(),
+				})
+			}
+			c.handleError(ConnectError{err})
+			c.mu.Lock()
+			if c.state != StateConnecting {
+				c.mu.Unlock()
+				return nil
+			}
+			c.scheduleReconnectLocked(err)
+			c.mu.Unlock()
+			return err
+		}
+	}
+
+	header, err := c.connectHeader()
+	if err != nil {
 		if c.logLevelEnabled(LogLevelDebug) {
-			c.log(LogLevelDebug, "not in connecting state, no need to reconnect", map[string]string{
-				"state": string(c.state),
+			c.log(LogLevelDebug, "error getting headers", map[string]string{
+				"error": err.Error(),
 			})
 		}
+		c.handleError(ConnectError{err})
+		c.mu.Lock()
+		if c.state != StateConnecting {
+			c.mu.Unlock()
+			return nil
+		}
+		c.scheduleReconnectLocked(err)
 		c.mu.Unlock()
-		return nil
+		return err
 	}
-	refreshRequired := c.refreshRequired
-	token := c.token
-	getTokenFunc := c.config.GetToken
-	c.mu.Unlock()
 
 	wsConfig := websocketConfig{
 		Proxy:             c.config.Proxy,
 		NetDialContext:    c.config.NetDialContext,
-		TLSConfig:         c.config.TLSConfig,
+		TLSConfig:         c.tlsConfig(),
 		HandshakeTimeout:  c.config.HandshakeTimeout,
 		EnableCompression: c.config.EnableCompression,
 		CookieJar:         c.config.CookieJar,
-		Header:            c.config.Header,
+		Header:            header,
+		Metrics:           c.metrics,
+		ProtocolDump:      c.protocolDump,
+		MaxBatchSize:      c.config.MaxBatchSize,
+		MaxBatchDelay:     c.config.MaxBatchDelay,
+		Watchdog:          c.watchdog,
+		Spawn:             c.spawn,
 	}
 
 	u := c.endpoints[round%len(c.endpoints)]
+	if token != "" {
+		u = c.applyTokenTransport(u, token, &wsConfig)
+	}
 	if c.logLevelEnabled(LogLevelDebug) {
 		c.log(LogLevelDebug, "creating new transport", nil)
 	}
@@ -1068,7 +1745,7 @@ func (c *Client) startReconnecting() error {
 			c.mu.Unlock()
 			return nil
 		}
-		c.scheduleReconnectLocked()
+		c.scheduleReconnectLocked(err)
 		c.mu.Unlock()
 		return err
 	}
@@ -1076,58 +1753,8 @@ func (c *Client) startReconnecting() error {
 		c.log(LogLevelDebug, "new transport created", nil)
 	}
 
-	if refreshRequired || (token == "" && getTokenFunc != nil) {
-		// Try to refresh token.
-		if c.logLevelEnabled(LogLevelDebug) {
-			c.log(LogLevelDebug, "refreshing token", nil)
-		}
-		newToken, err := c.refreshToken()
-		if err != nil {
-			if errors.Is(err, ErrUnauthorized) {
-				if c.logLevelEnabled(LogLevelDebug) {
-					c.log(LogLevelDebug, "unauthorized error, move to disconnected", nil)
-				}
-				c.moveToDisconnected(disconnectedUnauthorized, "unauthorized")
-				return nil
-			}
-			if c.logLevelEnabled(LogLevelDebug) {
-				c.log(LogLevelDebug, "error refreshing token", map[string]string{
-					"error": err.Error(),
-				})
-			}
-			c.handleError(RefreshError{err})
-			c.mu.Lock()
-			if c.state != StateConnecting {
-				if c.logLevelEnabled(LogLevelDebug) {
-					c.log(LogLevelDebug, "not in connecting state, no need to continue", map[string]string{
-						"state": string(c.state),
-					})
-				}
-				_ = t.Close()
-				c.mu.Unlock()
-				return nil
-			}
-			c.scheduleReconnectLocked()
-			c.mu.Unlock()
-			return err
-		} else {
-			c.mu.Lock()
-			c.token = newToken
-			if c.state != StateConnecting {
-				if c.logLevelEnabled(LogLevelDebug) {
-					c.log(LogLevelDebug, "got token, but not in connecting state anymore", map[string]string{
-						"state": string(c.state),
-					})
-				}
-				c.mu.Unlock()
-				return nil
-			}
-			c.mu.Unlock()
-		}
-	}
-
 	c.mu.Lock()
-	if c.state != StateConnecting {
+	if c.state != StateConnecting || c.suspended {
 		if c.logLevelEnabled(LogLevelDebug) {
 			c.log(LogLevelDebug, "not in connecting state, no need to reconnect", map[string]string{
 				"state": string(c.state),
@@ -1137,13 +1764,16 @@ func (c *Client) startReconnecting() error {
 		c.mu.Unlock()
 		return nil
 	}
+	if c.config.GetData != nil {
+		c.data = data
+	}
 	c.refreshRequired = false
 	disconnectCh := make(chan struct{})
 	c.receive = make(chan []byte, 64)
 	c.transport = t
 	c.disconnectedCh = disconnectCh
 
-	go c.reader(t, disconnectCh)
+	c.spawn("reader", func() { c.reader(t, disconnectCh) })
 	if c.logLevelEnabled(LogLevelDebug) {
 		c.log(LogLevelDebug, "started reader loop, sending connect frame", nil)
 	}
@@ -1182,9 +1812,23 @@ func (c *Client) startReconnecting() error {
 					return
 				}
 				c.refreshRequired = true
-				c.scheduleReconnectLocked()
+				c.scheduleReconnectLocked(err)
 				return
 			} else if isServerError(err) && !isTemporaryError(err) {
+				if isTokenRejectedError(err) {
+					c.rejectToken("")
+					c.mu.Lock()
+					hasTokenGetter := c.config.GetToken != nil || c.config.TokenProvider != nil
+					if c.tokenFromStore && hasTokenGetter && c.state == StateConnecting {
+						// Stored token may be revoked – try a fresh one before giving up.
+						c.tokenFromStore = false
+						c.refreshRequired = true
+						c.scheduleReconnectLocked(err)
+						c.mu.Unlock()
+						return
+					}
+					c.mu.Unlock()
+				}
 				var serverError *Error
 				if errors.As(err, &serverError) {
 					if c.logLevelEnabled(LogLevelDebug) {
@@ -1214,12 +1858,12 @@ func (c *Client) startReconnecting() error {
 					}
 					return
 				}
-				c.scheduleReconnectLocked()
+				c.scheduleReconnectLocked(err)
 				return
 			}
 		}
 		c.mu.Lock()
-		if c.state != StateConnecting {
+		if c.state != StateConnecting || c.suspended {
 			if c.logLevelEnabled(LogLevelDebug) {
 				c.log(LogLevelDebug, "not in connecting state, no need to continue", map[string]string{
 					"state": string(c.state),
@@ -1235,9 +1879,15 @@ func (c *Client) startReconnecting() error {
 			})
 		}
 		c.state = StateConnected
+		c.clientID = res.Client
+		c.serverInfo = serverInfoFromProto(res)
+		c.connectedAt = time.Now()
+		c.metrics.IncConnects()
+		connectionLostAt := c.connectionLostAt
+		c.connectionLostAt = time.Time{}
 
 		if res.Expires {
-			c.refreshTimer = time.AfterFunc(time.Duration(res.Ttl)*time.Second, c.sendRefresh)
+			c.refreshTimer = c.scheduler.AfterFunc(c.tokenRefreshDelay(res.Ttl, c.token), c.sendRefresh)
 		}
 		c.resolveConnectFutures(nil)
 		if c.logLevelEnabled(LogLevelDebug) {
@@ -1245,13 +1895,13 @@ func (c *Client) startReconnecting() error {
 		}
 		c.mu.Unlock()
 
-		if c.events != nil && c.events.onConnected != nil {
-			handler := c.events.onConnected
-			ev := ConnectedEvent{
-				ClientID: res.Client,
-				Version:  res.Version,
-				Data:     res.Data,
-			}
+		ev := ConnectedEvent{
+			ClientID: res.Client,
+			Version:  res.Version,
+			Data:     res.Data,
+			Node:     res.Node,
+		}
+		if handler := c.connectedHandler(ev); handler != nil {
 			c.runHandlerSync(func() {
 				handler(ev)
 			})
@@ -1260,6 +1910,25 @@ func (c *Client) startReconnecting() error {
 			c.log(LogLevelDebug, "connected event called", nil)
 		}
 
+		if !connectionLostAt.IsZero() {
+			var reconnectedHandler ReconnectedHandler
+			if c.events != nil && c.events.onReconnected != nil {
+				reconnectedHandler = c.events.onReconnected
+			}
+			if reconnectedHandler != nil {
+				ev := ReconnectedEvent{
+					ClientID:  res.Client,
+					Version:   res.Version,
+					Data:      res.Data,
+					Downtime:  time.Since(connectionLostAt),
+					Recovered: serverSubsRecovered(res.Subs),
+				}
+				c.runHandlerSync(func() {
+					reconnectedHandler(ev)
+				})
+			}
+		}
+
 		var subscribeHandler ServerSubscribedHandler
 		if c.events != nil && c.events.onServerSubscribe != nil {
 			subscribeHandler = c.events.onServerSubscribe
@@ -1347,6 +2016,9 @@ func (c *Client) startReconnecting() error {
 		defer c.mu.Unlock()
 		// Successfully connected – can reset reconnect attempts.
 		c.reconnectAttempts = 0
+		c.reconnectHistory = nil
+		c.reconnectFailures = 0
+		c.refreshAttempts = 0
 		if c.logLevelEnabled(LogLevelDebug) {
 			c.log(LogLevelDebug, "reset reconnect attempts counter", nil)
 		}
@@ -1367,12 +2039,20 @@ func (c *Client) startReconnecting() error {
 				})
 			}
 			c.sendPong = res.Pong
-			go c.waitServerPing(disconnectCh, res.Ping)
+			c.pingTimeout = c.config.MaxServerPingDelay + time.Duration(res.Ping)*time.Second
+			c.pingGen++
+			gen := c.pingGen
+			c.pingTimer = c.scheduler.AfterFunc(c.pingTimeout, func() {
+				c.handleNoPing(gen)
+			})
 		}
 		c.resubscribe()
 		if c.logLevelEnabled(LogLevelDebug) {
 			c.log(LogLevelDebug, "client-side subscriptions resubscribe called", nil)
 		}
+		if c.offlineQueue != nil {
+			c.flushOfflineQueue()
+		}
 	})
 	if err != nil {
 		if c.logLevelEnabled(LogLevelDebug) {
@@ -1381,7 +2061,7 @@ func (c *Client) startReconnecting() error {
 			})
 		}
 		_ = t.Close()
-		c.scheduleReconnectLocked()
+		c.scheduleReconnectLocked(err)
 	} else {
 		if c.logLevelEnabled(LogLevelDebug) {
 			c.log(LogLevelDebug, "connect frame successfully sent", nil)
@@ -1410,13 +2090,9 @@ func (c *Client) startConnecting() error {
 	c.state = StateConnecting
 	c.mu.Unlock()
 
-	var handler ConnectingHandler
-	if c.events != nil && c.events.onConnecting != nil {
-		handler = c.events.onConnecting
-	}
-	if handler != nil {
+	event := ConnectingEvent{Code: connectingConnectCalled, Reason: "connect called", Cause: DisconnectCauseClientRequest}
+	if handler := c.connectingHandler(event); handler != nil {
 		c.runHandlerSync(func() {
-			event := ConnectingEvent{Code: connectingConnectCalled, Reason: "connect called"}
 			handler(event)
 		})
 	}
@@ -1424,10 +2100,108 @@ func (c *Client) startConnecting() error {
 	return c.startReconnecting()
 }
 
+// resubscribe restores client-side subscriptions after connect. Subscribe commands
+// are sent in frames of Config.ResubscribeBatchSize commands, the next batch is only
+// sent after replies to the previous one received.
 func (c *Client) resubscribe() {
-	for _, sub := range c.subs {
-		sub.resubscribe()
+	var subs []*Subscription
+	c.subs.Range(func(_ string, s *Subscription) bool {
+		if s.State() == SubStateSubscribing {
+			subs = append(subs, s)
+		}
+		return true
+	})
+	if len(subs) == 0 {
+		return
+	}
+	c.spawn("resubscribe", func() { c.resubscribeBatched(subs) })
+}
+
+func (c *Client) resubscribeBatched(subs []*Subscription) {
+	total := len(subs)
+	var done int64
+	progress := func() {
+		c.handleResubscribeProgress(int(atomic.AddInt64(&done, 1)), total)
+	}
+	batchSize := c.config.ResubscribeBatchSize
+	for start := 0; start < total; start += batchSize {
+		if !c.isConnected() {
+			return
+		}
+		end := start + batchSize
+		if end > total {
+			end = total
+		}
+		batch := &commandBatch{}
+		var wg sync.WaitGroup
+		for _, s := range subs[start:end] {
+			n := len(batch.cmds)
+			s.resubscribe(batch)
+			if len(batch.cmds) == n {
+				// Subscribe command was not issued (already subscribed, unauthorized, etc).
+				progress()
+				continue
+			}
+			wg.Add(1)
+			var once sync.Once
+			cb := batch.cbs[n]
+			batch.cbs[n] = func(reply *protocol.Reply, err error) {
+				once.Do(func() {
+					defer wg.Done()
+					cb(reply, err)
+					progress()
+				})
+			}
+		}
+		if len(batch.cmds) == 0 {
+			continue
+		}
+		if err := c.sendAsyncBatch(batch); err != nil {
+			for _, cb := range batch.cbs {
+				cb(nil, err)
+			}
+			return
+		}
+		wg.Wait()
+	}
+}
+
+func (c *Client) handleResubscribeProgress(done int, total int) {
+	var handler ResubscribeProgressHandler
+	if c.events != nil && c.events.onResubscribeProgress != nil {
+		handler = c.events.onResubscribeProgress
+	}
+	if handler != nil {
+		c.runHandlerAsync(func() {
+			handler(ResubscribeProgressEvent{Done: done, Total: total})
+		})
+	}
+}
+
+// activeSubs returns client-side subscriptions which are not in unsubscribed state.
+func (c *Client) activeSubs() []*Subscription {
+	subs := make([]*Subscription, 0, c.subs.Len())
+	c.subs.Range(func(_ string, s *Subscription) bool {
+		if s.State() != SubStateUnsubscribed {
+			subs = append(subs, s)
+		}
+		return true
+	})
+	return subs
+}
+
+// serverSubsRecovered reports whether all server-side subscriptions restored upon
+// connect successfully recovered missed publications.
+func serverSubsRecovered(subs map[string]*protocol.SubscribeResult) bool {
+	if len(subs) == 0 {
+		return false
 	}
+	for _, subRes := range subs {
+		if !subRes.GetWasRecovering() || !subRes.GetRecovered() {
+			return false
+		}
+	}
+	return true
 }
 
 func isTokenExpiredError(err error) bool {
@@ -1452,29 +2226,40 @@ func isTemporaryError(err error) bool {
 }
 
 func (c *Client) refreshToken() (string, error) {
+	if c.config.TokenProvider != nil {
+		return callSafe(c, "TokenProvider", func() (string, error) {
+			return c.config.TokenProvider.ConnectionToken(ConnectionTokenEvent{})
+		})
+	}
 	handler := c.config.GetToken
 	if handler == nil {
 		c.handleError(ConfigurationError{Err: errors.New("GetToken must be set to handle expired token")})
 		return "", ErrUnauthorized
 	}
-	return handler(ConnectionTokenEvent{})
+	return callSafe(c, "GetToken", func() (string, error) {
+		return handler(ConnectionTokenEvent{})
+	})
 }
 
 func (c *Client) sendRefresh() {
+	// Current token is about to expire – make sure we get a new one.
+	c.invalidateToken("")
+	if c.logLevelEnabled(LogLevelDebug) {
+		c.log(LogLevelDebug, "refreshing connection token", nil)
+	}
 	token, err := c.refreshToken()
 	if err != nil {
 		if errors.Is(err, ErrUnauthorized) {
 			c.moveToDisconnected(disconnectedUnauthorized, "unauthorized")
 			return
 		}
-		c.handleError(RefreshError{err})
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		c.handleRefreshError()
+		c.handleRefreshFailure(err)
 		return
 	}
+	c.saveToken(token)
 	c.mu.Lock()
 	c.token = token
+	c.tokenFromStore = false
 	c.mu.Unlock()
 
 	cmd := &protocol.Command{
@@ -1487,46 +2272,87 @@ func (c *Client) sendRefresh() {
 
 	_ = c.sendAsync(cmd, func(r *protocol.Reply, err error) {
 		if err != nil {
-			c.handleError(RefreshError{err})
-			c.mu.Lock()
-			defer c.mu.Unlock()
-			c.handleRefreshError()
+			c.handleRefreshFailure(err)
 			return
 		}
 		if r.Error != nil {
-			c.mu.Lock()
-			if c.state != StateConnected {
-				c.mu.Unlock()
+			if !c.isConnected() {
 				return
 			}
 			if r.Error.Temporary {
-				c.handleError(RefreshError{err})
-				c.refreshTimer = time.AfterFunc(10*time.Second, c.sendRefresh)
-				c.mu.Unlock()
+				c.handleRefreshFailure(errorFromProto(r.Error))
 			} else {
-				c.mu.Unlock()
+				if isTokenRejectedError(errorFromProto(r.Error)) {
+					c.rejectToken("")
+				}
 				c.moveToDisconnected(r.Error.Code, r.Error.Message)
 			}
 			return
 		}
 		expires := r.Refresh.Expires
 		ttl := r.Refresh.Ttl
-		if expires {
-			c.mu.Lock()
-			if c.state == StateConnected {
-				c.refreshTimer = time.AfterFunc(time.Duration(ttl)*time.Second, c.sendRefresh)
-			}
-			c.mu.Unlock()
+		if c.logLevelEnabled(LogLevelDebug) {
+			c.log(LogLevelDebug, "connection token refreshed", map[string]string{
+				"ttl": strconv.Itoa(int(ttl)),
+			})
+		}
+		c.mu.Lock()
+		c.refreshAttempts = 0
+		if expires && c.state == StateConnected {
+			c.refreshTimer = c.scheduler.AfterFunc(c.tokenRefreshDelay(ttl, c.token), c.sendRefresh)
 		}
+		c.mu.Unlock()
 	})
 }
 
-// Lock must be held outside.
-func (c *Client) handleRefreshError() {
+// handleRefreshFailure applies Config.RefreshFailurePolicy after unsuccessful
+// attempt to refresh connection token.
+func (c *Client) handleRefreshFailure(err error) {
+	c.handleError(RefreshError{err})
+
+	c.mu.Lock()
 	if c.state != StateConnected {
+		c.mu.Unlock()
 		return
 	}
-	c.refreshTimer = time.AfterFunc(10*time.Second, c.sendRefresh)
+	c.refreshAttempts++
+	attempt := c.refreshAttempts
+	policy := c.config.RefreshFailurePolicy
+	if c.logLevelEnabled(LogLevelDebug) {
+		c.log(LogLevelDebug, "connection token refresh failed", map[string]string{
+			"error":   err.Error(),
+			"attempt": strconv.Itoa(attempt),
+			"policy":  string(policy),
+		})
+	}
+	if policy == RefreshFailureRetry {
+		c.refreshTimer = c.scheduler.AfterFunc(c.refreshRetryDelay(attempt), c.sendRefresh)
+	}
+	c.mu.Unlock()
+
+	var handler RefreshErrorHandler
+	if c.events != nil && c.events.onRefreshError != nil {
+		handler = c.events.onRefreshError
+	}
+	if handler != nil {
+		c.runHandlerSync(func() {
+			handler(RefreshErrorEvent{Error: err, Attempt: attempt, Policy: policy})
+		})
+	}
+
+	switch policy {
+	case RefreshFailureDisconnect:
+		c.moveToDisconnected(disconnectedRefreshFailed, "refresh failed")
+	case RefreshFailureClose:
+		// Close waits for reader goroutine to finish, and we may be inside it.
+		go c.Close()
+	}
+}
+
+// refreshRetryDelay returns delay before refresh retry attempt (starting from 1).
+func (c *Client) refreshRetryDelay(attempt int) time.Duration {
+	r := newBackoff(c.config, c.jitterRand, c.config.RefreshRetryMinDelay, c.config.RefreshRetryMaxDelay)
+	return r.timeBeforeNextAttempt(attempt - 1)
 }
 
 func (c *Client) sendSubRefresh(channel string, token string, fn func(*protocol.SubRefreshResult, error)) {
@@ -1558,7 +2384,9 @@ func (c *Client) sendConnect(fn func(*protocol.ConnectResult, error)) error {
 	}
 
 	req := &protocol.ConnectRequest{}
-	req.Token = c.token
+	if c.config.TokenTransport == TokenTransportConnect {
+		req.Token = c.token
+	}
 	req.Name = c.config.Name
 	req.Version = c.config.Version
 	req.Data = c.data
@@ -1599,7 +2427,7 @@ type StreamPosition struct {
 
 func (c *Client) sendSubscribe(
 	channel string, data []byte, recover bool, streamPos StreamPosition, token string,
-	positioned bool, recoverable bool, joinLeave bool, deltaType DeltaType,
+	positioned bool, recoverable bool, joinLeave bool, deltaType DeltaType, batch *commandBatch,
 	fn func(res *protocol.SubscribeResult, err error),
 ) error {
 	params := &protocol.SubscribeRequest{
@@ -1628,7 +2456,7 @@ func (c *Client) sendSubscribe(
 	}
 	cmd.Subscribe = params
 
-	return c.sendAsync(cmd, func(reply *protocol.Reply, err error) {
+	cb := func(reply *protocol.Reply, err error) {
 		if err != nil {
 			fn(nil, err)
 			return
@@ -1638,7 +2466,12 @@ func (c *Client) sendSubscribe(
 			return
 		}
 		fn(reply.Subscribe, nil)
-	})
+	}
+	if batch != nil {
+		batch.add(cmd, cb)
+		return nil
+	}
+	return c.sendAsync(cmd, cb)
 }
 
 func (c *Client) nextFutureID() uint64 {
@@ -1697,25 +2530,44 @@ func (c *Client) onConnect(fn func(err error)) {
 type PublishResult struct{}
 
 // Publish data into channel.
-func (c *Client) Publish(ctx context.Context, channel string, data []byte) (PublishResult, error) {
+func (c *Client) Publish(ctx context.Context, channel string, data []byte, opts ...PublishOption) (PublishResult, error) {
 	if c.isClosed() {
 		return PublishResult{}, ErrClientClosed
 	}
-	resCh := make(chan PublishResult, 1)
-	errCh := make(chan error, 1)
-	c.publish(ctx, channel, data, func(result PublishResult, err error) {
-		resCh <- result
-		errCh <- err
-	})
-	select {
-	case <-ctx.Done():
-		return PublishResult{}, ctx.Err()
-	case res := <-resCh:
-		return res, <-errCh
+	publishOpts := &PublishOptions{}
+	for _, opt := range opts {
+		opt(publishOpts)
 	}
+	return withRetry(ctx, publishOpts.Retry, func() (PublishResult, error) {
+		resCh := make(chan PublishResult, 1)
+		errCh := make(chan error, 1)
+		c.publish(ctx, channel, data, publishOpts.Priority, func(result PublishResult, err error) {
+			resCh <- result
+			errCh <- err
+		})
+		select {
+		case <-ctx.Done():
+			return PublishResult{}, ctx.Err()
+		case res := <-resCh:
+			return res, <-errCh
+		}
+	})
 }
 
-func (c *Client) publish(ctx context.Context, channel string, data []byte, fn func(PublishResult, error)) {
+func (c *Client) publish(ctx context.Context, channel string, data []byte, priority Priority, fn func(PublishResult, error)) {
+	if c.config.MaxPublishSize > 0 && len(data) > c.config.MaxPublishSize {
+		fn(PublishResult{}, ErrPublishTooLarge)
+		return
+	}
+	if c.offlineQueue != nil {
+		connected := c.isConnected()
+		if c.offlineQueue.push(ctx, connected, channel, data, priority, fn) {
+			if connected {
+				c.flushOfflineQueue()
+			}
+			return
+		}
+	}
 	c.onConnect(func(err error) {
 		select {
 		case <-ctx.Done():
@@ -1727,11 +2579,15 @@ func (c *Client) publish(ctx context.Context, channel string, data []byte, fn fu
 			fn(PublishResult{}, err)
 			return
 		}
-		c.sendPublish(channel, data, fn)
+		c.sendPublish(channel, data, priority, fn)
 	})
 }
 
-func (c *Client) sendPublish(channel string, data []byte, fn func(PublishResult, error)) {
+func (c *Client) sendPublish(channel string, data []byte, priority Priority, fn func(PublishResult, error)) {
+	if !c.allowCommand(ThrottledEvent{Command: "publish", Channel: channel}) {
+		fn(PublishResult{}, ErrRateLimited)
+		return
+	}
 	params := &protocol.PublishRequest{
 		Channel: channel,
 		Data:    protocol.Raw(data),
@@ -1741,11 +2597,13 @@ func (c *Client) sendPublish(channel string, data []byte, fn func(PublishResult,
 		Id: c.nextCmdID(),
 	}
 	cmd.Publish = params
-	err := c.sendAsync(cmd, func(r *protocol.Reply, err error) {
+	started := time.Now()
+	err := c.sendAsyncPriority(cmd, priority, func(r *protocol.Reply, err error) {
 		if err != nil {
 			fn(PublishResult{}, err)
 			return
 		}
+		c.metrics.ObservePublishDuration(time.Since(started))
 		if r.Error != nil {
 			fn(PublishResult{}, errorFromProto(r.Error))
 			return
@@ -1776,6 +2634,7 @@ func (c *Client) History(ctx context.Context, channel string, opts ...HistoryOpt
 		opt(historyOpts)
 	}
 	c.history(ctx, channel, *historyOpts, func(result HistoryResult, err error) {
+		result.Publications = filterByTags(result.Publications, historyOpts.TagFilter)
 		resCh <- result
 		errCh <- err
 	})
@@ -1799,7 +2658,9 @@ func (c *Client) history(ctx context.Context, channel string, opts HistoryOption
 			fn(HistoryResult{}, err)
 			return
 		}
-		c.sendHistory(channel, opts, fn)
+		c.historyCalls.do(newHistoryKey(channel, opts), fn, func(fn func(HistoryResult, error)) {
+			c.sendHistory(channel, opts, fn)
+		}, cloneHistoryResult)
 	})
 }
 
@@ -1857,13 +2718,20 @@ type PresenceResult struct {
 }
 
 // Presence for a channel without being subscribed.
-func (c *Client) Presence(ctx context.Context, channel string) (PresenceResult, error) {
+func (c *Client) Presence(ctx context.Context, channel string, opts ...PresenceOption) (PresenceResult, error) {
 	if c.isClosed() {
 		return PresenceResult{}, ErrClientClosed
 	}
+	presenceOpts := PresenceOptions{}
+	for _, opt := range opts {
+		opt(&presenceOpts)
+	}
 	resCh := make(chan PresenceResult, 1)
 	errCh := make(chan error, 1)
 	c.presence(ctx, channel, func(result PresenceResult, err error) {
+		if presenceOpts.WithoutSelf {
+			result = withoutClient(result, c.currentClientID())
+		}
 		resCh <- result
 		errCh <- err
 	})
@@ -1887,7 +2755,9 @@ func (c *Client) presence(ctx context.Context, channel string, fn func(PresenceR
 			fn(PresenceResult{}, err)
 			return
 		}
-		c.sendPresence(channel, fn)
+		c.presenceCalls.do(channel, fn, func(fn func(PresenceResult, error)) {
+			c.sendPresence(channel, fn)
+		}, clonePresenceResult)
 	})
 }
 
@@ -1965,7 +2835,9 @@ func (c *Client) presenceStats(ctx context.Context, channel string, fn func(Pres
 			fn(PresenceStatsResult{}, err)
 			return
 		}
-		c.sendPresenceStats(channel, fn)
+		c.presenceStatsCalls.do(channel, fn, func(fn func(PresenceStatsResult, error)) {
+			c.sendPresenceStats(channel, fn)
+		}, clonePresenceStatsResult)
 	})
 }
 
@@ -2040,40 +2912,75 @@ func (c *Client) sendUnsubscribe(channel string, fn func(UnsubscribeResult, erro
 }
 
 func (c *Client) sendAsync(cmd *protocol.Command, cb func(*protocol.Reply, error)) error {
+	return c.sendAsyncPriority(cmd, commandPriority(cmd), cb)
+}
+
+func (c *Client) sendAsyncPriority(cmd *protocol.Command, priority Priority, cb func(*protocol.Reply, error)) error {
 	c.addRequest(cmd.Id, cb)
 
-	err := c.send(cmd)
+	err := c.sendPriority(cmd, priority)
 	if err != nil {
 		return err
 	}
-	go func() {
-		c.mu.Lock()
-		closeCh := c.closeCh
-		c.mu.Unlock()
-		defer c.removeRequest(cmd.Id)
-		select {
-		case <-time.After(c.config.ReadTimeout):
-			c.requestsMu.RLock()
-			req, ok := c.requests[cmd.Id]
-			c.requestsMu.RUnlock()
-			if !ok {
-				return
-			}
-			req.cb(nil, ErrTimeout)
-		case <-closeCh:
-			c.requestsMu.RLock()
-			req, ok := c.requests[cmd.Id]
-			c.requestsMu.RUnlock()
-			if !ok {
-				return
-			}
-			req.cb(nil, ErrClientDisconnected)
+	go c.waitReply(cmd.Id)
+	return nil
+}
+
+// commandBatch collects commands to send them to a server in one frame.
+type commandBatch struct {
+	cmds []*protocol.Command
+	cbs  []func(*protocol.Reply, error)
+}
+
+func (b *commandBatch) add(cmd *protocol.Command, cb func(*protocol.Reply, error)) {
+	b.cmds = append(b.cmds, cmd)
+	b.cbs = append(b.cbs, cb)
+}
+
+func (c *Client) sendAsyncBatch(batch *commandBatch) error {
+	for i, cmd := range batch.cmds {
+		c.addRequest(cmd.Id, batch.cbs[i])
+	}
+	err := c.sendMany(batch.cmds)
+	if err != nil {
+		for _, cmd := range batch.cmds {
+			c.removeRequest(cmd.Id)
 		}
-	}()
+		return err
+	}
+	for _, cmd := range batch.cmds {
+		go c.waitReply(cmd.Id)
+	}
 	return nil
 }
 
+// waitReply calls request callback with an error if reply was not received in
+// time or client disconnected.
+func (c *Client) waitReply(id uint32) {
+	c.mu.Lock()
+	closeCh := c.closeCh
+	c.mu.Unlock()
+	select {
+	case <-time.After(c.config.ReadTimeout):
+		req, ok := c.takeRequest(id)
+		if !ok {
+			return
+		}
+		req.cb(nil, ErrTimeout)
+	case <-closeCh:
+		req, ok := c.takeRequest(id)
+		if !ok {
+			return
+		}
+		req.cb(nil, ErrClientDisconnected)
+	}
+}
+
 func (c *Client) send(cmd *protocol.Command) error {
+	return c.sendPriority(cmd, commandPriority(cmd))
+}
+
+func (c *Client) sendPriority(cmd *protocol.Command, priority Priority) error {
 	transport := c.transport
 	if transport == nil {
 		return ErrClientDisconnected
@@ -2081,7 +2988,27 @@ func (c *Client) send(cmd *protocol.Command) error {
 	if c.logLevelEnabled(LogLevelTrace) {
 		c.traceOutCmd(cmd)
 	}
-	err := transport.Write(cmd, c.config.WriteTimeout)
+	err := transport.WritePriority(cmd, priority, c.config.WriteTimeout)
+	if err != nil {
+		go c.handleDisconnect(&disconnect{Code: connectingTransportClosed, Reason: "write error", Reconnect: true})
+		return io.EOF
+	}
+	return nil
+}
+
+func (c *Client) sendMany(cmds []*protocol.Command) error {
+	c.mu.RLock()
+	transport := c.transport
+	c.mu.RUnlock()
+	if transport == nil {
+		return ErrClientDisconnected
+	}
+	if c.logLevelEnabled(LogLevelTrace) {
+		for _, cmd := range cmds {
+			c.traceOutCmd(cmd)
+		}
+	}
+	err := transport.WriteMany(cmds, c.config.WriteTimeout)
 	if err != nil {
 		go c.handleDisconnect(&disconnect{Code: connectingTransportClosed, Reason: "write error", Reconnect: true})
 		return io.EOF
@@ -2105,10 +3032,25 @@ func (c *Client) removeRequest(id uint32) {
 	delete(c.requests, id)
 }
 
+// takeRequest removes request and returns it, so only one of reply, timeout and
+// disconnect calls request callback.
+func (c *Client) takeRequest(id uint32) (request, bool) {
+	c.requestsMu.Lock()
+	defer c.requestsMu.Unlock()
+	req, ok := c.requests[id]
+	delete(c.requests, id)
+	return req, ok
+}
+
 type disconnect struct {
 	Code      uint32
 	Reason    string
 	Reconnect bool
+	// RetryAfter is an optional delay in seconds before the next connection attempt
+	// requested by a server. Only respected with Config.CloseRetryAfter.
+	RetryAfter uint32 `json:"retry_after,omitempty"`
+	// Cause overrides cause computed from Code.
+	Cause DisconnectCause `json:"-"`
 }
 
 type serverSub struct {
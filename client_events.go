@@ -1,10 +1,19 @@
 package centrifuge
 
+import "time"
+
 // ConnectionTokenEvent may contain some useful contextual information in the future.
 // For now, it's empty.
 type ConnectionTokenEvent struct {
 }
 
+// ConnectDataEvent is passed to Config.GetData.
+type ConnectDataEvent struct {
+	// Attempt is a number of connection attempt since last successful connect,
+	// starting from 1.
+	Attempt int
+}
+
 // SubscriptionTokenEvent contains info required to get subscription token when
 // client wants to subscribe on private channel.
 type SubscriptionTokenEvent struct {
@@ -15,6 +24,9 @@ type SubscriptionTokenEvent struct {
 type ServerPublicationEvent struct {
 	Channel string
 	Publication
+	// QueuedFor is the time event waited for previous handlers to finish. Large
+	// values mean handlers process stale data due to event backlog.
+	QueuedFor time.Duration
 }
 
 type ServerSubscribedEvent struct {
@@ -27,16 +39,20 @@ type ServerSubscribedEvent struct {
 	Data           []byte
 }
 
-// ServerJoinEvent has info about user who left channel.
+// ServerJoinEvent has info about user who joined server-side subscription channel.
 type ServerJoinEvent struct {
 	Channel string
 	ClientInfo
+	// QueuedFor is the time event waited in event queue.
+	QueuedFor time.Duration
 }
 
-// ServerLeaveEvent has info about user who joined channel.
+// ServerLeaveEvent has info about user who left server-side subscription channel.
 type ServerLeaveEvent struct {
 	Channel string
 	ClientInfo
+	// QueuedFor is the time event waited in event queue.
+	QueuedFor time.Duration
 }
 
 // ServerUnsubscribedEvent is an event passed to unsubscribe event handler.
@@ -54,18 +70,142 @@ type ConnectedEvent struct {
 	ClientID string
 	Version  string
 	Data     []byte
+	// Node is a name of server node client connected to if server exposes it.
+	Node string
+}
+
+// ReconnectedEvent is passed to OnReconnected callback when client connected after
+// losing previously established connection (i.e. not upon Client.Connect call).
+type ReconnectedEvent struct {
+	ClientID string
+	Version  string
+	Data     []byte
+	// Downtime is a time passed since connection was lost.
+	Downtime time.Duration
+	// Recovered is true if all server-side subscriptions recovered publications
+	// missed during downtime, so application may only apply incremental updates.
+	// It's false when there are no server-side subscriptions. Recovery of client-side
+	// subscriptions is reported over SubscribedEvent.Recovered.
+	Recovered bool
 }
 
 // ConnectingEvent is a connecting event context passed to OnConnecting callback.
 type ConnectingEvent struct {
 	Code   uint32
 	Reason string
+	// Cause is a category of Reason.
+	Cause DisconnectCause
+	// RetryAfter is a delay before the next connection attempt requested by a
+	// server. Client respects it instead of its own backoff. Zero if not set.
+	RetryAfter time.Duration
 }
 
 // DisconnectedEvent is a disconnected event context passed to OnDisconnected callback.
 type DisconnectedEvent struct {
 	Code   uint32
 	Reason string
+	// Cause is a category of Reason.
+	Cause DisconnectCause
+	// RetryAfter is a delay before connecting again requested by a server, see
+	// Config.CloseRetryAfter. Zero if not set.
+	RetryAfter time.Duration
+}
+
+// ResubscribeProgressEvent is passed to OnResubscribeProgress callback while client-side
+// subscriptions are restored after connect.
+type ResubscribeProgressEvent struct {
+	// Done is a number of subscriptions processed so far.
+	Done int
+	// Total is a number of subscriptions to restore.
+	Total int
+}
+
+// RefreshErrorEvent is passed to OnRefreshError callback when connection token
+// refresh failed.
+type RefreshErrorEvent struct {
+	// Error is a reason of refresh failure.
+	Error error
+	// Attempt is a number of consecutive failed refresh attempts, starting from 1.
+	Attempt int
+	// Policy is a Config.RefreshFailurePolicy applied to this failure.
+	Policy RefreshFailurePolicy
+}
+
+// AuthRequiredEvent is passed to OnAuthRequired callback when server permanently
+// rejected connection credentials and client moved to disconnected state.
+type AuthRequiredEvent struct {
+	// Code of disconnect.
+	Code uint32
+	// Reason of disconnect.
+	Reason string
+	// ReconnectAllowed is false when server denied connection permission for
+	// authenticated user – so re-authenticating with the same user won't help.
+	ReconnectAllowed bool
+}
+
+// FailedEvent is passed to OnFailed callback when client stopped reconnecting
+// because reconnect budget exhausted.
+type FailedEvent struct {
+	// NumAttempts is a number of failed connection attempts.
+	NumAttempts int
+	// Attempts contain the first and up to 31 last failed attempts.
+	Attempts []ReconnectAttempt
+}
+
+// PingEvent is passed to OnPing callback when round trip time measured. See
+// Config.MeasureRTT.
+type PingEvent struct {
+	// RTT is a round trip time of ping command.
+	RTT time.Duration
+}
+
+// SlowHandlerEvent is passed to OnSlowHandler callback when event handler took
+// longer than Config.SlowHandlerThreshold.
+type SlowHandlerEvent struct {
+	// Duration is an execution time of handler.
+	Duration time.Duration
+	// Delay is a time handler waited in queue before execution.
+	Delay time.Duration
+	// QueueDepth is the number of handlers waiting for execution after slow one.
+	QueueDepth int
+}
+
+// StalledEvent is passed to OnStalled callback when internal goroutine of client
+// did not make progress for Config.StallTimeout.
+type StalledEvent struct {
+	// Component is "reader" (processing of server replies and pushes), "writer"
+	// (writing to connection) or "dispatcher" (running event handlers).
+	Component string
+	// Duration is a time component is busy with the current work item.
+	Duration time.Duration
+	// Goroutines is a dump of all goroutine stacks.
+	Goroutines []byte
+}
+
+// ThrottledEvent is passed to OnThrottled callback when command rejected by
+// Config.CommandRateLimit.
+type ThrottledEvent struct {
+	// Command is "publish" or "rpc".
+	Command string
+	// Channel of publication, set for publish.
+	Channel string
+	// Method of RPC, set for rpc.
+	Method string
+}
+
+// PushEvent is passed to OnPush callback with push which client does not handle
+// itself.
+type PushEvent struct {
+	// Channel of push, may be empty.
+	Channel string
+	// Type of push, e.g. "connect" or "refresh". Empty for push of type unknown
+	// to protocol library.
+	Type string
+	// Data is JSON encoded push payload, nil for push of unknown type since its
+	// fields are dropped on decoding.
+	Data []byte
+	// QueuedFor is the time event waited in event queue.
+	QueuedFor time.Duration
 }
 
 // ErrorEvent is an error event context passed to OnError callback.
@@ -76,6 +216,8 @@ type ErrorEvent struct {
 // MessageEvent is an event for async message from server to client.
 type MessageEvent struct {
 	Data []byte
+	// QueuedFor is the time event waited in event queue.
+	QueuedFor time.Duration
 }
 
 // ConnectingHandler is an interface describing how to handle connecting event.
@@ -87,6 +229,9 @@ type ConnectedHandler func(ConnectedEvent)
 // DisconnectHandler is an interface describing how to handle moveToDisconnected event.
 type DisconnectHandler func(DisconnectedEvent)
 
+// ReconnectedHandler is an interface describing how to handle reconnected event.
+type ReconnectedHandler func(ReconnectedEvent)
+
 // MessageHandler is an interface describing how to handle async message from server.
 type MessageHandler func(MessageEvent)
 
@@ -114,45 +259,121 @@ type ServerJoinHandler func(ServerJoinEvent)
 // server-side subscriptions.
 type ServerLeaveHandler func(ServerLeaveEvent)
 
+// ResubscribeProgressHandler is an interface describing how to handle resubscribe
+// progress event.
+type ResubscribeProgressHandler func(ResubscribeProgressEvent)
+
+// RefreshErrorHandler is an interface describing how to handle refresh error event.
+type RefreshErrorHandler func(RefreshErrorEvent)
+
+// AuthRequiredHandler is an interface describing how to handle auth required event.
+type AuthRequiredHandler func(AuthRequiredEvent)
+
+// FailedHandler is an interface describing how to handle failed event.
+type FailedHandler func(FailedEvent)
+
+// PingHandler is an interface describing how to handle ping event.
+type PingHandler func(PingEvent)
+
+// SlowHandlerHandler is an interface describing how to handle slow handler event.
+type SlowHandlerHandler func(SlowHandlerEvent)
+
+// StalledHandler is a function to handle stalled client goroutine.
+type StalledHandler func(StalledEvent)
+
+// ThrottledHandler is an interface describing how to handle throttled event.
+type ThrottledHandler func(ThrottledEvent)
+
+// PushHandler is an interface describing how to handle unhandled push.
+type PushHandler func(PushEvent)
+
 // ErrorHandler is an interface describing how to handle error event.
 type ErrorHandler func(ErrorEvent)
 
 // eventHub has all event handlers for client.
 type eventHub struct {
-	onConnected          ConnectedHandler
-	onDisconnected       DisconnectHandler
-	onConnecting         ConnectingHandler
-	onError              ErrorHandler
-	onMessage            MessageHandler
-	onServerSubscribe    ServerSubscribedHandler
-	onServerSubscribing  ServerSubscribingHandler
-	onServerUnsubscribed ServerUnsubscribedHandler
-	onServerPublication  ServerPublicationHandler
-	onServerJoin         ServerJoinHandler
-	onServerLeave        ServerLeaveHandler
+	onConnected           ConnectedHandler
+	onReconnected         ReconnectedHandler
+	onDisconnected        DisconnectHandler
+	onConnecting          ConnectingHandler
+	onError               ErrorHandler
+	onMessage             MessageHandler
+	onServerSubscribe     ServerSubscribedHandler
+	onServerSubscribing   ServerSubscribingHandler
+	onServerUnsubscribed  ServerUnsubscribedHandler
+	onServerPublication   ServerPublicationHandler
+	onServerJoin          ServerJoinHandler
+	onServerLeave         ServerLeaveHandler
+	onResubscribeProgress ResubscribeProgressHandler
+	onRefreshError        RefreshErrorHandler
+	onAuthRequired        AuthRequiredHandler
+	onFailed              FailedHandler
+	onPing                PingHandler
+	onThrottled           ThrottledHandler
+	onSlowHandler         SlowHandlerHandler
+	onPush                PushHandler
+	onStalled             StalledHandler
+
+	// replay is nil unless Config.EventReplaySize set.
+	replay *eventReplayBuffer
+	// rpcMux routes server-initiated RPC, see OnRPC and SetRPCMux.
+	rpcMux *RPCMux
 }
 
 // newEventHub initializes new eventHub.
-func newEventHub() *eventHub {
-	return &eventHub{}
+func newEventHub(replaySize int) *eventHub {
+	h := &eventHub{}
+	if replaySize > 0 {
+		h.replay = newEventReplayBuffer(replaySize)
+	}
+	return h
 }
 
-// OnConnected is a function to handle connect event.
+// OnConnected is a function to handle connect event. If Config.EventReplaySize
+// set then buffered connected events which happened before are replayed to handler.
 func (c *Client) OnConnected(handler ConnectedHandler) {
-	c.events.onConnected = handler
+	c.setLifecycleHandler(func() {
+		c.events.onConnected = handler
+	}, func(event any) {
+		if e, ok := event.(ConnectedEvent); ok && handler != nil {
+			handler(e)
+		}
+	})
+}
+
+// OnReconnected is a function to handle reconnected event. It's called after
+// OnConnected when connection was established after connection loss.
+func (c *Client) OnReconnected(handler ReconnectedHandler) {
+	c.events.onReconnected = handler
 }
 
-// OnConnecting is a function to handle connecting event.
+// OnConnecting is a function to handle connecting event. If Config.EventReplaySize
+// set then buffered connecting events which happened before are replayed to handler.
 func (c *Client) OnConnecting(handler ConnectingHandler) {
-	c.events.onConnecting = handler
+	c.setLifecycleHandler(func() {
+		c.events.onConnecting = handler
+	}, func(event any) {
+		if e, ok := event.(ConnectingEvent); ok && handler != nil {
+			handler(e)
+		}
+	})
 }
 
-// OnDisconnected is a function to handle moveToDisconnected event.
+// OnDisconnected is a function to handle moveToDisconnected event. If
+// Config.EventReplaySize set then buffered disconnected events which happened
+// before are replayed to handler.
 func (c *Client) OnDisconnected(handler DisconnectHandler) {
-	c.events.onDisconnected = handler
+	c.setLifecycleHandler(func() {
+		c.events.onDisconnected = handler
+	}, func(event any) {
+		if e, ok := event.(DisconnectedEvent); ok && handler != nil {
+			handler(e)
+		}
+	})
 }
 
-// OnError is a function that will receive unhandled errors for logging.
+// OnError is a function that will receive unhandled errors for logging. Panics in
+// user callbacks are recovered and passed here as PanicError.
 func (c *Client) OnError(handler ErrorHandler) {
 	c.events.onError = handler
 }
@@ -182,12 +403,78 @@ func (c *Client) OnUnsubscribed(handler ServerUnsubscribedHandler) {
 	c.events.onServerUnsubscribed = handler
 }
 
-// OnJoin sets function to handle Join event from server-side subscriptions.
+// OnJoin sets function to handle Join event from server-side subscriptions. Join
+// messages are only sent by a server for server-side subscriptions with join/leave
+// option enabled.
 func (c *Client) OnJoin(handler ServerJoinHandler) {
 	c.events.onServerJoin = handler
 }
 
-// OnLeave sets function to handle Leave event from server-side subscriptions.
+// OnLeave sets function to handle Leave event from server-side subscriptions. Leave
+// messages are only sent by a server for server-side subscriptions with join/leave
+// option enabled.
 func (c *Client) OnLeave(handler ServerLeaveHandler) {
 	c.events.onServerLeave = handler
 }
+
+// OnResubscribeProgress sets function to track the progress of restoring client-side
+// subscriptions after connect. Handler is called after each subscription processed.
+func (c *Client) OnResubscribeProgress(handler ResubscribeProgressHandler) {
+	c.events.onResubscribeProgress = handler
+}
+
+// OnRefreshError sets function to be notified about failed connection token refresh
+// attempts. What happens with connection after the failure is defined by
+// Config.RefreshFailurePolicy. Errors are also passed to OnError handler.
+func (c *Client) OnRefreshError(handler RefreshErrorHandler) {
+	c.events.onRefreshError = handler
+}
+
+// OnAuthRequired sets function to handle permanent rejection of connection
+// credentials: GetToken returned ErrUnauthorized, or server responded with
+// unauthorized or permission denied error, or disconnected client due to invalid
+// token. Client is in disconnected state at this point and won't reconnect
+// automatically – application may re-authenticate user and call Client.Reconnect.
+// Called after OnDisconnected.
+func (c *Client) OnAuthRequired(handler AuthRequiredHandler) {
+	c.events.onAuthRequired = handler
+}
+
+// OnFailed sets function to handle the end of reconnecting after
+// Config.MaxReconnectAttempts or Config.ReconnectWindow exhausted. Called after
+// OnDisconnected. Client is in disconnected state, Client.Connect may be called
+// to start reconnecting again.
+func (c *Client) OnFailed(handler FailedHandler) {
+	c.events.onFailed = handler
+}
+
+// OnPing sets function to handle round trip time measurements. Called only when
+// Config.MeasureRTT enabled.
+func (c *Client) OnPing(handler PingHandler) {
+	c.events.onPing = handler
+}
+
+// OnSlowHandler sets function to be notified about event handlers which took longer
+// than Config.SlowHandlerThreshold. Called after slow handler returned.
+func (c *Client) OnSlowHandler(handler SlowHandlerHandler) {
+	c.events.onSlowHandler = handler
+}
+
+// OnStalled sets function to be notified about stalled internal goroutines, see
+// Config.StallTimeout. It's called on a separate goroutine, not over event queue,
+// so it's delivered even when event handler is blocked.
+func (c *Client) OnStalled(handler StalledHandler) {
+	c.events.onStalled = handler
+}
+
+// OnThrottled sets function to be notified about publish and RPC commands rejected
+// by Config.CommandRateLimit.
+func (c *Client) OnThrottled(handler ThrottledHandler) {
+	c.events.onThrottled = handler
+}
+
+// OnPush sets function to be notified about push frames which client does not
+// handle itself: push types client ignores and types added to protocol later.
+func (c *Client) OnPush(handler PushHandler) {
+	c.events.onPush = handler
+}
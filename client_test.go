@@ -7,8 +7,12 @@ import (
 	"fmt"
 	"math/rand"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/centrifugal/protocol"
 )
 
 type testEventHandler struct {
@@ -66,6 +70,50 @@ func (h *testSubscriptionHandler) OnUnsubscribe(e UnsubscribedEvent) {
 	}
 }
 
+func TestClient_ReplyRacingDisconnect(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+	const numRequests = 100
+	var calls int32
+	for i := uint32(1); i <= numRequests; i++ {
+		client.addRequest(i, func(*protocol.Reply, error) {
+			atomic.AddInt32(&calls, 1)
+		})
+	}
+	// Reply removes request under lock, so disconnect which fails pending
+	// requests concurrently does not call callback again.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := uint32(1); i <= numRequests; i++ {
+			client.handle(&protocol.Reply{Id: i})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		client.mu.Lock()
+		client.clearConnectedState()
+		client.mu.Unlock()
+	}()
+	wg.Wait()
+	waitFor(t, func() bool {
+		return atomic.LoadInt32(&calls) == numRequests
+	})
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != numRequests {
+		t.Fatalf("expected %d callback calls, got %d", numRequests, n)
+	}
+}
+
+func TestClient_NegativeResubscribeBatchSize(t *testing.T) {
+	client := NewJsonClient("ws://localhost:9000/connection/websocket", Config{ResubscribeBatchSize: -1})
+	defer client.Close()
+	if client.config.ResubscribeBatchSize != 100 {
+		t.Fatalf("expected default batch size, got %d", client.config.ResubscribeBatchSize)
+	}
+}
+
 func TestConnectWrongAddress(t *testing.T) {
 	client := NewJsonClient("ws://localhost:9000/connection/websocket", Config{})
 	defer client.Close()
@@ -0,0 +1,83 @@
+package centrifuge
+
+import (
+	"time"
+)
+
+// Clock is a source of time for client timers, see Config.Clock. Useful to make
+// reconnect and refresh timing deterministic in tests.
+type Clock interface {
+	Now() time.Time
+	// NewTimer creates Timer which sends current time to its channel after d.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc creates Timer which calls f in its own goroutine after d.
+	AfterFunc(d time.Duration, f func()) Timer
+	// NewTicker creates Ticker which sends current time to its channel every d.
+	NewTicker(d time.Duration) Ticker
+	// After is a shortcut for NewTimer(d).C().
+	After(d time.Duration) <-chan time.Time
+}
+
+// Timer is a timer created by Clock, semantics is the same as of time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is a ticker created by Clock, semantics is the same as of time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock is Clock using time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+func (t realTimer) Reset(d time.Duration) bool {
+	return t.t.Reset(d)
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTicker) Stop() {
+	t.t.Stop()
+}
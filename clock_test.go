@@ -0,0 +1,106 @@
+package centrifuge_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge-go"
+	"github.com/centrifugal/centrifuge-go/clocktest"
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClient_ClockReconnect(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	clock := clocktest.New()
+	client := centrifuge.NewJsonClient("ws"+strings.TrimPrefix(server.URL, "http"), centrifuge.Config{Clock: clock})
+	defer client.Close()
+	if err := client.Connect(); err == nil {
+		t.Fatal("expected error from the first attempt")
+	}
+	waitUntil(t, func() bool {
+		return clock.ActiveTimers() == 1
+	})
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Fatalf("expected no reconnect before clock advanced, got %d attempts", n)
+	}
+	clock.Advance(time.Minute)
+	waitUntil(t, func() bool {
+		return atomic.LoadInt32(&attempts) == 2
+	})
+}
+
+func TestClient_ClockServerPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			if cmd.Connect == nil {
+				continue
+			}
+			reply := fmt.Sprintf(`{"id":%d,"connect":{"client":"c","ping":25}}`, cmd.Id)
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	clock := clocktest.New()
+	client := centrifuge.NewJsonClient("ws"+strings.TrimPrefix(server.URL, "http"), centrifuge.Config{Clock: clock})
+	defer client.Close()
+	connecting := make(chan centrifuge.ConnectingEvent, 1)
+	client.OnConnecting(func(e centrifuge.ConnectingEvent) {
+		if e.Cause == centrifuge.DisconnectCausePingTimeout {
+			connecting <- e
+		}
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, func() bool {
+		return client.State() == centrifuge.StateConnected
+	})
+	clock.Advance(30 * time.Second)
+	select {
+	case <-connecting:
+		t.Fatal("ping timeout before server ping delay passed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	clock.Advance(10 * time.Second)
+	select {
+	case <-connecting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for ping timeout")
+	}
+}
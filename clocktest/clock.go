@@ -0,0 +1,141 @@
+// Package clocktest provides fake centrifuge.Clock to make reconnect, refresh
+// and other client timers deterministic in tests, see centrifuge.Config.Clock.
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/centrifugal/centrifuge-go"
+)
+
+// Clock is centrifuge.Clock which only moves forward on Advance. It starts at
+// Unix epoch.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+var _ centrifuge.Clock = (*Clock)(nil)
+
+// New creates Clock.
+func New() *Clock {
+	return &Clock{now: time.Unix(0, 0)}
+}
+
+type timer struct {
+	clock    *Clock
+	deadline time.Time
+	period   time.Duration
+	ch       chan time.Time
+	fn       func()
+	active   bool
+}
+
+// Now returns current fake time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) newTimer(d time.Duration, period time.Duration, fn func()) *timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &timer{clock: c, deadline: c.now.Add(d), period: period, ch: make(chan time.Time, 1), fn: fn, active: true}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// NewTimer creates timer which fires once Advance moves time by d.
+func (c *Clock) NewTimer(d time.Duration) centrifuge.Timer {
+	return c.newTimer(d, 0, nil)
+}
+
+// AfterFunc creates timer which calls f in its own goroutine once Advance moves
+// time by d.
+func (c *Clock) AfterFunc(d time.Duration, f func()) centrifuge.Timer {
+	return c.newTimer(d, 0, f)
+}
+
+// NewTicker creates ticker which fires every d of time moved by Advance.
+func (c *Clock) NewTicker(d time.Duration) centrifuge.Ticker {
+	return ticker{c.newTimer(d, d, nil)}
+}
+
+// After is a shortcut for NewTimer(d).C().
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Advance moves time forward firing due timers.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	var fns []func()
+	for _, t := range c.timers {
+		for t.active && !t.deadline.After(now) {
+			if t.fn != nil {
+				fns = append(fns, t.fn)
+			} else {
+				select {
+				case t.ch <- now:
+				default:
+				}
+			}
+			if t.period == 0 {
+				t.active = false
+				break
+			}
+			t.deadline = t.deadline.Add(t.period)
+		}
+	}
+	c.mu.Unlock()
+	for _, fn := range fns {
+		go fn()
+	}
+}
+
+// ActiveTimers returns the number of timers and tickers not fired or stopped
+// yet. Useful to wait until client armed a timer before Advance.
+func (c *Clock) ActiveTimers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, t := range c.timers {
+		if t.active {
+			n++
+		}
+	}
+	return n
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.active = true
+	t.deadline = t.clock.now.Add(d)
+	return active
+}
+
+type ticker struct {
+	t *timer
+}
+
+func (t ticker) C() <-chan time.Time { return t.t.C() }
+func (t ticker) Stop()               { t.t.Stop() }
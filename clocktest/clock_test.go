@@ -0,0 +1,44 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	c := New()
+	timer := c.NewTimer(time.Second)
+	ticker := c.NewTicker(time.Second)
+	fired := make(chan struct{}, 1)
+	c.AfterFunc(2*time.Second, func() { fired <- struct{}{} })
+	if n := c.ActiveTimers(); n != 3 {
+		t.Fatalf("expected 3 active timers, got %d", n)
+	}
+	c.Advance(time.Second)
+	select {
+	case now := <-timer.C():
+		if !now.Equal(time.Unix(1, 0)) {
+			t.Fatalf("unexpected time %v", now)
+		}
+	default:
+		t.Fatal("timer not fired")
+	}
+	<-ticker.C()
+	c.Advance(time.Second)
+	<-ticker.C()
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("func not called")
+	}
+	ticker.Stop()
+	if n := c.ActiveTimers(); n != 0 {
+		t.Fatalf("expected no active timers, got %d", n)
+	}
+	if timer.Reset(time.Second) {
+		t.Fatal("fired timer must not be active")
+	}
+	if !timer.Stop() {
+		t.Fatal("reset timer must be active")
+	}
+}
@@ -0,0 +1,130 @@
+package centrifuge
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newClosingTestClient creates client in connecting state with n client-side
+// subscriptions in subscribing state, so Close has events to emit without a server.
+func newClosingTestClient(t *testing.T, n int) (*Client, func() []string) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	client.mu.Lock()
+	client.state = StateConnecting
+	client.mu.Unlock()
+
+	var mu sync.Mutex
+	var events []string
+	record := func(e string) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}
+	for i := 0; i < n; i++ {
+		sub, err := client.NewSubscription("test" + string(rune('a'+i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub.mu.Lock()
+		sub.state = SubStateSubscribing
+		sub.mu.Unlock()
+		sub.OnUnsubscribed(func(e UnsubscribedEvent) {
+			if e.Code != unsubscribedClientClosed {
+				t.Errorf("unexpected unsubscribe code: %d", e.Code)
+			}
+			time.Sleep(time.Millisecond)
+			record("unsubscribed")
+		})
+	}
+	client.OnDisconnected(func(DisconnectedEvent) {
+		record("disconnected")
+	})
+	return client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), events...)
+	}
+}
+
+func checkCloseEvents(events []string, numSubs int) error {
+	if len(events) != numSubs+1 {
+		return fmt.Errorf("expected %d events, got %v", numSubs+1, events)
+	}
+	for i := 0; i < numSubs; i++ {
+		if events[i] != "unsubscribed" {
+			return fmt.Errorf("expected unsubscribed events first, got %v", events)
+		}
+	}
+	if events[numSubs] != "disconnected" {
+		return fmt.Errorf("expected disconnected event last, got %v", events)
+	}
+	return nil
+}
+
+func TestClose_Ordering(t *testing.T) {
+	client, getEvents := newClosingTestClient(t, 5)
+	client.Close()
+	if err := checkCloseEvents(getEvents(), 5); err != nil {
+		t.Fatal(err)
+	}
+	if client.State() != StateClosed {
+		t.Fatalf("unexpected state: %s", client.State())
+	}
+}
+
+func TestClose_ConcurrentDuringDispatch(t *testing.T) {
+	client, getEvents := newClosingTestClient(t, 3)
+
+	// Keep callback queue busy while Close called.
+	started := make(chan struct{})
+	client.runHandlerAsync(func() {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+	})
+	<-started
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Close()
+			// Every Close call returns only after all events delivered.
+			if err := checkCloseEvents(getEvents(), 3); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClose_FromHandler(t *testing.T) {
+	client, getEvents := newClosingTestClient(t, 3)
+
+	closed := make(chan struct{})
+	client.runHandlerAsync(func() {
+		client.Close()
+		// Second call must not wait for events too.
+		client.Close()
+		close(closed)
+	})
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close called from handler blocked")
+	}
+	// Events are delivered after the handler returned.
+	waitFor(t, func() bool {
+		return len(getEvents()) == 4
+	})
+	if err := checkCloseEvents(getEvents(), 3); err != nil {
+		t.Fatal(err)
+	}
+	// Close from other goroutine waits for queue to be closed.
+	client.Close()
+	if client.State() != StateClosed {
+		t.Fatalf("unexpected state: %s", client.State())
+	}
+}
@@ -0,0 +1,75 @@
+package centrifuge
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// inflightGroup coalesces concurrent identical requests: while request with key
+// is in flight, the same requests do not send commands and get its result.
+type inflightGroup[K comparable, T any] struct {
+	mu    sync.Mutex
+	calls map[K][]func(T, error)
+}
+
+// do calls send for the first request with key, fn of every request joined before
+// reply is called with result. Callers except the first one get result copied with
+// clone, so they may modify it.
+func (g *inflightGroup[K, T]) do(key K, fn func(T, error), send func(func(T, error)), clone func(T) T) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K][]func(T, error))
+	}
+	if fns, ok := g.calls[key]; ok {
+		g.calls[key] = append(fns, fn)
+		g.mu.Unlock()
+		return
+	}
+	g.calls[key] = []func(T, error){fn}
+	g.mu.Unlock()
+	send(func(res T, err error) {
+		g.mu.Lock()
+		fns := g.calls[key]
+		delete(g.calls, key)
+		g.mu.Unlock()
+		for i, fn := range fns {
+			if i > 0 && err == nil {
+				fn(clone(res), nil)
+				continue
+			}
+			fn(res, err)
+		}
+	})
+}
+
+type historyKey struct {
+	channel  string
+	limit    int32
+	reverse  bool
+	hasSince bool
+	since    StreamPosition
+}
+
+func newHistoryKey(channel string, opts HistoryOptions) historyKey {
+	key := historyKey{channel: channel, limit: opts.Limit, reverse: opts.Reverse}
+	if opts.Since != nil {
+		key.hasSince = true
+		key.since = *opts.Since
+	}
+	return key
+}
+
+func cloneHistoryResult(res HistoryResult) HistoryResult {
+	res.Publications = slices.Clone(res.Publications)
+	return res
+}
+
+func clonePresenceResult(res PresenceResult) PresenceResult {
+	res.Clients = maps.Clone(res.Clients)
+	return res
+}
+
+func clonePresenceStatsResult(res PresenceStatsResult) PresenceStatsResult {
+	return res
+}
@@ -0,0 +1,121 @@
+package centrifuge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+func TestInflightGroup(t *testing.T) {
+	var g inflightGroup[string, PresenceResult]
+	var reply func(PresenceResult, error)
+	numSent := 0
+	results := make(chan PresenceResult, 3)
+	for i := 0; i < 3; i++ {
+		g.do("ch", func(res PresenceResult, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			results <- res
+		}, func(fn func(PresenceResult, error)) {
+			numSent++
+			reply = fn
+		}, clonePresenceResult)
+	}
+	if numSent != 1 {
+		t.Fatalf("expected 1 request sent, got %d", numSent)
+	}
+	reply(PresenceResult{Clients: map[string]ClientInfo{"c": {Client: "c"}}}, nil)
+	first := <-results
+	for i := 0; i < 2; i++ {
+		res := <-results
+		if len(res.Clients) != 1 {
+			t.Fatalf("unexpected result: %v", res)
+		}
+		res.Clients["other"] = ClientInfo{}
+	}
+	if len(first.Clients) != 1 {
+		t.Fatal("result of joined request must be a copy")
+	}
+	// Next request after reply is sent again.
+	g.do("ch", func(PresenceResult, error) {}, func(fn func(PresenceResult, error)) {
+		numSent++
+	}, clonePresenceResult)
+	if numSent != 2 {
+		t.Fatalf("expected 2 requests sent, got %d", numSent)
+	}
+}
+
+// startPresenceServer replies to presence commands with delay and counts them.
+func startPresenceServer(t *testing.T) (string, *atomic.Int32) {
+	numPresence := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		var writeMu sync.Mutex
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			switch {
+			case cmd.Connect != nil:
+				writeMu.Lock()
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id)))
+				writeMu.Unlock()
+			case cmd.Presence != nil:
+				numPresence.Add(1)
+				go func(id uint32) {
+					time.Sleep(100 * time.Millisecond)
+					writeMu.Lock()
+					defer writeMu.Unlock()
+					_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"id":%d,"presence":{"presence":{"c":{"client":"c","user":"u"}}}}`, id)))
+				}(cmd.Id)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http"), numPresence
+}
+
+func TestClient_PresenceCoalescing(t *testing.T) {
+	u, numPresence := startPresenceServer(t)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Presence(ctx, "test")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if res.Clients["c"].User != "u" {
+				t.Errorf("unexpected result: %v", res)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := numPresence.Load(); n != 1 {
+		t.Fatalf("expected 1 presence command, got %d", n)
+	}
+}
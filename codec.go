@@ -0,0 +1,111 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes and decodes application payloads: publication data, connect
+// data and RPC payloads. JSONCodec and ProtobufCodec are built-in. MsgPack and
+// CBOR codecs are provided by codec/msgpack and codec/cbor modules, so the root
+// module does not depend on their libraries. Codec only changes payload bytes,
+// it does not depend on whether NewJsonClient or NewProtobufClient is used – but
+// note that JSON protocol requires payloads to be valid JSON.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	// ContentType is a MIME type of encoded payloads, e.g. application/json.
+	ContentType() string
+}
+
+// JSONCodec is Codec which uses encoding/json.
+type JSONCodec struct{}
+
+// Marshal encodes v to JSON.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON data into v.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// ContentType returns application/json.
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// ProtobufCodec is Codec which encodes proto.Message values.
+type ProtobufCodec struct{}
+
+// Marshal encodes v which must be proto.Message.
+func (ProtobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T is not proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal decodes data into v which must be proto.Message.
+func (ProtobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf codec: %T is not proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// ContentType returns application/x-protobuf.
+func (ProtobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+// Codec returns Config.Codec or JSONCodec if not set.
+func (c *Client) Codec() Codec {
+	if c.config.Codec == nil {
+		return JSONCodec{}
+	}
+	return c.config.Codec
+}
+
+// SetData encodes v with client Codec and sets it as data of Connect command
+// used by next connection attempts. Ignored if Config.GetData is set.
+func (c *Client) SetData(v any) error {
+	data, err := c.Codec().Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.data = data
+	c.mu.Unlock()
+	return nil
+}
+
+// Codec returns SubscriptionConfig.Codec or client Codec if not set.
+func (s *Subscription) Codec() Codec {
+	if s.codec == nil {
+		return s.centrifuge.Codec()
+	}
+	return s.codec
+}
+
+// Decode decodes publication data of Subscription into v with Subscription Codec.
+func (s *Subscription) Decode(data []byte, v any) error {
+	return s.Codec().Unmarshal(data, v)
+}
+
+// PublishValue encodes v with Subscription Codec and publishes it into channel.
+// Codec content type is set to PublicationEnvelope, see SubscriptionConfig.Envelope.
+func (s *Subscription) PublishValue(ctx context.Context, v any, opts ...PublishOption) (PublishResult, error) {
+	codec := s.Codec()
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return PublishResult{}, err
+	}
+	return s.Publish(ctx, data, append([]PublishOption{WithContentType(codec.ContentType())}, opts...)...)
+}
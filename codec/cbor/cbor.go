@@ -0,0 +1,30 @@
+// Package cbor provides centrifuge.Codec which encodes payloads with CBOR. It
+// lives in a separate module so the root module does not depend on CBOR library.
+package cbor
+
+import (
+	"github.com/centrifugal/centrifuge-go"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Codec is centrifuge.Codec which uses github.com/fxamacker/cbor/v2. Note that
+// CBOR payloads are binary, so Codec should be used with
+// centrifuge.NewProtobufClient.
+type Codec struct{}
+
+var _ centrifuge.Codec = Codec{}
+
+// Marshal encodes v to CBOR.
+func (Codec) Marshal(v any) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+// Unmarshal decodes CBOR data into v.
+func (Codec) Unmarshal(data []byte, v any) error {
+	return cbor.Unmarshal(data, v)
+}
+
+// ContentType returns application/cbor.
+func (Codec) ContentType() string {
+	return "application/cbor"
+}
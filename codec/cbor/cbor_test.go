@@ -0,0 +1,29 @@
+package cbor
+
+import (
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	type point struct {
+		X, Y int
+		Tag  string
+	}
+	data, err := Codec{}.Marshal(point{X: 1, Y: 2, Tag: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p point
+	if err := (Codec{}).Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != (point{X: 1, Y: 2, Tag: "a"}) {
+		t.Fatalf("unexpected value %v", p)
+	}
+	if (Codec{}).ContentType() != "application/cbor" {
+		t.Fatalf("unexpected content type %s", Codec{}.ContentType())
+	}
+	if err := (Codec{}).Unmarshal([]byte{0xff}, &p); err == nil {
+		t.Fatal("expected error for invalid data")
+	}
+}
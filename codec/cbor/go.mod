@@ -0,0 +1,25 @@
+module github.com/centrifugal/centrifuge-go/codec/cbor
+
+go 1.26
+
+replace github.com/centrifugal/centrifuge-go => ../../
+
+require (
+	github.com/centrifugal/centrifuge-go v0.10.4
+	github.com/fxamacker/cbor/v2 v2.9.2
+)
+
+require (
+	github.com/centrifugal/protocol v0.19.2 // indirect
+	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/mailru/easyjson v0.9.2 // indirect
+	github.com/planetscale/vtprotobuf v0.6.0 // indirect
+	github.com/segmentio/asm v1.2.1 // indirect
+	github.com/segmentio/encoding v0.5.4 // indirect
+	github.com/shadowspore/fossil-delta v0.0.0-20241213113458-1d797d70cbe3 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/sys v0.45.0 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+)
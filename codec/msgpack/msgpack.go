@@ -0,0 +1,31 @@
+// Package msgpack provides centrifuge.Codec which encodes payloads with
+// MessagePack. It lives in a separate module so the root module does not depend
+// on MessagePack library.
+package msgpack
+
+import (
+	"github.com/centrifugal/centrifuge-go"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec is centrifuge.Codec which uses github.com/vmihailenco/msgpack/v5.
+// Note that MessagePack payloads are binary, so Codec should be used with
+// centrifuge.NewProtobufClient.
+type Codec struct{}
+
+var _ centrifuge.Codec = Codec{}
+
+// Marshal encodes v to MessagePack.
+func (Codec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal decodes MessagePack data into v.
+func (Codec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// ContentType returns application/msgpack.
+func (Codec) ContentType() string {
+	return "application/msgpack"
+}
@@ -0,0 +1,86 @@
+package centrifuge
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"testing"
+	"time"
+)
+
+// gobCodec stands for binary codecs like MsgPack plugged in by application.
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (gobCodec) ContentType() string {
+	return "application/x-gob"
+}
+
+func TestProtobufCodec_NotMessage(t *testing.T) {
+	if _, err := (ProtobufCodec{}).Marshal(struct{}{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := (ProtobufCodec{}).Unmarshal(nil, &struct{}{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestClient_Codec(t *testing.T) {
+	type point struct{ X, Y int }
+
+	client := NewProtobufClient(startParityServer(t), Config{Codec: gobCodec{}})
+	defer client.Close()
+	if err := client.SetData(point{X: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var data point
+	if err := (gobCodec{}).Unmarshal(client.data, &data); err != nil || data.X != 1 {
+		t.Fatalf("unexpected connect data %v: %v", data, err)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := RPCCall[point, point](ctx, client, "echo", point{X: 2, Y: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != (point{X: 2, Y: 3}) {
+		t.Fatalf("unexpected RPC result %v", resp)
+	}
+
+	sub, err := client.NewSubscription("ch", SubscriptionConfig{Codec: JSONCodec{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Codec().ContentType() != "application/json" {
+		t.Fatalf("unexpected subscription codec %s", sub.Codec().ContentType())
+	}
+	pubCh := make(chan PublicationEvent, 1)
+	sub.OnPublication(func(e PublicationEvent) {
+		pubCh <- e
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-pubCh:
+		var value struct{ N int }
+		if err := sub.Decode(e.Data, &value); err != nil || value.N != 1 {
+			t.Fatalf("unexpected publication %v: %v", value, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for publication")
+	}
+}
@@ -5,6 +5,8 @@ const (
 	disconnectedUnauthorized     uint32 = 1
 	disconnectBadProtocol        uint32 = 2
 	disconnectMessageSizeLimit   uint32 = 3
+	disconnectedRefreshFailed    uint32 = 4
+	disconnectedReconnectFailed  uint32 = 5
 )
 
 const (
@@ -13,11 +15,15 @@ const (
 	connectingNoPing           uint32 = 2
 	connectingSubscribeTimeout uint32 = 3
 	connectingUnsubscribeError uint32 = 4
+	connectingReconnectCalled  uint32 = 5
+	connectingSuspended        uint32 = 6
+	connectingEventQueueFull   uint32 = 7
 )
 
 const (
 	subscribingSubscribeCalled uint32 = 0
 	subscribingTransportClosed uint32 = 1
+	subscribingGapDetected     uint32 = 2
 )
 
 const (
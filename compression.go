@@ -0,0 +1,114 @@
+package centrifuge
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+const (
+	defaultCompressionThreshold = 1024
+	defaultMaxDecompressedSize  = 16 << 20
+)
+
+// Compressor compresses publication data inside PublicationEnvelope, see
+// SubscriptionConfig.Compressor. GzipCompressor is built-in, other algorithms
+// like zstd can be plugged in by wrapping their library into Compressor.
+type Compressor interface {
+	// Encoding is a name of compression set to PublicationEnvelope.Encoding.
+	Encoding() string
+	Compress(data []byte) ([]byte, error)
+	// Decompress must limit size of decompressed data, since publications
+	// come from other clients and may be crafted to decompress into huge data.
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCompressor is Compressor which uses gzip.
+type GzipCompressor struct {
+	// MaxDecompressedSize is a maximum size of decompressed data, Decompress
+	// returns error for larger data.
+	// Zero value means 16 MiB.
+	MaxDecompressedSize int
+}
+
+// Encoding returns gzip.
+func (GzipCompressor) Encoding() string {
+	return "gzip"
+}
+
+// Compress compresses data with gzip.
+func (GzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses gzip data, at most MaxDecompressedSize bytes.
+func (g GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	maxSize := g.MaxDecompressedSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxDecompressedSize
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	decompressed, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxSize {
+		return nil, fmt.Errorf("decompressed data exceeds %d bytes", maxSize)
+	}
+	return decompressed, nil
+}
+
+// compressEnvelope compresses envelope data if it exceeds threshold and
+// compression makes it smaller.
+func (s *Subscription) compressEnvelope(envelope *PublicationEnvelope) error {
+	if s.compressor == nil || len(envelope.Data) < s.compressionThreshold {
+		return nil
+	}
+	compressed, err := s.compressor.Compress(envelope.Data)
+	if err != nil {
+		return err
+	}
+	if len(compressed) >= len(envelope.Data) {
+		return nil
+	}
+	envelope.Data = compressed
+	envelope.Encoding = s.compressor.Encoding()
+	return nil
+}
+
+// decompressEnvelope decompresses envelope data compressed with gzip or with
+// Subscription Compressor.
+func (s *Subscription) decompressEnvelope(envelope *PublicationEnvelope) error {
+	if envelope.Encoding == "" {
+		return nil
+	}
+	var compressor Compressor
+	switch {
+	case s.compressor != nil && s.compressor.Encoding() == envelope.Encoding:
+		compressor = s.compressor
+	case envelope.Encoding == GzipCompressor{}.Encoding():
+		compressor = GzipCompressor{}
+	default:
+		return fmt.Errorf("unknown publication encoding %q", envelope.Encoding)
+	}
+	data, err := compressor.Decompress(envelope.Data)
+	if err != nil {
+		return err
+	}
+	envelope.Data = data
+	envelope.Encoding = ""
+	return nil
+}
@@ -0,0 +1,97 @@
+package centrifuge
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubscription_Compression(t *testing.T) {
+	client := NewJsonClient(startEchoPublishServer(t), Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := client.NewSubscription("ch", SubscriptionConfig{Compressor: GzipCompressor{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubCh := make(chan PublicationEvent, 1)
+	sub.OnPublication(func(e PublicationEvent) {
+		pubCh <- e
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte(`{"sku":"abc","qty":1}`), 200)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := sub.Publish(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-pubCh:
+		if !bytes.Equal(e.Data, data) {
+			t.Fatalf("unexpected data of length %d", len(e.Data))
+		}
+		if e.Envelope == nil || e.Envelope.Encoding != "" {
+			t.Fatalf("unexpected envelope %+v", e.Envelope)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for publication")
+	}
+}
+
+func TestSubscription_CompressEnvelope(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("ch", SubscriptionConfig{Compressor: GzipCompressor{}, CompressionThreshold: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small := &PublicationEnvelope{Data: bytes.Repeat([]byte("a"), 99)}
+	if err := sub.compressEnvelope(small); err != nil {
+		t.Fatal(err)
+	}
+	if small.Encoding != "" {
+		t.Fatal("expected data below threshold not compressed")
+	}
+
+	large := &PublicationEnvelope{Data: bytes.Repeat([]byte("a"), 1000)}
+	if err := sub.compressEnvelope(large); err != nil {
+		t.Fatal(err)
+	}
+	if large.Encoding != "gzip" || len(large.Data) >= 1000 {
+		t.Fatalf("expected compressed data, got %d bytes with encoding %q", len(large.Data), large.Encoding)
+	}
+	if err := sub.decompressEnvelope(large); err != nil {
+		t.Fatal(err)
+	}
+	if len(large.Data) != 1000 || large.Encoding != "" {
+		t.Fatalf("unexpected decompressed data of length %d", len(large.Data))
+	}
+
+	if err := sub.decompressEnvelope(&PublicationEnvelope{Encoding: "zstd"}); err == nil {
+		t.Fatal("expected error for unknown encoding")
+	}
+}
+
+func TestGzipCompressor_MaxDecompressedSize(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 1001)
+	compressed, err := GzipCompressor{}.Compress(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (GzipCompressor{MaxDecompressedSize: 1000}).Decompress(compressed); err == nil {
+		t.Fatal("expected error for data exceeding limit")
+	}
+	decompressed, err := GzipCompressor{MaxDecompressedSize: 1001}.Decompress(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, data) {
+		t.Fatal("unexpected decompressed data")
+	}
+}
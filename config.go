@@ -3,25 +3,112 @@ package centrifuge
 import (
 	"context"
 	"crypto/tls"
+	"io"
+	"log/slog"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
 	"time"
 )
 
+// RefreshFailurePolicy defines what Client does when it fails to refresh connection
+// token: GetToken (or TokenProvider) returned an error, refresh command timed out or
+// server returned a temporary error.
+type RefreshFailurePolicy string
+
+const (
+	// RefreshFailureRetry means Client retries refresh with exponential backoff, see
+	// Config.RefreshRetryMinDelay and Config.RefreshRetryMaxDelay. Connection stays
+	// active until server closes it due to token expiration.
+	RefreshFailureRetry RefreshFailurePolicy = ""
+	// RefreshFailureDisconnect means Client moves to disconnected state. Application
+	// may call Client.Connect later.
+	RefreshFailureDisconnect RefreshFailurePolicy = "disconnect"
+	// RefreshFailureClose means Client is closed and can't be used anymore.
+	RefreshFailureClose RefreshFailurePolicy = "close"
+)
+
+// TokenTransport defines how connection token is passed to a server upon connect.
+type TokenTransport string
+
+const (
+	// TokenTransportConnect means token is sent in Connect command.
+	TokenTransportConnect TokenTransport = ""
+	// TokenTransportHeader means token is sent in WebSocket Upgrade request in
+	// Authorization header with Bearer scheme.
+	TokenTransportHeader TokenTransport = "header"
+	// TokenTransportSubprotocol means token is sent in WebSocket Upgrade request as
+	// Sec-WebSocket-Protocol value with TokenSubprotocolPrefix.
+	TokenTransportSubprotocol TokenTransport = "subprotocol"
+	// TokenTransportQuery means token is sent in WebSocket Upgrade request URL as
+	// token query parameter. Note that URLs may be logged by proxies.
+	TokenTransportQuery TokenTransport = "query"
+)
+
+// TokenSubprotocolPrefix prefixes token in Sec-WebSocket-Protocol header when
+// TokenTransportSubprotocol used.
+const TokenSubprotocolPrefix = "centrifuge-token."
+
 // Config contains various client options.
 type Config struct {
 	// Token for a connection authentication.
 	Token string
 	// GetToken called by SDK to get or refresh connection token.
 	GetToken func(ConnectionTokenEvent) (string, error)
+	// TokenRefreshAhead allows refreshing connection and subscription tokens before
+	// they expire. Expiration time is known from server TTL or from JWT exp claim
+	// (whichever comes first), SDK calls GetToken this amount of time earlier. This
+	// helps to avoid connection issues when getting a new token is slow.
+	// Zero value means token is refreshed after server TTL, JWT exp claim is not
+	// inspected.
+	TokenRefreshAhead time.Duration
+	// TokenTransport defines how connection token is passed to a server upon connect.
+	// Allows interoperating with proxies and servers which authenticate WebSocket
+	// Upgrade request. Token refresh for an established connection is still done
+	// over Refresh command.
+	// Zero value means TokenTransportConnect.
+	TokenTransport TokenTransport
+	// CredentialStore allows reusing connection token across process restarts. Token
+	// obtained with GetToken or TokenProvider is saved to the store, and on start
+	// client connects with a stored token (if not expired) instead of requesting a
+	// new one. Stored token is removed when a server rejects it. See
+	// FileCredentialStore.
+	CredentialStore CredentialStore
+	// RefreshFailurePolicy defines what to do when connection token refresh fails.
+	// Use Client.OnRefreshError to be notified about failed refresh attempts.
+	// Zero value means RefreshFailureRetry.
+	RefreshFailurePolicy RefreshFailurePolicy
+	// RefreshRetryMinDelay is a delay before the first refresh retry when
+	// RefreshFailureRetry policy used. Delay grows exponentially with each attempt.
+	// Zero value means 10 * time.Second.
+	RefreshRetryMinDelay time.Duration
+	// RefreshRetryMaxDelay is a maximum delay between refresh retries.
+	// Zero value means 60 * time.Second.
+	RefreshRetryMaxDelay time.Duration
+	// TokenProvider allows managing connection and subscription tokens in one place,
+	// see TokenProvider docs. If set, GetToken is not used. Subscriptions with their
+	// own SubscriptionConfig.GetToken set still use it.
+	TokenProvider TokenProvider
 	// Data is an arbitrary data which can be sent to a server in a Connect command.
 	// Make sure it's a valid JSON when using JSON protocol client.
 	Data []byte
+	// GetData called by SDK before each connection attempt to get data for a Connect
+	// command. Allows sending data which changes over time. If set, Data is not used.
+	// If GetData returns an error then the attempt is considered failed and client
+	// reconnects with backoff.
+	GetData func(ConnectDataEvent) ([]byte, error)
 	// CookieJar specifies the cookie jar to send in WebSocket Upgrade request.
 	CookieJar http.CookieJar
 	// Header specifies custom HTTP Header to send in WebSocket Upgrade request.
 	Header http.Header
+	// GetHeaders called by SDK before each connection attempt to get dynamic HTTP
+	// headers for WebSocket Upgrade request, e.g. trace ids or rotating API keys.
+	// Returned headers are added to Header replacing values with the same key.
+	// If GetHeaders returns an error then the attempt is considered failed and
+	// client reconnects with backoff.
+	// Zero value means only Header is sent.
+	GetHeaders func() (http.Header, error)
 	// Name allows setting client name. You should only use a limited
 	// amount of client names throughout your applications – i.e. don't
 	// make it unique per user for example, this name semantically represents
@@ -54,11 +141,183 @@ type Config struct {
 	// TLSConfig specifies the TLS configuration to use with tls.Client.
 	// If nil, the default configuration is used.
 	TLSConfig *tls.Config
+	// GetClientCertificate is called on every TLS handshake to get a client
+	// certificate for mutual TLS. Allows rotating short-lived certificates without
+	// re-creating Client. If set, overrides GetClientCertificate and Certificates
+	// of TLSConfig.
+	GetClientCertificate func() (*tls.Certificate, error)
 	// EnableCompression specifies if the client should attempt to negotiate
 	// per message compression (RFC 7692). Setting this value to true does not
 	// guarantee that compression will be supported. Currently, only "no context
 	// takeover" modes are supported.
 	EnableCompression bool
+	// ReconnectJitter is a fraction of exponential reconnect delay which is randomized
+	// to spread reconnects of many clients over time (for example, after a server
+	// restart). Must be in (0, 1] range, 1 means delay is uniformly distributed between
+	// minimal delay and exponential delay. Negative value disables jitter. Also
+	// applies to subscription resubscribe and token refresh retry delays.
+	// Zero value means 1.
+	ReconnectJitter float64
+	// ReconnectRandSource is a source of randomness for ReconnectJitter. Using
+	// source with a fixed seed makes reconnect delays deterministic which is useful
+	// in tests. If nil, global math/rand source is used.
+	ReconnectRandSource rand.Source
+	// MaxReconnectAttempts limits the number of consecutive failed connection
+	// attempts. After that client moves to disconnected state and OnFailed event
+	// is emitted, so application may fall back to another transport. Counter is
+	// reset after successful connect.
+	// Zero value means no limit.
+	MaxReconnectAttempts int
+	// ReconnectWindow limits the time client tries to establish connection since
+	// the first failed attempt. After that client moves to disconnected state and
+	// OnFailed event is emitted.
+	// Zero value means no limit.
+	ReconnectWindow time.Duration
+	// ReconnectCoordinator may be shared by several clients of one process to
+	// stagger their reconnects, so they don't dial all at once after a network
+	// flap. See NewReconnectCoordinator.
+	// Zero value means client reconnects independently.
+	ReconnectCoordinator *ReconnectCoordinator
+	// CloseRetryAfter makes client respect retry_after field (delay in seconds)
+	// of JSON object sent as WebSocket close reason, e.g.
+	// {"code":3501,"reason":"overloaded","retry_after":5}. It's not part of
+	// Centrifuge protocol but a convention for custom servers or proxies to slow
+	// down reconnects. Retry-After header of HTTP 429 and 503 responses is always
+	// respected.
+	// Zero value means retry_after of close reason is ignored.
+	CloseRetryAfter bool
+	// NetworkMonitor notifies client about network changes to reconnect without
+	// waiting for backoff delay. See Client.NotifyNetworkChange.
+	// Zero value means no network monitoring.
+	NetworkMonitor NetworkMonitor
+	// Clock is used by reconnect and resubscribe backoff timers, server ping
+	// timeout and token refresh timers. Set clocktest.Clock in tests to control
+	// time.
+	// Zero value means real time.
+	Clock Clock
+	// Metrics collects client metrics. See metrics package for implementation with
+	// Prometheus exposition format.
+	// Zero value means metrics are not collected.
+	Metrics Metrics
+	// MeasureRTT enables round trip time measurement. Upon every ping from server
+	// client sends ping command and measures time until reply. Result is available
+	// over Client.RTT, Client.Stats and OnPing event.
+	// Zero value means RTT is not measured.
+	MeasureRTT bool
+	// MeasureLockContention enables measurement of wait and hold times of client
+	// and subscription internal locks with sampled stacks of holders. Result is
+	// available over Client.Stats.
+	// Zero value means lock contention is not measured.
+	MeasureLockContention bool
+	// SlowHandlerThreshold is an execution time of event handler after which handler
+	// is considered slow and OnSlowHandler event is emitted. Event handlers are
+	// called one by one, so slow handler delays all other events of client.
+	// Zero value means slow handlers are not reported.
+	SlowHandlerThreshold time.Duration
+	// SyncHandlers makes Client call event handlers inline on goroutine emitting
+	// event instead of dedicated event queue goroutine: on connection reader
+	// goroutine for events from server, on goroutine calling Client or
+	// Subscription method for events caused by the call. Useful for tests and
+	// simple programs which want handlers executed immediately. Caveats: handlers
+	// block reading from connection, may be called concurrently from different
+	// goroutines, and must not wait for replies from server, e.g. call
+	// Subscription.Publish – reply can't be read until handler returns.
+	// EventQueueSize, EventQueuePolicy and QueuedFor of events do not apply.
+	// Zero value means handlers are called from event queue.
+	SyncHandlers bool
+	// EventQueueSize limits the number of events waiting for handlers. When queue
+	// is full EventQueuePolicy is applied to publication, join, leave and message
+	// events, state events are queued anyway.
+	// Zero value means queue is not limited.
+	EventQueueSize int
+	// EventQueuePolicy defines what happens when event queue is full, see
+	// EventQueueSize.
+	// Zero value means EventQueueBlock.
+	EventQueuePolicy EventQueuePolicy
+	// StallTimeout enables watchdog which checks that reader, writer and event
+	// dispatcher goroutines of client make progress. When one is busy with the
+	// same work item longer than StallTimeout, e.g. blocked in event handler,
+	// OnStalled event is emitted with goroutine dump once per stall.
+	// Zero value means watchdog is disabled.
+	StallTimeout time.Duration
+	// ProtocolDump receives all protocol frames sent and received by client, one
+	// timestamped JSON encoded ProtocolFrame per line. Dump may be printed in human
+	// readable form with PrettyPrintProtocolDump. Connection is not affected by
+	// write errors, but slow writer slows down client.
+	// Zero value means frames are not dumped.
+	ProtocolDump io.Writer
+	// ProtocolDumpRedactTokens replaces connection and subscription tokens in
+	// ProtocolDump, so dump may be shared safely.
+	// Zero value means tokens are written as is.
+	ProtocolDumpRedactTokens bool
+	// OfflineQueue enables buffering of publications while client is not connected.
+	// Queued publications are sent in order after connect. With offline queue
+	// Client.Publish waits for delivery until ctx done, see also
+	// Client.EnqueuePublish.
+	// Zero value means Client.Publish waits for connect up to ReadTimeout and fails
+	// if client is disconnected.
+	OfflineQueue *OfflineQueueConfig
+	// PublishAsyncQueueSize is the maximum number of publications from
+	// Client.PublishAsync waiting to be sent.
+	// Zero value means 1024.
+	PublishAsyncQueueSize int
+	// MaxConcurrentServerRPC is the maximum number of server-initiated RPC
+	// handlers registered with Client.OnRPC running at once. Requests over the
+	// limit are replied with ServerRPCErrorTooManyRequests.
+	// Zero value means 64.
+	MaxConcurrentServerRPC int
+	// MaxBatchSize enables write batching if greater than 1: commands issued
+	// concurrently or within MaxBatchDelay are written to connection in one frame
+	// of up to MaxBatchSize commands. Reduces the number of frames and syscalls
+	// for high-frequency publishers at the cost of latency.
+	// Zero value means every command is written in a separate frame.
+	MaxBatchSize int
+	// MaxBatchDelay is how long to wait for more commands before writing a batch.
+	// Only used if MaxBatchSize is greater than 1.
+	// Zero value means commands are only coalesced while previous frame is being
+	// written.
+	MaxBatchDelay time.Duration
+	// CommandRateLimit limits the rate of publish and RPC commands sent to server,
+	// so a buggy caller can't flood server and get disconnected. Commands over
+	// limit fail with ErrRateLimited and OnThrottled event is emitted. Publications
+	// from offline queue wait for limit instead.
+	// Zero value means no limit.
+	CommandRateLimit *RateLimitConfig
+	// MaxPublishSize is the maximum size of publication data in bytes, larger
+	// publications fail with ErrPublishTooLarge without being sent. Set it below
+	// server frame limit and use Client.PublishChunked for larger payloads.
+	// Zero value means no limit.
+	MaxPublishSize int
+	// PositionStore persists stream positions of processed publications of
+	// Recoverable subscriptions. Upon creation subscription loads stored position
+	// and recovers publications missed while process was not running. See
+	// FilePositionStore.
+	// Zero value means subscriptions start from the current stream position.
+	PositionStore PositionStore
+	// RPCCancelMethod is an RPC method called when context of in-flight RPC is
+	// done before reply received, so server may abort the work. Its data is JSON
+	// object {"id": <command id of canceled RPC>}.
+	// Zero value means canceled RPC is only abandoned locally.
+	RPCCancelMethod string
+	// Codec encodes and decodes application payloads: data set by Client.SetData,
+	// RPCCall payloads and Subscription publication data, see Subscription.Decode
+	// and Subscription.PublishValue. Config.Data and raw []byte methods are not
+	// affected.
+	// Zero value means JSONCodec.
+	Codec Codec
+	// ResubscribeBatchSize limits the number of subscribe commands sent to a server in
+	// one frame when client-side subscriptions are restored after reconnect. The next
+	// batch is sent only after replies to the previous one received. Use
+	// Client.OnResubscribeProgress to track the progress.
+	// Zero or negative value means 100.
+	ResubscribeBatchSize int
+	// EventReplaySize enables keeping the last EventReplaySize client lifecycle events
+	// (connecting, connected, disconnected). Handlers set with Client.OnConnecting,
+	// Client.OnConnected and Client.OnDisconnected after Client.Connect called then
+	// still observe events which already happened, in the original order. Useful when
+	// handlers are attached by independent parts of an application.
+	// Zero value means events are not buffered.
+	EventReplaySize int
 	// LogLevel to use, by default no logs will be exposed by centrifuge-go. Most of the
 	// time available protocol callbacks cover all necessary information about client-server
 	// communication.
@@ -68,4 +327,11 @@ type Config struct {
 	// intermediary channel buffer (fixed capacity 256). If your LogHandler is not
 	// processing log entries fast enough, centrifuge-go will drop log entries.
 	LogHandler func(LogEntry)
+	// Logger receives log entries as structured slog records: state transitions,
+	// token refreshes, reconnect decisions, dropped frames, etc. Records are logged
+	// synchronously, debug entries use slog.LevelDebug and trace entries use
+	// SlogLevelTrace. When set, Logger handler decides which entries are enabled
+	// and LogLevel with LogHandler are not used.
+	// Zero value means LogLevel and LogHandler are used.
+	Logger *slog.Logger
 }
@@ -0,0 +1,581 @@
+package centrifuge
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClient_GetData(t *testing.T) {
+	frames := make(chan []byte, 16)
+	u := startFrameServer(t, frames)
+
+	attempts := make(chan int, 16)
+	client := NewJsonClient(u, Config{
+		Data: []byte(`{"static":true}`),
+		GetData: func(e ConnectDataEvent) ([]byte, error) {
+			attempts <- e.Attempt
+			if e.Attempt == 1 {
+				return nil, errors.New("boom")
+			}
+			return []byte(fmt.Sprintf(`{"attempt":%d}`, e.Attempt)), nil
+		},
+	})
+	defer client.Close()
+	errCh := make(chan error, 1)
+	client.OnError(func(e ErrorEvent) {
+		select {
+		case errCh <- e.Error:
+		default:
+		}
+	})
+
+	if err := client.Connect(); err == nil {
+		t.Fatal("expected error from the first attempt")
+	}
+	var connectErr ConnectError
+	if err := <-errCh; !errors.As(err, &connectErr) {
+		t.Fatalf("expected ConnectError, got %v", err)
+	}
+	for _, expected := range []int{1, 2} {
+		select {
+		case attempt := <-attempts:
+			if attempt != expected {
+				t.Fatalf("expected attempt %d, got %d", expected, attempt)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for GetData call")
+		}
+	}
+	select {
+	case frame := <-frames:
+		if !bytes.Contains(frame, []byte(`"attempt":2`)) || bytes.Contains(frame, []byte("static")) {
+			t.Fatalf("unexpected connect frame: %s", frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for connect frame")
+	}
+}
+
+func TestClient_GetHeaders(t *testing.T) {
+	requests := make(chan http.Header, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Header
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	var calls int32
+	client := NewJsonClient("ws"+strings.TrimPrefix(server.URL, "http"), Config{
+		Header: http.Header{"X-Static": []string{"s"}, "X-Trace": []string{"static"}},
+		GetHeaders: func() (http.Header, error) {
+			n := atomic.AddInt32(&calls, 1)
+			if n == 1 {
+				return nil, errors.New("boom")
+			}
+			return http.Header{"x-trace": []string{fmt.Sprintf("t%d", n)}}, nil
+		},
+	})
+	defer client.Close()
+	errCh := make(chan error, 1)
+	client.OnError(func(e ErrorEvent) {
+		select {
+		case errCh <- e.Error:
+		default:
+		}
+	})
+
+	if err := client.Connect(); err == nil {
+		t.Fatal("expected error from the first attempt")
+	}
+	var connectErr ConnectError
+	if err := <-errCh; !errors.As(err, &connectErr) {
+		t.Fatalf("expected ConnectError, got %v", err)
+	}
+	select {
+	case header := <-requests:
+		if header.Get("X-Trace") != "t2" || header.Get("X-Static") != "s" {
+			t.Fatalf("unexpected upgrade request headers: %v", header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for upgrade request")
+	}
+}
+
+func TestClient_TokenTransport(t *testing.T) {
+	testCases := []struct {
+		transport TokenTransport
+		check     func(r *http.Request) bool
+	}{
+		{TokenTransportHeader, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "Bearer token"
+		}},
+		{TokenTransportSubprotocol, func(r *http.Request) bool {
+			protocols := websocket.Subprotocols(r)
+			return len(protocols) == 1 && protocols[0] == TokenSubprotocolPrefix+"token"
+		}},
+		{TokenTransportQuery, func(r *http.Request) bool {
+			return r.URL.Query().Get("token") == "token"
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.transport), func(t *testing.T) {
+			requests := make(chan *http.Request, 1)
+			frames := make(chan []byte, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests <- r
+				conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				frames <- data
+			}))
+			defer server.Close()
+
+			client := NewJsonClient("ws"+strings.TrimPrefix(server.URL, "http"), Config{
+				Token:          "token",
+				TokenTransport: tc.transport,
+			})
+			defer client.Close()
+			if err := client.Connect(); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case r := <-requests:
+				if !tc.check(r) {
+					t.Fatalf("token not found in upgrade request: %v, %s", r.Header, r.URL)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for upgrade request")
+			}
+			select {
+			case frame := <-frames:
+				if bytes.Contains(frame, []byte("token")) {
+					t.Fatalf("token must not be sent in connect command: %s", frame)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for connect frame")
+			}
+		})
+	}
+}
+
+func TestClient_OnAuthRequired(t *testing.T) {
+	frames := make(chan []byte, 1)
+	u := startFrameServer(t, frames)
+
+	var authorized atomic.Bool
+	client := NewJsonClient(u, Config{
+		GetToken: func(ConnectionTokenEvent) (string, error) {
+			if !authorized.Load() {
+				return "", ErrUnauthorized
+			}
+			return "new_token", nil
+		},
+	})
+	defer client.Close()
+
+	events := make(chan string, 2)
+	client.OnDisconnected(func(DisconnectedEvent) {
+		events <- "disconnected"
+	})
+	authRequired := make(chan AuthRequiredEvent, 1)
+	client.OnAuthRequired(func(e AuthRequiredEvent) {
+		events <- "auth_required"
+		authRequired <- e
+	})
+
+	_ = client.Connect()
+	for _, expected := range []string{"disconnected", "auth_required"} {
+		select {
+		case e := <-events:
+			if e != expected {
+				t.Fatalf("expected %s event, got %s", expected, e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+	}
+	e := <-authRequired
+	if e.Code != disconnectedUnauthorized || !e.ReconnectAllowed {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+	if client.State() != StateDisconnected {
+		t.Fatalf("unexpected state: %s", client.State())
+	}
+
+	authorized.Store(true)
+	if err := client.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case frame := <-frames:
+		if !bytes.Contains(frame, []byte("new_token")) {
+			t.Fatalf("expected new token in connect frame: %s", frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for connect frame")
+	}
+}
+
+func testCertificate(t *testing.T, serial int64) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestClient_GetClientCertificate(t *testing.T) {
+	serials := make(chan int64, 2)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serials <- r.TLS.PeerCertificates[0].SerialNumber.Int64()
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	var serial atomic.Int64
+	client := NewJsonClient("ws"+strings.TrimPrefix(server.URL, "http"), Config{
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		GetClientCertificate: func() (*tls.Certificate, error) {
+			return testCertificate(t, serial.Load()), nil
+		},
+	})
+	defer client.Close()
+
+	for _, s := range []int64{1, 2} {
+		// Certificate rotated between connections.
+		serial.Store(s)
+		if err := client.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got := <-serials:
+			if got != s {
+				t.Fatalf("expected certificate %d, got %d", s, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for connection")
+		}
+		if err := client.Disconnect(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClient_MaxReconnectAttempts(t *testing.T) {
+	// Nothing listens on this port, so every attempt fails.
+	server := httptest.NewServer(http.NotFoundHandler())
+	u := "ws" + strings.TrimPrefix(server.URL, "http")
+	server.Close()
+
+	client := NewJsonClient(u, Config{
+		MaxReconnectAttempts: 3,
+	})
+	defer client.Close()
+	client.reconnectStrategy = &backoffReconnect{MinDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	events := make(chan string, 2)
+	client.OnDisconnected(func(e DisconnectedEvent) {
+		if e.Code == disconnectedReconnectFailed {
+			events <- "disconnected"
+		}
+	})
+	failed := make(chan FailedEvent, 1)
+	client.OnFailed(func(e FailedEvent) {
+		events <- "failed"
+		failed <- e
+	})
+	_ = client.Connect()
+	for _, expected := range []string{"disconnected", "failed"} {
+		select {
+		case e := <-events:
+			if e != expected {
+				t.Fatalf("expected %s event, got %s", expected, e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+	}
+	e := <-failed
+	if e.NumAttempts != 3 || len(e.Attempts) != 3 {
+		t.Fatalf("unexpected failed event: %#v", e)
+	}
+	for _, attempt := range e.Attempts {
+		if attempt.Error == nil || attempt.Time.IsZero() {
+			t.Fatalf("unexpected attempt: %#v", attempt)
+		}
+	}
+	if client.State() != StateDisconnected {
+		t.Fatalf("unexpected state: %s", client.State())
+	}
+}
+
+func TestClient_ReconnectWindow(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+		ReconnectWindow: time.Minute,
+	})
+	defer client.Close()
+	if client.reconnectBudgetExhaustedLocked(errors.New("boom")) {
+		t.Fatal("budget must not be exhausted on first attempt")
+	}
+	client.reconnectHistory[0].Time = time.Now().Add(-time.Minute)
+	if !client.reconnectBudgetExhaustedLocked(errors.New("boom")) {
+		t.Fatal("budget must be exhausted after reconnect window passed")
+	}
+}
+
+// startConnectServer starts server which replies to connect and subscribe commands
+// and sends one ping after connect. If dropFirst is true then the first connection
+// is closed right after connect reply.
+func startConnectServer(t *testing.T, dropFirst bool) (string, *atomic.Int32) {
+	numConns := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		var cmd struct {
+			ID uint32 `json:"id"`
+		}
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return
+		}
+		reply := fmt.Sprintf(`{"id":%d,"connect":{"client":"c","subs":{"news":{"recoverable":true,"was_recovering":true,"recovered":true}}}}`, cmd.ID)
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+			return
+		}
+		if numConns.Add(1) == 1 && dropFirst {
+			return
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("{}")); err != nil {
+			return
+		}
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			// Several commands may be sent in one frame.
+			var replies []string
+			for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+				if err := json.Unmarshal(line, &cmd); err != nil {
+					return
+				}
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"subscribe":{}}`, cmd.ID))
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(strings.Join(replies, "\n"))); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http"), numConns
+}
+
+func TestClient_OnReconnected(t *testing.T) {
+	u, _ := startConnectServer(t, true)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	events := make(chan string, 4)
+	client.OnConnected(func(ConnectedEvent) {
+		events <- "connected"
+	})
+	reconnected := make(chan ReconnectedEvent, 1)
+	client.OnReconnected(func(e ReconnectedEvent) {
+		events <- "reconnected"
+		reconnected <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"connected", "connected", "reconnected"} {
+		select {
+		case e := <-events:
+			if e != expected {
+				t.Fatalf("expected %s event, got %s", expected, e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+	}
+	e := <-reconnected
+	if e.ClientID != "c" || !e.Recovered || e.Downtime <= 0 {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+}
+
+func TestClient_SuspendResume(t *testing.T) {
+	u, numConns := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	connected := make(chan struct{}, 2)
+	client.OnConnected(func(ConnectedEvent) {
+		connected <- struct{}{}
+	})
+	connecting := make(chan ConnectingEvent, 2)
+	client.OnConnecting(func(e ConnectingEvent) {
+		connecting <- e
+	})
+	sub, err := client.NewSubscription("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribed := make(chan struct{}, 2)
+	sub.OnSubscribed(func(SubscribedEvent) {
+		subscribed <- struct{}{}
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent := func() {
+		select {
+		case <-connected:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for connected event")
+		}
+	}
+	waitEvent()
+	<-connecting
+	<-subscribed
+
+	if err := client.Suspend(); err != nil {
+		t.Fatal(err)
+	}
+	e := <-connecting
+	if e.Code != connectingSuspended || client.State() != StateConnecting {
+		t.Fatalf("unexpected event: %#v, state: %s", e, client.State())
+	}
+	if sub.State() != SubStateSubscribing {
+		t.Fatalf("unexpected subscription state: %s", sub.State())
+	}
+	// Default minimal reconnect delay is 200ms, client must not reconnect.
+	time.Sleep(500 * time.Millisecond)
+	if n := numConns.Load(); n != 1 {
+		t.Fatalf("unexpected number of connections: %d", n)
+	}
+
+	if err := client.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent()
+	if n := numConns.Load(); n != 2 {
+		t.Fatalf("unexpected number of connections: %d", n)
+	}
+	select {
+	case <-subscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for subscription restore")
+	}
+}
+
+type testMetrics struct {
+	noopMetrics
+	connects      atomic.Int32
+	bytesSent     atomic.Int64
+	bytesReceived atomic.Int64
+}
+
+func (m *testMetrics) IncConnects()           { m.connects.Add(1) }
+func (m *testMetrics) AddBytesSent(n int)     { m.bytesSent.Add(int64(n)) }
+func (m *testMetrics) AddBytesReceived(n int) { m.bytesReceived.Add(int64(n)) }
+
+func TestClient_Metrics(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	m := &testMetrics{}
+	client := NewJsonClient(u, Config{Metrics: m})
+	defer client.Close()
+	connected := make(chan struct{}, 1)
+	client.OnConnected(func(ConnectedEvent) {
+		connected <- struct{}{}
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for connected event")
+	}
+	if m.connects.Load() != 1 || m.bytesSent.Load() == 0 || m.bytesReceived.Load() == 0 {
+		t.Fatalf("unexpected metrics: %d, %d, %d", m.connects.Load(), m.bytesSent.Load(), m.bytesReceived.Load())
+	}
+}
+
+func TestClient_OnSlowHandler(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{SlowHandlerThreshold: 50 * time.Millisecond})
+	defer client.Close()
+	client.OnConnected(func(ConnectedEvent) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	slow := make(chan SlowHandlerEvent, 1)
+	client.OnSlowHandler(func(e SlowHandlerEvent) {
+		slow <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-slow:
+		if e.Duration < 100*time.Millisecond {
+			t.Fatalf("unexpected duration: %v", e.Duration)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for slow handler event")
+	}
+}
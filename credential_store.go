@@ -0,0 +1,102 @@
+package centrifuge
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Credentials is a connection token kept in CredentialStore.
+type Credentials struct {
+	Token string `json:"token"`
+	// ExpiresAt is a token expiration time. Zero value means expiration time is
+	// unknown.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CredentialStore allows persisting connection token, so it can be reused across
+// process restarts instead of calling Config.GetToken on every start. Set it over
+// Config.CredentialStore.
+type CredentialStore interface {
+	// Load returns stored credentials. Empty Credentials returned if nothing stored.
+	Load() (Credentials, error)
+	// Save stores credentials. Empty Credentials mean stored token must be removed.
+	Save(Credentials) error
+}
+
+// FileCredentialStore is a CredentialStore which keeps credentials in a file
+// encrypted with AES-GCM.
+type FileCredentialStore struct {
+	path string
+	aead cipher.AEAD
+}
+
+var _ CredentialStore = (*FileCredentialStore)(nil)
+
+// NewFileCredentialStore creates FileCredentialStore. The key must be 16, 24 or 32
+// bytes long to select AES-128, AES-192 or AES-256.
+func NewFileCredentialStore(path string, key []byte) (*FileCredentialStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FileCredentialStore{path: path, aead: aead}, nil
+}
+
+// Load reads and decrypts credentials from file.
+func (s *FileCredentialStore) Load() (Credentials, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Credentials{}, nil
+		}
+		return Credentials{}, err
+	}
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return Credentials{}, errors.New("malformed credentials file")
+	}
+	plaintext, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var creds Credentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return Credentials{}, err
+	}
+	return creds, nil
+}
+
+// Save encrypts and writes credentials to file. File is replaced atomically.
+func (s *FileCredentialStore) Save(creds Credentials) error {
+	if creds.Token == "" {
+		err := os.Remove(s.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data := s.aead.Seal(nonce, nonce, plaintext, nil)
+	return writeFileAtomic(s.path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,118 @@
+package centrifuge
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileCredentialStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	key := bytes.Repeat([]byte("k"), 32)
+	store, err := NewFileCredentialStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := store.Load()
+	if err != nil || creds.Token != "" {
+		t.Fatalf("expected empty credentials for missing file: %v, %v", creds, err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := store.Save(Credentials{Token: "secret_token", ExpiresAt: expiresAt}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret_token")) {
+		t.Fatal("token must be encrypted")
+	}
+	creds, err = store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Token != "secret_token" || !creds.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected credentials: %v", creds)
+	}
+
+	otherStore, err := NewFileCredentialStore(path, bytes.Repeat([]byte("x"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := otherStore.Load(); err == nil {
+		t.Fatal("expected error with wrong key")
+	}
+
+	if err := store.Save(Credentials{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected file to be removed")
+	}
+}
+
+func TestClient_CredentialStore(t *testing.T) {
+	frames := make(chan []byte, 1)
+	u := startFrameServer(t, frames)
+	store, err := NewFileCredentialStore(filepath.Join(t.TempDir(), "token"), bytes.Repeat([]byte("k"), 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(Credentials{Token: "stored_token"}); err != nil {
+		t.Fatal(err)
+	}
+	client := NewJsonClient(u, Config{
+		GetToken: func(ConnectionTokenEvent) (string, error) {
+			t.Error("GetToken must not be called when stored token is valid")
+			return "", ErrUnauthorized
+		},
+		CredentialStore: store,
+	})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case frame := <-frames:
+		if !bytes.Contains(frame, []byte("stored_token")) {
+			t.Fatalf("expected stored token in connect frame: %s", frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for connect frame")
+	}
+}
+
+func TestClient_CredentialStoreKeptOnRefresh(t *testing.T) {
+	store, err := NewFileCredentialStore(filepath.Join(t.TempDir(), "token"), bytes.Repeat([]byte("k"), 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(Credentials{Token: "stored_token"}); err != nil {
+		t.Fatal(err)
+	}
+	client := NewJsonClient("ws://localhost:9000/connection/websocket", Config{
+		GetToken: func(ConnectionTokenEvent) (string, error) {
+			return "", errors.New("boom")
+		},
+		CredentialStore:      store,
+		RefreshRetryMinDelay: time.Hour,
+	})
+	defer client.Close()
+	client.mu.Lock()
+	client.state = StateConnected
+	client.mu.Unlock()
+	client.sendRefresh()
+	creds, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Token != "stored_token" {
+		t.Fatalf("stored token must be kept on proactive refresh, got %q", creds.Token)
+	}
+}
@@ -0,0 +1,82 @@
+package centrifuge
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// maxFailedAttempts limits the number of last failed connection attempts kept
+// for DebugHandler.
+const maxFailedAttempts = 32
+
+// recordFailedAttemptLocked keeps failed connection attempt in a ring buffer
+// rendered by DebugHandler. Unlike reconnect history used for reconnect budget
+// it's never reset, so it shows what happened before the last successful connect.
+// Lock must be held outside.
+func (c *Client) recordFailedAttemptLocked(err error) {
+	if len(c.failedAttempts) == maxFailedAttempts {
+		copy(c.failedAttempts, c.failedAttempts[1:])
+		c.failedAttempts = c.failedAttempts[:len(c.failedAttempts)-1]
+	}
+	c.failedAttempts = append(c.failedAttempts, ReconnectAttempt{Time: time.Now(), Error: err})
+}
+
+type debugAttempt struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+type debugInfo struct {
+	Goroutines          int            `json:"goroutines"`
+	CallbackQueueLength int            `json:"callback_queue_length"`
+	ReconnectAttempts   int            `json:"reconnect_attempts"`
+	ReconnectPending    bool           `json:"reconnect_pending"`
+	ReconnectDelay      string         `json:"reconnect_delay,omitempty"`
+	Suspended           bool           `json:"suspended"`
+	FailedAttempts      []debugAttempt `json:"failed_attempts"`
+	Stats               Stats          `json:"stats"`
+}
+
+func (c *Client) debugInfo() debugInfo {
+	info := debugInfo{
+		Goroutines:          runtime.NumGoroutine(),
+		CallbackQueueLength: c.cbQueue.Len(),
+		Stats:               c.Stats(),
+	}
+	c.mu.RLock()
+	info.ReconnectAttempts = c.reconnectAttempts
+	info.ReconnectPending = c.reconnectPending
+	if c.reconnectPending {
+		info.ReconnectDelay = c.reconnectDelay.String()
+	}
+	info.Suspended = c.suspended
+	info.FailedAttempts = make([]debugAttempt, 0, len(c.failedAttempts))
+	for _, attempt := range c.failedAttempts {
+		info.FailedAttempts = append(info.FailedAttempts, debugAttempt{Time: attempt.Time, Error: attempt.Error.Error()})
+	}
+	c.mu.RUnlock()
+	return info
+}
+
+// DebugHandler returns http.Handler which renders live internal state of client
+// in JSON: number of goroutines in process, callback queue length, reconnect
+// state, last failed connection attempts and Client.Stats. It may be exposed
+// together with net/http/pprof handlers, e.g.:
+//
+//	http.Handle("/debug/centrifuge", centrifuge.DebugHandler(client))
+//
+// Response contains channel names and connection details, so the handler must
+// not be exposed publicly.
+func DebugHandler(client *Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		data, err := json.MarshalIndent(client.debugInfo(), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
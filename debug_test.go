@@ -0,0 +1,37 @@
+package centrifuge
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+	if _, err := client.NewSubscription("news"); err != nil {
+		t.Fatal(err)
+	}
+	client.mu.Lock()
+	for i := 0; i < maxFailedAttempts+1; i++ {
+		client.recordFailedAttemptLocked(errors.New("boom"))
+	}
+	client.mu.Unlock()
+
+	rec := httptest.NewRecorder()
+	DebugHandler(client).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/centrifuge", nil))
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected content type: %s", rec.Header().Get("Content-Type"))
+	}
+	var info debugInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Goroutines == 0 || info.Stats.State != StateDisconnected || len(info.FailedAttempts) != maxFailedAttempts {
+		t.Fatalf("unexpected debug info: %#v", info)
+	}
+	if info.FailedAttempts[0].Error != "boom" {
+		t.Fatalf("unexpected failed attempt: %#v", info.FailedAttempts[0])
+	}
+}
@@ -0,0 +1,129 @@
+package centrifuge
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PublicationEnvelope carries metadata alongside publication data. It's
+// published as JSON object, so it's valid for both JSON and Protobuf protocols.
+// See SubscriptionConfig.Envelope.
+type PublicationEnvelope struct {
+	// Headers are custom key-value pairs, e.g. for routing.
+	Headers map[string]string `json:"headers,omitempty"`
+	// ContentType of Data, e.g. application/json.
+	ContentType string `json:"content_type,omitempty"`
+	// SchemaVersion of Data.
+	SchemaVersion string `json:"schema_version,omitempty"`
+	// Timestamp is a publisher time in Unix milliseconds.
+	Timestamp int64 `json:"ts,omitempty"`
+	// Encoding is a compression of Data, see SubscriptionConfig.Compressor.
+	// Received envelopes are decompressed, so it's always empty in
+	// PublicationEvent.Envelope.
+	Encoding string `json:"encoding,omitempty"`
+	// Data published.
+	Data []byte `json:"data"`
+}
+
+// Time returns Timestamp as time.Time, zero time if Timestamp not set.
+func (e PublicationEnvelope) Time() time.Time {
+	if e.Timestamp == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(e.Timestamp)
+}
+
+// EncodeEnvelope encodes PublicationEnvelope.
+func EncodeEnvelope(envelope PublicationEnvelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+// DecodeEnvelope decodes PublicationEnvelope, e.g. from History publications.
+// It returns ErrNotEnvelope if data is a JSON object without data key, so
+// arbitrary JSON objects are not mistaken for envelopes.
+func DecodeEnvelope(data []byte) (PublicationEnvelope, error) {
+	var envelope struct {
+		PublicationEnvelope
+		// Data shadows embedded Data to detect presence of data key, it's
+		// "null" for envelope with nil Data.
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return PublicationEnvelope{}, err
+	}
+	if envelope.Data == nil {
+		return PublicationEnvelope{}, ErrNotEnvelope
+	}
+	if err := json.Unmarshal(envelope.Data, &envelope.PublicationEnvelope.Data); err != nil {
+		return PublicationEnvelope{}, err
+	}
+	return envelope.PublicationEnvelope, nil
+}
+
+// WithHeaders sets envelope headers of publication, see SubscriptionConfig.Envelope.
+func WithHeaders(headers map[string]string) PublishOption {
+	return func(options *PublishOptions) {
+		options.Headers = headers
+	}
+}
+
+// WithSchemaVersion sets envelope schema version of publication, see
+// SubscriptionConfig.Envelope.
+func WithSchemaVersion(version string) PublishOption {
+	return func(options *PublishOptions) {
+		options.SchemaVersion = version
+	}
+}
+
+// WithContentType sets envelope content type of publication, see
+// SubscriptionConfig.Envelope. Subscription.PublishValue sets it from Codec.
+func WithContentType(contentType string) PublishOption {
+	return func(options *PublishOptions) {
+		options.ContentType = contentType
+	}
+}
+
+func (s *Subscription) wrapEnvelope(data []byte, opts *PublishOptions) ([]byte, error) {
+	envelope := PublicationEnvelope{
+		Headers:       opts.Headers,
+		ContentType:   opts.ContentType,
+		SchemaVersion: opts.SchemaVersion,
+		Timestamp:     time.Now().UnixMilli(),
+		Data:          data,
+	}
+	if err := s.compressEnvelope(&envelope); err != nil {
+		return nil, err
+	}
+	return EncodeEnvelope(envelope)
+}
+
+// unwrapEnvelope replaces event data with enveloped data. Publications which
+// are not envelopes are passed as is. It must be called without s.mu held.
+func (s *Subscription) unwrapEnvelope(event PublicationEvent) PublicationEvent {
+	if !s.envelope {
+		return event
+	}
+	envelope, err := DecodeEnvelope(event.Data)
+	if err != nil {
+		if s.centrifuge.logLevelEnabled(LogLevelDebug) {
+			s.centrifuge.log(LogLevelDebug, "publication is not an envelope", map[string]string{
+				"channel": s.Channel,
+				"error":   err.Error(),
+			})
+		}
+		return event
+	}
+	if err := s.decompressEnvelope(&envelope); err != nil {
+		if s.centrifuge.logLevelEnabled(LogLevelDebug) {
+			s.centrifuge.log(LogLevelDebug, "error decompressing publication", map[string]string{
+				"channel": s.Channel,
+				"error":   err.Error(),
+			})
+		}
+		return event
+	}
+	event.Data = envelope.Data
+	envelope.Data = nil
+	event.Envelope = &envelope
+	return event
+}
@@ -0,0 +1,144 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// startEchoPublishServer pushes published data back as publication.
+func startEchoPublishServer(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			cmds, err := DecodeProtocolCommands(ProtocolTypeJSON, data)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var replies []*ProtocolReply
+			for _, cmd := range cmds {
+				switch {
+				case cmd.Connect != nil:
+					replies = append(replies, &ProtocolReply{Id: cmd.Id, Connect: &ProtocolConnectResult{Client: "c"}})
+				case cmd.Subscribe != nil:
+					replies = append(replies, &ProtocolReply{Id: cmd.Id, Subscribe: &ProtocolSubscribeResult{}})
+				case cmd.Publish != nil:
+					replies = append(replies,
+						&ProtocolReply{Id: cmd.Id, Publish: &ProtocolPublishResult{}},
+						&ProtocolReply{Push: &ProtocolPush{Channel: cmd.Publish.Channel, Pub: &ProtocolPublication{Data: cmd.Publish.Data}}},
+					)
+				}
+			}
+			data, err = EncodeProtocolReplies(ProtocolTypeJSON, replies...)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestSubscription_Envelope(t *testing.T) {
+	client := NewJsonClient(startEchoPublishServer(t), Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := client.NewSubscription("ch", SubscriptionConfig{Envelope: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubCh := make(chan PublicationEvent, 1)
+	sub.OnPublication(func(e PublicationEvent) {
+		pubCh <- e
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = sub.PublishValue(ctx, map[string]int{"n": 1}, WithHeaders(map[string]string{"tenant": "t1"}), WithSchemaVersion("2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-pubCh:
+		if string(e.Data) != `{"n":1}` {
+			t.Fatalf("unexpected data %s", e.Data)
+		}
+		if e.Envelope == nil {
+			t.Fatal("expected envelope")
+		}
+		if e.Envelope.Headers["tenant"] != "t1" || e.Envelope.SchemaVersion != "2" || e.Envelope.ContentType != "application/json" {
+			t.Fatalf("unexpected envelope %+v", e.Envelope)
+		}
+		if time.Since(e.Envelope.Time()) > time.Minute {
+			t.Fatalf("unexpected envelope time %v", e.Envelope.Time())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for publication")
+	}
+}
+
+func TestSubscription_EnvelopeNotEnveloped(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("ch", SubscriptionConfig{Envelope: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := sub.unwrapEnvelope(PublicationEvent{Publication: Publication{Data: []byte(`[1]`)}})
+	if event.Envelope != nil || string(event.Data) != `[1]` {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	// JSON object without data key is not an envelope.
+	event = sub.unwrapEnvelope(PublicationEvent{Publication: Publication{Data: []byte(`{"input":"x"}`)}})
+	if event.Envelope != nil || string(event.Data) != `{"input":"x"}` {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestDecodeEnvelope(t *testing.T) {
+	if _, err := DecodeEnvelope([]byte(`{"input":"x","headers":{"a":"b"}}`)); !errors.Is(err, ErrNotEnvelope) {
+		t.Fatalf("expected ErrNotEnvelope, got %v", err)
+	}
+	data, err := EncodeEnvelope(PublicationEnvelope{Headers: map[string]string{"a": "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := DecodeEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Headers["a"] != "b" || envelope.Data != nil {
+		t.Fatalf("unexpected envelope %+v", envelope)
+	}
+	data, err = EncodeEnvelope(PublicationEnvelope{Data: []byte("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err = DecodeEnvelope(data)
+	if err != nil || string(envelope.Data) != "x" {
+		t.Fatalf("unexpected envelope %+v: %v", envelope, err)
+	}
+}
@@ -3,6 +3,7 @@ package centrifuge
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 var (
@@ -23,8 +24,34 @@ var (
 	// ErrUnauthorized is a special error which may be returned by application
 	// from GetToken function to indicate lack of operation permission.
 	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotEnvelope returned by DecodeEnvelope if data is not a
+	// PublicationEnvelope, i.e. not a JSON object with data key.
+	ErrNotEnvelope = errors.New("not an envelope")
 )
 
+// PanicError is passed to OnError handler when user callback panicked. Panic is
+// recovered and client keeps working.
+type PanicError struct {
+	// Callback which panicked.
+	Callback string
+	// Value passed to panic.
+	Value any
+	// Stack of panicked goroutine.
+	Stack []byte
+}
+
+func (p PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v", p.Callback, p.Value)
+}
+
+// Unwrap returns panic value if it is an error.
+func (p PanicError) Unwrap() error {
+	if err, ok := p.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
 type TransportError struct {
 	Err error
 }
@@ -61,6 +88,30 @@ func (r RefreshError) Unwrap() error {
 	return r.Err
 }
 
+type CredentialStoreError struct {
+	Err error
+}
+
+func (c CredentialStoreError) Error() string {
+	return fmt.Sprintf("credential store error: %v", c.Err)
+}
+
+func (c CredentialStoreError) Unwrap() error {
+	return c.Err
+}
+
+type SpoolError struct {
+	Err error
+}
+
+func (s SpoolError) Error() string {
+	return fmt.Sprintf("spool error: %v", s.Err)
+}
+
+func (s SpoolError) Unwrap() error {
+	return s.Err
+}
+
 type ConfigurationError struct {
 	Err error
 }
@@ -96,3 +147,55 @@ func (s SubscriptionRefreshError) Error() string {
 func (s SubscriptionRefreshError) Unwrap() error {
 	return s.Err
 }
+
+type HistoryRecoveryError struct {
+	Err error
+}
+
+func (h HistoryRecoveryError) Error() string {
+	return fmt.Sprintf("history recovery error: %v", h.Err)
+}
+
+func (h HistoryRecoveryError) Unwrap() error {
+	return h.Err
+}
+
+type PositionStoreError struct {
+	Err error
+}
+
+func (p PositionStoreError) Error() string {
+	return fmt.Sprintf("position store error: %v", p.Err)
+}
+
+func (p PositionStoreError) Unwrap() error {
+	return p.Err
+}
+
+type RPCCallError struct {
+	Index  int
+	Method string
+	Err    error
+}
+
+func (r RPCCallError) Error() string {
+	return fmt.Sprintf("rpc %d (%s) error: %v", r.Index, r.Method, r.Err)
+}
+
+func (r RPCCallError) Unwrap() error {
+	return r.Err
+}
+
+// GoroutineLeakError is passed to OnError when client goroutines did not exit
+// after Client.Close.
+type GoroutineLeakError struct {
+	Goroutines []GoroutineInfo
+}
+
+func (g GoroutineLeakError) Error() string {
+	names := make([]string, 0, len(g.Goroutines))
+	for _, info := range g.Goroutines {
+		names = append(names, info.Name)
+	}
+	return fmt.Sprintf("goroutines running after close: %s", strings.Join(names, ", "))
+}
@@ -49,6 +49,13 @@ func TestErrors(t *testing.T) {
 				return centrifuge.ConnectError{Err: err}
 			},
 		},
+		{
+			name:      "CredentialStoreError",
+			rootError: centrifuge.ErrClientClosed,
+			factory: func(err error) error {
+				return centrifuge.CredentialStoreError{Err: err}
+			},
+		},
 		{
 			name:      "TransportError",
 			rootError: centrifuge.ErrClientClosed,
@@ -0,0 +1,87 @@
+package centrifuge
+
+import (
+	"context"
+	"time"
+
+	"github.com/centrifugal/centrifuge-go/internal/queues"
+)
+
+// EventQueuePolicy defines what Client does when event queue limited by
+// Config.EventQueueSize is full, i.e. event handlers can't keep up with events.
+// Policy applies to publication, join, leave and message events. Client and
+// Subscription state events, e.g. OnConnected or OnUnsubscribed, are always
+// queued and never dropped, so they do not block event handlers calling Client
+// or Subscription methods.
+type EventQueuePolicy string
+
+const (
+	// EventQueueBlock means goroutine emitting event waits for free space in queue.
+	// For events from server it's connection reader, so server eventually stops
+	// sending to client.
+	EventQueueBlock EventQueuePolicy = ""
+	// EventQueueDropOldest means the oldest queued event is dropped without calling
+	// its handler. Number of dropped events is available in Stats.DroppedEvents.
+	EventQueueDropOldest EventQueuePolicy = "drop_oldest"
+	// EventQueueDisconnect means new event is rejected and Client reconnects, so
+	// subscriptions with recovery may catch up from history after handlers drained
+	// the queue.
+	EventQueueDisconnect EventQueuePolicy = "disconnect"
+)
+
+func openEventQueue(config Config) *queues.CallBackQueue {
+	if config.EventQueueSize <= 0 {
+		return queues.OpenCallBackQueue()
+	}
+	policy := queues.OverflowBlock
+	switch config.EventQueuePolicy {
+	case EventQueueDropOldest:
+		policy = queues.OverflowDropOldest
+	case EventQueueDisconnect:
+		policy = queues.OverflowReject
+	}
+	return queues.OpenBoundedCallBackQueue(config.EventQueueSize, policy)
+}
+
+// handleEventQueueFull reconnects client when event was rejected by full queue.
+func (c *Client) handleEventQueueFull() {
+	c.log(LogLevelDebug, "event queue is full, reconnecting", nil)
+	go c.handleDisconnect(&disconnect{Code: connectingEventQueueFull, Reason: "event queue full", Reconnect: true})
+}
+
+// FlushEvents blocks until all events emitted before the call are passed to
+// handlers or dropped due to EventQueueDropOldest policy. Useful in tests and upon
+// graceful shutdown to make sure the last publications were handed to application.
+// It returns nil once all such events are passed or dropped, ctx error if ctx is
+// done before that and ErrClientClosed if client is closed before that – Close
+// discards queued events without passing them to handlers, so some of them may be
+// never handled then. Must not be called from event handler – it would wait for
+// itself.
+func (c *Client) FlushEvents(ctx context.Context) error {
+	if c.config.SyncHandlers {
+		// Handlers are already called.
+		return nil
+	}
+	doneCh := make(chan struct{})
+	err := c.cbQueue.ForcePush(func(context.Context, time.Duration) {
+		close(doneCh)
+	})
+	if err != nil {
+		return ErrClientClosed
+	}
+	select {
+	case <-doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closedCh:
+		// Close discards queued events, but barrier may have been dispatched just
+		// before close.
+		select {
+		case <-doneCh:
+			return nil
+		default:
+			return ErrClientClosed
+		}
+	}
+}
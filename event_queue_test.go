@@ -0,0 +1,212 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_EventQueueDropOldest(t *testing.T) {
+	client := NewJsonClient(startParityServer(t), Config{
+		EventQueueSize:   2,
+		EventQueuePolicy: EventQueueDropOldest,
+	})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		return client.State() == StateConnected
+	})
+	// Block handlers, so state events fill the queue.
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	client.runHandlerAsync(func() {
+		close(started)
+		<-unblock
+	})
+	<-started
+	var numSubscribing, numPublications atomic.Int32
+	for i := 0; i < 5; i++ {
+		sub, err := client.NewSubscription("ch" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub.OnSubscribing(func(SubscribingEvent) {
+			numSubscribing.Add(1)
+		})
+		sub.OnPublication(func(PublicationEvent) {
+			numPublications.Add(1)
+		})
+		if err := sub.Subscribe(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Publications sent by server after subscribe are dropped.
+	waitFor(t, func() bool {
+		return client.Stats().DroppedEvents == 5
+	})
+	close(unblock)
+	if err := client.FlushEvents(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := numSubscribing.Load(); n != 5 {
+		t.Fatalf("state events must not be dropped, got %d", n)
+	}
+	if n := numPublications.Load(); n != 0 {
+		t.Fatalf("expected publications to be dropped, got %d", n)
+	}
+}
+
+func TestClient_EventQueueBlock_SubscribeFromHandler(t *testing.T) {
+	client := NewJsonClient("ws://localhost:9000/connection/websocket", Config{
+		EventQueueSize: 1,
+	})
+	defer client.Close()
+	sub, err := client.NewSubscription("ch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var numEvents atomic.Int32
+	sub.OnSubscribing(func(SubscribingEvent) {
+		numEvents.Add(1)
+	})
+	sub.OnUnsubscribed(func(UnsubscribedEvent) {
+		numEvents.Add(1)
+	})
+	done := make(chan struct{})
+	client.runHandlerAsync(func() {
+		// Queue is full with the event below.
+		client.runHandlerAsync(func() {})
+		_ = sub.Subscribe()
+		_ = sub.Unsubscribe()
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler blocked on full event queue")
+	}
+	if err := client.FlushEvents(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := numEvents.Load(); n != 2 {
+		t.Fatalf("expected 2 events, got %d", n)
+	}
+}
+
+func TestClient_EventQueuedFor(t *testing.T) {
+	client := NewJsonClient(startParityServer(t), Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		return client.State() == StateConnected
+	})
+	// Slow handler of async event creates backlog for the following publication.
+	slow, err := client.NewSubscription("slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow.OnSubscribing(func(SubscribingEvent) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	if err := slow.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := client.NewSubscription("ch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubCh := make(chan PublicationEvent, 1)
+	sub.OnPublication(func(e PublicationEvent) {
+		pubCh <- e
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-pubCh:
+		if e.QueuedFor < 50*time.Millisecond {
+			t.Fatalf("expected publication to wait in queue, got %s", e.QueuedFor)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for publication")
+	}
+}
+
+func TestClient_SyncHandlers(t *testing.T) {
+	client := NewJsonClient(startParityServer(t), Config{SyncHandlers: true})
+	defer client.Close()
+	sub, err := client.NewSubscription("ch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subscribing bool
+	sub.OnSubscribing(func(SubscribingEvent) {
+		subscribing = true
+	})
+	// Handler is called inline, so no synchronization is needed.
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if !subscribing {
+		t.Fatal("expected subscribing handler to be called before Subscribe returned")
+	}
+	pubCh := make(chan PublicationEvent, 1)
+	sub.OnPublication(func(e PublicationEvent) {
+		pubCh <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-pubCh:
+		if string(e.Data) != `{"n":1}` {
+			t.Fatalf("unexpected publication data %s", e.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for publication")
+	}
+}
+
+func TestClient_FlushEvents(t *testing.T) {
+	client := NewJsonClient(startParityServer(t), Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("ch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handled atomic.Bool
+	unblock := make(chan struct{})
+	sub.OnSubscribing(func(SubscribingEvent) {
+		<-unblock
+		handled.Store(true)
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.FlushEvents(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while handler is blocked, got %v", err)
+	}
+
+	close(unblock)
+	if err := client.FlushEvents(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !handled.Load() {
+		t.Fatal("expected event emitted before FlushEvents to be handled")
+	}
+
+	client.Close()
+	if err := client.FlushEvents(context.Background()); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package centrifuge
+
+import "sync"
+
+// eventReplayBuffer keeps the last lifecycle events of Client (ConnectingEvent,
+// ConnectedEvent, DisconnectedEvent) to replay them to handlers set after events
+// already happened. See Config.EventReplaySize.
+type eventReplayBuffer struct {
+	// mu makes recording an event and loading current handler atomic relative
+	// to setting a new handler, so every event is delivered to a handler exactly
+	// once – either live or replayed.
+	mu     sync.Mutex
+	size   int
+	events []any
+}
+
+func newEventReplayBuffer(size int) *eventReplayBuffer {
+	return &eventReplayBuffer{
+		size:   size,
+		events: make([]any, 0, size),
+	}
+}
+
+// Lock must be held outside.
+func (b *eventReplayBuffer) record(event any) {
+	if len(b.events) == b.size {
+		copy(b.events, b.events[1:])
+		b.events = b.events[:len(b.events)-1]
+	}
+	b.events = append(b.events, event)
+}
+
+// connectingHandler records event in replay buffer (if enabled) and returns the
+// current connecting handler which should be called with event.
+func (c *Client) connectingHandler(event ConnectingEvent) ConnectingHandler {
+	if c.events == nil {
+		return nil
+	}
+	if b := c.events.replay; b != nil {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.record(event)
+	}
+	return c.events.onConnecting
+}
+
+// connectedHandler records event in replay buffer (if enabled) and returns the
+// current connected handler which should be called with event.
+func (c *Client) connectedHandler(event ConnectedEvent) ConnectedHandler {
+	if c.events == nil {
+		return nil
+	}
+	if b := c.events.replay; b != nil {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.record(event)
+	}
+	return c.events.onConnected
+}
+
+// disconnectedHandler records event in replay buffer (if enabled) and returns the
+// current disconnected handler which should be called with event.
+func (c *Client) disconnectedHandler(event DisconnectedEvent) DisconnectHandler {
+	if c.events == nil {
+		return nil
+	}
+	if b := c.events.replay; b != nil {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.record(event)
+	}
+	return c.events.onDisconnected
+}
+
+// setLifecycleHandler calls set to register handler and replays buffered events
+// to it. Replayed events are pushed to callback queue before any event which
+// happens after this call, so the handler observes events in the original order.
+func (c *Client) setLifecycleHandler(set func(), replay func(event any)) {
+	b := c.events.replay
+	if b == nil {
+		set()
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	set()
+	if len(b.events) == 0 {
+		return
+	}
+	events := make([]any, len(b.events))
+	copy(events, b.events)
+	c.runHandlerAsync(func() {
+		for _, event := range events {
+			replay(event)
+		}
+	})
+}
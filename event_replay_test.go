@@ -0,0 +1,67 @@
+package centrifuge
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventReplay(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{EventReplaySize: 2})
+	defer client.Close()
+
+	// Emulate lifecycle events happening before handlers attached.
+	client.connectingHandler(ConnectingEvent{Code: 1})
+	client.connectingHandler(ConnectingEvent{Code: 2})
+	client.connectedHandler(ConnectedEvent{ClientID: "id"})
+
+	connectingCh := make(chan ConnectingEvent, 3)
+	client.OnConnecting(func(e ConnectingEvent) {
+		connectingCh <- e
+	})
+	connectedCh := make(chan ConnectedEvent, 1)
+	client.OnConnected(func(e ConnectedEvent) {
+		connectedCh <- e
+	})
+
+	// Events which happen after handler set are delivered as usual.
+	if handler := client.connectingHandler(ConnectingEvent{Code: 3}); handler != nil {
+		client.runHandlerAsync(func() {
+			handler(ConnectingEvent{Code: 3})
+		})
+	}
+
+	// Buffer keeps 2 events only, so first connecting event is evicted by the time
+	// handler set.
+	for _, code := range []uint32{2, 3} {
+		select {
+		case e := <-connectingCh:
+			if e.Code != code {
+				t.Fatalf("expected code %d, got %d", code, e.Code)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for connecting event")
+		}
+	}
+	select {
+	case e := <-connectedCh:
+		if e.ClientID != "id" {
+			t.Fatalf("unexpected client ID: %s", e.ClientID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for connected event")
+	}
+}
+
+func TestEventReplay_Disabled(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+
+	client.connectingHandler(ConnectingEvent{Code: 1})
+	client.OnConnecting(func(e ConnectingEvent) {
+		t.Errorf("unexpected replayed event: %v", e)
+	})
+	// Run a callback to make sure queue processed everything pushed before.
+	done := make(chan struct{})
+	client.runHandlerAsync(func() { close(done) })
+	<-done
+}
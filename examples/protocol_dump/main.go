@@ -0,0 +1,29 @@
+// Command protocol_dump pretty-prints protocol dump written over
+// centrifuge.Config.ProtocolDump. Dump is read from file passed as argument or
+// from stdin.
+//
+//	go run ./protocol_dump dump.jsonl
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/centrifugal/centrifuge-go"
+)
+
+func main() {
+	var r io.Reader = os.Stdin
+	if len(os.Args) > 1 {
+		f, err := os.Open(os.Args[1])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+	if err := centrifuge.PrettyPrintProtocolDump(os.Stdout, r); err != nil {
+		log.Fatalln(err)
+	}
+}
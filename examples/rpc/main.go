@@ -45,13 +45,14 @@ func newClient() *centrifuge.Client {
 func main() {
 	log.Println("Start program")
 
+	c := newClient()
+	defer c.Close()
+
+	http.Handle("/debug/centrifuge", centrifuge.DebugHandler(c))
 	go func() {
 		log.Println(http.ListenAndServe(":6060", nil))
 	}()
 
-	c := newClient()
-	defer c.Close()
-
 	err := c.Connect()
 	if err != nil {
 		log.Fatalln(err)
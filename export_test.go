@@ -0,0 +1,17 @@
+package centrifuge
+
+// Scheduler exports scheduler for tests using clocktest package, which can't be
+// imported by internal tests.
+type Scheduler = scheduler
+
+func NewScheduler(clock Clock) *Scheduler {
+	return newScheduler(clock, nil)
+}
+
+func (s *scheduler) Len() int {
+	return s.len()
+}
+
+func (s *scheduler) Stop() {
+	s.stop()
+}
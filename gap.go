@@ -0,0 +1,47 @@
+package centrifuge
+
+import (
+	"strconv"
+
+	"github.com/centrifugal/protocol"
+)
+
+// publicationEventLocked creates PublicationEvent with stream position.
+// Lock must be held outside.
+func (s *Subscription) publicationEventLocked(pub *protocol.Publication) PublicationEvent {
+	event := PublicationEvent{Publication: pubFromProto(pub)}
+	if pub.Offset > 0 {
+		event.StreamPosition = &StreamPosition{Offset: pub.Offset, Epoch: s.epoch}
+	}
+	return s.withAck(event)
+}
+
+// handleGap reports missed publications and re-syncs subscription: it's
+// unsubscribed on server and subscribed again recovering from the last received
+// publication.
+func (s *Subscription) handleGap(expected uint64, received uint64) {
+	s.mu.RLock()
+	epoch := s.epoch
+	s.mu.RUnlock()
+	if s.centrifuge.logLevelEnabled(LogLevelDebug) {
+		s.centrifuge.log(LogLevelDebug, "publication gap detected", map[string]string{
+			"channel":  s.Channel,
+			"expected": strconv.FormatUint(expected, 10),
+			"received": strconv.FormatUint(received, 10),
+		})
+	}
+	if s.events != nil && s.events.onGapDetected != nil {
+		handler := s.events.onGapDetected
+		s.centrifuge.runHandlerSync(func() {
+			handler(GapDetectedEvent{Expected: expected, Received: received, Epoch: epoch})
+		})
+	}
+	s.moveToSubscribing(subscribingGapDetected, "gap detected")
+	s.centrifuge.unsubscribe(s.Channel, func(_ UnsubscribeResult, err error) {
+		if err != nil {
+			go s.centrifuge.handleDisconnect(&disconnect{Code: connectingUnsubscribeError, Reason: "unsubscribe error", Reconnect: true})
+			return
+		}
+		s.resubscribe(nil)
+	})
+}
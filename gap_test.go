@@ -0,0 +1,111 @@
+package centrifuge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startGapServer pushes publications with offsets 2 and 4 after the first
+// subscribe. Subscription recovered from offset 2 gets publications 3 and 4.
+// Subscribe requests are sent to subCh.
+func startGapServer(t *testing.T, subCh chan<- *protocol.SubscribeRequest) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var replies []string
+			switch {
+			case cmd.Connect != nil:
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id))
+			case cmd.Subscribe != nil && !cmd.Subscribe.Recover:
+				subCh <- cmd.Subscribe
+				replies = append(replies,
+					fmt.Sprintf(`{"id":%d,"subscribe":{"recoverable":true,"epoch":"e","offset":1}}`, cmd.Id),
+					`{"push":{"channel":"test","pub":{"data":{},"offset":2}}}`,
+					`{"push":{"channel":"test","pub":{"data":{},"offset":2}}}`,
+					`{"push":{"channel":"test","pub":{"data":{},"offset":4}}}`,
+				)
+			case cmd.Subscribe != nil:
+				subCh <- cmd.Subscribe
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"subscribe":{"recoverable":true,"was_recovering":true,"recovered":true,"epoch":"e","offset":4,"publications":[{"data":{},"offset":3},{"data":{},"offset":4}]}}`, cmd.Id))
+			case cmd.Unsubscribe != nil:
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"unsubscribe":{}}`, cmd.Id))
+			}
+			for _, reply := range replies {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestSubscription_DetectGaps(t *testing.T) {
+	subCh := make(chan *protocol.SubscribeRequest, 2)
+	u := startGapServer(t, subCh)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test", SubscriptionConfig{Recoverable: true, DetectGaps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gaps := make(chan GapDetectedEvent, 1)
+	sub.OnGapDetected(func(e GapDetectedEvent) {
+		gaps <- e
+	})
+	pubs := make(chan PublicationEvent, 16)
+	sub.OnPublication(func(e PublicationEvent) {
+		pubs <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-gaps:
+		if e.Expected != 3 || e.Received != 4 || e.Epoch != "e" {
+			t.Fatalf("unexpected gap event: %#v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for gap")
+	}
+	<-subCh
+	select {
+	case req := <-subCh:
+		if !req.Recover || req.Offset != 2 || req.Epoch != "e" {
+			t.Fatalf("expected recovery from offset 2, got %#v", req)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for resubscribe")
+	}
+
+	for _, expected := range []uint64{2, 3, 4} {
+		select {
+		case e := <-pubs:
+			if e.Offset != expected || e.StreamPosition == nil || e.StreamPosition.Offset != expected || e.StreamPosition.Epoch != "e" {
+				t.Fatalf("expected publication %d, got %#v", expected, e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for publication %d", expected)
+		}
+	}
+}
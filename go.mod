@@ -7,7 +7,6 @@ toolchain go1.26.4
 require (
 	github.com/centrifugal/protocol v0.19.2
 	github.com/gorilla/websocket v1.5.3
-	github.com/jpillora/backoff v1.0.0
 	github.com/shadowspore/fossil-delta v0.0.0-20241213113458-1d797d70cbe3
 	google.golang.org/protobuf v1.36.11
 )
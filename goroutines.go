@@ -0,0 +1,117 @@
+package centrifuge
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// goroutineLeakTimeout is how long goroutines may take to exit after Close
+// before they are reported as leaked.
+const goroutineLeakTimeout = time.Second
+
+// GoroutineInfo describes long-running goroutine spawned by Client.
+type GoroutineInfo struct {
+	// Name of goroutine, e.g. "reader" or "reconnect_loop".
+	Name string
+	// StartedAt is a time goroutine started.
+	StartedAt time.Time
+}
+
+// goroutineRegistry keeps long-running goroutines of client.
+type goroutineRegistry struct {
+	mu      sync.Mutex
+	nextID  uint64
+	running map[uint64]GoroutineInfo
+}
+
+func newGoroutineRegistry() *goroutineRegistry {
+	return &goroutineRegistry{running: make(map[uint64]GoroutineInfo)}
+}
+
+func (r *goroutineRegistry) add(name string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	r.running[r.nextID] = GoroutineInfo{Name: name, StartedAt: time.Now()}
+	return r.nextID
+}
+
+func (r *goroutineRegistry) remove(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, id)
+}
+
+func (r *goroutineRegistry) list() []GoroutineInfo {
+	r.mu.Lock()
+	goroutines := make([]GoroutineInfo, 0, len(r.running))
+	for _, info := range r.running {
+		goroutines = append(goroutines, info)
+	}
+	r.mu.Unlock()
+	sort.Slice(goroutines, func(i, j int) bool {
+		if goroutines[i].StartedAt.Equal(goroutines[j].StartedAt) {
+			return goroutines[i].Name < goroutines[j].Name
+		}
+		return goroutines[i].StartedAt.Before(goroutines[j].StartedAt)
+	})
+	return goroutines
+}
+
+// spawn runs fn in a goroutine registered under name until fn returns.
+func (c *Client) spawn(name string, fn func()) {
+	id := c.goroutines.add(name)
+	go func() {
+		defer c.goroutines.remove(id)
+		fn()
+	}()
+}
+
+// curGoroutineID returns id of calling goroutine parsed from its stack header,
+// e.g. "goroutine 18 [running]:".
+func curGoroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// Goroutines returns long-running goroutines of client sorted by start time.
+// Short-lived goroutines are not included: ones waiting for a single reply or
+// measuring RTT exit on disconnect, ones delivering disconnect or completion
+// callbacks exit right after the call. All goroutines exit shortly after
+// Client.Close, so it's useful to check for leaks in tests.
+func (c *Client) Goroutines() []GoroutineInfo {
+	return c.goroutines.list()
+}
+
+// checkGoroutineLeaks reports goroutines still running after Close to OnError
+// handler with GoroutineLeakError. Handler is called directly since event queue
+// is already closed.
+func (c *Client) checkGoroutineLeaks() {
+	if c.events == nil || c.events.onError == nil {
+		return
+	}
+	handler := c.events.onError
+	deadline := time.Now().Add(goroutineLeakTimeout)
+	for {
+		goroutines := c.goroutines.list()
+		if len(goroutines) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			defer c.recoverCallback("OnError")
+			handler(ErrorEvent{Error: GoroutineLeakError{Goroutines: goroutines}})
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
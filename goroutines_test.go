@@ -0,0 +1,64 @@
+package centrifuge
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func goroutineNames(c *Client) map[string]bool {
+	names := make(map[string]bool)
+	for _, info := range c.Goroutines() {
+		names[info.Name] = true
+	}
+	return names
+}
+
+func TestClient_Goroutines(t *testing.T) {
+	client := NewJsonClient(startServerInfoServer(t), Config{})
+	errCh := make(chan error, 1)
+	client.OnError(func(e ErrorEvent) {
+		errCh <- e.Error
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := client.NewSubscription("chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.WatchPresence(time.Minute)
+	waitFor(t, func() bool {
+		names := goroutineNames(client)
+		return names["dispatcher"] && names["reconnect_loop"] && names["reader"] && names["transport_reader"] && names["presence_watcher"]
+	})
+	client.Close()
+	waitFor(t, func() bool {
+		return len(client.Goroutines()) == 0
+	})
+	select {
+	case err := <-errCh:
+		t.Fatalf("unexpected error %v", err)
+	case <-time.After(goroutineLeakTimeout + 100*time.Millisecond):
+	}
+}
+
+func TestClient_GoroutineLeakReported(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	errCh := make(chan error, 1)
+	client.OnError(func(e ErrorEvent) {
+		errCh <- e.Error
+	})
+	id := client.goroutines.add("stuck")
+	defer client.goroutines.remove(id)
+	client.Close()
+	select {
+	case err := <-errCh:
+		var leakErr GoroutineLeakError
+		if !errors.As(err, &leakErr) || len(leakErr.Goroutines) != 1 || leakErr.Goroutines[0].Name != "stuck" {
+			t.Fatalf("unexpected error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for leak error")
+	}
+}
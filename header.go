@@ -0,0 +1,24 @@
+package centrifuge
+
+import (
+	"net/http"
+)
+
+// connectHeader returns Config.Header merged with Config.GetHeaders result.
+func (c *Client) connectHeader() (http.Header, error) {
+	if c.config.GetHeaders == nil {
+		return c.config.Header, nil
+	}
+	extra, err := callSafe(c, "GetHeaders", c.config.GetHeaders)
+	if err != nil {
+		return nil, err
+	}
+	header := c.config.Header.Clone()
+	if header == nil {
+		header = make(http.Header, len(extra))
+	}
+	for key, values := range extra {
+		header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+	return header, nil
+}
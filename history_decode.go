@@ -0,0 +1,62 @@
+package centrifuge
+
+import (
+	"context"
+	"fmt"
+)
+
+// filterByTags returns publications having all tags.
+func filterByTags(pubs []Publication, tags map[string]string) []Publication {
+	if len(tags) == 0 {
+		return pubs
+	}
+	var filtered []Publication
+	for _, pub := range pubs {
+		if hasTags(pub, tags) {
+			filtered = append(filtered, pub)
+		}
+	}
+	return filtered
+}
+
+func hasTags(pub Publication, tags map[string]string) bool {
+	for k, v := range tags {
+		if value, ok := pub.Tags[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodedPublication is a Publication with Data decoded into Value.
+type DecodedPublication[T any] struct {
+	Publication
+	Value T
+}
+
+// DecodePublications decodes data of publications with decode function.
+func DecodePublications[T any](pubs []Publication, decode func(data []byte) (T, error)) ([]DecodedPublication[T], error) {
+	decoded := make([]DecodedPublication[T], 0, len(pubs))
+	for _, pub := range pubs {
+		value, err := decode(pub.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding publication with offset %d: %w", pub.Offset, err)
+		}
+		decoded = append(decoded, DecodedPublication[T]{Publication: pub, Value: value})
+	}
+	return decoded, nil
+}
+
+// HistoryAs is like Subscription.History but decodes data of publications into
+// T with Subscription Codec. Use DecodePublications for custom decoding.
+func HistoryAs[T any](ctx context.Context, s *Subscription, opts ...HistoryOption) ([]DecodedPublication[T], error) {
+	res, err := s.History(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return DecodePublications(res.Publications, func(data []byte) (T, error) {
+		var value T
+		err := s.Decode(data, &value)
+		return value, err
+	})
+}
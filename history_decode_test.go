@@ -0,0 +1,67 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFilterByTags(t *testing.T) {
+	pubs := []Publication{
+		{Offset: 1, Tags: map[string]string{"kind": "audit", "user": "1"}},
+		{Offset: 2, Tags: map[string]string{"kind": "metric"}},
+		{Offset: 3},
+		{Offset: 4, Tags: map[string]string{"kind": "audit", "user": "2"}},
+	}
+	filtered := filterByTags(pubs, map[string]string{"kind": "audit"})
+	if len(filtered) != 2 || filtered[0].Offset != 1 || filtered[1].Offset != 4 {
+		t.Fatalf("unexpected publications: %v", filtered)
+	}
+	if len(filterByTags(pubs, nil)) != 4 {
+		t.Fatal("publications must not be filtered without tags")
+	}
+}
+
+func TestHistoryIterator_TagFilter(t *testing.T) {
+	epoch := "e"
+	stream := testHistoryStream(25, &epoch)
+	fetch := func(ctx context.Context, opts HistoryOptions) (HistoryResult, error) {
+		res, err := stream(ctx, opts)
+		for i := range res.Publications {
+			if res.Publications[i].Offset%5 == 0 {
+				res.Publications[i].Tags = map[string]string{"kind": "audit"}
+			}
+		}
+		return res, err
+	}
+	it := &HistoryIterator{fetch: fetch, opts: HistoryOptions{Limit: 10, TagFilter: map[string]string{"kind": "audit"}}}
+	offsets := collectHistory(t, it)
+	if len(offsets) != 5 || offsets[0] != 5 || offsets[4] != 25 {
+		t.Fatalf("unexpected offsets: %v", offsets)
+	}
+}
+
+func TestDecodePublications(t *testing.T) {
+	type event struct {
+		Name string `json:"name"`
+	}
+	pubs := []Publication{{Offset: 1, Data: []byte(`{"name":"a"}`)}, {Offset: 2, Data: []byte(`{"name":"b"}`)}}
+	decode := func(data []byte) (event, error) {
+		var e event
+		err := json.Unmarshal(data, &e)
+		return e, err
+	}
+	decoded, err := DecodePublications(pubs, decode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0].Value.Name != "a" || decoded[1].Value.Name != "b" || decoded[1].Offset != 2 {
+		t.Fatalf("unexpected result: %v", decoded)
+	}
+	_, err = DecodePublications([]Publication{{Offset: 3, Data: []byte(`{`)}}, decode)
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected decode error, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrIteratorDone returned by iterator when there are no more items.
+	ErrIteratorDone = errors.New("iterator done")
+	// ErrEpochChanged returned by HistoryIterator when stream epoch changed during
+	// iteration.
+	ErrEpochChanged = errors.New("stream epoch changed")
+)
+
+const defaultHistoryPageSize = 100
+
+// HistoryIterator iterates over channel history page by page. Create it with
+// Subscription.HistoryIterator. Not safe for concurrent use.
+type HistoryIterator struct {
+	fetch func(ctx context.Context, opts HistoryOptions) (HistoryResult, error)
+	opts  HistoryOptions
+	page  []Publication
+	// epoch of the first page, all pages must belong to the same stream.
+	epoch   string
+	started bool
+	done    bool
+}
+
+// HistoryIterator returns iterator over channel history. Options are the same as
+// for History: WithHistorySince sets the starting position, WithHistoryReverse the
+// direction and WithHistoryLimit the page size (100 by default). Iterator requests
+// next pages transparently until history exhausted.
+func (s *Subscription) HistoryIterator(opts ...HistoryOption) *HistoryIterator {
+	historyOpts := HistoryOptions{}
+	for _, opt := range opts {
+		opt(&historyOpts)
+	}
+	if historyOpts.Limit <= 0 {
+		historyOpts.Limit = defaultHistoryPageSize
+	}
+	return &HistoryIterator{
+		fetch: func(ctx context.Context, opts HistoryOptions) (HistoryResult, error) {
+			// Publications are filtered by iterator, so pages are not shortened.
+			return s.History(ctx, WithHistorySince(opts.Since), WithHistoryLimit(opts.Limit), WithHistoryReverse(opts.Reverse))
+		},
+		opts: historyOpts,
+	}
+}
+
+// Next returns the next publication. Returns ErrIteratorDone when history
+// exhausted. Returns ErrEpochChanged if stream epoch changed between pages – stream
+// was reset on server and iteration should be started again. After error Next
+// may be called again to retry the same page.
+func (it *HistoryIterator) Next(ctx context.Context) (Publication, error) {
+	for len(it.page) == 0 {
+		if it.done {
+			return Publication{}, ErrIteratorDone
+		}
+		if err := it.nextPage(ctx); err != nil {
+			return Publication{}, err
+		}
+	}
+	pub := it.page[0]
+	it.page = it.page[1:]
+	return pub, nil
+}
+
+func (it *HistoryIterator) nextPage(ctx context.Context) error {
+	res, err := it.fetch(ctx, it.opts)
+	if err != nil {
+		return err
+	}
+	if it.started && res.Epoch != it.epoch {
+		return ErrEpochChanged
+	}
+	it.started = true
+	it.epoch = res.Epoch
+	if len(res.Publications) < int(it.opts.Limit) {
+		it.done = true
+	}
+	if n := len(res.Publications); n > 0 {
+		it.opts.Since = &StreamPosition{Offset: res.Publications[n-1].Offset, Epoch: res.Epoch}
+	}
+	it.page = filterByTags(res.Publications, it.opts.TagFilter)
+	return nil
+}
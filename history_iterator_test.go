@@ -0,0 +1,92 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// testHistoryStream imitates server history of publications with offsets from 1
+// to n.
+func testHistoryStream(n uint64, epoch *string) func(ctx context.Context, opts HistoryOptions) (HistoryResult, error) {
+	return func(ctx context.Context, opts HistoryOptions) (HistoryResult, error) {
+		res := HistoryResult{Offset: n, Epoch: *epoch}
+		if opts.Reverse {
+			from := n
+			if opts.Since != nil {
+				from = opts.Since.Offset - 1
+			}
+			for offset := from; offset >= 1 && len(res.Publications) < int(opts.Limit); offset-- {
+				res.Publications = append(res.Publications, Publication{Offset: offset})
+			}
+			return res, nil
+		}
+		from := uint64(1)
+		if opts.Since != nil {
+			from = opts.Since.Offset + 1
+		}
+		for offset := from; offset <= n && len(res.Publications) < int(opts.Limit); offset++ {
+			res.Publications = append(res.Publications, Publication{Offset: offset})
+		}
+		return res, nil
+	}
+}
+
+func collectHistory(t *testing.T, it *HistoryIterator) []uint64 {
+	var offsets []uint64
+	for {
+		pub, err := it.Next(context.Background())
+		if errors.Is(err, ErrIteratorDone) {
+			return offsets
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, pub.Offset)
+	}
+}
+
+func TestHistoryIterator(t *testing.T) {
+	epoch := "e"
+	testCases := []struct {
+		name    string
+		opts    HistoryOptions
+		total   uint64
+		first   uint64
+		last    uint64
+		reverse bool
+	}{
+		{name: "forward", opts: HistoryOptions{Limit: 10}, total: 25, first: 1, last: 25},
+		{name: "forward_exact_pages", opts: HistoryOptions{Limit: 5}, total: 25, first: 1, last: 25},
+		{name: "forward_since", opts: HistoryOptions{Limit: 10, Since: &StreamPosition{Offset: 20, Epoch: epoch}}, total: 25, first: 21, last: 25},
+		{name: "reverse", opts: HistoryOptions{Limit: 10, Reverse: true}, total: 25, first: 25, last: 1, reverse: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			it := &HistoryIterator{fetch: testHistoryStream(tc.total, &epoch), opts: tc.opts}
+			offsets := collectHistory(t, it)
+			if len(offsets) == 0 || offsets[0] != tc.first || offsets[len(offsets)-1] != tc.last {
+				t.Fatalf("unexpected offsets: %v", offsets)
+			}
+			for i := 1; i < len(offsets); i++ {
+				if (offsets[i] < offsets[i-1]) != tc.reverse || offsets[i] == offsets[i-1] {
+					t.Fatalf("offsets out of order: %v", offsets)
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryIterator_EpochChanged(t *testing.T) {
+	epoch := "e1"
+	it := &HistoryIterator{fetch: testHistoryStream(25, &epoch), opts: HistoryOptions{Limit: 10}}
+	for i := 0; i < 10; i++ {
+		if _, err := it.Next(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	epoch = "e2"
+	if _, err := it.Next(context.Background()); !errors.Is(err, ErrEpochChanged) {
+		t.Fatalf("expected ErrEpochChanged, got %v", err)
+	}
+}
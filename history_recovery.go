@@ -0,0 +1,116 @@
+package centrifuge
+
+import (
+	"context"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+// HistoryRecoveryConfig configures replay of missed publications from history when
+// server could not recover subscription. Set it over
+// SubscriptionConfig.RecoverViaHistory.
+type HistoryRecoveryConfig struct {
+	// MaxMessages is the maximum number of publications to replay, if more
+	// publications were missed then only the latest MaxMessages are replayed.
+	// Zero value means 100.
+	MaxMessages int
+	// MaxAge is the maximum time subscription may be lost for publications to be
+	// replayed. Publications missed during longer outage are not replayed.
+	// Zero value means no limit.
+	MaxAge time.Duration
+}
+
+const defaultHistoryRecoveryMaxMessages = 100
+
+// historyReplayLocked decides whether missed publications must be replayed from
+// history after resubscribe, returns the position to replay from and limit.
+// Publications can be replayed only if stream epoch has not changed.
+// Lock must be held outside.
+func (s *Subscription) historyReplayLocked(res *protocol.SubscribeResult) (StreamPosition, int32, bool) {
+	if s.historyRecovery == nil || !res.GetWasRecovering() || res.GetRecovered() {
+		return StreamPosition{}, 0, false
+	}
+	if s.epoch == "" || s.epoch != res.GetEpoch() || res.GetOffset() <= s.offset {
+		return StreamPosition{}, 0, false
+	}
+	if s.historyRecovery.MaxAge > 0 && !s.lostAt.IsZero() && time.Since(s.lostAt) > s.historyRecovery.MaxAge {
+		return StreamPosition{}, 0, false
+	}
+	maxMessages := uint64(s.historyRecovery.MaxMessages)
+	if maxMessages == 0 {
+		maxMessages = defaultHistoryRecoveryMaxMessages
+	}
+	if s.recoveryMode == RecoveryModeCache {
+		maxMessages = 1
+	}
+	since := StreamPosition{Offset: s.offset, Epoch: s.epoch}
+	if res.GetOffset()-since.Offset > maxMessages {
+		since.Offset = res.GetOffset() - maxMessages
+	}
+	return since, int32(res.GetOffset() - since.Offset), true
+}
+
+// replayHistory loads publications missed while subscription was lost and passes
+// them to OnPublication handler marked as Replayed, up to offset of subscribe
+// result. Live publications received meanwhile are buffered and delivered after
+// replayed ones. Errors are reported over OnError as HistoryRecoveryError.
+func (s *Subscription) replayHistory(since StreamPosition, limit int32, offset uint64) {
+	opts := HistoryOptions{Since: &since, Limit: limit}
+	s.centrifuge.history(context.Background(), s.Channel, opts, func(res HistoryResult, err error) {
+		defer s.flushReplayBuffer()
+		if err != nil {
+			s.emitError(HistoryRecoveryError{err})
+			s.markProcessed(offset)
+			return
+		}
+		var handler PublicationHandler
+		if s.events != nil && s.events.onPublication != nil {
+			handler = s.events.onPublication
+		}
+		if handler == nil {
+			s.markProcessed(offset)
+			return
+		}
+		s.centrifuge.runDataHandler(func() {
+			progress := s.newRecoveryProgress(len(res.Publications))
+			for _, pub := range res.Publications {
+				if s.State() != SubStateSubscribed {
+					return
+				}
+				if pub.Offset > offset {
+					// Delivered as live publication.
+					break
+				}
+				handler(s.withAck(s.unwrapEnvelope(PublicationEvent{Publication: pub, StreamPosition: &StreamPosition{Offset: pub.Offset, Epoch: res.Epoch}, Replayed: true, QueuedFor: s.centrifuge.queuedFor})))
+				s.markProcessed(pub.Offset)
+				progress.advance()
+			}
+			s.markProcessed(offset)
+		})
+	})
+}
+
+// flushReplayBuffer delivers live publications buffered during history replay.
+func (s *Subscription) flushReplayBuffer() {
+	for {
+		s.mu.Lock()
+		pubs := s.replayBuffer
+		if len(pubs) == 0 || s.state != SubStateSubscribed {
+			s.replayBuffer = nil
+			s.mu.Unlock()
+			return
+		}
+		// Keep buffering until all publications taken are delivered.
+		s.replayBuffer = []*protocol.Publication{}
+		s.mu.Unlock()
+		for _, pub := range pubs {
+			s.mu.Lock()
+			if s.state != SubStateSubscribed {
+				s.mu.Unlock()
+				break
+			}
+			s.deliverPublicationLocked(pub)
+		}
+	}
+}
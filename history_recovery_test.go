@@ -0,0 +1,132 @@
+package centrifuge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startHistoryRecoveryServer makes subscription to "test" channel at offset 6 in
+// the first connection and then drops it. Subscription is not recovered in the
+// next connection while stream is at offset 10. History requests are sent to
+// historyCh.
+func startHistoryRecoveryServer(t *testing.T, historyCh chan<- *protocol.HistoryRequest) string {
+	numConns := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		first := numConns.Add(1) == 1
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+				var cmd protocol.Command
+				if err := json.Unmarshal(line, &cmd); err != nil {
+					return
+				}
+				var replies []string
+				switch {
+				case cmd.Connect != nil:
+					replies = append(replies, fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id))
+				case cmd.Subscribe != nil && first:
+					replies = append(replies,
+						fmt.Sprintf(`{"id":%d,"subscribe":{"recoverable":true,"epoch":"e","offset":5}}`, cmd.Id),
+						`{"push":{"channel":"test","pub":{"data":{},"offset":6}}}`,
+					)
+				case cmd.Subscribe != nil:
+					replies = append(replies,
+						fmt.Sprintf(`{"id":%d,"subscribe":{"recoverable":true,"was_recovering":true,"epoch":"e","offset":10}}`, cmd.Id),
+						// Live publication comes before history reply.
+						`{"push":{"channel":"test","pub":{"data":{},"offset":11}}}`,
+					)
+				case cmd.History != nil:
+					historyCh <- cmd.History
+					var pubs []string
+					for offset := cmd.History.Since.Offset + 1; offset <= 10; offset++ {
+						pubs = append(pubs, fmt.Sprintf(`{"data":{},"offset":%d}`, offset))
+					}
+					replies = append(replies, fmt.Sprintf(`{"id":%d,"history":{"epoch":"e","offset":10,"publications":[%s]}}`, cmd.Id, strings.Join(pubs, ",")))
+				}
+				for _, reply := range replies {
+					if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+						return
+					}
+				}
+				if first && cmd.Subscribe != nil {
+					// Give client time to process publication and drop connection.
+					time.Sleep(100 * time.Millisecond)
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestSubscription_RecoverViaHistory(t *testing.T) {
+	historyCh := make(chan *protocol.HistoryRequest, 1)
+	u := startHistoryRecoveryServer(t, historyCh)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test", SubscriptionConfig{
+		Recoverable:       true,
+		RecoverViaHistory: &HistoryRecoveryConfig{MaxMessages: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubs := make(chan PublicationEvent, 16)
+	sub.OnPublication(func(e PublicationEvent) {
+		pubs <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case req := <-historyCh:
+		// Publications 7-10 were missed, only the latest 3 are requested.
+		if req.Since == nil || req.Since.Offset != 7 || req.Since.Epoch != "e" || req.Limit != 3 {
+			t.Fatalf("unexpected history request: %#v", req)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for history request")
+	}
+
+	var offsets []uint64
+	for len(offsets) < 5 {
+		select {
+		case e := <-pubs:
+			if e.Replayed != (e.Offset > 6 && e.Offset <= 10) {
+				t.Fatalf("unexpected Replayed flag for offset %d", e.Offset)
+			}
+			offsets = append(offsets, e.Offset)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for publications, got %v", offsets)
+		}
+	}
+	if fmt.Sprint(offsets) != "[6 8 9 10 11]" {
+		t.Fatalf("unexpected offsets: %v", offsets)
+	}
+	if offset := sub.Stats().ProcessedOffset; offset != 11 {
+		t.Fatalf("unexpected processed offset: %d", offset)
+	}
+}
@@ -0,0 +1,63 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrPublicationTimeUnknown returned by HistorySince when server does not send
+// publication time.
+var ErrPublicationTimeUnknown = errors.New("publication time unknown")
+
+// HistorySince returns publications published at or after t in chronological
+// order. History is loaded page by page from the latest publication backwards
+// until publication published before t found. Result Offset and Epoch describe
+// the top of the stream. Publications must have Time set by server, otherwise
+// ErrPublicationTimeUnknown returned. Returns ErrEpochChanged if stream epoch
+// changed while history was loaded.
+func (s *Subscription) HistorySince(ctx context.Context, t time.Time) (HistoryResult, error) {
+	return historySince(ctx, t, func(ctx context.Context, opts HistoryOptions) (HistoryResult, error) {
+		return s.History(ctx, WithHistorySince(opts.Since), WithHistoryLimit(opts.Limit), WithHistoryReverse(true))
+	})
+}
+
+func historySince(ctx context.Context, t time.Time, fetch func(ctx context.Context, opts HistoryOptions) (HistoryResult, error)) (HistoryResult, error) {
+	opts := HistoryOptions{Limit: defaultHistoryPageSize, Reverse: true}
+	var result HistoryResult
+	var pubs []Publication
+	started := false
+	for {
+		res, err := fetch(ctx, opts)
+		if err != nil {
+			return HistoryResult{}, err
+		}
+		if started && res.Epoch != result.Epoch {
+			return HistoryResult{}, ErrEpochChanged
+		}
+		if !started {
+			started = true
+			result.Offset = res.Offset
+			result.Epoch = res.Epoch
+		}
+		done := len(res.Publications) < int(opts.Limit)
+		for _, pub := range res.Publications {
+			if pub.Time.IsZero() {
+				return HistoryResult{}, ErrPublicationTimeUnknown
+			}
+			if pub.Time.Before(t) {
+				done = true
+				break
+			}
+			pubs = append(pubs, pub)
+		}
+		if done {
+			slices.Reverse(pubs)
+			result.Publications = pubs
+			return result, nil
+		}
+		last := res.Publications[len(res.Publications)-1]
+		opts.Since = &StreamPosition{Offset: last.Offset, Epoch: res.Epoch}
+	}
+}
@@ -0,0 +1,75 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+// testTimedHistoryStream is testHistoryStream where publication with offset N
+// was published N minutes after base.
+func testTimedHistoryStream(n uint64, base time.Time) func(ctx context.Context, opts HistoryOptions) (HistoryResult, error) {
+	epoch := "e"
+	fetch := testHistoryStream(n, &epoch)
+	return func(ctx context.Context, opts HistoryOptions) (HistoryResult, error) {
+		res, err := fetch(ctx, opts)
+		for i := range res.Publications {
+			res.Publications[i].Time = base.Add(time.Duration(res.Publications[i].Offset) * time.Minute)
+		}
+		return res, err
+	}
+}
+
+func TestHistorySince(t *testing.T) {
+	base := time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC)
+	fetch := testTimedHistoryStream(250, base)
+	res, err := historySince(context.Background(), base.Add(100*time.Minute), fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Offset != 250 || res.Epoch != "e" || len(res.Publications) != 151 {
+		t.Fatalf("unexpected result: offset %d, epoch %s, %d publications", res.Offset, res.Epoch, len(res.Publications))
+	}
+	for i, pub := range res.Publications {
+		if pub.Offset != uint64(100+i) {
+			t.Fatalf("unexpected offset %d at %d", pub.Offset, i)
+		}
+	}
+
+	res, err = historySince(context.Background(), base.Add(time.Hour*24), fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Publications) != 0 {
+		t.Fatalf("expected no publications, got %d", len(res.Publications))
+	}
+
+	res, err = historySince(context.Background(), base, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Publications) != 250 {
+		t.Fatalf("expected all publications, got %d", len(res.Publications))
+	}
+}
+
+func TestHistorySince_TimeUnknown(t *testing.T) {
+	epoch := "e"
+	_, err := historySince(context.Background(), time.Now(), testHistoryStream(10, &epoch))
+	if !errors.Is(err, ErrPublicationTimeUnknown) {
+		t.Fatalf("expected ErrPublicationTimeUnknown, got %v", err)
+	}
+}
+
+func TestPubFromProto_Time(t *testing.T) {
+	pub := pubFromProto(&protocol.Publication{Time: 1700000000123})
+	if !pub.Time.Equal(time.UnixMilli(1700000000123)) {
+		t.Fatalf("unexpected time: %v", pub.Time)
+	}
+	if !pubFromProto(&protocol.Publication{}).Time.IsZero() {
+		t.Fatal("expected zero time")
+	}
+}
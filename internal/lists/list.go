@@ -1,28 +1,49 @@
 package lists
 
 import (
-	"container/list"
+	"context"
+	"errors"
 	"sync"
 )
 
-// List is a generic list that is safe for concurrent use. It is mostly a
-// convenience wrapper around the 'container/list' package.
+// ErrListClosed is returned by PopFrontCtx when list is closed and empty.
+var ErrListClosed = errors.New("list is closed")
+
+// minCapacity is the initial capacity of ring buffer, buffer never shrinks below it.
+const minCapacity = 16
+
+// List is a generic list that is safe for concurrent use. Values are kept in a
+// ring buffer which grows and shrinks by power of two, so pushing does not
+// allocate in steady state.
 type List[T any] struct {
-	mu     sync.Mutex
-	values *list.List
+	mu   sync.Mutex
+	buf  []T
+	head int
+	n    int
+	// wait is closed on PushBack and Close to wake PopFrontCtx waiters. Nil if
+	// there are no waiters.
+	wait   chan struct{}
+	closed bool
 }
 
 func NewList[T any]() *List[T] {
-	return &List[T]{
-		values: list.New(),
-	}
+	return &List[T]{}
 }
 
-// PushBack adds a new element to the back of the list.
+// PushBack adds a new element to the back of the list. It's a no-op if the list
+// is closed.
 func (l *List[T]) PushBack(value T) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.values.PushBack(value)
+	if l.closed {
+		return
+	}
+	if l.n == len(l.buf) {
+		l.resize(max(minCapacity, 2*len(l.buf)))
+	}
+	l.buf[(l.head+l.n)&(len(l.buf)-1)] = value
+	l.n++
+	l.wake()
 }
 
 // PopFront removes and returns the first element of the list. It returns false
@@ -30,25 +51,152 @@ func (l *List[T]) PushBack(value T) {
 func (l *List[T]) PopFront() (T, bool) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.values.Len() == 0 {
+	if l.n == 0 {
 		var zero T
 		return zero, false
 	}
-	elem := l.values.Front()
-	l.values.Remove(elem)
-	return elem.Value.(T), true
+	value := l.popFront()
+	l.shrink()
+	return value, true
+}
+
+// RemoveFirst removes and returns the first element for which fn returns true. It
+// returns false if there is no such element. Elements before removed one are
+// shifted, so it's cheap when matching element is close to the front.
+func (l *List[T]) RemoveFirst(fn func(T) bool) (T, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	mask := len(l.buf) - 1
+	for i := 0; i < l.n; i++ {
+		value := l.buf[(l.head+i)&mask]
+		if !fn(value) {
+			continue
+		}
+		for j := i; j > 0; j-- {
+			l.buf[(l.head+j)&mask] = l.buf[(l.head+j-1)&mask]
+		}
+		l.popFront()
+		l.shrink()
+		return value, true
+	}
+	var zero T
+	return zero, false
+}
+
+// DrainTo removes elements from the front of the list passing each to fn until
+// the list is empty or fn returns false. The element passed to fn is removed
+// in any case. It returns the number of removed elements. Lock is held while
+// draining, so fn must not call List methods.
+func (l *List[T]) DrainTo(fn func(T) bool) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	drained := 0
+	for l.n > 0 {
+		drained++
+		if !fn(l.popFront()) {
+			break
+		}
+	}
+	l.shrink()
+	return drained
+}
+
+// PopFrontCtx removes and returns the first element of the list, waiting for it
+// if the list is empty. It returns ctx error if ctx is done before an element is
+// available. Elements left in closed list are still returned, ErrListClosed is
+// returned once closed list is empty.
+func (l *List[T]) PopFrontCtx(ctx context.Context) (T, error) {
+	for {
+		l.mu.Lock()
+		if l.n > 0 {
+			value := l.popFront()
+			l.shrink()
+			l.mu.Unlock()
+			return value, nil
+		}
+		if l.closed {
+			l.mu.Unlock()
+			var zero T
+			return zero, ErrListClosed
+		}
+		if l.wait == nil {
+			l.wait = make(chan struct{})
+		}
+		wait := l.wait
+		l.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
 }
 
 // Len returns the number of elements in the list.
 func (l *List[T]) Len() int {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.values.Len()
+	return l.n
+}
+
+// Close wakes all PopFrontCtx waiters with ErrListClosed. Elements pushed after
+// Close are discarded. Calling Close multiple times is a no-op.
+func (l *List[T]) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	l.wake()
 }
 
 // Clear removes all elements from the list, making it empty.
 func (l *List[T]) Clear() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.values.Init()
+	l.buf = nil
+	l.head = 0
+	l.n = 0
+}
+
+// popFront must be called with lock held on non-empty list.
+func (l *List[T]) popFront() T {
+	var zero T
+	value := l.buf[l.head]
+	// Release reference for GC.
+	l.buf[l.head] = zero
+	l.head = (l.head + 1) & (len(l.buf) - 1)
+	l.n--
+	return value
+}
+
+// shrink halves buffer while it's at most quarter full. Must be called with lock
+// held.
+func (l *List[T]) shrink() {
+	for len(l.buf) > minCapacity && l.n <= len(l.buf)/4 {
+		l.resize(len(l.buf) / 2)
+	}
+}
+
+// wake wakes PopFrontCtx waiters. Must be called with lock held.
+func (l *List[T]) wake() {
+	if l.wait != nil {
+		close(l.wait)
+		l.wait = nil
+	}
+}
+
+// resize copies elements to a new buffer of size, which must be a power of two
+// not less than the number of elements.
+func (l *List[T]) resize(size int) {
+	buf := make([]T, size)
+	if l.n > 0 {
+		if l.head+l.n <= len(l.buf) {
+			copy(buf, l.buf[l.head:l.head+l.n])
+		} else {
+			k := copy(buf, l.buf[l.head:])
+			copy(buf[k:], l.buf[:l.n-k])
+		}
+	}
+	l.buf = buf
+	l.head = 0
 }
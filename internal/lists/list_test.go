@@ -1,7 +1,11 @@
 package lists
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 )
 
 func TestList_PushBack(t *testing.T) {
@@ -39,3 +43,193 @@ func TestList_PopFront(t *testing.T) {
 		t.Fatalf("expected false on empty list")
 	}
 }
+
+func TestList_WrapAround(t *testing.T) {
+	l := NewList[int]()
+	next := 0
+	// Push and pop with different pace, so head moves around buffer while it
+	// grows and shrinks.
+	for i := 0; i < 1000; i++ {
+		l.PushBack(i)
+		if i%3 == 0 {
+			v, ok := l.PopFront()
+			if !ok || v != next {
+				t.Fatalf("expected %d, got %d, ok=%v", next, v, ok)
+			}
+			next++
+		}
+	}
+	for l.Len() > 0 {
+		v, _ := l.PopFront()
+		if v != next {
+			t.Fatalf("expected %d, got %d", next, v)
+		}
+		next++
+	}
+	if next != 1000 {
+		t.Fatalf("expected 1000 elements, got %d", next)
+	}
+	if len(l.buf) != minCapacity {
+		t.Fatalf("expected buffer to shrink to %d, got %d", minCapacity, len(l.buf))
+	}
+}
+
+func TestList_Clear(t *testing.T) {
+	l := NewList[int]()
+	l.PushBack(1)
+	l.PushBack(2)
+	l.Clear()
+	if l.Len() != 0 {
+		t.Fatalf("expected length 0, got %d", l.Len())
+	}
+	l.PushBack(3)
+	if v, ok := l.PopFront(); !ok || v != 3 {
+		t.Fatalf("expected 3, got %d, ok=%v", v, ok)
+	}
+}
+
+func TestList_RemoveFirst(t *testing.T) {
+	l := NewList[int]()
+	// Move head, so removal shifts elements around buffer end.
+	for i := 0; i < 14; i++ {
+		l.PushBack(-1)
+		l.PopFront()
+	}
+	for i := 0; i < 6; i++ {
+		l.PushBack(i)
+	}
+	v, ok := l.RemoveFirst(func(v int) bool { return v >= 3 })
+	if !ok || v != 3 {
+		t.Fatalf("expected 3, got %d, ok=%v", v, ok)
+	}
+	if _, ok := l.RemoveFirst(func(v int) bool { return v > 10 }); ok {
+		t.Fatal("expected no match")
+	}
+	var values []int
+	for l.Len() > 0 {
+		v, _ := l.PopFront()
+		values = append(values, v)
+	}
+	if fmt.Sprint(values) != "[0 1 2 4 5]" {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+func TestList_PopFrontCtx(t *testing.T) {
+	l := NewList[int]()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.PushBack(1)
+	}()
+	v, err := l.PopFrontCtx(context.Background())
+	if err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d, err=%v", v, err)
+	}
+}
+
+func TestList_PopFrontCtx_Canceled(t *testing.T) {
+	l := NewList[int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := l.PopFrontCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	// Canceled waiter must not take pushed value.
+	l.PushBack(2)
+	if v, ok := l.PopFront(); !ok || v != 2 {
+		t.Fatalf("expected 2, got %d, ok=%v", v, ok)
+	}
+}
+
+func TestList_PushPop_NoAllocations(t *testing.T) {
+	l := NewList[int]()
+	allocs := testing.AllocsPerRun(100, func() {
+		l.PushBack(1)
+		l.PopFront()
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkList_PushPop(b *testing.B) {
+	l := NewList[int]()
+	b.ReportAllocs()
+	for b.Loop() {
+		l.PushBack(1)
+		l.PopFront()
+	}
+}
+
+func TestList_DrainTo(t *testing.T) {
+	l := NewList[int]()
+	for i := 1; i <= 4; i++ {
+		l.PushBack(i)
+	}
+	var got []int
+	n := l.DrainTo(func(v int) bool {
+		got = append(got, v)
+		return v < 2
+	})
+	if n != 2 || len(got) != 2 || got[1] != 2 {
+		t.Fatalf("unexpected drained %d %v", n, got)
+	}
+	n = l.DrainTo(func(v int) bool {
+		got = append(got, v)
+		return true
+	})
+	if n != 2 || len(got) != 4 || got[3] != 4 || l.Len() != 0 {
+		t.Fatalf("unexpected drained %d %v", n, got)
+	}
+}
+
+func BenchmarkList_DrainTo(b *testing.B) {
+	l := NewList[int]()
+	b.ReportAllocs()
+	for b.Loop() {
+		for i := 0; i < 64; i++ {
+			l.PushBack(i)
+		}
+		l.DrainTo(func(int) bool { return true })
+	}
+}
+
+func TestList_Close(t *testing.T) {
+	l := NewList[int]()
+	const waiters = 3
+	errCh := make(chan error, waiters)
+	for range waiters {
+		go func() {
+			_, err := l.PopFrontCtx(context.Background())
+			errCh <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	l.Close()
+	l.Close()
+	for range waiters {
+		select {
+		case err := <-errCh:
+			if !errors.Is(err, ErrListClosed) {
+				t.Fatalf("expected ErrListClosed, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("waiter was not woken by Close")
+		}
+	}
+}
+
+func TestList_Close_Drains(t *testing.T) {
+	l := NewList[int]()
+	l.PushBack(1)
+	l.Close()
+	l.PushBack(2)
+	v, err := l.PopFrontCtx(context.Background())
+	if err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d, err=%v", v, err)
+	}
+	if _, err := l.PopFrontCtx(context.Background()); !errors.Is(err, ErrListClosed) {
+		t.Fatalf("expected ErrListClosed, got %v", err)
+	}
+}
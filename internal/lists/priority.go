@@ -0,0 +1,102 @@
+package lists
+
+import (
+	"sync"
+)
+
+// PriorityQueue is a generic priority queue that is safe for concurrent use.
+// PopFront returns the element with the highest priority according to less,
+// i.e. the element which is less than all others. It's a binary heap over a
+// slice, so values are not boxed.
+type PriorityQueue[T any] struct {
+	mu     sync.Mutex
+	values []T
+	less   func(a, b T) bool
+}
+
+// NewPriorityQueue creates PriorityQueue ordered by less.
+func NewPriorityQueue[T any](less func(a, b T) bool) *PriorityQueue[T] {
+	return &PriorityQueue[T]{less: less}
+}
+
+// Push adds a new element to the queue.
+func (q *PriorityQueue[T]) Push(value T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.values = append(q.values, value)
+	q.up(len(q.values) - 1)
+}
+
+// PopFront removes and returns the element with the highest priority. It returns
+// false if the queue is empty.
+func (q *PriorityQueue[T]) PopFront() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var zero T
+	n := len(q.values)
+	if n == 0 {
+		return zero, false
+	}
+	value := q.values[0]
+	q.values[0] = q.values[n-1]
+	// Release reference for GC.
+	q.values[n-1] = zero
+	q.values = q.values[:n-1]
+	q.down(0)
+	return value, true
+}
+
+// Peek returns the element with the highest priority without removing it. It
+// returns false if the queue is empty.
+func (q *PriorityQueue[T]) Peek() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.values) == 0 {
+		var zero T
+		return zero, false
+	}
+	return q.values[0], true
+}
+
+// Len returns the number of elements in the queue.
+func (q *PriorityQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.values)
+}
+
+// Clear removes all elements from the queue, making it empty.
+func (q *PriorityQueue[T]) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.values = nil
+}
+
+func (q *PriorityQueue[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !q.less(q.values[i], q.values[parent]) {
+			return
+		}
+		q.values[i], q.values[parent] = q.values[parent], q.values[i]
+		i = parent
+	}
+}
+
+func (q *PriorityQueue[T]) down(i int) {
+	n := len(q.values)
+	for {
+		smallest := i
+		if left := 2*i + 1; left < n && q.less(q.values[left], q.values[smallest]) {
+			smallest = left
+		}
+		if right := 2*i + 2; right < n && q.less(q.values[right], q.values[smallest]) {
+			smallest = right
+		}
+		if smallest == i {
+			return
+		}
+		q.values[i], q.values[smallest] = q.values[smallest], q.values[i]
+		i = smallest
+	}
+}
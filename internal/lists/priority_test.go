@@ -0,0 +1,48 @@
+package lists
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestPriorityQueue_Order(t *testing.T) {
+	q := NewPriorityQueue[int](func(a, b int) bool { return a < b })
+	values := rand.Perm(100)
+	for _, v := range values {
+		q.Push(v)
+	}
+	if q.Len() != 100 {
+		t.Fatalf("expected length 100, got %d", q.Len())
+	}
+	if v, ok := q.Peek(); !ok || v != 0 {
+		t.Fatalf("expected 0, got %d, ok=%v", v, ok)
+	}
+	sort.Ints(values)
+	for _, expected := range values {
+		v, ok := q.PopFront()
+		if !ok || v != expected {
+			t.Fatalf("expected %d, got %d, ok=%v", expected, v, ok)
+		}
+	}
+	if _, ok := q.PopFront(); ok {
+		t.Fatalf("expected false on empty queue")
+	}
+	if _, ok := q.Peek(); ok {
+		t.Fatalf("expected false on empty queue")
+	}
+}
+
+func TestPriorityQueue_Clear(t *testing.T) {
+	q := NewPriorityQueue[int](func(a, b int) bool { return a > b })
+	q.Push(1)
+	q.Push(3)
+	q.Push(2)
+	if v, _ := q.PopFront(); v != 3 {
+		t.Fatalf("expected 3, got %d", v)
+	}
+	q.Clear()
+	if q.Len() != 0 {
+		t.Fatalf("expected length 0, got %d", q.Len())
+	}
+}
@@ -0,0 +1,120 @@
+//go:build lockorder
+
+package locks
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// Lock order checking is enabled with lockorder build tag, e.g.
+// go test -tags lockorder ./... It records which lock classes are acquired
+// while holding others and panics as soon as acquisition order forms a cycle,
+// which is a potential deadlock even if it did not happen in this run.
+
+var order = struct {
+	mu sync.Mutex
+	// after[a][b] means b was acquired while holding a.
+	after map[string]map[string]string
+	// held are lock classes held by goroutines.
+	held map[uint64][]string
+}{
+	after: make(map[string]map[string]string),
+	held:  make(map[uint64][]string),
+}
+
+func beforeAcquire(class string) {
+	if class == "" {
+		return
+	}
+	order.mu.Lock()
+	defer order.mu.Unlock()
+	for _, h := range order.held[goroutineID()] {
+		if h == class {
+			continue
+		}
+		if path := orderPath(class, h); path != nil {
+			panic(fmt.Sprintf("lock order inversion: acquiring %q while holding %q, but earlier %s", class, h, path))
+		}
+		if order.after[h] == nil {
+			order.after[h] = make(map[string]string)
+		}
+		if _, ok := order.after[h][class]; !ok {
+			order.after[h][class] = string(stack())
+		}
+	}
+}
+
+func afterAcquire(class string) {
+	if class == "" {
+		return
+	}
+	id := goroutineID()
+	order.mu.Lock()
+	order.held[id] = append(order.held[id], class)
+	order.mu.Unlock()
+}
+
+func afterRelease(class string) {
+	if class == "" {
+		return
+	}
+	id := goroutineID()
+	order.mu.Lock()
+	defer order.mu.Unlock()
+	held := order.held[id]
+	for i := len(held) - 1; i >= 0; i-- {
+		if held[i] == class {
+			held = append(held[:i], held[i+1:]...)
+			break
+		}
+	}
+	if len(held) == 0 {
+		delete(order.held, id)
+		return
+	}
+	order.held[id] = held
+}
+
+// orderPath returns description of recorded acquisition chain from one lock
+// class to another, nil if there is none.
+func orderPath(from, to string) []string {
+	visited := map[string]bool{from: true}
+	var walk func(class string) []string
+	walk = func(class string) []string {
+		for next, stack := range order.after[class] {
+			step := fmt.Sprintf("%q was acquired while holding %q at:\n%s", next, class, stack)
+			if next == to {
+				return []string{step}
+			}
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			if path := walk(next); path != nil {
+				return append([]string{step}, path...)
+			}
+		}
+		return nil
+	}
+	return walk(from)
+}
+
+func stack() []byte {
+	buf := make([]byte, 4096)
+	return buf[:runtime.Stack(buf, false)]
+}
+
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
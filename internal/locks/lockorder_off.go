@@ -0,0 +1,9 @@
+//go:build !lockorder
+
+package locks
+
+func beforeAcquire(string) {}
+
+func afterAcquire(string) {}
+
+func afterRelease(string) {}
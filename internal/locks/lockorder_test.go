@@ -0,0 +1,46 @@
+//go:build lockorder
+
+package locks
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLockOrderInversion(t *testing.T) {
+	var a, b RWMutex
+	a.SetClass("test-a")
+	b.SetClass("test-b")
+
+	a.Lock()
+	b.RLock()
+	b.RUnlock()
+	a.Unlock()
+
+	b.Lock()
+	defer b.Unlock()
+	defer func() {
+		v := recover()
+		if v == nil {
+			t.Fatal("expected panic")
+		}
+		if !strings.Contains(v.(string), "lock order inversion") {
+			t.Fatalf("unexpected panic: %v", v)
+		}
+	}()
+	a.Lock()
+}
+
+func TestLockOrderSameClass(t *testing.T) {
+	var a, b RWMutex
+	a.SetClass("test-same")
+	b.SetClass("test-same")
+	a.Lock()
+	b.Lock()
+	b.Unlock()
+	a.Unlock()
+	b.Lock()
+	a.Lock()
+	a.Unlock()
+	b.Unlock()
+}
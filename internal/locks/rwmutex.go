@@ -0,0 +1,158 @@
+package locks
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stackSampleRate is how often holder stack is captured: once per this number
+// of write lock acquisitions.
+const stackSampleRate = 100
+
+// Stats accumulates contention of mutexes sharing it. It is safe for concurrent
+// use.
+type Stats struct {
+	acquisitions atomic.Uint64
+	writes       atomic.Uint64
+	waitTotal    atomic.Int64
+	maxWait      atomic.Int64
+	holdTotal    atomic.Int64
+	maxHold      atomic.Int64
+
+	mu           sync.Mutex
+	maxSampled   time.Duration
+	maxHoldStack []byte
+}
+
+// Snapshot is a point-in-time copy of Stats.
+type Snapshot struct {
+	// Acquisitions is the number of write and read lock acquisitions.
+	Acquisitions uint64
+	// WaitTotal is a total time spent waiting for lock.
+	WaitTotal time.Duration
+	// MaxWait is the longest wait for lock.
+	MaxWait time.Duration
+	// HoldTotal is a total time write lock was held.
+	HoldTotal time.Duration
+	// MaxHold is the longest time write lock was held.
+	MaxHold time.Duration
+	// MaxHoldStack is a stack of the longest write lock holder among sampled
+	// acquisitions.
+	MaxHoldStack string
+}
+
+// Snapshot returns current values of Stats.
+func (s *Stats) Snapshot() Snapshot {
+	s.mu.Lock()
+	stack := string(s.maxHoldStack)
+	s.mu.Unlock()
+	return Snapshot{
+		Acquisitions: s.acquisitions.Load(),
+		WaitTotal:    time.Duration(s.waitTotal.Load()),
+		MaxWait:      time.Duration(s.maxWait.Load()),
+		HoldTotal:    time.Duration(s.holdTotal.Load()),
+		MaxHold:      time.Duration(s.maxHold.Load()),
+		MaxHoldStack: stack,
+	}
+}
+
+func (s *Stats) observeWait(d time.Duration) {
+	s.waitTotal.Add(int64(d))
+	storeMax(&s.maxWait, int64(d))
+	s.acquisitions.Add(1)
+}
+
+func (s *Stats) observeHold(d time.Duration, stack []byte) {
+	s.holdTotal.Add(int64(d))
+	storeMax(&s.maxHold, int64(d))
+	if stack == nil {
+		return
+	}
+	s.mu.Lock()
+	if d > s.maxSampled {
+		s.maxSampled = d
+		s.maxHoldStack = stack
+	}
+	s.mu.Unlock()
+}
+
+func storeMax(v *atomic.Int64, n int64) {
+	for {
+		current := v.Load()
+		if n <= current || v.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+// RWMutex is sync.RWMutex which measures wait and hold times when Stats set and
+// checks acquisition order of lock classes when built with lockorder tag.
+// Zero value is an unlocked mutex without measurements.
+type RWMutex struct {
+	mu    sync.RWMutex
+	stats *Stats
+	class string
+	// Protected by write lock.
+	acquired time.Time
+	stack    []byte
+}
+
+// SetStats enables measurements, must be called before mutex is used.
+func (m *RWMutex) SetStats(stats *Stats) {
+	m.stats = stats
+}
+
+// SetClass sets name of lock class for lock order checking, e.g. all client
+// locks share one class. Must be called before mutex is used.
+func (m *RWMutex) SetClass(class string) {
+	m.class = class
+}
+
+// Lock locks m for writing.
+func (m *RWMutex) Lock() {
+	beforeAcquire(m.class)
+	defer afterAcquire(m.class)
+	if m.stats == nil {
+		m.mu.Lock()
+		return
+	}
+	started := time.Now()
+	m.mu.Lock()
+	m.acquired = time.Now()
+	m.stats.observeWait(m.acquired.Sub(started))
+	if m.stats.writes.Add(1)%stackSampleRate == 1 {
+		buf := make([]byte, 4096)
+		m.stack = buf[:runtime.Stack(buf, false)]
+	}
+}
+
+// Unlock unlocks m for writing.
+func (m *RWMutex) Unlock() {
+	if m.stats != nil {
+		m.stats.observeHold(time.Since(m.acquired), m.stack)
+		m.stack = nil
+	}
+	m.mu.Unlock()
+	afterRelease(m.class)
+}
+
+// RLock locks m for reading. Only wait time is measured for readers.
+func (m *RWMutex) RLock() {
+	beforeAcquire(m.class)
+	defer afterAcquire(m.class)
+	if m.stats == nil {
+		m.mu.RLock()
+		return
+	}
+	started := time.Now()
+	m.mu.RLock()
+	m.stats.observeWait(time.Since(started))
+}
+
+// RUnlock undoes a single RLock call.
+func (m *RWMutex) RUnlock() {
+	m.mu.RUnlock()
+	afterRelease(m.class)
+}
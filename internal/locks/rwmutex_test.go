@@ -0,0 +1,48 @@
+package locks
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRWMutex_WithoutStats(t *testing.T) {
+	var m RWMutex
+	m.Lock()
+	m.Unlock()
+	m.RLock()
+	m.RUnlock()
+}
+
+func TestRWMutex_Stats(t *testing.T) {
+	stats := &Stats{}
+	var m RWMutex
+	m.SetStats(stats)
+
+	m.Lock()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.RLock()
+		m.RUnlock()
+	}()
+	time.Sleep(20 * time.Millisecond)
+	m.Unlock()
+	wg.Wait()
+
+	s := stats.Snapshot()
+	if s.Acquisitions != 2 {
+		t.Fatalf("expected 2 acquisitions, got %d", s.Acquisitions)
+	}
+	if s.MaxHold < 20*time.Millisecond || s.HoldTotal < s.MaxHold {
+		t.Fatalf("unexpected hold times %s, %s", s.MaxHold, s.HoldTotal)
+	}
+	if s.MaxWait < 10*time.Millisecond || s.WaitTotal < s.MaxWait {
+		t.Fatalf("unexpected wait times %s, %s", s.MaxWait, s.WaitTotal)
+	}
+	if !strings.Contains(s.MaxHoldStack, "TestRWMutex_Stats") {
+		t.Fatalf("expected holder stack, got %q", s.MaxHoldStack)
+	}
+}
@@ -0,0 +1,107 @@
+package maps
+
+import (
+	"sync"
+)
+
+const numShards = 32
+
+// ShardedMap is a generic string-keyed map that is safe for concurrent use. Keys
+// are distributed over a fixed number of shards each protected by its own lock, so
+// operations on different keys rarely contend with each other.
+type ShardedMap[V any] struct {
+	shards [numShards]shard[V]
+}
+
+type shard[V any] struct {
+	mu     sync.RWMutex
+	values map[string]V
+}
+
+func NewShardedMap[V any]() *ShardedMap[V] {
+	m := &ShardedMap[V]{}
+	for i := range m.shards {
+		m.shards[i].values = make(map[string]V)
+	}
+	return m
+}
+
+// getShard returns shard for a key using inlined FNV-1a hash to avoid allocations.
+func (m *ShardedMap[V]) getShard(key string) *shard[V] {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return &m.shards[h%numShards]
+}
+
+// Load returns the value stored for a key and whether it was found.
+func (m *ShardedMap[V]) Load(key string) (V, bool) {
+	s := m.getShard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// StoreIfAbsent sets the value for a key only if the key is not in the map yet.
+// It returns false if the key already exists.
+func (m *ShardedMap[V]) StoreIfAbsent(key string, value V) bool {
+	s := m.getShard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		return false
+	}
+	s.values[key] = value
+	return true
+}
+
+// Delete removes the value for a key.
+func (m *ShardedMap[V]) Delete(key string) {
+	s := m.getShard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// Len returns the number of elements in the map.
+func (m *ShardedMap[V]) Len() int {
+	var n int
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		n += len(s.values)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Range calls fn for each key and value in the map. Shards are visited one by one,
+// only one shard is locked at a time, so Range does not provide a consistent
+// snapshot of the entire map. Range stops if fn returns false. It's not allowed
+// to modify the map from inside fn.
+func (m *ShardedMap[V]) Range(fn func(key string, value V) bool) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		for k, v := range s.values {
+			if !fn(k, v) {
+				s.mu.RUnlock()
+				return
+			}
+		}
+		s.mu.RUnlock()
+	}
+}
+
+// Values returns a slice with all values currently stored in the map.
+func (m *ShardedMap[V]) Values() []V {
+	values := make([]V, 0, m.Len())
+	m.Range(func(_ string, v V) bool {
+		values = append(values, v)
+		return true
+	})
+	return values
+}
@@ -0,0 +1,153 @@
+package maps
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestShardedMap_StoreIfAbsent(t *testing.T) {
+	m := NewShardedMap[int]()
+	if !m.StoreIfAbsent("a", 1) {
+		t.Fatal("expected value to be stored")
+	}
+	if m.StoreIfAbsent("a", 2) {
+		t.Fatal("expected duplicate key to be rejected")
+	}
+	v, ok := m.Load("a")
+	if !ok || v != 1 {
+		t.Fatalf("expected 1, got %d, ok=%v", v, ok)
+	}
+}
+
+func TestShardedMap_Delete(t *testing.T) {
+	m := NewShardedMap[int]()
+	m.StoreIfAbsent("a", 1)
+	m.Delete("a")
+	if _, ok := m.Load("a"); ok {
+		t.Fatal("expected key to be deleted")
+	}
+	if m.Len() != 0 {
+		t.Fatalf("expected length 0, got %d", m.Len())
+	}
+}
+
+func TestShardedMap_Range(t *testing.T) {
+	m := NewShardedMap[int]()
+	for i := 0; i < 100; i++ {
+		m.StoreIfAbsent(strconv.Itoa(i), i)
+	}
+	if m.Len() != 100 {
+		t.Fatalf("expected length 100, got %d", m.Len())
+	}
+	if len(m.Values()) != 100 {
+		t.Fatalf("expected 100 values, got %d", len(m.Values()))
+	}
+	var visited int
+	m.Range(func(_ string, _ int) bool {
+		visited++
+		return visited < 10
+	})
+	if visited != 10 {
+		t.Fatalf("expected Range to stop after 10 elements, visited %d", visited)
+	}
+}
+
+const benchNumKeys = 10000
+
+func benchKeys() []string {
+	keys := make([]string, benchNumKeys)
+	for i := range keys {
+		keys[i] = "channel_" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+// mutexMap is a map protected by a single lock – how Client kept subscriptions
+// before ShardedMap. Used as a baseline in benchmarks.
+type mutexMap struct {
+	mu     sync.RWMutex
+	values map[string]int
+}
+
+func (m *mutexMap) Load(key string) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.values[key]
+	return v, ok
+}
+
+func (m *mutexMap) Store(key string, value int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+}
+
+func (m *mutexMap) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+}
+
+// Benchmarks below emulate dispatch of publications over 10k subscriptions while
+// another goroutine constantly modifies registry.
+
+func BenchmarkMutexMap_LoadWithWrites(b *testing.B) {
+	keys := benchKeys()
+	m := &mutexMap{values: make(map[string]int)}
+	for i, k := range keys {
+		m.Store(k, i)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				k := keys[i%len(keys)]
+				m.Delete(k)
+				m.Store(k, i)
+			}
+		}
+	}()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			m.Load(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkShardedMap_LoadWithWrites(b *testing.B) {
+	keys := benchKeys()
+	m := NewShardedMap[int]()
+	for i, k := range keys {
+		m.StoreIfAbsent(k, i)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				k := keys[i%len(keys)]
+				m.Delete(k)
+				m.StoreIfAbsent(k, i)
+			}
+		}
+	}()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			m.Load(keys[i%len(keys)])
+			i++
+		}
+	})
+}
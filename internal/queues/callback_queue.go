@@ -15,12 +15,31 @@ import (
 // interact with the queue.
 var ErrQueueClosed = errors.New("queue is closed")
 
+// ErrQueueFull is returned by Push when bounded queue with OverflowReject policy
+// is full.
+var ErrQueueFull = errors.New("queue is full")
+
+// OverflowPolicy defines what Push does when bounded queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks Push until there is space in the queue or queue is
+	// closed.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest callback to free space, callbacks added
+	// by ForcePush are not dropped. Dropped callback is called by Push with
+	// canceled context so it can release its waiters.
+	OverflowDropOldest
+	// OverflowReject makes Push return ErrQueueFull.
+	OverflowReject
+)
+
 // CallBackQueue runs callbacks in the order they are pushed to the queue. It is
 // used as a synchronization mechanism for invoking functions in order across
 // goroutines. It should not be used after being closed.
 type CallBackQueue struct {
 	// The ordered list of callbacks to be processed.
-	list *lists.List[*callBackRequest]
+	list *lists.List[callBackRequest]
 	// enqueueSignals are sent when a new item is added. It must be a buffered
 	// channel to avoid missing signals.
 	enqueueSignals chan struct{}
@@ -32,24 +51,52 @@ type CallBackQueue struct {
 	closeSignal chan struct{}
 	// doneSignal is closed to signal the queue is fully shutdown.
 	doneSignal chan struct{}
+	// size limits the number of queued callbacks, zero means no limit.
+	size   int
+	policy OverflowPolicy
+	// pushMu makes checking size and pushing atomic for bounded queue.
+	pushMu sync.Mutex
+	// dequeueSignals wake producers blocked on full queue.
+	dequeueSignals chan struct{}
+	// dropped counts callbacks dropped due to OverflowDropOldest.
+	dropped atomic.Uint64
+	// ctx is passed to all callbacks, it's done when closeSignal is closed.
+	ctx context.Context
+	// droppedCtx is passed to dropped callbacks, it's always canceled.
+	droppedCtx context.Context
 }
 
 // newUnopenedCallBackQueue creates a queue in the closed state. Use
 // OpenCallBackQueue instead.
 func newUnopenedCallBackQueue() *CallBackQueue {
-	return &CallBackQueue{
-		list:           lists.NewList[*callBackRequest](),
+	droppedCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	q := &CallBackQueue{
+		list:           lists.NewList[callBackRequest](),
 		enqueueSignals: make(chan struct{}, 1),
+		dequeueSignals: make(chan struct{}, 1),
 		closeSignal:    make(chan struct{}),
 		doneSignal:     make(chan struct{}),
+		droppedCtx:     droppedCtx,
 	}
+	q.ctx = closeContext{done: q.closeSignal}
+	return q
 }
 
 // OpenCallBackQueue creates a new callback queue and starts the queuing
 // process. The caller is responsible for closing the queue when it is no longer
 // needed. The queue cannot be reused after it is closed.
 func OpenCallBackQueue() *CallBackQueue {
+	return OpenBoundedCallBackQueue(0, OverflowBlock)
+}
+
+// OpenBoundedCallBackQueue creates a new callback queue which holds at most size
+// callbacks waiting for execution, policy is applied by Push when queue is full.
+// Zero size means no limit.
+func OpenBoundedCallBackQueue(size int, policy OverflowPolicy) *CallBackQueue {
 	q := newUnopenedCallBackQueue()
+	q.size = size
+	q.policy = policy
 	q.running.Lock()
 	processCallBacksIsRunning := make(chan struct{})
 	go func() {
@@ -81,24 +128,134 @@ func (q *CallBackQueue) Close() {
 	if !q.opened.Swap(false) {
 		return // The queue is already closed.
 	}
+	// Closed list discards callbacks of Push racing with Close.
+	q.list.Close()
 	q.list.Clear()
 	close(q.closeSignal)
 	<-q.doneSignal
 }
 
 // Push adds a callback to the queue. It panics if cb is nil. It returns
-// ErrQueueClosed if the queue is closed.
+// ErrQueueClosed if the queue is closed. When bounded queue is full overflow
+// policy is applied.
 func (q *CallBackQueue) Push(cb CallBackFunc) error {
+	return q.push(cb, true)
+}
+
+// PushNoWait is like Push, but it never blocks: with OverflowBlock policy the
+// callback is added to full queue. Use WaitSpace before PushNoWait to wait for
+// free space without holding caller locks.
+func (q *CallBackQueue) PushNoWait(cb CallBackFunc) error {
+	return q.push(cb, false)
+}
+
+// WaitSpace blocks until bounded queue with OverflowBlock policy has free space.
+// It returns ErrQueueClosed if the queue is closed.
+func (q *CallBackQueue) WaitSpace() error {
+	if q.size <= 0 || q.policy != OverflowBlock {
+		return nil
+	}
+	for q.list.Len() >= q.size {
+		select {
+		case <-q.dequeueSignals:
+		case <-q.closeSignal:
+			return ErrQueueClosed
+		}
+	}
+	// Pass wake up to the next waiter.
+	q.signalDequeue()
+	return nil
+}
+
+func (q *CallBackQueue) push(cb CallBackFunc, wait bool) error {
+	if cb == nil {
+		panic("nil callback function")
+	}
+	if !q.opened.Load() {
+		return ErrQueueClosed
+	}
+	if q.size <= 0 || (!wait && q.policy == OverflowBlock) {
+		q.pushBack(cb, false)
+		return nil
+	}
+	for {
+		q.pushMu.Lock()
+		if q.list.Len() < q.size {
+			q.pushBack(cb, false)
+			hasSpace := q.list.Len() < q.size
+			q.pushMu.Unlock()
+			if hasSpace {
+				// Pass wake up to the next blocked producer.
+				q.signalDequeue()
+			}
+			return nil
+		}
+		switch q.policy {
+		case OverflowReject:
+			q.pushMu.Unlock()
+			return ErrQueueFull
+		case OverflowDropOldest:
+			// Callbacks added by ForcePush are never dropped, so new callback is
+			// dropped if there are only such callbacks in queue.
+			req := callBackRequest{fn: cb, tm: time.Now()}
+			if oldest, ok := q.list.RemoveFirst(func(req callBackRequest) bool {
+				return !req.keep
+			}); ok {
+				q.pushBack(cb, false)
+				req = oldest
+			}
+			q.pushMu.Unlock()
+			q.drop(req)
+			return nil
+		}
+		q.pushMu.Unlock()
+		select {
+		case <-q.dequeueSignals:
+		case <-q.closeSignal:
+			return ErrQueueClosed
+		}
+	}
+}
+
+// ForcePush adds a callback to the queue ignoring size limit. Such callback is
+// never dropped due to OverflowDropOldest policy. It must be used for callbacks
+// pushed by callbacks being executed: blocking there would never let the queue
+// drain.
+func (q *CallBackQueue) ForcePush(cb CallBackFunc) error {
 	if cb == nil {
 		panic("nil callback function")
 	}
 	if !q.opened.Load() {
 		return ErrQueueClosed
 	}
+	q.pushBack(cb, true)
+	return nil
+}
+
+func (q *CallBackQueue) pushBack(cb CallBackFunc, keep bool) {
 	// Preserve order.
-	q.list.PushBack(&callBackRequest{fn: cb, tm: time.Now()})
+	q.list.PushBack(callBackRequest{fn: cb, tm: time.Now(), keep: keep})
 	q.signalEnqueue()
-	return nil
+}
+
+// drop calls dropped callback with canceled context.
+func (q *CallBackQueue) drop(req callBackRequest) {
+	q.dropped.Add(1)
+	if req.fn == nil {
+		return
+	}
+	req.fn(q.droppedCtx, time.Since(req.tm))
+}
+
+// Dropped returns the number of callbacks dropped due to OverflowDropOldest
+// policy.
+func (q *CallBackQueue) Dropped() uint64 {
+	return q.dropped.Load()
+}
+
+// Len returns the number of callbacks waiting for execution.
+func (q *CallBackQueue) Len() int {
+	return q.list.Len()
 }
 
 // processCallBacks is responsible for invoking callbacks from the list when it
@@ -133,6 +290,14 @@ func (q *CallBackQueue) signalEnqueue() {
 	}
 }
 
+// signalDequeue wakes producer blocked on full queue.
+func (q *CallBackQueue) signalDequeue() {
+	select {
+	case q.dequeueSignals <- struct{}{}:
+	default:
+	}
+}
+
 // invokeOneCallBack is responsible for invoking a single callback from the
 // list.
 func (q *CallBackQueue) invokeOneCallBack() {
@@ -140,29 +305,45 @@ func (q *CallBackQueue) invokeOneCallBack() {
 	if !ok {
 		return
 	}
-	if curr == nil {
-		return
+	if q.size > 0 {
+		q.signalDequeue()
 	}
 	if curr.fn == nil {
 		return
 	}
-	// signal to cancel the callback if the queue is closed while the callback
-	// is executing.
-	callbackCtx, callbackCancel := context.WithCancel(context.Background())
-	defer callbackCancel()
-	go func() {
-		select {
-		case <-q.closeSignal:
-			callbackCancel()
-		case <-callbackCtx.Done():
-			return
-		}
-	}()
-	curr.fn(callbackCtx, time.Since(curr.tm))
+	curr.fn(q.ctx, time.Since(curr.tm))
+}
+
+// closeContext is canceled when the queue is closed. Unlike context.WithCancel
+// it does not need a goroutine or allocation per callback to follow closeSignal.
+type closeContext struct {
+	done chan struct{}
+}
+
+func (c closeContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (c closeContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c closeContext) Err() error {
+	select {
+	case <-c.done:
+		return context.Canceled
+	default:
+		return nil
+	}
+}
+
+func (c closeContext) Value(any) any {
+	return nil
 }
 
 // CallBackFunc is a function type that represents a callback to be executed.
-// The ctx is canceled if the queue is closed while the callback is executing.
+// The ctx is canceled if the queue is closed while the callback is executing or
+// if the callback was dropped from full queue before execution.
 // The delay is the time since the callback was added to the queue.
 type CallBackFunc = func(ctx context.Context, delay time.Duration)
 
@@ -171,4 +352,6 @@ type callBackRequest struct {
 	fn CallBackFunc
 	// The time the callback was added to the queue.
 	tm time.Time
+	// keep protects callback from being dropped.
+	keep bool
 }
@@ -3,6 +3,7 @@ package queues
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -72,7 +73,7 @@ func TestCallbackQueue_processCallBacks_starts_and_cancels(t *testing.T) {
 		<-ctx.Done()
 		close(cbFinished)
 	}
-	q.list.PushBack(&callBackRequest{fn: cb, tm: time.Now()})
+	q.list.PushBack(callBackRequest{fn: cb, tm: time.Now()})
 	// Run the processCallBacks method
 	go q.processCallBacks()
 	<-cbStarted // wait for the callback to start processing
@@ -93,7 +94,7 @@ func TestCallbackQueue_processCallBacks_auto_dequeues(t *testing.T) {
 		cb := func(ctx context.Context, d time.Duration) {
 			defer wg.Done()
 		}
-		q.list.PushBack(&callBackRequest{fn: cb, tm: time.Now()})
+		q.list.PushBack(callBackRequest{fn: cb, tm: time.Now()})
 	}
 	// Only send one signal; processCallBacks should dequeue the rest.
 	q.signalEnqueue()
@@ -218,7 +219,7 @@ func TestCallbackQueue_nextCallBack_returns_true_when_callback_is_queued(t *test
 	n := 10
 	go func() {
 		for range n {
-			q.list.PushBack(&callBackRequest{})
+			q.list.PushBack(callBackRequest{})
 		}
 		q.signalEnqueue()
 	}()
@@ -234,3 +235,165 @@ func TestCallbackQueue_nextCallBack_returns_false_when_closed(t *testing.T) {
 	}()
 	assertTrue(t, !q.nextCallBack(), "nextCallBack should return false when there is no callback to process")
 }
+
+// blockedBoundedQueue returns bounded queue with dispatcher blocked in callback
+// until returned function is called.
+func blockedBoundedQueue(t *testing.T, size int, policy OverflowPolicy) (*CallBackQueue, func()) {
+	q := OpenBoundedCallBackQueue(size, policy)
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	err := q.Push(func(_ context.Context, _ time.Duration) {
+		close(started)
+		<-unblock
+	})
+	assertNoError(t, err, "Push should not return an error")
+	<-started
+	var once sync.Once
+	return q, func() { once.Do(func() { close(unblock) }) }
+}
+
+func TestCallbackQueue_Bounded_Reject(t *testing.T) {
+	q, unblock := blockedBoundedQueue(t, 2, OverflowReject)
+	defer q.Close()
+	defer unblock()
+	noop := func(_ context.Context, _ time.Duration) {}
+	assertNoError(t, q.Push(noop), "Push should not return an error")
+	assertNoError(t, q.Push(noop), "Push should not return an error")
+	assertErrorIs(t, q.Push(noop), ErrQueueFull, "Push to full queue should be rejected")
+	assertNoError(t, q.ForcePush(noop), "ForcePush should ignore size limit")
+	assertEqual(t, 3, q.Len(), "unexpected queue length")
+}
+
+func TestCallbackQueue_Bounded_DropOldest(t *testing.T) {
+	q, unblock := blockedBoundedQueue(t, 2, OverflowDropOldest)
+	defer q.Close()
+	var mu sync.Mutex
+	var results []string
+	var wg sync.WaitGroup
+	for _, v := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		err := q.Push(func(ctx context.Context, _ time.Duration) {
+			defer wg.Done()
+			if ctx.Err() != nil {
+				return
+			}
+			mu.Lock()
+			results = append(results, v)
+			mu.Unlock()
+		})
+		assertNoError(t, err, "Push should not return an error")
+	}
+	assertEqual(t, uint64(2), q.Dropped(), "unexpected number of dropped callbacks")
+	unblock()
+	wg.Wait()
+	assertEqual(t, "c,d", strings.Join(results, ","), "oldest callbacks must be dropped")
+}
+
+func TestCallbackQueue_Bounded_DropOldest_ForcePush(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		force    []bool
+		dropped  uint64
+		expected string
+	}{
+		{"oldest not forced dropped", []bool{true, false, false, false}, 2, "a,d"},
+		{"new dropped", []bool{true, true, false}, 1, "a,b"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q, unblock := blockedBoundedQueue(t, 2, OverflowDropOldest)
+			defer q.Close()
+			var mu sync.Mutex
+			var results []string
+			var wg sync.WaitGroup
+			for i, force := range tc.force {
+				v := string(rune('a' + i))
+				wg.Add(1)
+				cb := func(ctx context.Context, _ time.Duration) {
+					defer wg.Done()
+					if ctx.Err() != nil {
+						return
+					}
+					mu.Lock()
+					results = append(results, v)
+					mu.Unlock()
+				}
+				if force {
+					assertNoError(t, q.ForcePush(cb), "ForcePush should not return an error")
+				} else {
+					assertNoError(t, q.Push(cb), "Push should not return an error")
+				}
+			}
+			assertEqual(t, tc.dropped, q.Dropped(), "unexpected number of dropped callbacks")
+			unblock()
+			wg.Wait()
+			assertEqual(t, tc.expected, strings.Join(results, ","), "force pushed callbacks must be kept")
+		})
+	}
+}
+
+func TestCallbackQueue_Bounded_Block(t *testing.T) {
+	q, unblock := blockedBoundedQueue(t, 1, OverflowBlock)
+	defer q.Close()
+	noop := func(_ context.Context, _ time.Duration) {}
+	assertNoError(t, q.Push(noop), "Push should not return an error")
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- q.Push(noop)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("Push to full queue should block")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unblock()
+	select {
+	case err := <-pushed:
+		assertNoError(t, err, "Push should not return an error")
+	case <-time.After(time.Second):
+		t.Fatal("Push should be unblocked when queue has space")
+	}
+}
+
+func TestCallbackQueue_Bounded_Block_Close(t *testing.T) {
+	q, unblock := blockedBoundedQueue(t, 1, OverflowBlock)
+	noop := func(_ context.Context, _ time.Duration) {}
+	assertNoError(t, q.Push(noop), "Push should not return an error")
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- q.Push(noop)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go q.Close()
+	select {
+	case err := <-pushed:
+		assertErrorIs(t, err, ErrQueueClosed, "blocked Push should fail on close")
+	case <-time.After(time.Second):
+		t.Fatal("Push should be unblocked when queue is closed")
+	}
+	unblock()
+}
+
+func TestCallbackQueue_invokeOneCallBack_no_allocations(t *testing.T) {
+	q := newUnopenedCallBackQueue()
+	cb := func(ctx context.Context, d time.Duration) {}
+	allocs := testing.AllocsPerRun(100, func() {
+		q.list.PushBack(callBackRequest{fn: cb})
+		q.invokeOneCallBack()
+	})
+	assertTrue(t, allocs == 0, "invokeOneCallBack should not allocate")
+}
+
+func BenchmarkCallbackQueue_Push(b *testing.B) {
+	q := OpenCallBackQueue()
+	defer q.Close()
+	var wg sync.WaitGroup
+	cb := func(ctx context.Context, d time.Duration) {
+		wg.Done()
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		wg.Add(1)
+		_ = q.Push(cb)
+	}
+	wg.Wait()
+}
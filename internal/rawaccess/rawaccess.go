@@ -0,0 +1,12 @@
+// Package rawaccess links centrifuge package with its raw subpackage, so raw
+// protocol access is not a part of centrifuge package API.
+package rawaccess
+
+import (
+	"context"
+
+	"github.com/centrifugal/protocol"
+)
+
+// SendCommand is set by centrifuge package, client is *centrifuge.Client.
+var SendCommand func(ctx context.Context, client any, cmd *protocol.Command) (*protocol.Reply, error)
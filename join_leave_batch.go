@@ -0,0 +1,49 @@
+package centrifuge
+
+import (
+	"sync"
+	"time"
+)
+
+// joinLeaveBuffer collects join and leave messages of subscription and passes
+// them to OnJoinLeave handler in batches at most once per window.
+type joinLeaveBuffer struct {
+	s      *Subscription
+	window time.Duration
+
+	mu     sync.Mutex
+	events []JoinLeaveEvent
+	timer  *time.Timer
+}
+
+func newJoinLeaveBuffer(s *Subscription, window time.Duration) *joinLeaveBuffer {
+	return &joinLeaveBuffer{s: s, window: window}
+}
+
+// add buffers event, flush is scheduled upon the first event in window.
+func (b *joinLeaveBuffer) add(event JoinLeaveEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+}
+
+func (b *joinLeaveBuffer) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events := b.events
+	b.events = nil
+	b.timer = nil
+	if len(events) == 0 {
+		return
+	}
+	if b.s.events != nil && b.s.events.onJoinLeave != nil {
+		handler := b.s.events.onJoinLeave
+		// Pushed under lock to keep batches in order.
+		b.s.centrifuge.runHandlerAsync(func() {
+			handler(events)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package centrifuge
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscription_JoinLeaveWindow(t *testing.T) {
+	ch := &testPresenceChannel{pushCh: make(chan string, 8)}
+	client := NewJsonClient(startWatchPresenceServer(t, ch), Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test", SubscriptionConfig{JoinLeave: true, JoinLeaveWindow: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.OnJoin(func(JoinEvent) {
+		t.Error("unexpected join event")
+	})
+	sub.OnLeave(func(LeaveEvent) {
+		t.Error("unexpected leave event")
+	})
+	batches := make(chan []JoinLeaveEvent, 4)
+	sub.OnJoinLeave(func(events []JoinLeaveEvent) {
+		batches <- events
+	})
+	subscribed := make(chan struct{})
+	sub.OnSubscribed(func(SubscribedEvent) {
+		close(subscribed)
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-subscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for subscribed event")
+	}
+	ch.pushCh <- `{"push":{"channel":"test","join":{"info":{"client":"a"}}}}`
+	ch.pushCh <- `{"push":{"channel":"test","join":{"info":{"client":"b"}}}}`
+	ch.pushCh <- `{"push":{"channel":"test","leave":{"info":{"client":"a"}}}}`
+	ch.pushCh <- `{"push":{"channel":"test","join":{"info":{"client":"a"}}}}`
+
+	var got []string
+	for len(got) < 4 {
+		select {
+		case events := <-batches:
+			for _, e := range events {
+				got = append(got, fmt.Sprintf("%s:%t", e.Client, e.Leave))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for join/leave batch, got %v", got)
+		}
+	}
+	if strings.Join(got, " ") != "a:false b:false a:true a:false" {
+		t.Fatalf("unexpected events: %v", got)
+	}
+}
+
+func TestJoinLeaveBuffer_Batches(t *testing.T) {
+	client := NewJsonClient("ws://localhost", Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test", SubscriptionConfig{JoinLeaveWindow: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batches := make(chan []JoinLeaveEvent, 4)
+	sub.OnJoinLeave(func(events []JoinLeaveEvent) {
+		batches <- events
+	})
+	for i := 0; i < 100; i++ {
+		sub.joinLeaveBuffer.add(JoinLeaveEvent{ClientInfo: ClientInfo{Client: fmt.Sprint(i)}})
+	}
+	select {
+	case events := <-batches:
+		if len(events) != 100 || events[0].Client != "0" || events[99].Client != "99" {
+			t.Fatalf("unexpected batch of %d events", len(events))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for batch")
+	}
+}
@@ -0,0 +1,125 @@
+// Package jwtutil allows generating connection and subscription JWTs for Centrifugo
+// and Centrifuge based servers. It's useful for development, tests and examples –
+// in real applications tokens must be generated on a backend side since client
+// should never know the secret key.
+package jwtutil
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Signer signs JWT header and payload.
+type Signer interface {
+	// Alg returns JWT alg header value.
+	Alg() string
+	// Sign returns signature of data.
+	Sign(data []byte) ([]byte, error)
+}
+
+type hmacSigner struct {
+	secret []byte
+}
+
+// NewHMACSigner returns Signer which uses HMAC SHA-256 (HS256).
+func NewHMACSigner(secret []byte) Signer {
+	return &hmacSigner{secret: secret}
+}
+
+func (s *hmacSigner) Alg() string {
+	return "HS256"
+}
+
+func (s *hmacSigner) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+type rsaSigner struct {
+	key *rsa.PrivateKey
+}
+
+// NewRSASigner returns Signer which uses RSA PKCS #1 v1.5 with SHA-256 (RS256).
+func NewRSASigner(key *rsa.PrivateKey) Signer {
+	return &rsaSigner{key: key}
+}
+
+func (s *rsaSigner) Alg() string {
+	return "RS256"
+}
+
+func (s *rsaSigner) Sign(data []byte) ([]byte, error) {
+	hash := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+}
+
+// ConnectionClaims are claims of connection JWT.
+type ConnectionClaims struct {
+	// Subject is an ID of user. Empty for anonymous connection.
+	Subject string `json:"sub"`
+	// ExpiresAt is a Unix time in seconds when token expires. Zero value means
+	// token never expires.
+	ExpiresAt int64 `json:"exp,omitempty"`
+	// Channels to subscribe connection to on server side.
+	Channels []string `json:"channels,omitempty"`
+	// Info is an additional information about connection, must be valid JSON.
+	Info json.RawMessage `json:"info,omitempty"`
+	// Base64Info is an additional information about connection in base64 format.
+	// Useful for binary info in Protobuf protocol case.
+	Base64Info string `json:"b64info,omitempty"`
+}
+
+// SubscriptionClaims are claims of subscription JWT.
+type SubscriptionClaims struct {
+	// Subject is an ID of user. Must match connection user ID.
+	Subject string `json:"sub"`
+	// Channel to subscribe.
+	Channel string `json:"channel"`
+	// ExpiresAt is a Unix time in seconds when token expires. Zero value means
+	// token never expires.
+	ExpiresAt int64 `json:"exp,omitempty"`
+	// Info is an additional information about connection in the channel, must be
+	// valid JSON.
+	Info json.RawMessage `json:"info,omitempty"`
+	// Base64Info is an additional information about connection in the channel in
+	// base64 format.
+	Base64Info string `json:"b64info,omitempty"`
+}
+
+// ConnectionToken generates connection JWT.
+func ConnectionToken(signer Signer, claims ConnectionClaims) (string, error) {
+	return Sign(signer, claims)
+}
+
+// SubscriptionToken generates subscription JWT.
+func SubscriptionToken(signer Signer, claims SubscriptionClaims) (string, error) {
+	if claims.Channel == "" {
+		return "", errors.New("channel required")
+	}
+	return Sign(signer, claims)
+}
+
+// Sign generates JWT with arbitrary claims which will be encoded to JSON.
+func Sign(signer Signer, claims any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": signer.Alg(), "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := signer.Sign([]byte(unsigned))
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,90 @@
+package jwtutil
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func splitToken(t *testing.T, token string) (string, map[string]any, []byte) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed token: %s", token)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parts[0] + "." + parts[1], claims, signature
+}
+
+func TestConnectionToken_HMAC(t *testing.T) {
+	secret := []byte("secret")
+	token, err := ConnectionToken(NewHMACSigner(secret), ConnectionClaims{
+		Subject:    "42",
+		ExpiresAt:  1700000000,
+		Channels:   []string{"news"},
+		Base64Info: "e30=",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, claims, signature := splitToken(t, token)
+	if claims["sub"] != "42" || claims["exp"] != float64(1700000000) || claims["b64info"] != "e30=" {
+		t.Fatalf("unexpected claims: %v", claims)
+	}
+	if channels, ok := claims["channels"].([]any); !ok || len(channels) != 1 || channels[0] != "news" {
+		t.Fatalf("unexpected channels: %v", claims["channels"])
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		t.Fatal("wrong signature")
+	}
+}
+
+func TestSubscriptionToken_RSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := SubscriptionToken(NewRSASigner(key), SubscriptionClaims{
+		Subject: "42",
+		Channel: "$private",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, claims, signature := splitToken(t, token)
+	if claims["channel"] != "$private" {
+		t.Fatalf("unexpected claims: %v", claims)
+	}
+	if _, ok := claims["exp"]; ok {
+		t.Fatal("exp must be omitted when not set")
+	}
+	hash := sha256.Sum256([]byte(unsigned))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+		t.Fatalf("wrong signature: %v", err)
+	}
+}
+
+func TestSubscriptionToken_NoChannel(t *testing.T) {
+	_, err := SubscriptionToken(NewHMACSigner([]byte("secret")), SubscriptionClaims{Subject: "42"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -1,5 +1,11 @@
 package centrifuge
 
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
 // LogHandler handles log entries - i.e. writes into correct destination if necessary.
 type LogHandler func(LogEntry)
 
@@ -37,3 +43,28 @@ type LogEntry struct {
 	Message string
 	Fields  map[string]string
 }
+
+// SlogLevelTrace is a slog level used for LogLevelTrace entries when Config.Logger
+// set. LogLevelDebug entries are logged with slog.LevelDebug.
+const SlogLevelTrace = slog.LevelDebug - 4
+
+func (l LogLevel) slogLevel() slog.Level {
+	if l == LogLevelTrace {
+		return SlogLevelTrace
+	}
+	return slog.LevelDebug
+}
+
+// logAttrs converts log entry fields to slog attributes sorted by key.
+func logAttrs(fields map[string]string) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for k, v := range fields {
+		attrs = append(attrs, slog.String(k, v))
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
+
+func (c *Client) logSlog(level LogLevel, message string, fields map[string]string) {
+	c.config.Logger.LogAttrs(context.Background(), level.slogLevel(), message, logAttrs(fields)...)
+}
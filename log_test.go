@@ -0,0 +1,24 @@
+package centrifuge
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestClient_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{Logger: logger})
+	defer client.Close()
+
+	if !client.logLevelEnabled(LogLevelDebug) || client.logLevelEnabled(LogLevelTrace) {
+		t.Fatal("log levels must be decided by logger")
+	}
+	client.log(LogLevelDebug, "test message", map[string]string{"b": "2", "a": "1"})
+	out := buf.String()
+	if !strings.Contains(out, `level=DEBUG msg="test message" a=1 b=2`) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
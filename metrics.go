@@ -0,0 +1,53 @@
+package centrifuge
+
+import "time"
+
+// Metrics collects Client metrics. Methods are called synchronously from Client
+// internals, so implementation must be fast and safe for concurrent use. Package
+// metrics provides implementation with Prometheus text exposition format. Set it
+// over Config.Metrics.
+type Metrics interface {
+	// IncConnects is called when client successfully connected.
+	IncConnects()
+	// IncDisconnects is called when client lost connection or moved to disconnected
+	// state, code is the one passed to ConnectingEvent or DisconnectedEvent.
+	IncDisconnects(code uint32)
+	// IncReconnectAttempts is called when reconnect attempt scheduled.
+	IncReconnectAttempts()
+	// ObservePublishDuration is called with publish round trip time.
+	ObservePublishDuration(d time.Duration)
+	// ObserveRPCDuration is called with RPC round trip time.
+	ObserveRPCDuration(method string, d time.Duration)
+	// AddBytesSent is called with the number of bytes written to connection.
+	AddBytesSent(n int)
+	// AddBytesReceived is called with the number of bytes read from connection.
+	AddBytesReceived(n int)
+	// IncPublications is called when publication received in channel.
+	IncPublications(channel string)
+	// SetCallbackQueueDepth is called with the number of event handlers waiting
+	// for execution.
+	SetCallbackQueueDepth(n int)
+	// ObserveCallbackDelay is called with the time event handler waited in queue
+	// before execution.
+	ObserveCallbackDelay(d time.Duration)
+	// ObserveCallbackDuration is called with event handler execution time.
+	ObserveCallbackDuration(d time.Duration)
+	// SetPresence is called with the number of clients and unique users present
+	// in channel watched by PresenceWatcher.
+	SetPresence(channel string, numClients int, numUsers int)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) IncConnects()                             {}
+func (noopMetrics) IncDisconnects(uint32)                    {}
+func (noopMetrics) IncReconnectAttempts()                    {}
+func (noopMetrics) ObservePublishDuration(time.Duration)     {}
+func (noopMetrics) ObserveRPCDuration(string, time.Duration) {}
+func (noopMetrics) AddBytesSent(int)                         {}
+func (noopMetrics) AddBytesReceived(int)                     {}
+func (noopMetrics) IncPublications(string)                   {}
+func (noopMetrics) SetCallbackQueueDepth(int)                {}
+func (noopMetrics) ObserveCallbackDelay(time.Duration)       {}
+func (noopMetrics) ObserveCallbackDuration(time.Duration)    {}
+func (noopMetrics) SetPresence(string, int, int)             {}
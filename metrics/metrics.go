@@ -0,0 +1,321 @@
+// Package metrics provides Registry which collects centrifuge-go Client metrics
+// and exposes them in Prometheus text exposition format. Registry implements
+// centrifuge.Metrics interface – set it over centrifuge.Config.Metrics. One
+// Registry may be shared by several clients, metrics are aggregated then.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are default latency histogram buckets in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Config of Registry.
+type Config struct {
+	// Namespace is a prefix of metric names.
+	// Zero value means "centrifuge_client".
+	Namespace string
+	// Buckets are latency histogram buckets in seconds.
+	// Zero value means DefaultBuckets.
+	Buckets []float64
+}
+
+// Registry collects client metrics.
+type Registry struct {
+	namespace string
+	buckets   []float64
+
+	connects          atomic.Uint64
+	reconnectAttempts atomic.Uint64
+	bytesSent         atomic.Uint64
+	bytesReceived     atomic.Uint64
+	queueDepth        atomic.Int64
+	publishDuration   *histogram
+	callbackDelay     *histogram
+	callbackDuration  *histogram
+
+	mu           sync.Mutex
+	disconnects  map[uint32]uint64
+	publications map[string]uint64
+	rpcDuration  map[string]*histogram
+	presence     map[string]presenceCounts
+}
+
+type presenceCounts struct {
+	clients int
+	users   int
+}
+
+// NewRegistry creates Registry.
+func NewRegistry(config Config) *Registry {
+	if config.Namespace == "" {
+		config.Namespace = "centrifuge_client"
+	}
+	if len(config.Buckets) == 0 {
+		config.Buckets = DefaultBuckets
+	}
+	buckets := make([]float64, len(config.Buckets))
+	copy(buckets, config.Buckets)
+	sort.Float64s(buckets)
+	return &Registry{
+		namespace:        config.Namespace,
+		buckets:          buckets,
+		publishDuration:  newHistogram(buckets),
+		callbackDelay:    newHistogram(buckets),
+		callbackDuration: newHistogram(buckets),
+		disconnects:      make(map[uint32]uint64),
+		publications:     make(map[string]uint64),
+		rpcDuration:      make(map[string]*histogram),
+		presence:         make(map[string]presenceCounts),
+	}
+}
+
+// IncConnects increments the number of successful connects.
+func (r *Registry) IncConnects() {
+	r.connects.Add(1)
+}
+
+// IncDisconnects increments the number of disconnects with code.
+func (r *Registry) IncDisconnects(code uint32) {
+	r.mu.Lock()
+	r.disconnects[code]++
+	r.mu.Unlock()
+}
+
+// IncReconnectAttempts increments the number of scheduled reconnect attempts.
+func (r *Registry) IncReconnectAttempts() {
+	r.reconnectAttempts.Add(1)
+}
+
+// ObservePublishDuration observes publish round trip time.
+func (r *Registry) ObservePublishDuration(d time.Duration) {
+	r.publishDuration.observe(d.Seconds())
+}
+
+// ObserveRPCDuration observes RPC round trip time.
+func (r *Registry) ObserveRPCDuration(method string, d time.Duration) {
+	r.mu.Lock()
+	h, ok := r.rpcDuration[method]
+	if !ok {
+		h = newHistogram(r.buckets)
+		r.rpcDuration[method] = h
+	}
+	r.mu.Unlock()
+	h.observe(d.Seconds())
+}
+
+// AddBytesSent adds the number of bytes written to connection.
+func (r *Registry) AddBytesSent(n int) {
+	r.bytesSent.Add(uint64(n))
+}
+
+// AddBytesReceived adds the number of bytes read from connection.
+func (r *Registry) AddBytesReceived(n int) {
+	r.bytesReceived.Add(uint64(n))
+}
+
+// IncPublications increments the number of publications received in channel.
+func (r *Registry) IncPublications(channel string) {
+	r.mu.Lock()
+	r.publications[channel]++
+	r.mu.Unlock()
+}
+
+// SetCallbackQueueDepth sets the number of event handlers waiting for execution.
+func (r *Registry) SetCallbackQueueDepth(n int) {
+	r.queueDepth.Store(int64(n))
+}
+
+// ObserveCallbackDelay observes the time event handler waited in queue.
+func (r *Registry) ObserveCallbackDelay(d time.Duration) {
+	r.callbackDelay.observe(d.Seconds())
+}
+
+// ObserveCallbackDuration observes event handler execution time.
+func (r *Registry) ObserveCallbackDuration(d time.Duration) {
+	r.callbackDuration.observe(d.Seconds())
+}
+
+// SetPresence sets the number of clients and unique users present in channel.
+func (r *Registry) SetPresence(channel string, numClients int, numUsers int) {
+	r.mu.Lock()
+	r.presence[channel] = presenceCounts{clients: numClients, users: numUsers}
+	r.mu.Unlock()
+}
+
+// Handler returns http.Handler which serves metrics in Prometheus text exposition
+// format, so it can be scraped by Prometheus directly.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// WriteTo writes metrics in Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	r.writeHeader(bw, "connects_total", "counter", "Number of successful connects.")
+	r.writeSample(bw, "connects_total", "", float64(r.connects.Load()))
+
+	r.mu.Lock()
+	disconnects := make([]uint32, 0, len(r.disconnects))
+	for code := range r.disconnects {
+		disconnects = append(disconnects, code)
+	}
+	sort.Slice(disconnects, func(i, j int) bool { return disconnects[i] < disconnects[j] })
+	r.writeHeader(bw, "disconnects_total", "counter", "Number of disconnects by code.")
+	for _, code := range disconnects {
+		r.writeSample(bw, "disconnects_total", labels("code", strconv.FormatUint(uint64(code), 10)), float64(r.disconnects[code]))
+	}
+	channels := sortedKeys(r.publications)
+	r.writeHeader(bw, "publications_total", "counter", "Number of publications received by channel.")
+	for _, ch := range channels {
+		r.writeSample(bw, "publications_total", labels("channel", ch), float64(r.publications[ch]))
+	}
+	presenceChannels := sortedKeys(r.presence)
+	r.writeHeader(bw, "presence_clients", "gauge", "Number of clients present in watched channel.")
+	for _, ch := range presenceChannels {
+		r.writeSample(bw, "presence_clients", labels("channel", ch), float64(r.presence[ch].clients))
+	}
+	r.writeHeader(bw, "presence_users", "gauge", "Number of unique users present in watched channel.")
+	for _, ch := range presenceChannels {
+		r.writeSample(bw, "presence_users", labels("channel", ch), float64(r.presence[ch].users))
+	}
+	methods := sortedKeys(r.rpcDuration)
+	rpcDuration := make([]*histogram, 0, len(methods))
+	for _, method := range methods {
+		rpcDuration = append(rpcDuration, r.rpcDuration[method])
+	}
+	r.mu.Unlock()
+
+	r.writeHeader(bw, "reconnect_attempts_total", "counter", "Number of reconnect attempts.")
+	r.writeSample(bw, "reconnect_attempts_total", "", float64(r.reconnectAttempts.Load()))
+	r.writeHeader(bw, "bytes_sent_total", "counter", "Number of bytes written to connection.")
+	r.writeSample(bw, "bytes_sent_total", "", float64(r.bytesSent.Load()))
+	r.writeHeader(bw, "bytes_received_total", "counter", "Number of bytes read from connection.")
+	r.writeSample(bw, "bytes_received_total", "", float64(r.bytesReceived.Load()))
+	r.writeHeader(bw, "callback_queue_depth", "gauge", "Number of event handlers waiting for execution.")
+	r.writeSample(bw, "callback_queue_depth", "", float64(r.queueDepth.Load()))
+
+	r.writeHeader(bw, "publish_duration_seconds", "histogram", "Publish round trip time.")
+	r.writeHistogram(bw, "publish_duration_seconds", "", r.publishDuration)
+	r.writeHeader(bw, "rpc_duration_seconds", "histogram", "RPC round trip time by method.")
+	for i, method := range methods {
+		r.writeHistogram(bw, "rpc_duration_seconds", "method=\""+escapeLabel(method)+"\",", rpcDuration[i])
+	}
+
+	r.writeHeader(bw, "callback_delay_seconds", "histogram", "Time event handlers waited in queue.")
+	r.writeHistogram(bw, "callback_delay_seconds", "", r.callbackDelay)
+	r.writeHeader(bw, "callback_duration_seconds", "histogram", "Event handler execution time.")
+	r.writeHistogram(bw, "callback_duration_seconds", "", r.callbackDuration)
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func (r *Registry) writeHeader(w *bufio.Writer, name string, typ string, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", r.namespace, name, help, r.namespace, name, typ)
+}
+
+func (r *Registry) writeSample(w *bufio.Writer, name string, labels string, value float64) {
+	_, _ = fmt.Fprintf(w, "%s_%s%s %s\n", r.namespace, name, labels, formatFloat(value))
+}
+
+// writeHistogram writes histogram samples, labelPrefix must be empty or end with comma.
+func (r *Registry) writeHistogram(w *bufio.Writer, name string, labelPrefix string, h *histogram) {
+	counts, count, sum := h.snapshot()
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += counts[i]
+		r.writeSample(w, name+"_bucket", "{"+labelPrefix+"le=\""+formatFloat(bound)+"\"}", float64(cumulative))
+	}
+	r.writeSample(w, name+"_bucket", "{"+labelPrefix+"le=\"+Inf\"}", float64(count))
+	labels := ""
+	if labelPrefix != "" {
+		labels = "{" + strings.TrimSuffix(labelPrefix, ",") + "}"
+	}
+	r.writeSample(w, name+"_sum", labels, sum)
+	r.writeSample(w, name+"_count", labels, float64(count))
+}
+
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+func (h *histogram) snapshot() ([]uint64, uint64, float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	return counts, h.count, h.sum
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func labels(name string, value string) string {
+	return "{" + name + "=\"" + escapeLabel(value) + "\"}"
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(value string) string {
+	return labelReplacer.Replace(value)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
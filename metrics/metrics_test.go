@@ -0,0 +1,60 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge-go"
+	"github.com/centrifugal/centrifuge-go/metrics"
+)
+
+var _ centrifuge.Metrics = (*metrics.Registry)(nil)
+
+func TestRegistry(t *testing.T) {
+	r := metrics.NewRegistry(metrics.Config{Buckets: []float64{0.1, 1}})
+	r.IncConnects()
+	r.IncDisconnects(3001)
+	r.IncDisconnects(3001)
+	r.IncReconnectAttempts()
+	r.AddBytesSent(10)
+	r.AddBytesReceived(20)
+	r.IncPublications(`chat"1`)
+	r.SetCallbackQueueDepth(3)
+	r.ObservePublishDuration(50 * time.Millisecond)
+	r.ObservePublishDuration(2 * time.Second)
+	r.ObserveRPCDuration("getUser", 500*time.Millisecond)
+	r.ObserveCallbackDelay(time.Millisecond)
+	r.ObserveCallbackDuration(3 * time.Second)
+	r.SetPresence("chat", 3, 2)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+	for _, expected := range []string{
+		"centrifuge_client_connects_total 1\n",
+		`centrifuge_client_disconnects_total{code="3001"} 2` + "\n",
+		"centrifuge_client_reconnect_attempts_total 1\n",
+		"centrifuge_client_bytes_sent_total 10\n",
+		"centrifuge_client_bytes_received_total 20\n",
+		`centrifuge_client_publications_total{channel="chat\"1"} 1` + "\n",
+		"centrifuge_client_callback_queue_depth 3\n",
+		`centrifuge_client_publish_duration_seconds_bucket{le="0.1"} 1` + "\n",
+		`centrifuge_client_publish_duration_seconds_bucket{le="1"} 1` + "\n",
+		`centrifuge_client_publish_duration_seconds_bucket{le="+Inf"} 2` + "\n",
+		"centrifuge_client_publish_duration_seconds_count 2\n",
+		`centrifuge_client_rpc_duration_seconds_bucket{method="getUser",le="1"} 1` + "\n",
+		`centrifuge_client_rpc_duration_seconds_count{method="getUser"} 1` + "\n",
+		"# TYPE centrifuge_client_publish_duration_seconds histogram\n",
+		`centrifuge_client_callback_delay_seconds_bucket{le="0.1"} 1` + "\n",
+		`centrifuge_client_callback_duration_seconds_bucket{le="1"} 0` + "\n",
+		"centrifuge_client_callback_duration_seconds_count 1\n",
+		`centrifuge_client_presence_clients{channel="chat"} 3` + "\n",
+		`centrifuge_client_presence_users{channel="chat"} 2` + "\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %q in output:\n%s", expected, out)
+		}
+	}
+}
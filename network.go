@@ -0,0 +1,40 @@
+package centrifuge
+
+// NetworkMonitor allows platform-specific code (netlink, SCNetworkReachability,
+// Windows NLM, etc.) to notify Client about network changes. Set it over
+// Config.NetworkMonitor.
+type NetworkMonitor interface {
+	// Watch starts watching network changes and calls notify on every change.
+	// Client calls returned stop function when closed.
+	Watch(notify func()) (stop func())
+}
+
+// NotifyNetworkChange tells client that network configuration changed (for example,
+// routes or active interface changed). If client is waiting for the next reconnect
+// attempt then reconnect starts immediately, bypassing the remaining backoff delay,
+// and reconnect attempts counter is reset. Otherwise this is a no-op.
+func (c *Client) NotifyNetworkChange() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != StateConnecting || !c.reconnectPending {
+		return
+	}
+	if c.logLevelEnabled(LogLevelDebug) {
+		c.log(LogLevelDebug, "network changed, reconnect immediately", nil)
+	}
+	c.reconnectAttempts = 0
+	c.reconnectGen++
+	c.reconnectDelay = 0
+	c.signalReconnectLoop()
+}
+
+// watchNetwork subscribes client to Config.NetworkMonitor until client closed.
+func (c *Client) watchNetwork(monitor NetworkMonitor) {
+	stop := monitor.Watch(c.NotifyNetworkChange)
+	c.spawn("network_monitor", func() {
+		<-c.closedCh
+		if stop != nil {
+			stop()
+		}
+	})
+}
@@ -0,0 +1,363 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy defines what happens when publication is added to full offline
+// queue. See OfflineQueueConfig.
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest means the oldest queued publication is dropped to free
+	// space, its callback gets ErrPublishDropped.
+	OverflowDropOldest OverflowPolicy = ""
+	// OverflowRejectNew means new publication is rejected with ErrOfflineQueueFull.
+	OverflowRejectNew OverflowPolicy = "reject_new"
+)
+
+// OfflineQueueConfig configures buffering of publications while client is not
+// connected. Set it over Config.OfflineQueue.
+type OfflineQueueConfig struct {
+	// MaxSize is the maximum number of queued publications.
+	// Zero value means 1000.
+	MaxSize int
+	// MaxAge is the maximum time publication may wait in queue, after that it's
+	// dropped with ErrPublishExpired.
+	// Zero value means publications do not expire.
+	MaxAge time.Duration
+	// OverflowPolicy defines what happens when queue is full.
+	// Zero value means OverflowDropOldest.
+	OverflowPolicy OverflowPolicy
+	// Spool persists queued publications, so they survive process restarts.
+	// Publications restored from Spool upon client creation are sent without
+	// callbacks. Publications may be sent twice if process stopped after sending
+	// but before acknowledging in Spool. See FileSpool.
+	// Zero value means queue is kept in memory only.
+	Spool Spool
+}
+
+const defaultOfflineQueueSize = 1000
+
+var (
+	// ErrOfflineQueueFull returned when publication rejected since offline queue is
+	// full. See OverflowRejectNew.
+	ErrOfflineQueueFull = errors.New("offline queue full")
+	// ErrPublishDropped returned when queued publication dropped to free space in
+	// offline queue. See OverflowDropOldest.
+	ErrPublishDropped = errors.New("publication dropped from offline queue")
+	// ErrPublishExpired returned when queued publication was not delivered within
+	// OfflineQueueConfig.MaxAge.
+	ErrPublishExpired = errors.New("queued publication expired")
+	// ErrOfflineQueueDisabled returned by Client.EnqueuePublish if
+	// Config.OfflineQueue is not set.
+	ErrOfflineQueueDisabled = errors.New("offline queue disabled")
+)
+
+type queuedPublication struct {
+	ctx       context.Context
+	channel   string
+	data      []byte
+	priority  Priority
+	fn        func(PublishResult, error)
+	expiresAt time.Time
+	spoolID   uint64
+	spooled   bool
+	// inFlight is set while publication is being sent, it can't be dropped then.
+	inFlight bool
+}
+
+// offlineQueue buffers publications while client is not connected and sends them
+// one by one in order after connect. Publication is removed from queue only after
+// server replied, so publications interrupted by disconnect are sent again upon
+// next connect.
+type offlineQueue struct {
+	config OfflineQueueConfig
+
+	mu    sync.Mutex
+	items []*queuedPublication
+	// flushing is true while flush goroutine is running.
+	flushing bool
+	// kick asks flush goroutine to make one more pass before exiting, set upon
+	// connect.
+	kick   bool
+	closed bool
+}
+
+func newOfflineQueue(config OfflineQueueConfig) *offlineQueue {
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultOfflineQueueSize
+	}
+	return &offlineQueue{config: config}
+}
+
+// publishCallback is a publication callback to call outside queue lock.
+type publishCallback struct {
+	fn  func(PublishResult, error)
+	err error
+}
+
+func runPublishCallbacks(callbacks []publishCallback) {
+	for _, cb := range callbacks {
+		cb.fn(PublishResult{}, cb.err)
+	}
+}
+
+// restore loads publications from spool. If there are more publications than
+// queue may keep then the oldest ones are dropped.
+func (q *offlineQueue) restore() error {
+	if q.config.Spool == nil {
+		return nil
+	}
+	records, err := q.config.Spool.Load()
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(records) > q.config.MaxSize {
+		_ = q.config.Spool.Ack(records[0].ID)
+		records = records[1:]
+	}
+	for _, record := range records {
+		// Spool does not keep priority, restored publications are sent with
+		// PriorityNormal.
+		item := &queuedPublication{
+			ctx:      context.Background(),
+			channel:  record.Channel,
+			data:     record.Data,
+			priority: PriorityNormal,
+			fn:       func(PublishResult, error) {},
+			spoolID:  record.ID,
+			spooled:  true,
+		}
+		if q.config.MaxAge > 0 {
+			item.expiresAt = record.Time.Add(q.config.MaxAge)
+		}
+		q.items = append(q.items, item)
+	}
+	return nil
+}
+
+// ackLocked removes publication from spool after it's removed from queue. Ack
+// errors are ignored – publication is sent again after restart then.
+// Lock must be held outside.
+func (q *offlineQueue) ackLocked(item *queuedPublication) {
+	if item.spooled {
+		_ = q.config.Spool.Ack(item.spoolID)
+	}
+}
+
+// dropExpiredLocked removes expired and canceled publications which are not
+// being sent at the moment.
+// Lock must be held outside.
+func (q *offlineQueue) dropExpiredLocked(now time.Time, callbacks []publishCallback) []publishCallback {
+	items := q.items[:0]
+	for _, item := range q.items {
+		if !item.inFlight {
+			if !item.expiresAt.IsZero() && now.After(item.expiresAt) {
+				q.ackLocked(item)
+				callbacks = append(callbacks, publishCallback{item.fn, ErrPublishExpired})
+				continue
+			}
+			if err := item.ctx.Err(); err != nil {
+				q.ackLocked(item)
+				callbacks = append(callbacks, publishCallback{item.fn, err})
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	clear(q.items[len(items):])
+	q.items = items
+	return callbacks
+}
+
+// push adds publication to queue. Returns false if queue is not used and
+// publication must be sent directly.
+func (q *offlineQueue) push(ctx context.Context, connected bool, channel string, data []byte, priority Priority, fn func(PublishResult, error)) bool {
+	var callbacks []publishCallback
+	defer func() { runPublishCallbacks(callbacks) }()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		callbacks = append(callbacks, publishCallback{fn, ErrClientClosed})
+		return true
+	}
+	if connected && len(q.items) == 0 {
+		return false
+	}
+	now := time.Now()
+	callbacks = q.dropExpiredLocked(now, callbacks)
+	if len(q.items) >= q.config.MaxSize {
+		if q.config.OverflowPolicy == OverflowRejectNew {
+			callbacks = append(callbacks, publishCallback{fn, ErrOfflineQueueFull})
+			return true
+		}
+		for i, item := range q.items {
+			if item.inFlight {
+				continue
+			}
+			q.ackLocked(item)
+			callbacks = append(callbacks, publishCallback{item.fn, ErrPublishDropped})
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			break
+		}
+	}
+	item := &queuedPublication{ctx: ctx, channel: channel, data: data, priority: priority, fn: fn}
+	if q.config.MaxAge > 0 {
+		item.expiresAt = now.Add(q.config.MaxAge)
+	}
+	if q.config.Spool != nil {
+		id, err := q.config.Spool.Append(channel, data)
+		if err != nil {
+			callbacks = append(callbacks, publishCallback{fn, SpoolError{err}})
+			return true
+		}
+		item.spoolID = id
+		item.spooled = true
+	}
+	q.items = append(q.items, item)
+	return true
+}
+
+// close fails all queued publications with ErrClientClosed. Publications are kept
+// in spool to be sent after restart.
+func (q *offlineQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	callbacks := make([]publishCallback, 0, len(q.items))
+	for _, item := range q.items {
+		if !item.inFlight {
+			callbacks = append(callbacks, publishCallback{item.fn, ErrClientClosed})
+		}
+	}
+	q.items = nil
+	q.mu.Unlock()
+	runPublishCallbacks(callbacks)
+}
+
+func (q *offlineQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// isFinalPublishError tells whether publication must be removed from offline queue
+// after error. Server errors (except temporary ones) are final, transport errors
+// and timeouts are not – publication is sent again upon next connect.
+func isFinalPublishError(err error) bool {
+	var serverErr *Error
+	if errors.As(err, &serverErr) {
+		return !serverErr.Temporary
+	}
+	return false
+}
+
+// flushOfflineQueue starts sending queued publications if not started yet.
+func (c *Client) flushOfflineQueue() {
+	q := c.offlineQueue
+	q.mu.Lock()
+	q.kick = true
+	if q.flushing {
+		q.mu.Unlock()
+		return
+	}
+	q.flushing = true
+	q.mu.Unlock()
+	c.spawn("offline_queue_flush", c.runOfflineQueueFlush)
+}
+
+func (c *Client) runOfflineQueueFlush() {
+	q := c.offlineQueue
+	for {
+		q.mu.Lock()
+		q.kick = false
+		callbacks := q.dropExpiredLocked(time.Now(), nil)
+		var item *queuedPublication
+		if len(q.items) > 0 && !q.closed {
+			item = q.items[0]
+			item.inFlight = true
+		}
+		q.mu.Unlock()
+		runPublishCallbacks(callbacks)
+
+		var err error
+		if item != nil && c.isConnected() {
+			err = c.sendQueuedPublication(item)
+			if err == nil || isFinalPublishError(err) {
+				q.mu.Lock()
+				if len(q.items) > 0 && q.items[0] == item {
+					q.items[0] = nil
+					q.items = q.items[1:]
+				}
+				q.ackLocked(item)
+				q.mu.Unlock()
+				item.fn(PublishResult{}, err)
+				continue
+			}
+		}
+
+		q.mu.Lock()
+		closed := q.closed
+		if item != nil {
+			item.inFlight = false
+		}
+		if q.kick && !closed {
+			// Client connected again while sending, try one more time.
+			q.mu.Unlock()
+			continue
+		}
+		q.flushing = false
+		q.mu.Unlock()
+		if item != nil && closed {
+			// Queue was closed while sending, item was not failed by close.
+			item.fn(PublishResult{}, ErrClientClosed)
+		}
+		if err != nil && c.logLevelEnabled(LogLevelDebug) {
+			c.log(LogLevelDebug, "offline queue flush interrupted", map[string]string{"error": err.Error()})
+		}
+		return
+	}
+}
+
+// sendQueuedPublication sends publication from offline queue and waits for reply.
+// Publication waits for Config.CommandRateLimit instead of failing.
+func (c *Client) sendQueuedPublication(item *queuedPublication) error {
+	for {
+		if c.rateLimiter != nil && !c.waitRateLimit() {
+			return ErrClientClosed
+		}
+		errCh := make(chan error, 1)
+		c.sendPublish(item.channel, item.data, item.priority, func(_ PublishResult, err error) {
+			errCh <- err
+		})
+		err := <-errCh
+		if !errors.Is(err, ErrRateLimited) {
+			return err
+		}
+		// Concurrent publish took a token, wait again.
+	}
+}
+
+// EnqueuePublish publishes data into channel over offline queue without waiting
+// for result: if client is not connected then publication is buffered and sent
+// after connect. Publications are sent in order. The fn is called once
+// publication delivered or failed – dropped from full queue, expired or rejected
+// by server. Returns ErrOfflineQueueDisabled if Config.OfflineQueue not set.
+func (c *Client) EnqueuePublish(channel string, data []byte, fn func(PublishResult, error)) error {
+	if c.offlineQueue == nil {
+		return ErrOfflineQueueDisabled
+	}
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+	if fn == nil {
+		fn = func(PublishResult, error) {}
+	}
+	c.publish(context.Background(), channel, data, PriorityNormal, fn)
+	return nil
+}
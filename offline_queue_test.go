@@ -0,0 +1,156 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+func TestClient_OfflineQueue(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{OfflineQueue: &OfflineQueueConfig{}})
+	defer client.Close()
+
+	results := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		err := client.EnqueuePublish("test", []byte(`{}`), func(_ PublishResult, err error) {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results <- i
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := client.Stats().QueuedPublications; n != 3 {
+		t.Fatalf("expected 3 queued publications, got %d", n)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case n := <-results:
+			if n != i {
+				t.Fatalf("expected publication %d, got %d", i, n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for delivery")
+		}
+	}
+	if n := client.Stats().QueuedPublications; n != 0 {
+		t.Fatalf("expected empty queue, got %d", n)
+	}
+}
+
+func TestClient_OfflineQueueOverflow(t *testing.T) {
+	testCases := []struct {
+		policy   OverflowPolicy
+		failed   int
+		expected error
+	}{
+		{OverflowDropOldest, 0, ErrPublishDropped},
+		{OverflowRejectNew, 2, ErrOfflineQueueFull},
+	}
+	for _, tc := range testCases {
+		client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+			OfflineQueue: &OfflineQueueConfig{MaxSize: 2, OverflowPolicy: tc.policy},
+		})
+		errs := make([]error, 3)
+		for i := 0; i < 3; i++ {
+			if err := client.EnqueuePublish("test", nil, func(_ PublishResult, err error) {
+				errs[i] = err
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !errors.Is(errs[tc.failed], tc.expected) {
+			t.Fatalf("policy %q: expected %v, got %v", tc.policy, tc.expected, errs[tc.failed])
+		}
+		client.Close()
+		for i, err := range errs {
+			if i != tc.failed && !errors.Is(err, ErrClientClosed) {
+				t.Fatalf("policy %q: expected ErrClientClosed, got %v", tc.policy, err)
+			}
+		}
+	}
+}
+
+func TestClient_OfflineQueueExpired(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+		OfflineQueue: &OfflineQueueConfig{MaxAge: time.Millisecond},
+	})
+	defer client.Close()
+	var expiredErr error
+	if err := client.EnqueuePublish("test", nil, func(_ PublishResult, err error) {
+		expiredErr = err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := client.EnqueuePublish("test", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(expiredErr, ErrPublishExpired) {
+		t.Fatalf("expected ErrPublishExpired, got %v", expiredErr)
+	}
+}
+
+// priorityTransport records priorities of written commands.
+type priorityTransport struct {
+	closed     chan struct{}
+	priorities chan Priority
+}
+
+func (t *priorityTransport) Read() (*protocol.Reply, *disconnect, error) {
+	<-t.closed
+	return nil, nil, io.EOF
+}
+
+func (t *priorityTransport) Write(cmd *protocol.Command, timeout time.Duration) error {
+	return t.WritePriority(cmd, PriorityNormal, timeout)
+}
+
+func (t *priorityTransport) WritePriority(_ *protocol.Command, priority Priority, _ time.Duration) error {
+	t.priorities <- priority
+	return nil
+}
+
+func (t *priorityTransport) WriteMany(_ []*protocol.Command, _ time.Duration) error {
+	return nil
+}
+
+func (t *priorityTransport) Close() error {
+	return nil
+}
+
+func TestClient_OfflineQueuePriority(t *testing.T) {
+	client := NewJsonClient("ws://localhost:9000/connection/websocket", Config{
+		OfflineQueue: &OfflineQueueConfig{},
+	})
+	defer client.Close()
+	client.publish(context.Background(), "test", []byte("{}"), PriorityBulk, func(PublishResult, error) {})
+	client.offlineQueue.mu.Lock()
+	item := client.offlineQueue.items[0]
+	client.offlineQueue.mu.Unlock()
+
+	transport := &priorityTransport{closed: make(chan struct{}), priorities: make(chan Priority, 1)}
+	client.mu.Lock()
+	client.state = StateConnected
+	client.transport = transport
+	client.mu.Unlock()
+	go func() { _ = client.sendQueuedPublication(item) }()
+	select {
+	case priority := <-transport.priorities:
+		if priority != PriorityBulk {
+			t.Fatalf("expected PriorityBulk, got %v", priority)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for queued publication")
+	}
+}
@@ -0,0 +1,54 @@
+package centrifuge
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// recoverCallback recovers panic of user callback, so one misbehaving handler does
+// not take down the whole process. Must be called directly by defer.
+func (c *Client) recoverCallback(callback string) {
+	if v := recover(); v != nil {
+		_ = c.handlePanic(callback, v)
+	}
+}
+
+// callSafe calls user provided function and returns recovered panic as PanicError.
+func callSafe[T any](c *Client, callback string, fn func() (T, error)) (res T, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = c.handlePanic(callback, v)
+		}
+	}()
+	return fn()
+}
+
+// handlePanic reports recovered panic to OnError handler. Handler is called
+// asynchronously since panic may be recovered on event handling goroutine.
+func (c *Client) handlePanic(callback string, v any) error {
+	err := PanicError{Callback: callback, Value: v, Stack: debug.Stack()}
+	if c.logLevelEnabled(LogLevelDebug) {
+		c.log(LogLevelDebug, "panic in user callback", map[string]string{
+			"callback": callback,
+			"panic":    fmt.Sprint(v),
+		})
+	}
+	var handler ErrorHandler
+	if c.events != nil && c.events.onError != nil {
+		handler = c.events.onError
+	}
+	if handler != nil {
+		c.runHandlerAsync(func() {
+			// OnError itself panicked – only log to avoid endless loop.
+			defer func() {
+				if v := recover(); v != nil && c.logLevelEnabled(LogLevelDebug) {
+					c.log(LogLevelDebug, "panic in OnError handler", map[string]string{
+						"panic": fmt.Sprint(v),
+					})
+				}
+			}()
+			handler(ErrorEvent{Error: err})
+		})
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_PanicInHandler(t *testing.T) {
+	client := NewJsonClient(startParityServer(t), Config{})
+	defer client.Close()
+	errCh := make(chan error, 1)
+	client.OnError(func(e ErrorEvent) {
+		errCh <- e.Error
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := client.NewSubscription("ch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.OnPublication(func(e PublicationEvent) {
+		panic("boom")
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errCh:
+		var panicErr PanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("expected PanicError, got %v", err)
+		}
+		if panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+			t.Fatalf("unexpected panic error %#v", panicErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for panic error")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.RPC(ctx, "echo", []byte(`{}`)); err != nil {
+		t.Fatalf("client must keep working after handler panic: %v", err)
+	}
+}
+
+func TestClient_PanicInGetHeaders(t *testing.T) {
+	client := NewJsonClient(startParityServer(t), Config{
+		GetHeaders: func() (http.Header, error) {
+			panic(errors.New("boom"))
+		},
+	})
+	defer client.Close()
+	errCh := make(chan error, 1)
+	client.OnError(func(e ErrorEvent) {
+		select {
+		case errCh <- e.Error:
+		default:
+		}
+	})
+	_ = client.Connect()
+	select {
+	case err := <-errCh:
+		var panicErr PanicError
+		if !errors.As(err, &panicErr) || panicErr.Callback != "GetHeaders" {
+			t.Fatalf("expected PanicError from GetHeaders, got %v", err)
+		}
+		if err.Error() != "panic in GetHeaders: boom" {
+			t.Fatalf("unexpected error text %q", err.Error())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for panic error")
+	}
+}
@@ -0,0 +1,282 @@
+package centrifuge
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// PositionStore persists stream positions of processed publications per channel,
+// so recoverable subscriptions resume from the last processed publication after
+// process restart. Set it over Config.PositionStore.
+type PositionStore interface {
+	// Save stores position of the last processed publication in channel.
+	Save(channel string, sp StreamPosition) error
+	// Load returns stored position of channel, false if there is none.
+	Load(channel string) (StreamPosition, bool, error)
+}
+
+// MemoryPositionStore is a PositionStore which keeps positions in memory. It's
+// useful to resume subscriptions of a new Client within the same process.
+type MemoryPositionStore struct {
+	mu        sync.Mutex
+	positions map[string]StreamPosition
+}
+
+var _ PositionStore = (*MemoryPositionStore)(nil)
+
+// NewMemoryPositionStore creates MemoryPositionStore.
+func NewMemoryPositionStore() *MemoryPositionStore {
+	return &MemoryPositionStore{positions: make(map[string]StreamPosition)}
+}
+
+// Save stores position.
+func (s *MemoryPositionStore) Save(channel string, sp StreamPosition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.positions[channel] = sp
+	return nil
+}
+
+// Load returns stored position.
+func (s *MemoryPositionStore) Load(channel string) (StreamPosition, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sp, ok := s.positions[channel]
+	return sp, ok, nil
+}
+
+// PositionFlusher is implemented by PositionStore which batches saves. Client
+// flushes it when Subscription unsubscribes and when Client is closed.
+type PositionFlusher interface {
+	// Flush persists saved positions.
+	Flush() error
+}
+
+const defaultPositionFlushInterval = 100 * time.Millisecond
+
+// FilePositionStoreConfig configures FilePositionStore.
+type FilePositionStoreConfig struct {
+	// FlushInterval is a maximum time between Save and file rewrite. Saves made
+	// within interval are written together, so file is not rewritten and synced
+	// on every processed publication. Negative value rewrites file on every Save.
+	// Zero value means 100ms.
+	FlushInterval time.Duration
+}
+
+// FilePositionStore is a PositionStore which keeps positions of all channels in
+// JSON file. File is replaced atomically, so it's never left partially written.
+// Saves are batched, see FilePositionStoreConfig.FlushInterval, call Close to
+// write pending positions when store is not used anymore.
+type FilePositionStore struct {
+	mu        sync.Mutex
+	path      string
+	interval  time.Duration
+	positions map[string]StreamPosition
+	dirty     bool
+	timer     *time.Timer
+	// err of background flush, returned by the next Save.
+	err error
+}
+
+var (
+	_ PositionStore   = (*FilePositionStore)(nil)
+	_ PositionFlusher = (*FilePositionStore)(nil)
+)
+
+type filePosition struct {
+	Offset uint64 `json:"offset"`
+	Epoch  string `json:"epoch"`
+}
+
+// NewFilePositionStore opens FilePositionStore at path, the file is created upon
+// first flush.
+func NewFilePositionStore(path string, config ...FilePositionStoreConfig) (*FilePositionStore, error) {
+	s := &FilePositionStore{
+		path:      path,
+		interval:  defaultPositionFlushInterval,
+		positions: make(map[string]StreamPosition),
+	}
+	if len(config) == 1 && config[0].FlushInterval != 0 {
+		s.interval = config[0].FlushInterval
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var positions map[string]filePosition
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, err
+	}
+	for channel, p := range positions {
+		s.positions[channel] = StreamPosition{Offset: p.Offset, Epoch: p.Epoch}
+	}
+	return s, nil
+}
+
+// Save stores position, file is rewritten within FlushInterval. It returns error
+// of previous background flush if any.
+func (s *FilePositionStore) Save(channel string, sp StreamPosition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.positions[channel]; ok && prev == sp {
+		return nil
+	}
+	s.positions[channel] = sp
+	s.dirty = true
+	if s.interval < 0 {
+		return s.flushLocked()
+	}
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.interval, s.flushBackground)
+	}
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// Load returns stored position.
+func (s *FilePositionStore) Load(channel string) (StreamPosition, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sp, ok := s.positions[channel]
+	return sp, ok, nil
+}
+
+// Flush writes pending positions to file.
+func (s *FilePositionStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if err := s.flushLocked(); err != nil {
+		return err
+	}
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// Close writes pending positions to file. Store may still be used after Close,
+// but then it must be closed again.
+func (s *FilePositionStore) Close() error {
+	return s.Flush()
+}
+
+func (s *FilePositionStore) flushBackground() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timer = nil
+	if err := s.flushLocked(); err != nil {
+		s.err = err
+	}
+}
+
+// Lock must be held outside.
+func (s *FilePositionStore) flushLocked() error {
+	if !s.dirty {
+		return nil
+	}
+	positions := make(map[string]filePosition, len(s.positions))
+	for ch, p := range s.positions {
+		positions[ch] = filePosition{Offset: p.Offset, Epoch: p.Epoch}
+	}
+	data, err := json.Marshal(positions)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(s.path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// restorePosition loads stored position of recoverable subscription, so the first
+// subscribe recovers publications missed while process was not running.
+func (s *Subscription) restorePosition() error {
+	store := s.centrifuge.config.PositionStore
+	if store == nil || !s.recoverable {
+		return nil
+	}
+	sp, ok, err := store.Load(s.Channel)
+	if err != nil || !ok {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset = sp.Offset
+	s.epoch = sp.Epoch
+	s.recover = true
+	return nil
+}
+
+// savePosition stores position of publication processed by handler. With
+// SubscriptionConfig.ManualAck position is stored upon Ack instead.
+func (s *Subscription) savePosition(offset uint64) {
+	if s.acks != nil || offset == 0 {
+		return
+	}
+	s.mu.RLock()
+	epoch := s.epoch
+	s.mu.RUnlock()
+	s.storePosition(StreamPosition{Offset: offset, Epoch: epoch})
+}
+
+// storePosition saves position to Config.PositionStore. Errors are reported over
+// OnError as PositionStoreError.
+func (s *Subscription) storePosition(sp StreamPosition) {
+	store := s.centrifuge.config.PositionStore
+	if store == nil || !s.recoverable {
+		return
+	}
+	if err := store.Save(s.Channel, sp); err != nil {
+		s.emitPositionStoreError(err)
+	}
+}
+
+// flushPositions flushes Config.PositionStore if it batches saves.
+func (s *Subscription) flushPositions() {
+	flusher, ok := s.centrifuge.config.PositionStore.(PositionFlusher)
+	if !ok || !s.recoverable {
+		return
+	}
+	if err := flusher.Flush(); err != nil {
+		s.emitPositionStoreError(err)
+	}
+}
+
+// emitPositionStoreError reports error over OnError as PositionStoreError.
+func (s *Subscription) emitPositionStoreError(err error) {
+	if s.events != nil && s.events.onError != nil {
+		handler := s.events.onError
+		// Called from event handler, so must not wait for handler.
+		s.centrifuge.runHandlerAsync(func() {
+			handler(SubscriptionErrorEvent{Error: PositionStoreError{err}})
+		})
+	}
+}
+
+// flushPositions flushes Config.PositionStore on close if it batches saves.
+func (c *Client) flushPositions() {
+	flusher, ok := c.config.PositionStore.(PositionFlusher)
+	if !ok {
+		return
+	}
+	if err := flusher.Flush(); err != nil && c.logLevelEnabled(LogLevelDebug) {
+		c.log(LogLevelDebug, "error flushing position store", map[string]string{"error": err.Error()})
+	}
+}
@@ -0,0 +1,172 @@
+package centrifuge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+func TestFilePositionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "positions.json")
+	s, err := NewFilePositionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := s.Load("a"); err != nil || ok {
+		t.Fatalf("unexpected position: %v %v", ok, err)
+	}
+	if err := s.Save("a", StreamPosition{Offset: 1, Epoch: "e1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save("b", StreamPosition{Offset: 2, Epoch: "e2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save("a", StreamPosition{Offset: 3, Epoch: "e1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewFilePositionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for channel, expected := range map[string]StreamPosition{"a": {3, "e1"}, "b": {2, "e2"}} {
+		sp, ok, err := s.Load(channel)
+		if err != nil || !ok || sp != expected {
+			t.Fatalf("unexpected position of %s: %v %v %v", channel, sp, ok, err)
+		}
+	}
+}
+
+func TestFilePositionStore_FlushInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "positions.json")
+	s, err := NewFilePositionStore(path, FilePositionStoreConfig{FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(1); i <= 3; i++ {
+		if err := s.Save("a", StreamPosition{Offset: i, Epoch: "e"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file must not be written before flush: %v", err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewFilePositionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp, ok, _ := reopened.Load("a"); !ok || sp.Offset != 3 {
+		t.Fatalf("unexpected position: %v %v", sp, ok)
+	}
+
+	// Background flush writes file after interval.
+	s, err = NewFilePositionStore(path, FilePositionStoreConfig{FlushInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save("a", StreamPosition{Offset: 4, Epoch: "e"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		reopened, err := NewFilePositionStore(path)
+		if err != nil {
+			return false
+		}
+		sp, _, _ := reopened.Load("a")
+		return sp.Offset == 4
+	})
+}
+
+// startPositionServer subscribes to "test" channel at offset 5 and pushes
+// publication with offset 6. Subscribe requests are sent to subCh.
+func startPositionServer(t *testing.T, subCh chan<- *protocol.SubscribeRequest) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var replies []string
+			switch {
+			case cmd.Connect != nil:
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id))
+			case cmd.Subscribe != nil:
+				subCh <- cmd.Subscribe
+				replies = append(replies,
+					fmt.Sprintf(`{"id":%d,"subscribe":{"recoverable":true,"epoch":"e","offset":5}}`, cmd.Id),
+					`{"push":{"channel":"test","pub":{"data":{},"offset":6}}}`,
+				)
+			}
+			for _, reply := range replies {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestSubscription_PositionStore(t *testing.T) {
+	subCh := make(chan *protocol.SubscribeRequest, 2)
+	u := startPositionServer(t, subCh)
+	store := NewMemoryPositionStore()
+
+	run := func() {
+		client := NewJsonClient(u, Config{PositionStore: store})
+		defer client.Close()
+		sub, err := client.NewSubscription("test", SubscriptionConfig{Recoverable: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		processed := make(chan struct{}, 1)
+		sub.OnPublication(func(PublicationEvent) {
+			processed <- struct{}{}
+		})
+		if err := client.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		if err := sub.Subscribe(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-processed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for publication")
+		}
+		waitFor(t, func() bool {
+			sp, _, _ := store.Load("test")
+			return sp == StreamPosition{Offset: 6, Epoch: "e"}
+		})
+	}
+
+	run()
+	if req := <-subCh; req.Recover {
+		t.Fatal("first subscribe must not recover")
+	}
+	// New client resumes from stored position.
+	run()
+	if req := <-subCh; !req.Recover || req.Offset != 6 || req.Epoch != "e" {
+		t.Fatalf("expected recovery from stored position, got %#v", req)
+	}
+}
@@ -0,0 +1,41 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeClientInfo decodes JSON ConnInfo and ChanInfo of client into T. ConnInfo
+// is decoded first and ChanInfo over it, so T may have fields of both. Empty
+// infos are skipped.
+func DecodeClientInfo[T any](info ClientInfo) (T, error) {
+	var value T
+	for _, data := range [][]byte{info.ConnInfo, info.ChanInfo} {
+		if len(data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return value, err
+		}
+	}
+	return value, nil
+}
+
+// PresenceTyped is like Subscription.Presence but decodes info of each client
+// with DecodeClientInfo. Result is keyed by client ID.
+func PresenceTyped[T any](ctx context.Context, s *Subscription) (map[string]T, error) {
+	res, err := s.Presence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make(map[string]T, len(res.Clients))
+	for client, info := range res.Clients {
+		value, err := DecodeClientInfo[T](info)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding info of client %s: %w", client, err)
+		}
+		decoded[client] = value
+	}
+	return decoded, nil
+}
@@ -0,0 +1,31 @@
+package centrifuge
+
+import (
+	"testing"
+)
+
+func TestDecodeClientInfo(t *testing.T) {
+	type info struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+	}
+	value, err := DecodeClientInfo[info](ClientInfo{
+		ConnInfo: []byte(`{"name":"alice","role":"user"}`),
+		ChanInfo: []byte(`{"role":"moderator"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Name != "alice" || value.Role != "moderator" {
+		t.Fatalf("unexpected value: %#v", value)
+	}
+
+	value, err = DecodeClientInfo[info](ClientInfo{})
+	if err != nil || value != (info{}) {
+		t.Fatalf("unexpected result for empty info: %#v, %v", value, err)
+	}
+
+	if _, err := DecodeClientInfo[info](ClientInfo{ConnInfo: []byte(`{`)}); err == nil {
+		t.Fatal("expected decode error")
+	}
+}
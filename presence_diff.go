@@ -0,0 +1,74 @@
+package centrifuge
+
+import (
+	"bytes"
+	"sort"
+)
+
+// PresenceDiff is a difference between two presence snapshots.
+type PresenceDiff struct {
+	// Added clients are present only in the new snapshot.
+	Added []ClientInfo
+	// Removed clients are present only in the old snapshot.
+	Removed []ClientInfo
+	// Changed clients are present in both snapshots with different info, new info
+	// is set.
+	Changed []ClientInfo
+	// AddedUsers had no clients in the old snapshot.
+	AddedUsers []string
+	// RemovedUsers have no clients in the new snapshot.
+	RemovedUsers []string
+}
+
+// Empty tells whether snapshots are equal.
+func (d PresenceDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffPresence computes difference between presence snapshots keyed by client ID,
+// such as PresenceResult.Clients. Results are sorted by client ID and user ID.
+func DiffPresence(prev map[string]ClientInfo, next map[string]ClientInfo) PresenceDiff {
+	var diff PresenceDiff
+	prevUsers := make(map[string]struct{}, len(prev))
+	nextUsers := make(map[string]struct{}, len(next))
+	for client, info := range next {
+		nextUsers[info.User] = struct{}{}
+		prevInfo, ok := prev[client]
+		if !ok {
+			diff.Added = append(diff.Added, info)
+		} else if !clientInfoEqual(prevInfo, info) {
+			diff.Changed = append(diff.Changed, info)
+		}
+	}
+	for client, info := range prev {
+		prevUsers[info.User] = struct{}{}
+		if _, ok := next[client]; !ok {
+			diff.Removed = append(diff.Removed, info)
+		}
+	}
+	for user := range nextUsers {
+		if _, ok := prevUsers[user]; !ok {
+			diff.AddedUsers = append(diff.AddedUsers, user)
+		}
+	}
+	for user := range prevUsers {
+		if _, ok := nextUsers[user]; !ok {
+			diff.RemovedUsers = append(diff.RemovedUsers, user)
+		}
+	}
+	sortClientInfos(diff.Added)
+	sortClientInfos(diff.Removed)
+	sortClientInfos(diff.Changed)
+	sort.Strings(diff.AddedUsers)
+	sort.Strings(diff.RemovedUsers)
+	return diff
+}
+
+func clientInfoEqual(a ClientInfo, b ClientInfo) bool {
+	return a.Client == b.Client && a.User == b.User &&
+		bytes.Equal(a.ConnInfo, b.ConnInfo) && bytes.Equal(a.ChanInfo, b.ChanInfo)
+}
+
+func sortClientInfos(infos []ClientInfo) {
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Client < infos[j].Client })
+}
@@ -0,0 +1,35 @@
+package centrifuge
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiffPresence(t *testing.T) {
+	prev := map[string]ClientInfo{
+		"a": {Client: "a", User: "1"},
+		"b": {Client: "b", User: "2", ChanInfo: []byte(`{"role":"user"}`)},
+		"c": {Client: "c", User: "3"},
+	}
+	next := map[string]ClientInfo{
+		"b": {Client: "b", User: "2", ChanInfo: []byte(`{"role":"admin"}`)},
+		"c": {Client: "c", User: "3"},
+		"d": {Client: "d", User: "4"},
+		"e": {Client: "e", User: "3"},
+	}
+	diff := DiffPresence(prev, next)
+	got := fmt.Sprint(presenceChangeClients(diff.Added), presenceChangeClients(diff.Removed),
+		presenceChangeClients(diff.Changed), diff.AddedUsers, diff.RemovedUsers)
+	if got != "[d e] [a] [b] [4] [1]" {
+		t.Fatalf("unexpected diff: %s", got)
+	}
+	if diff.Empty() {
+		t.Fatal("diff must not be empty")
+	}
+	if !DiffPresence(next, next).Empty() {
+		t.Fatal("diff of equal snapshots must be empty")
+	}
+	if diff := DiffPresence(nil, prev); len(diff.Added) != 3 || len(diff.AddedUsers) != 3 {
+		t.Fatalf("unexpected diff with empty snapshot: %#v", diff)
+	}
+}
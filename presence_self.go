@@ -0,0 +1,45 @@
+package centrifuge
+
+// PresenceOptions define presence request behaviour.
+type PresenceOptions struct {
+	// WithoutSelf excludes connection of this Client from presence result.
+	WithoutSelf bool
+}
+
+// PresenceOption is a type to represent various Presence call options.
+type PresenceOption func(options *PresenceOptions)
+
+// WithoutSelf excludes connection of this Client from presence result.
+func WithoutSelf() PresenceOption {
+	return func(options *PresenceOptions) {
+		options.WithoutSelf = true
+	}
+}
+
+// currentClientID returns ID of current connection, empty if not connected.
+func (c *Client) currentClientID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clientID
+}
+
+// withoutClient returns presence result without client. Result is copied since
+// it may be shared by coalesced requests.
+func withoutClient(res PresenceResult, client string) PresenceResult {
+	if _, ok := res.Clients[client]; !ok {
+		return res
+	}
+	clients := make(map[string]ClientInfo, len(res.Clients)-1)
+	for id, info := range res.Clients {
+		if id != client {
+			clients[id] = info
+		}
+	}
+	return PresenceResult{Clients: clients}
+}
+
+// isSelf tells whether join or leave message is about own connection which must
+// be skipped due to SubscriptionConfig.WithoutSelf.
+func (s *Subscription) isSelf(client string) bool {
+	return s.withoutSelf && client != "" && client == s.centrifuge.currentClientID()
+}
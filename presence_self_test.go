@@ -0,0 +1,63 @@
+package centrifuge
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubscription_WithoutSelf(t *testing.T) {
+	ch := &testPresenceChannel{clients: []string{"a", "c"}, pushCh: make(chan string, 2)}
+	client := NewJsonClient(startWatchPresenceServer(t, ch), Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test", SubscriptionConfig{JoinLeave: true, WithoutSelf: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	joins := make(chan string, 2)
+	sub.OnJoin(func(e JoinEvent) {
+		joins <- e.Client
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Connection of client has ID "c".
+	res, err := sub.Presence(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := res.Clients["c"]; ok || len(res.Clients) != 1 {
+		t.Fatalf("unexpected subscription presence: %v", res.Clients)
+	}
+	res, err = client.Presence(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Clients) != 2 {
+		t.Fatalf("unexpected client presence: %v", res.Clients)
+	}
+	res, err = client.Presence(ctx, "test", WithoutSelf())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := res.Clients["c"]; ok || len(res.Clients) != 1 {
+		t.Fatalf("unexpected client presence without self: %v", res.Clients)
+	}
+
+	ch.pushCh <- `{"push":{"channel":"test","join":{"info":{"client":"c"}}}}`
+	ch.pushCh <- `{"push":{"channel":"test","join":{"info":{"client":"b"}}}}`
+	select {
+	case client := <-joins:
+		if client != "b" {
+			t.Fatalf("expected join of b, got %s", client)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for join")
+	}
+}
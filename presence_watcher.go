@@ -0,0 +1,263 @@
+package centrifuge
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+const defaultPresenceWatchInterval = 30 * time.Second
+
+// PresenceWatcher maintains local map of clients present in channel. Membership
+// is updated by join/leave messages and periodically reconciled with full channel
+// presence, so clients missed while subscription was lost are caught up. Changes
+// are passed to OnPresenceChanged. Create it with Subscription.WatchPresence.
+type PresenceWatcher struct {
+	sub      *Subscription
+	interval time.Duration
+	opts     PresenceWatchOptions
+
+	mu      sync.Mutex
+	clients map[string]ClientInfo
+	// confirmed keeps the last time client was reported by server.
+	confirmed map[string]time.Time
+	stale     map[string]struct{}
+
+	syncCh    chan struct{}
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// PresenceWatchOptions define PresenceWatcher behaviour.
+type PresenceWatchOptions struct {
+	// StaleAfter is the period after which client not confirmed by join message or
+	// reconciliation is reported to OnPresenceStale, e.g. when reconciliations
+	// fail or subscription is lost. Stale client stays in membership until
+	// reconciliation removes it.
+	// Zero value means staleness is not detected.
+	StaleAfter time.Duration
+}
+
+// PresenceWatchOption is a type to represent various PresenceWatcher options.
+type PresenceWatchOption func(options *PresenceWatchOptions)
+
+// WithPresenceStaleAfter enables detection of stale clients.
+func WithPresenceStaleAfter(staleAfter time.Duration) PresenceWatchOption {
+	return func(opts *PresenceWatchOptions) {
+		opts.StaleAfter = staleAfter
+	}
+}
+
+// WatchPresence starts PresenceWatcher which reconciles presence every interval
+// and after each subscribe. Subscription should be created with JoinLeave flag
+// to get changes between reconciliations. Non-positive interval means 30
+// seconds. Previous watcher of Subscription is stopped.
+func (s *Subscription) WatchPresence(interval time.Duration, opts ...PresenceWatchOption) *PresenceWatcher {
+	if interval <= 0 {
+		interval = defaultPresenceWatchInterval
+	}
+	w := &PresenceWatcher{
+		sub:       s,
+		interval:  interval,
+		clients:   make(map[string]ClientInfo),
+		confirmed: make(map[string]time.Time),
+		stale:     make(map[string]struct{}),
+		syncCh:    make(chan struct{}, 1),
+		closeCh:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+	s.mu.Lock()
+	prev := s.presenceWatcher
+	s.presenceWatcher = w
+	s.mu.Unlock()
+	if prev != nil {
+		prev.Stop()
+	}
+	s.centrifuge.spawn("presence_watcher", w.run)
+	return w
+}
+
+// Clients returns copy of current channel membership.
+func (w *PresenceWatcher) Clients() map[string]ClientInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return maps.Clone(w.clients)
+}
+
+// Stop stops watching presence.
+func (w *PresenceWatcher) Stop() {
+	w.closeOnce.Do(func() {
+		close(w.closeCh)
+		w.sub.mu.Lock()
+		if w.sub.presenceWatcher == w {
+			w.sub.presenceWatcher = nil
+		}
+		w.sub.mu.Unlock()
+	})
+}
+
+func (w *PresenceWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	w.reconcile()
+	for {
+		select {
+		case <-w.closeCh:
+			return
+		case <-w.sub.centrifuge.closedCh:
+			return
+		case <-ticker.C:
+		case <-w.syncCh:
+		}
+		w.reconcile()
+		w.checkStale(time.Now())
+	}
+}
+
+// requestSync schedules reconciliation, called after subscribe.
+func (w *PresenceWatcher) requestSync() {
+	select {
+	case w.syncCh <- struct{}{}:
+	default:
+	}
+}
+
+func (w *PresenceWatcher) reconcile() {
+	if w.sub.State() != SubStateSubscribed {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.sub.centrifuge.config.ReadTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-w.closeCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	res, err := w.sub.Presence(ctx)
+	if err != nil {
+		if w.sub.centrifuge.logLevelEnabled(LogLevelDebug) {
+			w.sub.centrifuge.log(LogLevelDebug, "presence reconciliation error", map[string]string{
+				"channel": w.sub.Channel,
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+	w.mu.Lock()
+	diff := DiffPresence(w.clients, res.Clients)
+	event := PresenceChangedEvent{Joined: diff.Added, Left: diff.Removed, Changed: diff.Changed}
+	w.clients = maps.Clone(res.Clients)
+	if w.clients == nil {
+		w.clients = make(map[string]ClientInfo)
+	}
+	now := time.Now()
+	clear(w.confirmed)
+	clear(w.stale)
+	for client := range w.clients {
+		w.confirmed[client] = now
+	}
+	w.mu.Unlock()
+	w.reportMetrics()
+	w.emit(event)
+}
+
+// join adds client upon join message.
+func (w *PresenceWatcher) join(info ClientInfo) {
+	w.mu.Lock()
+	_, ok := w.clients[info.Client]
+	w.clients[info.Client] = info
+	w.confirmed[info.Client] = time.Now()
+	delete(w.stale, info.Client)
+	w.mu.Unlock()
+	w.reportMetrics()
+	if !ok {
+		w.emit(PresenceChangedEvent{Joined: []ClientInfo{info}})
+	}
+}
+
+// leave removes client upon leave message.
+func (w *PresenceWatcher) leave(info ClientInfo) {
+	w.mu.Lock()
+	_, ok := w.clients[info.Client]
+	delete(w.clients, info.Client)
+	delete(w.confirmed, info.Client)
+	delete(w.stale, info.Client)
+	w.mu.Unlock()
+	w.reportMetrics()
+	if ok {
+		w.emit(PresenceChangedEvent{Left: []ClientInfo{info}})
+	}
+}
+
+// reportMetrics passes membership counts to Config.Metrics.
+func (w *PresenceWatcher) reportMetrics() {
+	if w.sub.centrifuge.config.Metrics == nil {
+		return
+	}
+	w.mu.Lock()
+	users := make(map[string]struct{}, len(w.clients))
+	for _, info := range w.clients {
+		users[info.User] = struct{}{}
+	}
+	numClients := len(w.clients)
+	w.mu.Unlock()
+	w.sub.centrifuge.metrics.SetPresence(w.sub.Channel, numClients, len(users))
+}
+
+// checkStale reports clients not confirmed within StaleAfter, each client is
+// reported once until confirmed again.
+func (w *PresenceWatcher) checkStale(now time.Time) {
+	if w.opts.StaleAfter <= 0 {
+		return
+	}
+	var event PresenceStaleEvent
+	w.mu.Lock()
+	for client, confirmedAt := range w.confirmed {
+		if _, ok := w.stale[client]; ok || now.Sub(confirmedAt) < w.opts.StaleAfter {
+			continue
+		}
+		w.stale[client] = struct{}{}
+		event.Clients = append(event.Clients, w.clients[client])
+	}
+	w.mu.Unlock()
+	if len(event.Clients) == 0 {
+		return
+	}
+	s := w.sub
+	if s.events != nil && s.events.onPresenceStale != nil {
+		handler := s.events.onPresenceStale
+		s.centrifuge.runHandlerSync(func() {
+			handler(event)
+		})
+	}
+}
+
+func (w *PresenceWatcher) emit(event PresenceChangedEvent) {
+	if len(event.Joined) == 0 && len(event.Left) == 0 && len(event.Changed) == 0 {
+		return
+	}
+	select {
+	case <-w.closeCh:
+		return
+	default:
+	}
+	s := w.sub
+	if s.events != nil && s.events.onPresenceChanged != nil {
+		handler := s.events.onPresenceChanged
+		s.centrifuge.runHandlerSync(func() {
+			handler(event)
+		})
+	}
+}
+
+// watcher returns current PresenceWatcher of Subscription or nil.
+func (s *Subscription) watcher() *PresenceWatcher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.presenceWatcher
+}
@@ -0,0 +1,218 @@
+package centrifuge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// testPresenceChannel is a channel membership served by startWatchPresenceServer.
+type testPresenceChannel struct {
+	mu      sync.Mutex
+	clients []string
+	pushCh  chan string
+}
+
+func (ch *testPresenceChannel) set(clients ...string) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.clients = clients
+}
+
+func (ch *testPresenceChannel) presence() string {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	var entries []string
+	for _, client := range ch.clients {
+		entries = append(entries, fmt.Sprintf(`"%s":{"client":"%s"}`, client, client))
+	}
+	return "{" + strings.Join(entries, ",") + "}"
+}
+
+// startWatchPresenceServer replies to presence commands with membership of ch and
+// sends pushes from ch.pushCh.
+func startWatchPresenceServer(t *testing.T, ch *testPresenceChannel) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		var writeMu sync.Mutex
+		write := func(data string) error {
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			return conn.WriteMessage(websocket.TextMessage, []byte(data))
+		}
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case push := <-ch.pushCh:
+					_ = write(push)
+				}
+			}
+		}()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var reply string
+			switch {
+			case cmd.Connect != nil:
+				reply = fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id)
+			case cmd.Subscribe != nil:
+				reply = fmt.Sprintf(`{"id":%d,"subscribe":{}}`, cmd.Id)
+			case cmd.Presence != nil:
+				reply = fmt.Sprintf(`{"id":%d,"presence":{"presence":%s}}`, cmd.Id, ch.presence())
+			}
+			if err := write(reply); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func presenceChangeClients(infos []ClientInfo) []string {
+	clients := make([]string, 0, len(infos))
+	for _, info := range infos {
+		clients = append(clients, info.Client)
+	}
+	slices.Sort(clients)
+	return clients
+}
+
+func TestSubscription_WatchPresence(t *testing.T) {
+	ch := &testPresenceChannel{clients: []string{"a", "b"}, pushCh: make(chan string, 4)}
+	client := NewJsonClient(startWatchPresenceServer(t, ch), Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test", SubscriptionConfig{JoinLeave: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan PresenceChangedEvent, 16)
+	sub.OnPresenceChanged(func(e PresenceChangedEvent) {
+		changes <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	w := sub.WatchPresence(500 * time.Millisecond)
+	defer w.Stop()
+
+	expectChange := func(joined, left string) {
+		t.Helper()
+		select {
+		case e := <-changes:
+			if got := strings.Join(presenceChangeClients(e.Joined), ","); got != joined {
+				t.Fatalf("expected joined %q, got %q", joined, got)
+			}
+			if got := strings.Join(presenceChangeClients(e.Left), ","); got != left {
+				t.Fatalf("expected left %q, got %q", left, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for presence change")
+		}
+	}
+
+	expectChange("a,b", "")
+	ch.set("b", "c")
+	ch.pushCh <- `{"push":{"channel":"test","join":{"info":{"client":"c"}}}}`
+	expectChange("c", "")
+	ch.pushCh <- `{"push":{"channel":"test","leave":{"info":{"client":"a"}}}}`
+	expectChange("", "a")
+
+	// Leave of b is missed, reconciliation catches it up.
+	ch.set("c")
+	expectChange("", "b")
+	if clients := w.Clients(); len(clients) != 1 || clients["c"].Client != "c" {
+		t.Fatalf("unexpected clients: %v", clients)
+	}
+}
+
+func TestPresenceWatcher_Stale(t *testing.T) {
+	client := NewJsonClient("ws://localhost", Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stale []string
+	sub.OnPresenceStale(func(e PresenceStaleEvent) {
+		stale = append(stale, presenceChangeClients(e.Clients)...)
+	})
+	w := sub.WatchPresence(time.Hour, WithPresenceStaleAfter(time.Minute))
+	defer w.Stop()
+	w.join(ClientInfo{Client: "a"})
+	w.join(ClientInfo{Client: "b"})
+	now := time.Now()
+
+	w.checkStale(now.Add(30 * time.Second))
+	if len(stale) != 0 {
+		t.Fatalf("unexpected stale clients: %v", stale)
+	}
+	w.leave(ClientInfo{Client: "b"})
+	w.checkStale(now.Add(2 * time.Minute))
+	if strings.Join(stale, ",") != "a" {
+		t.Fatalf("expected a stale, got %v", stale)
+	}
+	// Reported once.
+	w.checkStale(now.Add(3 * time.Minute))
+	if len(stale) != 1 {
+		t.Fatalf("unexpected stale clients: %v", stale)
+	}
+	// Stale client stays in membership until reconciliation.
+	if _, ok := w.Clients()["a"]; !ok {
+		t.Fatal("stale client must stay in membership")
+	}
+}
+
+type testPresenceMetrics struct {
+	noopMetrics
+	mu       sync.Mutex
+	presence map[string][2]int
+}
+
+func (m *testPresenceMetrics) SetPresence(channel string, numClients int, numUsers int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.presence[channel] = [2]int{numClients, numUsers}
+}
+
+func TestPresenceWatcher_Metrics(t *testing.T) {
+	m := &testPresenceMetrics{presence: make(map[string][2]int)}
+	client := NewJsonClient("ws://localhost", Config{Metrics: m})
+	defer client.Close()
+	sub, err := client.NewSubscription("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := sub.WatchPresence(time.Hour)
+	defer w.Stop()
+	w.join(ClientInfo{Client: "a", User: "1"})
+	w.join(ClientInfo{Client: "b", User: "1"})
+	w.join(ClientInfo{Client: "c", User: "2"})
+	w.leave(ClientInfo{Client: "a", User: "1"})
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if counts := m.presence["test"]; counts != [2]int{2, 2} {
+		t.Fatalf("unexpected presence counts: %v", counts)
+	}
+}
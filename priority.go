@@ -0,0 +1,124 @@
+package centrifuge
+
+import (
+	"sync"
+
+	"github.com/centrifugal/protocol"
+)
+
+// Priority of outgoing command. When connection is slow and several commands wait
+// to be written, commands with higher priority are written first. See WithPriority
+// and WithRPCPriority.
+type Priority string
+
+const (
+	// PriorityNormal is a priority of publish, RPC and other commands by default.
+	PriorityNormal Priority = ""
+	// PriorityHigh is for latency sensitive commands. Pongs and token refreshes
+	// are always sent with high priority.
+	PriorityHigh Priority = "high"
+	// PriorityBulk is for commands which may wait, like telemetry.
+	PriorityBulk Priority = "bulk"
+)
+
+const numPriorityLanes = 3
+
+// lane returns index of write queue for priority, lower index is written first.
+func (p Priority) lane() int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityBulk:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// commandPriority returns default priority of cmd.
+func commandPriority(cmd *protocol.Command) Priority {
+	if cmd.Refresh != nil || cmd.SubRefresh != nil {
+		return PriorityHigh
+	}
+	if cmd.Id == 0 && cmd.Send == nil {
+		// Pong to server ping.
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
+
+// maxWriteSkips is a number of times waiting writer may be overtaken by writers
+// with higher priority before it's let to write. Protects bulk commands from
+// starvation under constant load of higher priority ones.
+const maxWriteSkips = 8
+
+// writeScheduler serializes writes to connection. While connection is busy
+// writers wait in per-priority queues, the next writer is taken from the highest
+// priority queue unless lower priority writer was overtaken maxWriteSkips times.
+type writeScheduler struct {
+	mu      sync.Mutex
+	busy    bool
+	lanes   [numPriorityLanes][]chan struct{}
+	skipped [numPriorityLanes]int
+}
+
+// acquire waits until writer with priority may write.
+func (s *writeScheduler) acquire(priority Priority) {
+	s.mu.Lock()
+	if !s.busy {
+		s.busy = true
+		s.mu.Unlock()
+		return
+	}
+	ch := make(chan struct{})
+	lane := priority.lane()
+	s.lanes[lane] = append(s.lanes[lane], ch)
+	s.mu.Unlock()
+	<-ch
+}
+
+// release passes connection to the next waiting writer.
+func (s *writeScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lane := s.nextLaneLocked()
+	if lane < 0 {
+		s.busy = false
+		return
+	}
+	ch := s.lanes[lane][0]
+	s.lanes[lane][0] = nil
+	s.lanes[lane] = s.lanes[lane][1:]
+	// Connection stays busy, it's handed over to woken writer.
+	close(ch)
+}
+
+// Lock must be held outside.
+func (s *writeScheduler) nextLaneLocked() int {
+	next := -1
+	// Starving lowest priority writers go first.
+	for lane := numPriorityLanes - 1; lane > 0; lane-- {
+		if len(s.lanes[lane]) > 0 && s.skipped[lane] >= maxWriteSkips {
+			next = lane
+			break
+		}
+	}
+	if next < 0 {
+		for lane := 0; lane < numPriorityLanes; lane++ {
+			if len(s.lanes[lane]) > 0 {
+				next = lane
+				break
+			}
+		}
+	}
+	if next < 0 {
+		return -1
+	}
+	s.skipped[next] = 0
+	for lane := next + 1; lane < numPriorityLanes; lane++ {
+		if len(s.lanes[lane]) > 0 {
+			s.skipped[lane]++
+		}
+	}
+	return next
+}
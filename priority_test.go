@@ -0,0 +1,111 @@
+package centrifuge
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+// queueWriters makes writers with priorities wait for busy scheduler. Returns
+// channel with priorities in order writers acquired scheduler.
+func queueWriters(t *testing.T, s *writeScheduler, priorities []Priority) <-chan Priority {
+	order := make(chan Priority, len(priorities))
+	var wg sync.WaitGroup
+	for i, p := range priorities {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.acquire(p)
+			order <- p
+			s.release()
+		}()
+		// Wait until writer queued to keep order within lane.
+		waitFor(t, func() bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			n := 0
+			for _, lane := range s.lanes {
+				n += len(lane)
+			}
+			return n == i+1
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(order)
+	}()
+	return order
+}
+
+func TestWriteScheduler_Priority(t *testing.T) {
+	s := &writeScheduler{}
+	s.acquire(PriorityNormal)
+	order := queueWriters(t, s, []Priority{PriorityBulk, PriorityNormal, PriorityHigh, PriorityNormal})
+	s.release()
+	var got []Priority
+	for p := range order {
+		got = append(got, p)
+	}
+	expected := []Priority{PriorityHigh, PriorityNormal, PriorityNormal, PriorityBulk}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected order %q, got %q", expected, got)
+		}
+	}
+}
+
+func TestWriteScheduler_Starvation(t *testing.T) {
+	s := &writeScheduler{}
+	s.acquire(PriorityNormal)
+	priorities := []Priority{PriorityBulk}
+	for i := 0; i < 2*maxWriteSkips; i++ {
+		priorities = append(priorities, PriorityHigh)
+	}
+	order := queueWriters(t, s, priorities)
+	s.release()
+	pos := 0
+	for p := range order {
+		if p == PriorityBulk {
+			break
+		}
+		pos++
+	}
+	if pos != maxWriteSkips {
+		t.Fatalf("expected bulk writer after %d high priority ones, got %d", maxWriteSkips, pos)
+	}
+}
+
+func TestCommandPriority(t *testing.T) {
+	if p := commandPriority(&protocol.Command{}); p != PriorityHigh {
+		t.Fatalf("pong must have high priority, got %q", p)
+	}
+	if p := commandPriority(&protocol.Command{Id: 1, Refresh: &protocol.RefreshRequest{}}); p != PriorityHigh {
+		t.Fatalf("refresh must have high priority, got %q", p)
+	}
+	if p := commandPriority(&protocol.Command{Id: 1, Publish: &protocol.PublishRequest{}}); p != PriorityNormal {
+		t.Fatalf("publish must have normal priority, got %q", p)
+	}
+	if p := commandPriority(&protocol.Command{Send: &protocol.SendRequest{}}); p != PriorityNormal {
+		t.Fatalf("send must have normal priority, got %q", p)
+	}
+}
+
+func TestClient_PublishWithPriority(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Publish(ctx, "test", []byte("{}"), WithPriority(PriorityBulk)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Publish(ctx, "test", []byte("{}"), WithPriority(PriorityHigh)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,6 +2,7 @@ package centrifuge
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/centrifugal/protocol"
 )
@@ -18,6 +19,9 @@ type Publication struct {
 	Info *ClientInfo
 	// Tags contain custom key-value pairs attached to Publication.
 	Tags map[string]string
+	// Time when Publication was published. Zero value means server does not
+	// send publication time.
+	Time time.Time
 }
 
 // ClientInfo contains information about client connection.
@@ -82,6 +86,9 @@ func pubFromProto(pub *protocol.Publication) Publication {
 		Data:   pub.Data,
 		Tags:   pub.GetTags(),
 	}
+	if pub.Time > 0 {
+		p.Time = time.UnixMilli(pub.Time)
+	}
 	if pub.GetInfo() != nil {
 		info := infoFromProto(pub.GetInfo())
 		p.Info = &info
@@ -0,0 +1,131 @@
+package centrifuge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/centrifugal/protocol"
+)
+
+// Protocol types are exported so applications can construct and inspect
+// protocol frames without depending on protocol package or copying .proto files.
+type (
+	// ProtocolType is a protocol format: ProtocolTypeJSON or ProtocolTypeProtobuf.
+	ProtocolType = protocol.Type
+	// ProtocolCommand is a command sent by client.
+	ProtocolCommand = protocol.Command
+	// ProtocolReply is a reply or async push sent by server.
+	ProtocolReply = protocol.Reply
+	// ProtocolPush is an async message sent by server.
+	ProtocolPush = protocol.Push
+	// ProtocolPublication is a publication as sent over the wire.
+	ProtocolPublication = protocol.Publication
+	// ProtocolClientInfo is a client info as sent over the wire.
+	ProtocolClientInfo = protocol.ClientInfo
+	// ProtocolError is an error as sent over the wire.
+	ProtocolError = protocol.Error
+	// ProtocolConnectResult is a result of connect command.
+	ProtocolConnectResult = protocol.ConnectResult
+	// ProtocolSubscribeResult is a result of subscribe command.
+	ProtocolSubscribeResult = protocol.SubscribeResult
+	// ProtocolRPCRequest is a request of RPC command.
+	ProtocolRPCRequest = protocol.RPCRequest
+	// ProtocolRPCResult is a result of RPC command.
+	ProtocolRPCResult = protocol.RPCResult
+	// ProtocolPublishResult is a result of publish command.
+	ProtocolPublishResult = protocol.PublishResult
+)
+
+const (
+	// ProtocolTypeJSON is a protocol format of NewJsonClient.
+	ProtocolTypeJSON = protocol.TypeJSON
+	// ProtocolTypeProtobuf is a protocol format of NewProtobufClient.
+	ProtocolTypeProtobuf = protocol.TypeProtobuf
+)
+
+// ProtocolType returns protocol format used by Client.
+func (c *Client) ProtocolType() ProtocolType {
+	return c.protocolType
+}
+
+// EncodeProtocolCommands encodes commands into one frame the same way Client
+// writes them to connection.
+func EncodeProtocolCommands(protocolType ProtocolType, cmds ...*ProtocolCommand) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeCommands(&buf, protocolType, newCommandEncoder(protocolType), cmds); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeProtocolCommands decodes all commands of one frame.
+func DecodeProtocolCommands(protocolType ProtocolType, data []byte) ([]*ProtocolCommand, error) {
+	var decoder protocol.CommandDecoder
+	if protocolType == protocol.TypeProtobuf {
+		decoder = protocol.NewProtobufCommandDecoder(data)
+	} else {
+		decoder = protocol.NewJSONCommandDecoder(data)
+	}
+	var cmds []*ProtocolCommand
+	for {
+		cmd, err := decoder.Decode()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		// Decoder returns the last command together with io.EOF.
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		if err == io.EOF {
+			return cmds, nil
+		}
+	}
+}
+
+// EncodeProtocolReplies encodes replies into one frame the same way server
+// writes them, useful to emulate server in tests.
+func EncodeProtocolReplies(protocolType ProtocolType, replies ...*ProtocolReply) ([]byte, error) {
+	var encoder protocol.ReplyEncoder
+	if protocolType == protocol.TypeProtobuf {
+		encoder = protocol.NewProtobufReplyEncoder()
+	} else {
+		encoder = protocol.NewJSONReplyEncoder()
+	}
+	var buf bytes.Buffer
+	for _, reply := range replies {
+		data, err := encoder.Encode(reply)
+		if err != nil {
+			return nil, err
+		}
+		if protocolType == protocol.TypeProtobuf {
+			// Protobuf replies in one frame are length-delimited.
+			buf.Write(binary.AppendUvarint(nil, uint64(len(data))))
+			buf.Write(data)
+			continue
+		}
+		// JSON replies in one frame are separated by new line.
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(bytes.TrimRight(data, "\n"))
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeProtocolReplies decodes all replies of one frame the same way Client
+// reads them from connection.
+func DecodeProtocolReplies(protocolType ProtocolType, data []byte) ([]*ProtocolReply, error) {
+	decoder := newReplyDecoder(protocolType, data)
+	var replies []*ProtocolReply
+	for {
+		reply, err := decoder.Decode()
+		if err != nil {
+			if err == io.EOF {
+				return replies, nil
+			}
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+}
@@ -0,0 +1,162 @@
+package centrifuge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestProtocolCodecRoundTrip(t *testing.T) {
+	for _, protocolType := range []ProtocolType{ProtocolTypeJSON, ProtocolTypeProtobuf} {
+		t.Run(string(protocolType), func(t *testing.T) {
+			data, err := EncodeProtocolCommands(protocolType,
+				&ProtocolCommand{Id: 1, Rpc: &ProtocolRPCRequest{Method: "a"}},
+				&ProtocolCommand{Id: 2, Rpc: &ProtocolRPCRequest{Method: "b"}},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmds, err := DecodeProtocolCommands(protocolType, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cmds) != 2 || cmds[0].Rpc.Method != "a" || cmds[1].Id != 2 {
+				t.Fatalf("unexpected commands %v", cmds)
+			}
+			data, err = EncodeProtocolCommands(protocolType, &ProtocolCommand{Id: 3, Rpc: &ProtocolRPCRequest{Method: "c"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmds, err = DecodeProtocolCommands(protocolType, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cmds) != 1 || cmds[0].Id != 3 || cmds[0].Rpc.Method != "c" {
+				t.Fatalf("unexpected single command frame %v", cmds)
+			}
+			data, err = EncodeProtocolReplies(protocolType,
+				&ProtocolReply{Id: 1, Error: &ProtocolError{Code: 100}},
+				&ProtocolReply{Push: &ProtocolPush{Channel: "ch", Pub: &ProtocolPublication{Offset: 3}}},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replies, err := DecodeProtocolReplies(protocolType, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(replies) != 2 || replies[0].Error.Code != 100 || replies[1].Push.Pub.Offset != 3 {
+				t.Fatalf("unexpected replies %v", replies)
+			}
+		})
+	}
+}
+
+// startParityServer speaks both protocol formats using exported codec helpers.
+// Subscribe is followed by publication push, RPC replies with request data.
+func startParityServer(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{Subprotocols: []string{"centrifuge-protobuf"}}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		protocolType, messageType := ProtocolTypeJSON, websocket.TextMessage
+		if conn.Subprotocol() == "centrifuge-protobuf" {
+			protocolType, messageType = ProtocolTypeProtobuf, websocket.BinaryMessage
+		}
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			cmds, err := DecodeProtocolCommands(protocolType, data)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var replies []*ProtocolReply
+			for _, cmd := range cmds {
+				switch {
+				case cmd.Connect != nil:
+					replies = append(replies, &ProtocolReply{Id: cmd.Id, Connect: &ProtocolConnectResult{Client: "c"}})
+				case cmd.Subscribe != nil:
+					replies = append(replies,
+						&ProtocolReply{Id: cmd.Id, Subscribe: &ProtocolSubscribeResult{}},
+						&ProtocolReply{Push: &ProtocolPush{Channel: cmd.Subscribe.Channel, Pub: &ProtocolPublication{Data: []byte(`{"n":1}`)}}},
+					)
+				case cmd.Rpc != nil:
+					replies = append(replies, &ProtocolReply{Id: cmd.Id, Rpc: &ProtocolRPCResult{Data: cmd.Rpc.Data}})
+				}
+			}
+			data, err = EncodeProtocolReplies(protocolType, replies...)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestClient_ProtocolParity(t *testing.T) {
+	for _, newClient := range []func(string, Config) *Client{NewJsonClient, NewProtobufClient} {
+		client := newClient(startParityServer(t), Config{})
+		t.Run(string(client.ProtocolType()), func(t *testing.T) {
+			defer client.Close()
+			if err := client.Connect(); err != nil {
+				t.Fatal(err)
+			}
+			sub, err := client.NewSubscription("ch")
+			if err != nil {
+				t.Fatal(err)
+			}
+			pubCh := make(chan PublicationEvent, 1)
+			sub.OnPublication(func(e PublicationEvent) {
+				pubCh <- e
+			})
+			if err := sub.Subscribe(); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case e := <-pubCh:
+				if string(e.Data) != `{"n":1}` {
+					t.Fatalf("unexpected publication data %s", e.Data)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for publication")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			res, err := client.RPC(ctx, "echo", []byte(`{"x":1}`), WithRPCDeadlinePropagation())
+			if err != nil {
+				t.Fatal(err)
+			}
+			envelope, err := DecodeRPCEnvelope(res.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(envelope.Data) != `{"x":1}` {
+				t.Fatalf("unexpected RPC data %s", envelope.Data)
+			}
+			results, err := client.RPCAll(ctx, []RPCRequest{{Method: "a", Data: []byte(`"a"`)}, {Method: "b", Data: []byte(`"b"`)}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(results[0].Data) != `"a"` || string(results[1].Data) != `"b"` {
+				t.Fatalf("unexpected RPCAll results %v", results)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package centrifuge
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+// Directions of ProtocolFrame.
+const (
+	ProtocolFrameOut = "out"
+	ProtocolFrameIn  = "in"
+)
+
+// redactedToken replaces tokens in protocol dump when Config.ProtocolDumpRedactTokens
+// enabled.
+const redactedToken = "[REDACTED]"
+
+// ProtocolFrame is a WebSocket frame written to Config.ProtocolDump. Dump consists
+// of frames encoded to JSON, one per line.
+type ProtocolFrame struct {
+	// Time when frame was sent or received.
+	Time time.Time
+	// Direction is ProtocolFrameOut for frames sent to server and ProtocolFrameIn
+	// for frames received from server.
+	Direction string
+	// Binary is true for Protobuf protocol frames.
+	Binary bool
+	// Data is a frame payload. JSON frames may contain several newline delimited
+	// commands or replies, Protobuf frames – several varint length prefixed ones.
+	Data []byte
+}
+
+// protocolFrameJSON is a line of protocol dump. JSON frames are kept as text to
+// make dump readable without tools, Protobuf frames are base64 encoded.
+type protocolFrameJSON struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"dir"`
+	Text      string    `json:"text,omitempty"`
+	Binary    []byte    `json:"binary,omitempty"`
+}
+
+// MarshalJSON encodes frame as a line of protocol dump.
+func (f ProtocolFrame) MarshalJSON() ([]byte, error) {
+	v := protocolFrameJSON{Time: f.Time, Direction: f.Direction}
+	if f.Binary {
+		v.Binary = f.Data
+	} else {
+		v.Text = string(f.Data)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes frame from a line of protocol dump.
+func (f *ProtocolFrame) UnmarshalJSON(data []byte) error {
+	var v protocolFrameJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = ProtocolFrame{Time: v.Time, Direction: v.Direction}
+	if v.Binary != nil {
+		f.Binary = true
+		f.Data = v.Binary
+	} else {
+		f.Data = []byte(v.Text)
+	}
+	return nil
+}
+
+// protocolDumper writes frames to Config.ProtocolDump. It's shared by all
+// transports of Client.
+type protocolDumper struct {
+	mu           sync.Mutex
+	w            io.Writer
+	protocolType protocol.Type
+	redact       bool
+}
+
+func newProtocolDumper(w io.Writer, protocolType protocol.Type, redact bool) *protocolDumper {
+	return &protocolDumper{w: w, protocolType: protocolType, redact: redact}
+}
+
+func (d *protocolDumper) dump(direction string, data []byte) {
+	frame := ProtocolFrame{
+		Time:      time.Now(),
+		Direction: direction,
+		Binary:    d.protocolType == protocol.TypeProtobuf,
+		Data:      data,
+	}
+	line, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Dump is best effort, write errors must not affect connection.
+	_, _ = d.w.Write(line)
+}
+
+// dumpOut dumps frame with cmds sent to server. If tokens must be redacted then
+// frame is encoded again from redacted copies of commands.
+func (d *protocolDumper) dumpOut(data []byte, cmds []*protocol.Command) {
+	if !d.redact || !mayHaveTokens(cmds) {
+		d.dump(ProtocolFrameOut, data)
+		return
+	}
+	redacted := make([]*protocol.Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		cmd, err := redactCommand(cmd)
+		if err != nil {
+			return
+		}
+		redacted = append(redacted, cmd)
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeCommands(buf, d.protocolType, newCommandEncoder(d.protocolType), redacted); err != nil {
+		return
+	}
+	d.dump(ProtocolFrameOut, buf.Bytes())
+}
+
+func (d *protocolDumper) dumpIn(data []byte) {
+	d.dump(ProtocolFrameIn, data)
+}
+
+func mayHaveTokens(cmds []*protocol.Command) bool {
+	for _, cmd := range cmds {
+		if cmd.Connect != nil || cmd.Refresh != nil || cmd.Subscribe != nil || cmd.SubRefresh != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// redactCommand returns a copy of cmd with connection and subscription tokens
+// replaced.
+func redactCommand(cmd *protocol.Command) (*protocol.Command, error) {
+	if !mayHaveTokens([]*protocol.Command{cmd}) {
+		return cmd, nil
+	}
+	data, err := cmd.MarshalVT()
+	if err != nil {
+		return nil, err
+	}
+	clone := &protocol.Command{}
+	if err := clone.UnmarshalVT(data); err != nil {
+		return nil, err
+	}
+	if clone.Connect != nil {
+		clone.Connect.Token = redact(clone.Connect.Token)
+		for _, sub := range clone.Connect.Subs {
+			if sub != nil {
+				sub.Token = redact(sub.Token)
+			}
+		}
+	}
+	if clone.Refresh != nil {
+		clone.Refresh.Token = redact(clone.Refresh.Token)
+	}
+	if clone.Subscribe != nil {
+		clone.Subscribe.Token = redact(clone.Subscribe.Token)
+	}
+	if clone.SubRefresh != nil {
+		clone.SubRefresh.Token = redact(clone.SubRefresh.Token)
+	}
+	return clone, nil
+}
+
+func redact(token string) string {
+	if token == "" {
+		return ""
+	}
+	return redactedToken
+}
+
+// ProtocolDumpReader reads frames from protocol dump written over
+// Config.ProtocolDump.
+type ProtocolDumpReader struct {
+	scanner *bufio.Scanner
+}
+
+// NewProtocolDumpReader creates ProtocolDumpReader.
+func NewProtocolDumpReader(r io.Reader) *ProtocolDumpReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	return &ProtocolDumpReader{scanner: scanner}
+}
+
+// Next returns the next frame of dump. Returns io.EOF when there are no more frames.
+func (r *ProtocolDumpReader) Next() (ProtocolFrame, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var frame ProtocolFrame
+		if err := json.Unmarshal(line, &frame); err != nil {
+			return ProtocolFrame{}, fmt.Errorf("malformed protocol dump line: %w", err)
+		}
+		return frame, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return ProtocolFrame{}, err
+	}
+	return ProtocolFrame{}, io.EOF
+}
+
+// PrettyPrintProtocolDump reads protocol dump from r and writes it to w in human
+// readable form: every frame is decoded into commands or replies printed as
+// indented JSON.
+func PrettyPrintProtocolDump(w io.Writer, r io.Reader) error {
+	reader := NewProtocolDumpReader(r)
+	for {
+		frame, err := reader.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := prettyPrintFrame(w, frame); err != nil {
+			return err
+		}
+	}
+}
+
+func prettyPrintFrame(w io.Writer, frame ProtocolFrame) error {
+	arrow := "<-in--"
+	if frame.Direction == ProtocolFrameOut {
+		arrow = "-out->"
+	}
+	if _, err := fmt.Fprintf(w, "%s %s\n", frame.Time.Format(time.RFC3339Nano), arrow); err != nil {
+		return err
+	}
+	messages, err := decodeFrame(frame)
+	if err != nil {
+		// Print frame as is, so the dump is still useful.
+		_, err = fmt.Fprintf(w, "undecodable frame (%v): %q\n", err, frame.Data)
+		return err
+	}
+	for _, msg := range messages {
+		data, err := json.MarshalIndent(msg, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeFrame(frame ProtocolFrame) ([]any, error) {
+	protocolType := protocol.TypeJSON
+	if frame.Binary {
+		protocolType = protocol.TypeProtobuf
+	}
+	var messages []any
+	if frame.Direction == ProtocolFrameOut {
+		cmds, err := DecodeProtocolCommands(protocolType, frame.Data)
+		if err != nil {
+			return nil, err
+		}
+		for _, cmd := range cmds {
+			messages = append(messages, cmd)
+		}
+		return messages, nil
+	}
+	replies, err := DecodeProtocolReplies(protocolType, frame.Data)
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		messages = append(messages, reply)
+	}
+	return messages, nil
+}
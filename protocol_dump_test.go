@@ -0,0 +1,132 @@
+package centrifuge
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestClient_ProtocolDump(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	var dump lockedBuffer
+	client := NewJsonClient(u, Config{
+		Token:                    "secret_token",
+		ProtocolDump:             &dump,
+		ProtocolDumpRedactTokens: true,
+	})
+	defer client.Close()
+	connected := make(chan struct{}, 1)
+	client.OnConnected(func(ConnectedEvent) {
+		connected <- struct{}{}
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for connected event")
+	}
+
+	out := dump.String()
+	if strings.Contains(out, "secret_token") {
+		t.Fatalf("token not redacted: %s", out)
+	}
+	reader := NewProtocolDumpReader(strings.NewReader(out))
+	first, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Direction != ProtocolFrameOut || first.Binary || !strings.Contains(string(first.Data), `"token":"[REDACTED]"`) {
+		t.Fatalf("unexpected first frame: %#v", first)
+	}
+	second, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Direction != ProtocolFrameIn || !strings.Contains(string(second.Data), `"client":"c"`) {
+		t.Fatalf("unexpected second frame: %#v", second)
+	}
+
+	var pretty bytes.Buffer
+	if err := PrettyPrintProtocolDump(&pretty, strings.NewReader(out)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(pretty.String(), "-out->\n{\n  \"id\": 1,") || !strings.Contains(pretty.String(), "<-in--") {
+		t.Fatalf("unexpected pretty output: %s", pretty.String())
+	}
+}
+
+func TestProtocolDump_Protobuf(t *testing.T) {
+	var dump lockedBuffer
+	d := newProtocolDumper(&dump, protocol.TypeProtobuf, true)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	cmd := &protocol.Command{Id: 1, Subscribe: &protocol.SubscribeRequest{Channel: "news", Token: "secret_token"}}
+	if err := encodeProtobufCommand(buf, cmd); err != nil {
+		t.Fatal(err)
+	}
+	d.dumpOut(buf.Bytes(), []*protocol.Command{cmd})
+	if cmd.Subscribe.Token != "secret_token" {
+		t.Fatal("original command must not be modified")
+	}
+	frame, err := NewProtocolDumpReader(strings.NewReader(dump.String())).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !frame.Binary {
+		t.Fatal("expected binary frame")
+	}
+	commands, err := decodeFrame(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 1 || commands[0].(*protocol.Command).Subscribe.Token != redactedToken {
+		t.Fatalf("unexpected commands: %#v", commands)
+	}
+}
+
+func TestProtocolDump_PrettyPrintSingleCommandFrame(t *testing.T) {
+	for _, protocolType := range []protocol.Type{protocol.TypeJSON, protocol.TypeProtobuf} {
+		t.Run(string(protocolType), func(t *testing.T) {
+			var dump lockedBuffer
+			d := newProtocolDumper(&dump, protocolType, false)
+			cmd := &protocol.Command{Id: 7, Subscribe: &protocol.SubscribeRequest{Channel: "news"}}
+			data, err := EncodeProtocolCommands(protocolType, cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.dumpOut(data, []*protocol.Command{cmd})
+			var pretty bytes.Buffer
+			if err := PrettyPrintProtocolDump(&pretty, strings.NewReader(dump.String())); err != nil {
+				t.Fatal(err)
+			}
+			// Decoder returns the only command of a frame together with io.EOF,
+			// it must not be lost.
+			if !strings.Contains(pretty.String(), `"channel": "news"`) {
+				t.Fatalf("empty out frame: %s", pretty.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPublishQueueFull returned by Client.PublishAsync when too many publications
+// wait to be sent. See Config.PublishAsyncQueueSize.
+var ErrPublishQueueFull = errors.New("publish queue full")
+
+const defaultPublishAsyncQueueSize = 1024
+
+type asyncPublication struct {
+	channel string
+	data    []byte
+	fn      func(PublishResult, error)
+}
+
+// publishAsyncQueue is a bounded queue of publications from Client.PublishAsync.
+type publishAsyncQueue struct {
+	mu     sync.Mutex
+	closed bool
+	items  chan asyncPublication
+}
+
+func newPublishAsyncQueue(size int) *publishAsyncQueue {
+	if size <= 0 {
+		size = defaultPublishAsyncQueueSize
+	}
+	return &publishAsyncQueue{items: make(chan asyncPublication, size)}
+}
+
+func (q *publishAsyncQueue) push(item asyncPublication) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClientClosed
+	}
+	select {
+	case q.items <- item:
+		return nil
+	default:
+		return ErrPublishQueueFull
+	}
+}
+
+// close prevents adding new publications and fails queued ones.
+func (q *publishAsyncQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	for {
+		select {
+		case item := <-q.items:
+			item.fn(PublishResult{}, ErrClientClosed)
+		default:
+			return
+		}
+	}
+}
+
+// PublishAsync publishes data into channel without waiting for result. The fn is
+// called with result once server replied, may be nil. Publications are sent in
+// order of PublishAsync calls. If client is connecting then publications wait
+// for connection up to Config.ReadTimeout, or are buffered if Config.OfflineQueue
+// set. Returns ErrPublishQueueFull if Config.PublishAsyncQueueSize publications
+// already wait to be sent – caller should slow down then.
+func (c *Client) PublishAsync(channel string, data []byte, fn func(PublishResult, error)) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+	if fn == nil {
+		fn = func(PublishResult, error) {}
+	}
+	c.publishAsyncOnce.Do(func() {
+		c.spawn("publish_async", c.runPublishAsync)
+	})
+	return c.publishAsync.push(asyncPublication{channel: channel, data: data, fn: fn})
+}
+
+// runPublishAsync sends publications from PublishAsync queue one by one, so they
+// are written to connection in order. Exits when client closed.
+func (c *Client) runPublishAsync() {
+	for {
+		select {
+		case <-c.closedCh:
+			c.publishAsync.close()
+			return
+		default:
+		}
+		select {
+		case <-c.closedCh:
+			c.publishAsync.close()
+			return
+		case item := <-c.publishAsync.items:
+			if c.offlineQueue != nil {
+				// Offline queue keeps order itself.
+				c.publish(context.Background(), item.channel, item.data, PriorityNormal, item.fn)
+				continue
+			}
+			// Wait for connection here instead of registering connect callback, since
+			// connect callbacks are not ordered.
+			errCh := make(chan error, 1)
+			c.onConnect(func(err error) {
+				errCh <- err
+			})
+			if err := <-errCh; err != nil {
+				item.fn(PublishResult{}, err)
+				continue
+			}
+			c.sendPublish(item.channel, item.data, PriorityNormal, item.fn)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package centrifuge
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClient_PublishAsync(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		if err := client.PublishAsync("test", []byte(`{}`), func(_ PublishResult, err error) {
+			results <- err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for publish result")
+		}
+	}
+}
+
+func TestClient_PublishAsyncQueueFull(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{PublishAsyncQueueSize: 1})
+	// Not started dispatcher, so queue is not drained.
+	client.publishAsyncOnce.Do(func() {})
+	var closedErr error
+	if err := client.PublishAsync("test", nil, func(_ PublishResult, err error) {
+		closedErr = err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PublishAsync("test", nil, nil); !errors.Is(err, ErrPublishQueueFull) {
+		t.Fatalf("expected ErrPublishQueueFull, got %v", err)
+	}
+	client.Close()
+	client.publishAsync.close()
+	if !errors.Is(closedErr, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", closedErr)
+	}
+	if err := client.PublishAsync("test", nil, nil); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+}
+
+func TestClient_PublishAsyncBatched(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{MaxBatchSize: 10, MaxBatchDelay: time.Millisecond})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		if err := client.PublishAsync("test", []byte(`{}`), func(_ PublishResult, err error) {
+			results <- err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for publish result")
+		}
+	}
+}
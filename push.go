@@ -0,0 +1,33 @@
+package centrifuge
+
+import (
+	"encoding/json"
+
+	"github.com/centrifugal/protocol"
+)
+
+// handleUnknownPush passes push not handled by client to OnPush handler.
+func (c *Client) handleUnknownPush(push *protocol.Push) {
+	if c.events == nil || c.events.onPush == nil {
+		return
+	}
+	handler := c.events.onPush
+	event := PushEvent{Channel: push.Channel}
+	var payload any
+	switch {
+	case push.Connect != nil:
+		event.Type, payload = "connect", push.Connect
+	case push.Refresh != nil:
+		event.Type, payload = "refresh", push.Refresh
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err == nil {
+			event.Data = data
+		}
+	}
+	c.runHandlerSync(func() {
+		event.QueuedFor = c.queuedFor
+		handler(event)
+	})
+}
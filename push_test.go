@@ -0,0 +1,72 @@
+package centrifuge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startPushServer sends pushes after connect.
+func startPushServer(t *testing.T, pushes ...string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			if cmd.Connect == nil {
+				continue
+			}
+			replies := append([]string{fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id)}, pushes...)
+			for _, reply := range replies {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestClient_OnPush(t *testing.T) {
+	u := startPushServer(t,
+		`{"push":{"refresh":{"expires":true,"ttl":10}}}`,
+		`{"push":{"channel":"news","future_type":{"key":"value"}}}`,
+	)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	events := make(chan PushEvent, 2)
+	client.OnPush(func(e PushEvent) {
+		events <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	var got []PushEvent
+	for len(got) < 2 {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for push events")
+		}
+	}
+	if got[0].Type != "refresh" || string(got[0].Data) != `{"expires":true,"ttl":10}` {
+		t.Fatalf("unexpected refresh push event: %#v", got[0])
+	}
+	if got[1].Type != "" || got[1].Channel != "news" || got[1].Data != nil {
+		t.Fatalf("unexpected unknown push event: %#v", got[1])
+	}
+}
@@ -0,0 +1,135 @@
+package centrifuge
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited returned when publish or RPC rejected by Config.CommandRateLimit.
+// It's retryable – command may succeed after a while.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitConfig configures client-side rate limit of outgoing commands. Set it
+// over Config.CommandRateLimit.
+type RateLimitConfig struct {
+	// Rate is a number of commands per second allowed on average. Must be
+	// positive, NewClient panics otherwise.
+	Rate float64
+	// Burst is the maximum number of commands which may be sent at once.
+	// Zero value means Rate rounded up, but at least 1.
+	Burst int
+}
+
+// newCommandRateLimiter creates token bucket for Config.CommandRateLimit.
+func newCommandRateLimiter(config RateLimitConfig) *tokenBucket {
+	if config.Rate <= 0 {
+		panic("command rate limit requires positive rate")
+	}
+	burst := config.Burst
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(config.Rate)))
+	}
+	return newTokenBucket(config.Rate, burst)
+}
+
+// tokenBucket is a token bucket rate limiter shared by Config.CommandRateLimit
+// and ReconnectCoordinator, bucket starts full. Rate and burst must be positive.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// Lock must be held outside.
+func (b *tokenBucket) refillLocked(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+}
+
+// allow takes a token if available.
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// delay returns time until the next token is available.
+func (b *tokenBucket) delay(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// reserve takes a token even if bucket is empty and returns the time to wait
+// until it's available.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// allowCommand checks Config.CommandRateLimit before sending publish or RPC and
+// emits OnThrottled event if command rejected.
+func (c *Client) allowCommand(event ThrottledEvent) bool {
+	if c.rateLimiter == nil || c.rateLimiter.allow(time.Now()) {
+		return true
+	}
+	var handler ThrottledHandler
+	if c.events != nil && c.events.onThrottled != nil {
+		handler = c.events.onThrottled
+	}
+	if handler != nil {
+		c.runHandlerAsync(func() {
+			handler(event)
+		})
+	}
+	return false
+}
+
+// waitRateLimit waits until Config.CommandRateLimit allows the next command.
+// Returns false if client closed while waiting.
+func (c *Client) waitRateLimit() bool {
+	for {
+		delay := c.rateLimiter.delay(time.Now())
+		if delay <= 0 {
+			return true
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.closedCh:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(10, 2)
+	now := time.Now()
+	if !b.allow(now) || !b.allow(now) {
+		t.Fatal("burst must be allowed")
+	}
+	if b.allow(now) {
+		t.Fatal("command over burst must be rejected")
+	}
+	if d := b.delay(now); d <= 0 || d > 100*time.Millisecond {
+		t.Fatalf("unexpected delay: %s", d)
+	}
+	if !b.allow(now.Add(100 * time.Millisecond)) {
+		t.Fatal("token must be refilled")
+	}
+}
+
+func TestTokenBucket_DefaultBurst(t *testing.T) {
+	b := newCommandRateLimiter(RateLimitConfig{Rate: 0.5})
+	now := time.Now()
+	if !b.allow(now) {
+		t.Fatal("expected one token")
+	}
+	if b.allow(now) {
+		t.Fatal("expected burst 1")
+	}
+}
+
+func TestClient_CommandRateLimitNonPositiveRate(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for rate %v", rate)
+				}
+			}()
+			NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+				CommandRateLimit: &RateLimitConfig{Rate: rate, Burst: 1},
+			})
+		}()
+	}
+}
+
+func TestClient_CommandRateLimit(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{
+		CommandRateLimit: &RateLimitConfig{Rate: 0.001, Burst: 2},
+	})
+	defer client.Close()
+	throttled := make(chan ThrottledEvent, 1)
+	client.OnThrottled(func(e ThrottledEvent) {
+		throttled <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		if _, err := client.Publish(ctx, "test", []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := client.RPC(ctx, "method", []byte("{}"))
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	if !IsRetryableError(err) {
+		t.Fatal("ErrRateLimited must be retryable")
+	}
+	select {
+	case e := <-throttled:
+		if e.Command != "rpc" || e.Method != "method" {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+	case <-ctx.Done():
+		t.Fatal("no throttled event")
+	}
+}
+
+func TestClient_CommandRateLimitOfflineQueue(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{
+		CommandRateLimit: &RateLimitConfig{Rate: 50, Burst: 1},
+		OfflineQueue:     &OfflineQueueConfig{},
+	})
+	defer client.Close()
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		if err := client.EnqueuePublish("test", []byte("{}"), func(_ PublishResult, err error) {
+			results <- err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for queued publication")
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package raw gives low-level access to centrifuge-go Client connection: it
+// allows sending hand-constructed protocol commands for features the Client API
+// does not wrap yet. Commands sent this way bypass Client bookkeeping (state of
+// subscriptions, rate limits, metrics), so prefer Client methods when possible.
+package raw
+
+import (
+	"context"
+
+	"github.com/centrifugal/centrifuge-go"
+	"github.com/centrifugal/centrifuge-go/internal/rawaccess"
+	"github.com/centrifugal/protocol"
+)
+
+// SendCommand sends cmd over client connection and returns the matched reply.
+// Command is sent once client connected, its Id is set by client. If reply
+// contains error it's returned together with reply as *centrifuge.Error.
+func SendCommand(ctx context.Context, c *centrifuge.Client, cmd *protocol.Command) (*protocol.Reply, error) {
+	return rawaccess.SendCommand(ctx, c, cmd)
+}
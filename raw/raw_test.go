@@ -0,0 +1,75 @@
+package raw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge-go"
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startServer replies to RPC "fail" with error and to other RPC with method
+// name as data.
+func startServer(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var reply string
+			switch {
+			case cmd.Connect != nil:
+				reply = fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id)
+			case cmd.Rpc != nil && cmd.Rpc.Method == "fail":
+				reply = fmt.Sprintf(`{"id":%d,"error":{"code":107,"message":"bad request"}}`, cmd.Id)
+			case cmd.Rpc != nil:
+				reply = fmt.Sprintf(`{"id":%d,"rpc":{"data":%q}}`, cmd.Id, cmd.Rpc.Method)
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestSendCommand(t *testing.T) {
+	client := centrifuge.NewJsonClient(startServer(t), centrifuge.Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reply, err := SendCommand(ctx, client, &protocol.Command{Rpc: &protocol.RPCRequest{Method: "echo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reply.Rpc.Data) != `"echo"` {
+		t.Fatalf("unexpected reply data %s", reply.Rpc.Data)
+	}
+
+	reply, err = SendCommand(ctx, client, &protocol.Command{Rpc: &protocol.RPCRequest{Method: "fail"}})
+	var protoErr *centrifuge.Error
+	if !errors.As(err, &protoErr) || protoErr.Code != 107 {
+		t.Fatalf("expected error with code 107, got %v", err)
+	}
+	if reply == nil || reply.Error == nil {
+		t.Fatal("expected reply with error")
+	}
+}
@@ -0,0 +1,52 @@
+package centrifuge
+
+import (
+	"context"
+
+	"github.com/centrifugal/centrifuge-go/internal/rawaccess"
+	"github.com/centrifugal/protocol"
+)
+
+func init() {
+	rawaccess.SendCommand = func(ctx context.Context, client any, cmd *protocol.Command) (*protocol.Reply, error) {
+		return client.(*Client).sendRawCommand(ctx, cmd)
+	}
+}
+
+// sendRawCommand sends command once client connected and waits for its reply.
+// Command id is set by client.
+func (c *Client) sendRawCommand(ctx context.Context, cmd *protocol.Command) (*protocol.Reply, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+	replyCh := make(chan *protocol.Reply, 1)
+	errCh := make(chan error, 1)
+	c.onConnect(func(err error) {
+		if err != nil {
+			errCh <- err
+			return
+		}
+		cmd.Id = c.nextCmdID()
+		err = c.sendAsync(cmd, func(r *protocol.Reply, err error) {
+			if err != nil {
+				errCh <- err
+				return
+			}
+			replyCh <- r
+		})
+		if err != nil {
+			errCh <- err
+		}
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err := <-errCh:
+		return nil, err
+	case reply := <-replyCh:
+		if reply.Error != nil {
+			return reply, errorFromProto(reply.Error)
+		}
+		return reply, nil
+	}
+}
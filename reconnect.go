@@ -1,9 +1,13 @@
 package centrifuge
 
 import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
-
-	"github.com/jpillora/backoff"
 )
 
 type reconnectStrategy interface {
@@ -13,27 +17,293 @@ type reconnectStrategy interface {
 type backoffReconnect struct {
 	// Factor is the multiplying factor for each increment step.
 	Factor float64
-	// Jitter eases contention by randomizing backoff steps.
-	Jitter bool
+	// Jitter eases contention by randomizing backoff steps. It's a fraction of
+	// exponential delay (above MinDelay) which is randomized: 0 means no jitter,
+	// 1 means delay is uniformly distributed between MinDelay and exponential delay.
+	Jitter float64
 	// MinMilliseconds is a minimum value of reconnect interval.
 	MinDelay time.Duration
 	// MaxMilliseconds is a maximum value of reconnect interval.
 	MaxDelay time.Duration
+	// Rand is a random source for jitter. If nil then global math/rand source used.
+	Rand *lockedRand
 }
 
 var defaultBackoffReconnect = &backoffReconnect{
 	MinDelay: 200 * time.Millisecond,
 	MaxDelay: 20 * time.Second,
 	Factor:   2,
-	Jitter:   true,
+	Jitter:   1,
 }
 
 func (r *backoffReconnect) timeBeforeNextAttempt(attempt int) time.Duration {
-	b := &backoff.Backoff{
-		Min:    r.MinDelay,
-		Max:    r.MaxDelay,
-		Factor: r.Factor,
-		Jitter: r.Jitter,
+	minDelay := float64(r.MinDelay)
+	maxDelay := float64(r.MaxDelay)
+	if minDelay >= maxDelay {
+		return r.MaxDelay
+	}
+	delay := minDelay * math.Pow(r.Factor, float64(attempt))
+	if delay > maxDelay || math.IsNaN(delay) {
+		delay = maxDelay
+	}
+	if r.Jitter > 0 {
+		var f float64
+		if r.Rand != nil {
+			f = r.Rand.Float64()
+		} else {
+			f = rand.Float64()
+		}
+		delay -= f * r.Jitter * (delay - minDelay)
+	}
+	if delay < minDelay {
+		delay = minDelay
+	}
+	return time.Duration(delay)
+}
+
+// lockedRand makes rand.Rand safe for concurrent use.
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand(src rand.Source) *lockedRand {
+	return &lockedRand{r: rand.New(src)}
+}
+
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Float64()
+}
+
+// newBackoff creates exponential backoff strategy which respects Config.ReconnectJitter
+// and Config.ReconnectRandSource.
+func newBackoff(config Config, rnd *lockedRand, minDelay time.Duration, maxDelay time.Duration) *backoffReconnect {
+	jitter := config.ReconnectJitter
+	if jitter == 0 {
+		jitter = defaultBackoffReconnect.Jitter
+	} else if jitter < 0 {
+		jitter = 0
+	} else if jitter > 1 {
+		jitter = 1
+	}
+	return &backoffReconnect{
+		MinDelay: minDelay,
+		MaxDelay: maxDelay,
+		Factor:   defaultBackoffReconnect.Factor,
+		Jitter:   jitter,
+		Rand:     rnd,
+	}
+}
+
+// signalReconnectLoop wakes up reconnectLoop to pick up changes of reconnect
+// schedule. Never blocks, so can be called with lock held.
+func (c *Client) signalReconnectLoop() {
+	select {
+	case c.reconnectSignal <- struct{}{}:
+	default:
+	}
+}
+
+// reconnectLoop runs reconnect timer. Reconnect is scheduled or canceled under
+// client lock by changing reconnectPending, reconnectDelay and bumping reconnectGen,
+// timer itself is only managed by this goroutine – so no timer operations happen
+// while holding the lock, and a timer which fired concurrently with cancellation
+// can't start reconnecting since generation has changed. Loop exits when client
+// closed.
+func (c *Client) reconnectLoop() {
+	var timer Timer
+	var gen uint64
+	// Timer is run by scheduler, it passes sequence number it was armed with, so
+	// stopped timer which fired concurrently is ignored.
+	var timerSeq uint64
+	fired := make(chan uint64)
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+		}
+	}
+	for {
+		select {
+		case <-c.reconnectSignal:
+			c.mu.RLock()
+			pending := c.reconnectPending
+			delay := c.reconnectDelay
+			gen = c.reconnectGen
+			c.mu.RUnlock()
+			stopTimer()
+			if pending {
+				timerSeq++
+				seq := timerSeq
+				timer = c.scheduler.AfterFunc(delay, func() {
+					select {
+					case fired <- seq:
+					case <-c.closedCh:
+					}
+				})
+			}
+		case seq := <-fired:
+			if seq != timerSeq || timer == nil {
+				continue
+			}
+			timer = nil
+			c.mu.Lock()
+			fire := c.reconnectPending && c.reconnectGen == gen
+			if fire {
+				c.reconnectPending = false
+			}
+			c.mu.Unlock()
+			if !fire {
+				continue
+			}
+			if c.logLevelEnabled(LogLevelDebug) {
+				c.log(LogLevelDebug, "reconnect timer fired, start reconnecting", nil)
+			}
+			_ = c.startReconnecting()
+		case <-c.closedCh:
+			stopTimer()
+			return
+		}
+	}
+}
+
+// ReconnectAttempt describes failed connection attempt.
+type ReconnectAttempt struct {
+	// Time when attempt failed.
+	Time time.Time
+	// Error is a reason of failure.
+	Error error
+}
+
+// maxReconnectHistory limits the number of last failed attempts passed to FailedEvent.
+const maxReconnectHistory = 32
+
+// reconnectBudgetExhaustedLocked records failed connection attempt and checks
+// whether client should stop reconnecting.
+// Lock must be held outside.
+func (c *Client) reconnectBudgetExhaustedLocked(err error) bool {
+	if c.config.MaxReconnectAttempts <= 0 && c.config.ReconnectWindow <= 0 {
+		return false
+	}
+	if len(c.reconnectHistory) == 0 {
+		c.reconnectFailures = 0
+	}
+	c.reconnectFailures++
+	if len(c.reconnectHistory) == maxReconnectHistory {
+		// Keep the first attempt to know when reconnecting started.
+		copy(c.reconnectHistory[1:], c.reconnectHistory[2:])
+		c.reconnectHistory = c.reconnectHistory[:len(c.reconnectHistory)-1]
 	}
-	return b.ForAttempt(float64(attempt))
+	c.reconnectHistory = append(c.reconnectHistory, ReconnectAttempt{Time: time.Now(), Error: err})
+	if c.config.MaxReconnectAttempts > 0 && c.reconnectFailures >= c.config.MaxReconnectAttempts {
+		return true
+	}
+	if c.config.ReconnectWindow > 0 && time.Since(c.reconnectHistory[0].Time) >= c.config.ReconnectWindow {
+		return true
+	}
+	return false
+}
+
+// failReconnectLocked moves client to disconnected state after reconnect budget
+// exhausted and emits OnFailed event.
+// Lock must be held outside.
+func (c *Client) failReconnectLocked() {
+	event := FailedEvent{
+		NumAttempts: c.reconnectFailures,
+		Attempts:    c.reconnectHistory,
+	}
+	c.reconnectAttempts = 0
+	c.reconnectFailures = 0
+	if c.logLevelEnabled(LogLevelDebug) {
+		c.log(LogLevelDebug, "reconnect budget exhausted, move to disconnected", map[string]string{
+			"attempts": strconv.Itoa(event.NumAttempts),
+		})
+	}
+	emitEvents := c.moveToDisconnectedLocked(disconnectedReconnectFailed, "reconnect failed")
+	go func() {
+		emitEvents()
+		var handler FailedHandler
+		if c.events != nil && c.events.onFailed != nil {
+			handler = c.events.onFailed
+		}
+		if handler != nil {
+			c.runHandlerAsync(func() {
+				handler(event)
+			})
+		}
+	}()
+}
+
+// retryAfterError is returned by transport when server asked to retry after a
+// delay, e.g. with Retry-After header in 429 response.
+type retryAfterError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+func retryAfterFromError(err error) (time.Duration, bool) {
+	var retryAfterErr *retryAfterError
+	if errors.As(err, &retryAfterErr) {
+		return retryAfterErr.retryAfter, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses Retry-After HTTP header value, which is either a number
+// of seconds or HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// ReconnectCoordinator limits the rate of reconnects of clients which share it
+// using token bucket algorithm. Client reserves a token when scheduling reconnect
+// and waits until the token is available if it's longer than reconnect backoff
+// delay. Initial connect upon Client.Connect call is not limited. Set it over
+// Config.ReconnectCoordinator.
+type ReconnectCoordinator struct {
+	bucket *tokenBucket
+}
+
+// NewReconnectCoordinator creates ReconnectCoordinator which allows up to burst
+// reconnects at once and rate reconnects per second after that. Non-positive rate
+// and burst mean 1.
+func NewReconnectCoordinator(rate float64, burst int) *ReconnectCoordinator {
+	if rate <= 0 {
+		rate = 1
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &ReconnectCoordinator{bucket: newTokenBucket(rate, burst)}
+}
+
+// reserve takes a token and returns the time to wait until it's available.
+func (rc *ReconnectCoordinator) reserve(now time.Time) time.Duration {
+	return rc.bucket.reserve(now)
 }
@@ -0,0 +1,301 @@
+package centrifuge
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestBackoffReconnect(t *testing.T) {
+	r := &backoffReconnect{
+		MinDelay: 100 * time.Millisecond,
+		MaxDelay: time.Second,
+		Factor:   2,
+	}
+	expected := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for attempt, d := range expected {
+		if delay := r.timeBeforeNextAttempt(attempt); delay != d*time.Millisecond {
+			t.Fatalf("attempt %d: expected %s, got %s", attempt, d*time.Millisecond, delay)
+		}
+	}
+
+	r.Jitter = 0.5
+	for attempt := 0; attempt < 10; attempt++ {
+		full := (&backoffReconnect{MinDelay: r.MinDelay, MaxDelay: r.MaxDelay, Factor: 2}).timeBeforeNextAttempt(attempt)
+		delay := r.timeBeforeNextAttempt(attempt)
+		if delay > full || delay < r.MinDelay+(full-r.MinDelay)/2 {
+			t.Fatalf("attempt %d: delay %s out of jitter bounds, full delay %s", attempt, delay, full)
+		}
+	}
+}
+
+func TestClient_ReconnectRandSource(t *testing.T) {
+	delays := func() []time.Duration {
+		client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+			ReconnectRandSource: rand.NewSource(42),
+		})
+		defer client.Close()
+		var result []time.Duration
+		for attempt := 0; attempt < 5; attempt++ {
+			result = append(result, client.reconnectStrategy.timeBeforeNextAttempt(attempt))
+		}
+		return result
+	}
+	first, second := delays(), delays()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("expected deterministic delays, got %v and %v", first, second)
+		}
+	}
+
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+		ReconnectJitter: -1,
+	})
+	defer client.Close()
+	if delay := client.reconnectStrategy.timeBeforeNextAttempt(1); delay != 400*time.Millisecond {
+		t.Fatalf("expected no jitter, got %s", delay)
+	}
+}
+
+func TestClient_ReconnectHammer(t *testing.T) {
+	// Server accepts connections and closes them right away, so client is
+	// constantly failing and rescheduling reconnects.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = conn.Close()
+	}))
+	defer server.Close()
+
+	client := NewJsonClient("ws"+strings.TrimPrefix(server.URL, "http"), Config{})
+	client.reconnectStrategy = &backoffReconnect{MinDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond, Factor: 2}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				switch (i + j) % 3 {
+				case 0:
+					_ = client.Connect()
+				case 1:
+					_ = client.Reconnect()
+				case 2:
+					_ = client.Disconnect()
+				}
+				time.Sleep(time.Duration(j%3) * time.Millisecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	closed := make(chan struct{})
+	go func() {
+		client.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("deadlock: client not closed")
+	}
+}
+
+func TestClient_ReconnectCancel(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+
+	client.mu.Lock()
+	client.state = StateConnecting
+	client.reconnectStrategy = &backoffReconnect{MinDelay: 20 * time.Millisecond, MaxDelay: 20 * time.Millisecond}
+	client.scheduleReconnectLocked(nil)
+	// Cancellation right after scheduling must win.
+	client.state = StateDisconnected
+	client.clearConnectedState()
+	client.mu.Unlock()
+
+	time.Sleep(100 * time.Millisecond)
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	if client.reconnectPending || client.round != 0 {
+		t.Fatal("reconnect must not start after cancellation")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("3"); !ok || d != 3*time.Second {
+		t.Fatalf("unexpected result: %v, %v", d, ok)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d <= 59*time.Minute || d > time.Hour {
+		t.Fatalf("unexpected result: %v, %v", d, ok)
+	}
+	for _, value := range []string{"", "-1", "soon"} {
+		if _, ok := parseRetryAfter(value); ok {
+			t.Fatalf("expected %q to be ignored", value)
+		}
+	}
+}
+
+func TestClient_RetryAfterHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewJsonClient("ws"+strings.TrimPrefix(server.URL, "http"), Config{})
+	defer client.Close()
+	if err := client.Connect(); err == nil {
+		t.Fatal("expected dial error")
+	}
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	if !client.reconnectPending {
+		t.Fatal("reconnect not scheduled")
+	}
+	if client.reconnectDelay != 30*time.Second {
+		t.Fatalf("expected delay from Retry-After, got %v", client.reconnectDelay)
+	}
+}
+
+func TestClient_RetryAfterDisconnect(t *testing.T) {
+	for _, closeRetryAfter := range []bool{false, true} {
+		client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{CloseRetryAfter: closeRetryAfter})
+		disconnected := make(chan DisconnectedEvent, 1)
+		client.OnDisconnected(func(e DisconnectedEvent) {
+			disconnected <- e
+		})
+		client.mu.Lock()
+		client.state = StateConnected
+		client.mu.Unlock()
+
+		d := extractDisconnectWebsocket(&websocket.CloseError{Code: 3501, Text: `{"code":3501,"reason":"overloaded","retry_after":5}`})
+		client.handleDisconnect(d)
+		expected := time.Duration(0)
+		if closeRetryAfter {
+			expected = 5 * time.Second
+		}
+		select {
+		case e := <-disconnected:
+			if e.Code != 3501 || e.RetryAfter != expected {
+				t.Fatalf("unexpected event with CloseRetryAfter %v: %+v", closeRetryAfter, e)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for disconnected event")
+		}
+		client.Close()
+	}
+}
+
+func TestReconnectCoordinator(t *testing.T) {
+	rc := NewReconnectCoordinator(10, 2)
+	now := time.Now()
+	var waits []time.Duration
+	for i := 0; i < 4; i++ {
+		waits = append(waits, rc.reserve(now))
+	}
+	expected := []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond}
+	for i, wait := range waits {
+		if (wait - expected[i]).Abs() > time.Millisecond {
+			t.Fatalf("unexpected waits: %v", waits)
+		}
+	}
+	// Bucket refills over time but not above burst.
+	if wait := rc.reserve(now.Add(time.Hour)); wait != 0 {
+		t.Fatalf("unexpected wait: %v", wait)
+	}
+	if wait := rc.reserve(now.Add(time.Hour)); wait != 0 {
+		t.Fatalf("unexpected wait: %v", wait)
+	}
+	if wait := rc.reserve(now.Add(time.Hour)); wait <= 0 {
+		t.Fatalf("unexpected wait: %v", wait)
+	}
+}
+
+func TestReconnectCoordinator_NonPositiveRate(t *testing.T) {
+	rc := NewReconnectCoordinator(0, 0)
+	now := time.Now()
+	if wait := rc.reserve(now); wait != 0 {
+		t.Fatalf("unexpected wait: %v", wait)
+	}
+	if wait := rc.reserve(now); (wait - time.Second).Abs() > time.Millisecond {
+		t.Fatalf("unexpected wait: %v", wait)
+	}
+}
+
+func TestClient_ReconnectCoordinator(t *testing.T) {
+	rc := NewReconnectCoordinator(1, 1)
+	var delays []time.Duration
+	for i := 0; i < 3; i++ {
+		client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+			ReconnectCoordinator: rc,
+		})
+		client.mu.Lock()
+		client.state = StateConnecting
+		client.scheduleReconnectLocked(nil)
+		delays = append(delays, client.reconnectDelay)
+		client.state = StateDisconnected
+		client.clearConnectedState()
+		client.mu.Unlock()
+		client.Close()
+	}
+	if delays[1] < 900*time.Millisecond || delays[2] < 1900*time.Millisecond {
+		t.Fatalf("reconnects not staggered: %v", delays)
+	}
+}
+
+type testNetworkMonitor struct {
+	notify  chan func()
+	stopped chan struct{}
+}
+
+func (m *testNetworkMonitor) Watch(notify func()) func() {
+	m.notify <- notify
+	return func() { close(m.stopped) }
+}
+
+func TestClient_NetworkMonitor(t *testing.T) {
+	monitor := &testNetworkMonitor{notify: make(chan func(), 1), stopped: make(chan struct{})}
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+		NetworkMonitor: monitor,
+	})
+	notify := <-monitor.notify
+
+	client.mu.Lock()
+	client.state = StateConnecting
+	client.reconnectStrategy = &backoffReconnect{MinDelay: time.Hour, MaxDelay: time.Hour}
+	client.scheduleReconnectLocked(nil)
+	client.mu.Unlock()
+
+	notify()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		client.mu.RLock()
+		round := client.round
+		client.mu.RUnlock()
+		if round > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("network change must trigger reconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	client.Close()
+	select {
+	case <-monitor.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("monitor must be stopped on close")
+	}
+}
@@ -0,0 +1,59 @@
+package centrifuge
+
+import (
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+// recoveringProgressInterval is the number of recovered publications passed to
+// OnPublication between OnRecovering progress events.
+const recoveringProgressInterval = 100
+
+// recoveryProgress reports progress of passing recovered publications to handler.
+// Used from handler goroutine only.
+type recoveryProgress struct {
+	handler   RecoveringHandler
+	total     int
+	recovered int
+	started   time.Time
+}
+
+func (s *Subscription) newRecoveryProgress(total int) *recoveryProgress {
+	p := &recoveryProgress{total: total, started: time.Now()}
+	if s.events != nil && s.events.onRecovering != nil {
+		p.handler = s.events.onRecovering
+	}
+	return p
+}
+
+// advance counts publication passed to handler. Called from handler goroutine.
+func (p *recoveryProgress) advance() {
+	p.recovered++
+	if p.handler == nil {
+		return
+	}
+	if p.recovered%recoveringProgressInterval == 0 || p.recovered == p.total {
+		p.handler(RecoveringEvent{
+			Recovered: p.recovered,
+			Total:     p.total,
+			Elapsed:   time.Since(p.started),
+		})
+	}
+}
+
+// recoveryAbortedLocked tells whether too many publications were recovered, so
+// they must be dropped in favor of fresh state. Lock must be held outside.
+func (s *Subscription) recoveryAbortedLocked(pubs []*protocol.Publication) bool {
+	return s.maxRecovered > 0 && len(pubs) > s.maxRecovered
+}
+
+// emitRecoveryAborted reports that recovered publications were dropped.
+func (s *Subscription) emitRecoveryAborted(total int) {
+	if s.events != nil && s.events.onRecovering != nil {
+		handler := s.events.onRecovering
+		s.centrifuge.runHandlerSync(func() {
+			handler(RecoveringEvent{Total: total, Aborted: true})
+		})
+	}
+}
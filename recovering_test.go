@@ -0,0 +1,130 @@
+package centrifuge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startRecoveringServer recovers numPubs publications upon subscribe.
+func startRecoveringServer(t *testing.T, numPubs int) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var reply string
+			switch {
+			case cmd.Connect != nil:
+				reply = fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id)
+			case cmd.Subscribe != nil:
+				pubs := make([]string, 0, numPubs)
+				for offset := 1; offset <= numPubs; offset++ {
+					pubs = append(pubs, fmt.Sprintf(`{"data":{},"offset":%d}`, offset))
+				}
+				reply = fmt.Sprintf(`{"id":%d,"subscribe":{"recoverable":true,"was_recovering":true,"recovered":true,"epoch":"e","offset":%d,"publications":[%s]}}`, cmd.Id, numPubs, strings.Join(pubs, ","))
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func newRecoveringSubscription(t *testing.T, numPubs int, config SubscriptionConfig) (*Client, *Subscription) {
+	store := NewMemoryPositionStore()
+	_ = store.Save("test", StreamPosition{Epoch: "e"})
+	client := NewJsonClient(startRecoveringServer(t, numPubs), Config{PositionStore: store})
+	t.Cleanup(client.Close)
+	config.Recoverable = true
+	sub, err := client.NewSubscription("test", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, sub
+}
+
+func TestSubscription_OnRecovering(t *testing.T) {
+	client, sub := newRecoveringSubscription(t, 250, SubscriptionConfig{})
+	numPubs := 0
+	sub.OnPublication(func(PublicationEvent) {
+		numPubs++
+	})
+	events := make(chan RecoveringEvent, 8)
+	sub.OnRecovering(func(e RecoveringEvent) {
+		if e.Recovered != numPubs {
+			t.Errorf("expected %d recovered, got %d", numPubs, e.Recovered)
+		}
+		events <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []int{100, 200, 250} {
+		select {
+		case e := <-events:
+			if e.Recovered != expected || e.Total != 250 || e.Aborted {
+				t.Fatalf("unexpected event: %#v", e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for recovering event")
+		}
+	}
+}
+
+func TestSubscription_MaxRecoveredPublications(t *testing.T) {
+	client, sub := newRecoveringSubscription(t, 20, SubscriptionConfig{MaxRecoveredPublications: 10})
+	sub.OnPublication(func(e PublicationEvent) {
+		t.Errorf("unexpected publication %d", e.Offset)
+	})
+	events := make(chan RecoveringEvent, 1)
+	sub.OnRecovering(func(e RecoveringEvent) {
+		events <- e
+	})
+	subscribed := make(chan SubscribedEvent, 1)
+	sub.OnSubscribed(func(e SubscribedEvent) {
+		subscribed <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-events:
+		if !e.Aborted || e.Total != 20 || e.Recovered != 0 {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for recovering event")
+	}
+	select {
+	case e := <-subscribed:
+		if e.Recovered || !e.WasRecovering || e.StreamPosition == nil || e.StreamPosition.Offset != 20 {
+			t.Fatalf("unexpected subscribed event: %#v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for subscribed event")
+	}
+	if offset := sub.processedOffset.Load(); offset != 20 {
+		t.Fatalf("expected processed offset 20, got %d", offset)
+	}
+}
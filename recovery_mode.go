@@ -0,0 +1,29 @@
+package centrifuge
+
+import (
+	"github.com/centrifugal/protocol"
+)
+
+// RecoveryMode defines which missed publications Recoverable subscription passes
+// to OnPublication after resubscribe.
+type RecoveryMode string
+
+const (
+	// RecoveryModeStream passes all missed publications. This is the default.
+	RecoveryModeStream RecoveryMode = ""
+	// RecoveryModeCache passes only the latest missed publication, useful when
+	// channel publications carry the full state, so only the latest one matters.
+	// Server channel may be configured with cache recovery mode too, so it does
+	// not send the whole missed stream.
+	RecoveryModeCache RecoveryMode = "cache"
+)
+
+// recoveredPublicationsLocked returns recovered publications to pass to handler
+// according to recovery mode. Lock must be held outside.
+func (s *Subscription) recoveredPublicationsLocked(res *protocol.SubscribeResult) []*protocol.Publication {
+	pubs := res.Publications
+	if s.recoveryMode == RecoveryModeCache && len(pubs) > 1 {
+		pubs = pubs[len(pubs)-1:]
+	}
+	return pubs
+}
@@ -0,0 +1,76 @@
+package centrifuge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+func TestSubscription_RecoveryModeCache(t *testing.T) {
+	client, sub := newRecoveringSubscription(t, 20, SubscriptionConfig{RecoveryMode: RecoveryModeCache})
+	pubs := make(chan PublicationEvent, 20)
+	sub.OnPublication(func(e PublicationEvent) {
+		pubs <- e
+	})
+	subscribed := make(chan SubscribedEvent, 1)
+	sub.OnSubscribed(func(e SubscribedEvent) {
+		subscribed <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-subscribed:
+		if !e.Recovered {
+			t.Fatalf("expected recovered subscription: %#v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for subscribed event")
+	}
+	select {
+	case e := <-pubs:
+		if e.Offset != 20 {
+			t.Fatalf("expected only the latest publication, got %d", e.Offset)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for publication")
+	}
+	select {
+	case e := <-pubs:
+		t.Fatalf("unexpected publication %d", e.Offset)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscription_RecoveryModeCacheHistory(t *testing.T) {
+	historyCh := make(chan *protocol.HistoryRequest, 1)
+	u := startHistoryRecoveryServer(t, historyCh)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test", SubscriptionConfig{
+		Recoverable:       true,
+		RecoverViaHistory: &HistoryRecoveryConfig{},
+		RecoveryMode:      RecoveryModeCache,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case req := <-historyCh:
+		if req.Since == nil || req.Since.Offset != 9 || req.Limit != 1 {
+			t.Fatalf("unexpected history request: %#v", req)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for history request")
+	}
+}
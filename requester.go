@@ -0,0 +1,165 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrNotRequest returned by DecodeRequest for data which is not a request
+// published by Requester.
+var ErrNotRequest = errors.New("not a request")
+
+// RequestMessage is an envelope of request and reply published by Requester. It's
+// published as JSON object, so it's valid for both JSON and Protobuf protocols.
+type RequestMessage struct {
+	// CorrelationID is the same for request and its reply.
+	CorrelationID string `json:"correlation_id"`
+	// ReplyTo is a channel to publish reply into, empty for reply.
+	ReplyTo string `json:"reply_to,omitempty"`
+	// Data is request or reply payload.
+	Data []byte `json:"data,omitempty"`
+}
+
+// DecodeRequest decodes request published by Requester, reply to it with
+// Client.Reply.
+func DecodeRequest(data []byte) (RequestMessage, error) {
+	var req RequestMessage
+	if err := json.Unmarshal(data, &req); err != nil || req.CorrelationID == "" || req.ReplyTo == "" {
+		return RequestMessage{}, ErrNotRequest
+	}
+	return req, nil
+}
+
+// Reply publishes reply to request decoded with DecodeRequest.
+func (c *Client) Reply(ctx context.Context, req RequestMessage, data []byte) error {
+	reply, err := json.Marshal(RequestMessage{CorrelationID: req.CorrelationID, Data: data})
+	if err != nil {
+		return err
+	}
+	_, err = c.Publish(ctx, req.ReplyTo, reply)
+	return err
+}
+
+// RequesterConfig of Requester.
+type RequesterConfig struct {
+	// OnOrphanReply is called with replies which have no waiting request, e.g.
+	// arrived after request timed out.
+	// Zero value means orphan replies are dropped.
+	OnOrphanReply func(RequestMessage)
+}
+
+// Requester implements request/response over channels: request is published with
+// generated correlation ID and reply is awaited on reply channel of Requester.
+// Create it with Client.NewRequester.
+type Requester struct {
+	client       *Client
+	sub          *Subscription
+	replyChannel string
+	config       RequesterConfig
+
+	mu      sync.Mutex
+	pending map[string]chan []byte
+}
+
+// NewRequester creates Requester which subscribes to replyChannel. Reply channel
+// should be unique for Requester, e.g. personal channel of client.
+func (c *Client) NewRequester(replyChannel string, config ...RequesterConfig) (*Requester, error) {
+	sub, err := c.NewSubscription(replyChannel)
+	if err != nil {
+		return nil, err
+	}
+	r := &Requester{
+		client:       c,
+		sub:          sub,
+		replyChannel: replyChannel,
+		pending:      make(map[string]chan []byte),
+	}
+	if len(config) == 1 {
+		r.config = config[0]
+	}
+	sub.OnPublication(r.handleReply)
+	if err := sub.Subscribe(); err != nil {
+		_ = c.RemoveSubscription(sub)
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Requester) handleReply(e PublicationEvent) {
+	var reply RequestMessage
+	if err := json.Unmarshal(e.Data, &reply); err != nil || reply.CorrelationID == "" {
+		return
+	}
+	r.mu.Lock()
+	ch, ok := r.pending[reply.CorrelationID]
+	delete(r.pending, reply.CorrelationID)
+	r.mu.Unlock()
+	if !ok {
+		if r.config.OnOrphanReply != nil {
+			r.config.OnOrphanReply(reply)
+		}
+		return
+	}
+	ch <- reply.Data
+}
+
+// Request publishes payload into channel and waits for reply until ctx done.
+// Requester must be subscribed to reply channel before request is published, so
+// Request waits for subscription first.
+func (r *Requester) Request(ctx context.Context, channel string, data []byte) ([]byte, error) {
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	req, err := json.Marshal(RequestMessage{CorrelationID: id, ReplyTo: r.replyChannel, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.waitSubscribed(ctx); err != nil {
+		return nil, err
+	}
+	replyCh := make(chan []byte, 1)
+	r.mu.Lock()
+	r.pending[id] = replyCh
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+	}()
+	if _, err := r.client.Publish(ctx, channel, req); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case reply := <-replyCh:
+		return reply, nil
+	}
+}
+
+func (r *Requester) waitSubscribed(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	r.sub.onSubscribe(func(err error) {
+		errCh <- err
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
+
+// Close unsubscribes from reply channel. Pending requests wait until their
+// contexts done.
+func (r *Requester) Close() error {
+	if r.sub.State() != SubStateUnsubscribed {
+		if err := r.sub.Unsubscribe(); err != nil {
+			return err
+		}
+	}
+	return r.client.RemoveSubscription(r.sub)
+}
@@ -0,0 +1,122 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startRequesterServer answers requests published into "service" channel with
+// upper-cased data, preceded by orphan reply. Requests into other channels are
+// not answered.
+func startRequesterServer(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var replies []string
+			switch {
+			case cmd.Connect != nil:
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id))
+			case cmd.Subscribe != nil:
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"subscribe":{}}`, cmd.Id))
+			case cmd.Publish != nil:
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"publish":{}}`, cmd.Id))
+				if cmd.Publish.Channel != "service" {
+					break
+				}
+				req, err := DecodeRequest(cmd.Publish.Data)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for _, reply := range []RequestMessage{
+					{CorrelationID: "orphan", Data: []byte("late")},
+					{CorrelationID: req.CorrelationID, Data: []byte(strings.ToUpper(string(req.Data)))},
+				} {
+					data, _ := json.Marshal(reply)
+					replies = append(replies, fmt.Sprintf(`{"push":{"channel":"%s","pub":{"data":%s}}}`, req.ReplyTo, data))
+				}
+			}
+			for _, reply := range replies {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestRequester(t *testing.T) {
+	client := NewJsonClient(startRequesterServer(t), Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	orphans := make(chan RequestMessage, 1)
+	r, err := client.NewRequester("replies", RequesterConfig{
+		OnOrphanReply: func(m RequestMessage) {
+			orphans <- m
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := r.Request(ctx, "service", []byte("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reply) != "PING" {
+		t.Fatalf("unexpected reply: %s", reply)
+	}
+	select {
+	case m := <-orphans:
+		if m.CorrelationID != "orphan" {
+			t.Fatalf("unexpected orphan: %#v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for orphan reply")
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer timeoutCancel()
+	if _, err := r.Request(timeoutCtx, "silent", []byte("ping")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) != 0 {
+		t.Fatalf("pending requests must be cleaned up: %d", len(r.pending))
+	}
+}
+
+func TestDecodeRequest(t *testing.T) {
+	if _, err := DecodeRequest([]byte(`{"correlation_id":"1"}`)); !errors.Is(err, ErrNotRequest) {
+		t.Fatalf("expected ErrNotRequest for reply, got %v", err)
+	}
+	if _, err := DecodeRequest([]byte(`{}`)); !errors.Is(err, ErrNotRequest) {
+		t.Fatalf("expected ErrNotRequest, got %v", err)
+	}
+}
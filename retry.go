@@ -0,0 +1,120 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// RetryPolicy configures retrying of failed operation, see WithRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	// Zero value means 3.
+	MaxAttempts int
+	// MinDelay is a delay before the first retry, it grows exponentially with
+	// jitter for next retries.
+	// Zero value means 100 milliseconds.
+	MinDelay time.Duration
+	// MaxDelay is the maximum delay between retries.
+	// Zero value means 5 seconds.
+	MaxDelay time.Duration
+	// Retryable decides whether operation failed with err may be retried.
+	// Zero value means IsRetryableError.
+	Retryable func(err error) bool
+}
+
+// RetryAttempt describes failed attempt of operation.
+type RetryAttempt struct {
+	// Time when attempt failed.
+	Time time.Time
+	// Error is a reason of failure.
+	Error error
+}
+
+// RetryError is returned when operation with RetryPolicy failed. It unwraps to
+// the final error.
+type RetryError struct {
+	// Attempts contain all failed attempts in order.
+	Attempts []RetryAttempt
+	// Err is the final error: the error of the last attempt or ctx error if ctx
+	// was done while waiting for retry.
+	Err error
+}
+
+func (e RetryError) Error() string {
+	return fmt.Sprintf("failed after %d attempts: %v", len(e.Attempts), e.Err)
+}
+
+func (e RetryError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryableError tells whether operation failed with err may succeed when
+// retried: timeouts, connection problems, temporary server errors, server internal
+// errors and rate limiting (including ErrRateLimited). Permission and validation errors are not retryable.
+func IsRetryableError(err error) bool {
+	if errors.Is(err, ErrTimeout) || errors.Is(err, ErrClientDisconnected) || errors.Is(err, io.EOF) || errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var transportErr TransportError
+	if errors.As(err, &transportErr) {
+		return true
+	}
+	var serverErr *Error
+	if errors.As(err, &serverErr) {
+		// 100 is internal server error, 111 is too many requests.
+		return serverErr.Temporary || serverErr.Code == 100 || serverErr.Code == 111
+	}
+	return false
+}
+
+// withRetry calls fn until it succeeds or policy exhausted. Without policy fn is
+// called once and its error returned as is.
+func withRetry[T any](ctx context.Context, policy *RetryPolicy, fn func() (T, error)) (T, error) {
+	if policy == nil {
+		return fn()
+	}
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	minDelay := policy.MinDelay
+	if minDelay <= 0 {
+		minDelay = 100 * time.Millisecond
+	}
+	maxDelay := policy.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 5 * time.Second
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryableError
+	}
+	backoff := &backoffReconnect{
+		MinDelay: minDelay,
+		MaxDelay: maxDelay,
+		Factor:   defaultBackoffReconnect.Factor,
+		Jitter:   defaultBackoffReconnect.Jitter,
+	}
+	var zero T
+	var attempts []RetryAttempt
+	for attempt := 0; ; attempt++ {
+		res, err := fn()
+		if err == nil {
+			return res, nil
+		}
+		attempts = append(attempts, RetryAttempt{Time: time.Now(), Error: err})
+		if ctx.Err() != nil || attempt+1 >= maxAttempts || !retryable(err) {
+			return zero, RetryError{Attempts: attempts, Err: err}
+		}
+		timer := time.NewTimer(backoff.timeBeforeNextAttempt(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, RetryError{Attempts: attempts, Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// startPublishErrorServer accepts connection and replies to publish commands with
+// errors in order, publications after that succeed.
+func startPublishErrorServer(t *testing.T, errs ...string) (string, *atomic.Int32) {
+	numPublishes := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd struct {
+				ID      uint32          `json:"id"`
+				Connect json.RawMessage `json:"connect"`
+			}
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var reply string
+			if cmd.Connect != nil {
+				reply = fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.ID)
+			} else if n := int(numPublishes.Add(1)); n <= len(errs) {
+				reply = fmt.Sprintf(`{"id":%d,"error":%s}`, cmd.ID, errs[n-1])
+			} else {
+				reply = fmt.Sprintf(`{"id":%d,"publish":{}}`, cmd.ID)
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http"), numPublishes
+}
+
+func TestIsRetryableError(t *testing.T) {
+	testCases := []struct {
+		err       error
+		retryable bool
+	}{
+		{ErrTimeout, true},
+		{ErrClientDisconnected, true},
+		{io.EOF, true},
+		{TransportError{errors.New("boom")}, true},
+		{&Error{Code: 100, Message: "internal server error"}, true},
+		{&Error{Code: 111, Message: "too many requests"}, true},
+		{&Error{Code: 2000, Message: "custom", Temporary: true}, true},
+		{&Error{Code: 103, Message: "permission denied"}, false},
+		{&Error{Code: 101, Message: "unauthorized"}, false},
+		{ErrClientClosed, false},
+		{context.Canceled, false},
+	}
+	for _, tc := range testCases {
+		if got := IsRetryableError(tc.err); got != tc.retryable {
+			t.Errorf("IsRetryableError(%v) = %v, want %v", tc.err, got, tc.retryable)
+		}
+	}
+}
+
+func TestClient_PublishWithRetry(t *testing.T) {
+	u, numPublishes := startPublishErrorServer(t,
+		`{"code":100,"message":"internal server error"}`,
+		`{"code":111,"message":"too many requests"}`,
+	)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.Publish(ctx, "test", []byte("{}"), WithRetry(RetryPolicy{MinDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := numPublishes.Load(); n != 3 {
+		t.Fatalf("expected 3 publish attempts, got %d", n)
+	}
+}
+
+func TestClient_PublishWithRetryNotRetryable(t *testing.T) {
+	u, numPublishes := startPublishErrorServer(t,
+		`{"code":100,"message":"internal server error"}`,
+		`{"code":103,"message":"permission denied"}`,
+	)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.Publish(ctx, "test", []byte("{}"), WithRetry(RetryPolicy{MaxAttempts: 5, MinDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}))
+	var retryErr RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected RetryError, got %v", err)
+	}
+	if len(retryErr.Attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(retryErr.Attempts))
+	}
+	var serverErr *Error
+	if !errors.As(err, &serverErr) || serverErr.Code != 103 {
+		t.Fatalf("expected permission denied error, got %v", err)
+	}
+	if n := numPublishes.Load(); n != 2 {
+		t.Fatalf("expected 2 publish attempts, got %d", n)
+	}
+}
+
+func TestClient_PublishWithRetryExhausted(t *testing.T) {
+	u, numPublishes := startPublishErrorServer(t,
+		`{"code":100,"message":"internal server error"}`,
+		`{"code":100,"message":"internal server error"}`,
+		`{"code":100,"message":"internal server error"}`,
+	)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.Publish(ctx, "test", []byte("{}"), WithRetry(RetryPolicy{MaxAttempts: 2, MinDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}))
+	var retryErr RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected RetryError, got %v", err)
+	}
+	if len(retryErr.Attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(retryErr.Attempts))
+	}
+	if n := numPublishes.Load(); n != 2 {
+		t.Fatalf("expected 2 publish attempts, got %d", n)
+	}
+}
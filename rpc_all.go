@@ -0,0 +1,75 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const defaultRPCAllConcurrency = 16
+
+// RPCRequest describes one RPC call of RPCAll.
+type RPCRequest struct {
+	Method  string
+	Data    []byte
+	Options []RPCOption
+}
+
+// RPCAllOptions are options for RPCAll.
+type RPCAllOptions struct {
+	// Concurrency is a maximum number of RPCs in flight. Zero value means 16.
+	Concurrency int
+}
+
+// RPCAllOption is a way to configure RPCAll.
+type RPCAllOption func(options *RPCAllOptions)
+
+// WithRPCAllConcurrency limits the number of RPCs RPCAll keeps in flight.
+func WithRPCAllConcurrency(concurrency int) RPCAllOption {
+	return func(options *RPCAllOptions) {
+		options.Concurrency = concurrency
+	}
+}
+
+// RPCAll sends calls concurrently over the client connection. Results are
+// returned in order of calls, failed calls have zero RPCResult. Returned error
+// joins RPCCallError of every failed call, so it's nil only if all calls
+// succeeded.
+func (c *Client) RPCAll(ctx context.Context, calls []RPCRequest, opts ...RPCAllOption) ([]RPCResult, error) {
+	allOpts := &RPCAllOptions{}
+	for _, opt := range opts {
+		opt(allOpts)
+	}
+	concurrency := allOpts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultRPCAllConcurrency
+	}
+
+	results := make([]RPCResult, len(calls))
+	errs := make([]error, len(calls))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = RPCCallError{Index: i, Method: call.Method, Err: ctx.Err()}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res, err := c.RPC(ctx, call.Method, call.Data, call.Options...)
+			if err != nil {
+				errs[i] = RPCCallError{Index: i, Method: call.Method, Err: err}
+				return
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,111 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startRPCAllServer replies to RPC with method name as data, "fail" method
+// gets an error. Replies are delayed to keep several RPCs in flight.
+func startRPCAllServer(t *testing.T, inflight, maxInflight *int32) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		replies := make(chan string, 64)
+		go func() {
+			for reply := range replies {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+					return
+				}
+			}
+		}()
+		defer close(replies)
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			switch {
+			case cmd.Connect != nil:
+				replies <- fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id)
+			case cmd.Rpc != nil:
+				n := atomic.AddInt32(inflight, 1)
+				for {
+					m := atomic.LoadInt32(maxInflight)
+					if n <= m || atomic.CompareAndSwapInt32(maxInflight, m, n) {
+						break
+					}
+				}
+				id, method := cmd.Id, cmd.Rpc.Method
+				time.AfterFunc(20*time.Millisecond, func() {
+					atomic.AddInt32(inflight, -1)
+					if method == "fail" {
+						replies <- fmt.Sprintf(`{"id":%d,"error":{"code":107,"message":"bad request"}}`, id)
+						return
+					}
+					replies <- fmt.Sprintf(`{"id":%d,"rpc":{"data":%q}}`, id, method)
+				})
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestClient_RPCAll(t *testing.T) {
+	var inflight, maxInflight int32
+	client := NewJsonClient(startRPCAllServer(t, &inflight, &maxInflight), Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	var calls []RPCRequest
+	for i := 0; i < 10; i++ {
+		method := fmt.Sprintf("m%d", i)
+		if i == 3 || i == 7 {
+			method = "fail"
+		}
+		calls = append(calls, RPCRequest{Method: method})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := client.RPCAll(ctx, calls, WithRPCAllConcurrency(3))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	var failed []int
+	for i, res := range results {
+		if i == 3 || i == 7 {
+			continue
+		}
+		if want := fmt.Sprintf(`"m%d"`, i); string(res.Data) != want {
+			t.Fatalf("result %d: expected %s, got %s", i, want, res.Data)
+		}
+	}
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var callErr RPCCallError
+		if !errors.As(e, &callErr) {
+			t.Fatalf("unexpected error %v", e)
+		}
+		failed = append(failed, callErr.Index)
+	}
+	if fmt.Sprint(failed) != "[3 7]" {
+		t.Fatalf("unexpected failed calls %v", failed)
+	}
+	if m := atomic.LoadInt32(&maxInflight); m > 3 || m < 2 {
+		t.Fatalf("unexpected max in flight %d", m)
+	}
+}
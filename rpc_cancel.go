@@ -0,0 +1,49 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+)
+
+// rpcCancelNotice is data of Config.RPCCancelMethod call.
+type rpcCancelNotice struct {
+	ID uint32 `json:"id"`
+}
+
+// watchRPCCancel calls Config.RPCCancelMethod if ctx done before RPC command
+// replied. Returned function must be called once reply received or command
+// failed.
+func (c *Client) watchRPCCancel(ctx context.Context, method string, cmdID uint32) func() {
+	cancelMethod := c.config.RPCCancelMethod
+	if cancelMethod == "" || method == cancelMethod || ctx.Done() == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+		data, err := json.Marshal(rpcCancelNotice{ID: cmdID})
+		if err != nil {
+			return
+		}
+		noticeCtx, cancel := context.WithTimeout(context.Background(), c.config.ReadTimeout)
+		c.sendRPC(noticeCtx, cancelMethod, data, RPCOptions{Priority: PriorityHigh}, func(_ RPCResult, err error) {
+			cancel()
+			if err != nil && c.logLevelEnabled(LogLevelDebug) {
+				c.log(LogLevelDebug, "error sending RPC cancellation", map[string]string{
+					"id":    strconv.FormatUint(uint64(cmdID), 10),
+					"error": err.Error(),
+				})
+			}
+		})
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
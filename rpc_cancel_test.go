@@ -0,0 +1,108 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startRPCCancelServer never replies to "slow" RPC, other RPC requests are sent
+// to rpcCh and replied.
+func startRPCCancelServer(t *testing.T, rpcCh chan<- *protocol.Command) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var reply string
+			switch {
+			case cmd.Connect != nil:
+				reply = fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id)
+			case cmd.Rpc != nil && cmd.Rpc.Method == "slow":
+				rpcCh <- &cmd
+				continue
+			case cmd.Rpc != nil:
+				rpcCh <- &cmd
+				reply = fmt.Sprintf(`{"id":%d,"rpc":{}}`, cmd.Id)
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestClient_RPCCancelMethod(t *testing.T) {
+	rpcCh := make(chan *protocol.Command, 2)
+	client := NewJsonClient(startRPCCancelServer(t, rpcCh), Config{RPCCancelMethod: "cancel"})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := client.RPC(ctx, "slow", nil)
+		errCh <- err
+	}()
+	var slowID uint32
+	select {
+	case cmd := <-rpcCh:
+		slowID = cmd.Id
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for RPC")
+	}
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+	select {
+	case cmd := <-rpcCh:
+		var notice rpcCancelNotice
+		if err := json.Unmarshal(cmd.Rpc.Data, &notice); err != nil {
+			t.Fatal(err)
+		}
+		if cmd.Rpc.Method != "cancel" || notice.ID != slowID {
+			t.Fatalf("unexpected cancellation notice: %s %s", cmd.Rpc.Method, cmd.Rpc.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for cancellation notice")
+	}
+}
+
+func TestClient_RPCCancelMethod_Replied(t *testing.T) {
+	rpcCh := make(chan *protocol.Command, 2)
+	client := NewJsonClient(startRPCCancelServer(t, rpcCh), Config{RPCCancelMethod: "cancel"})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := client.RPC(ctx, "fast", nil); err != nil {
+		t.Fatal(err)
+	}
+	<-rpcCh
+	cancel()
+	select {
+	case cmd := <-rpcCh:
+		t.Fatalf("unexpected RPC after reply: %s", cmd.Rpc.Method)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,55 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// RPCEnvelope wraps RPC data sent with WithRPCDeadlinePropagation. It's sent as
+// JSON object, so it's valid for both JSON and Protobuf protocols. Server
+// handler decodes it with DecodeRPCEnvelope.
+type RPCEnvelope struct {
+	// Timeout is remaining time of caller context deadline in milliseconds when
+	// RPC was sent. Zero means caller has no deadline. Remaining time is sent
+	// instead of deadline itself, so clock difference does not matter.
+	Timeout int64 `json:"timeout_ms,omitempty"`
+	// Data of RPC.
+	Data []byte `json:"data,omitempty"`
+}
+
+// WithRPCDeadlinePropagation wraps RPC data into RPCEnvelope with remaining time
+// of context deadline, so server handler can budget its work.
+func WithRPCDeadlinePropagation() RPCOption {
+	return func(options *RPCOptions) {
+		options.PropagateDeadline = true
+	}
+}
+
+// DecodeRPCEnvelope decodes RPCEnvelope.
+func DecodeRPCEnvelope(data []byte) (RPCEnvelope, error) {
+	var envelope RPCEnvelope
+	err := json.Unmarshal(data, &envelope)
+	return envelope, err
+}
+
+// Context returns context with envelope deadline, context is not canceled if
+// envelope has no deadline.
+func (e RPCEnvelope) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	if e.Timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, time.Duration(e.Timeout)*time.Millisecond)
+}
+
+func deadlineEnvelope(ctx context.Context, data []byte) ([]byte, error) {
+	envelope := RPCEnvelope{Data: data}
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline).Milliseconds()
+		if remaining <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		envelope.Timeout = remaining
+	}
+	return json.Marshal(envelope)
+}
@@ -0,0 +1,49 @@
+package centrifuge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+func TestClient_RPCDeadlinePropagation(t *testing.T) {
+	rpcCh := make(chan *protocol.Command, 2)
+	client := NewJsonClient(startRPCCancelServer(t, rpcCh), Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.RPC(ctx, "method", []byte(`{"x":1}`), WithRPCDeadlinePropagation()); err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := DecodeRPCEnvelope((<-rpcCh).Rpc.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Timeout <= 0 || envelope.Timeout > 5000 {
+		t.Fatalf("unexpected timeout %d", envelope.Timeout)
+	}
+	if string(envelope.Data) != `{"x":1}` {
+		t.Fatalf("unexpected data %s", envelope.Data)
+	}
+
+	if _, err := client.RPC(context.Background(), "method", []byte(`{}`), WithRPCDeadlinePropagation()); err != nil {
+		t.Fatal(err)
+	}
+	envelope, err = DecodeRPCEnvelope((<-rpcCh).Rpc.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Timeout != 0 {
+		t.Fatalf("expected no timeout, got %d", envelope.Timeout)
+	}
+	envCtx, envCancel := envelope.Context(context.Background())
+	defer envCancel()
+	if _, ok := envCtx.Deadline(); ok {
+		t.Fatal("expected context without deadline")
+	}
+}
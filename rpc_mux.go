@@ -0,0 +1,109 @@
+package centrifuge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// RPCMiddleware wraps handler of server-initiated RPC method.
+type RPCMiddleware func(method string, next ServerRPCHandler) ServerRPCHandler
+
+// RPCMux routes server-initiated RPC to handlers registered by method, analogous
+// to http.ServeMux. Set it with Client.SetRPCMux. Safe for concurrent use.
+type RPCMux struct {
+	mu         sync.RWMutex
+	handlers   map[string]ServerRPCHandler
+	middleware []RPCMiddleware
+	notFound   ServerRPCHandler
+}
+
+// NewRPCMux creates RPCMux.
+func NewRPCMux() *RPCMux {
+	return &RPCMux{handlers: make(map[string]ServerRPCHandler)}
+}
+
+// Handle registers handler of method, replacing previous one.
+func (m *RPCMux) Handle(method string, handler ServerRPCHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[method] = handler
+}
+
+// Use appends middleware applied to all handlers including not found handler.
+// The first middleware is the outermost one.
+func (m *RPCMux) Use(middleware ...RPCMiddleware) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.middleware = append(m.middleware, middleware...)
+}
+
+// NotFound sets handler called for methods without handler. By default Error
+// with ServerRPCErrorMethodNotFound code returned.
+func (m *RPCMux) NotFound(handler ServerRPCHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notFound = handler
+}
+
+// ServeRPC calls handler of method wrapped with middleware.
+func (m *RPCMux) ServeRPC(ctx context.Context, method string, data []byte) ([]byte, error) {
+	m.mu.RLock()
+	handler, ok := m.handlers[method]
+	if !ok {
+		handler = m.notFound
+		if handler == nil {
+			handler = methodNotFound
+		}
+	}
+	middleware := m.middleware
+	m.mu.RUnlock()
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](method, handler)
+	}
+	return handler(ctx, data)
+}
+
+func methodNotFound(context.Context, []byte) ([]byte, error) {
+	return nil, &Error{Code: ServerRPCErrorMethodNotFound, Message: "method not found"}
+}
+
+// RecoverRPC returns middleware which turns handler panic into error with
+// ServerRPCErrorInternal code.
+func RecoverRPC() RPCMiddleware {
+	return func(method string, next ServerRPCHandler) ServerRPCHandler {
+		return func(ctx context.Context, data []byte) (res []byte, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					res = nil
+					err = &Error{Code: ServerRPCErrorInternal, Message: fmt.Sprintf("panic in %s: %v", method, r)}
+				}
+			}()
+			return next(ctx, data)
+		}
+	}
+}
+
+// LogRPC returns middleware which logs every call with method, duration and error.
+func LogRPC(logger *slog.Logger) RPCMiddleware {
+	return func(method string, next ServerRPCHandler) ServerRPCHandler {
+		return func(ctx context.Context, data []byte) ([]byte, error) {
+			started := time.Now()
+			res, err := next(ctx, data)
+			if err != nil {
+				logger.LogAttrs(ctx, slog.LevelWarn, "rpc handler error", slog.String("method", method), slog.Duration("duration", time.Since(started)), slog.String("error", err.Error()))
+			} else {
+				logger.LogAttrs(ctx, slog.LevelDebug, "rpc handled", slog.String("method", method), slog.Duration("duration", time.Since(started)))
+			}
+			return res, err
+		}
+	}
+}
+
+// SetRPCMux sets RPCMux which handles server-initiated RPC, replacing handlers
+// registered with OnRPC. Must be called before Connect.
+func (c *Client) SetRPCMux(mux *RPCMux) {
+	c.events.rpcMux = mux
+}
@@ -0,0 +1,69 @@
+package centrifuge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRPCMux(t *testing.T) {
+	mux := NewRPCMux()
+	var calls []string
+	trace := func(name string) RPCMiddleware {
+		return func(method string, next ServerRPCHandler) ServerRPCHandler {
+			return func(ctx context.Context, data []byte) ([]byte, error) {
+				calls = append(calls, name+":"+method)
+				return next(ctx, data)
+			}
+		}
+	}
+	mux.Use(trace("outer"), trace("inner"))
+	mux.Handle("echo", func(_ context.Context, data []byte) ([]byte, error) {
+		return data, nil
+	})
+
+	res, err := mux.ServeRPC(context.Background(), "echo", []byte("hi"))
+	if err != nil || string(res) != "hi" {
+		t.Fatalf("unexpected result: %s, %v", res, err)
+	}
+	if strings.Join(calls, ",") != "outer:echo,inner:echo" {
+		t.Fatalf("unexpected middleware order: %v", calls)
+	}
+
+	_, err = mux.ServeRPC(context.Background(), "missing", nil)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != ServerRPCErrorMethodNotFound {
+		t.Fatalf("expected method not found error, got %v", err)
+	}
+	if len(calls) != 4 {
+		t.Fatalf("middleware must wrap not found handler: %v", calls)
+	}
+
+	mux.NotFound(func(context.Context, []byte) ([]byte, error) {
+		return []byte("fallback"), nil
+	})
+	res, err = mux.ServeRPC(context.Background(), "missing", nil)
+	if err != nil || string(res) != "fallback" {
+		t.Fatalf("unexpected not found result: %s, %v", res, err)
+	}
+}
+
+func TestRPCMux_RecoverAndLog(t *testing.T) {
+	var buf bytes.Buffer
+	mux := NewRPCMux()
+	mux.Use(LogRPC(slog.New(slog.NewTextHandler(&buf, nil))), RecoverRPC())
+	mux.Handle("panic", func(context.Context, []byte) ([]byte, error) {
+		panic("boom")
+	})
+	_, err := mux.ServeRPC(context.Background(), "panic", nil)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != ServerRPCErrorInternal || !strings.Contains(e.Message, "boom") {
+		t.Fatalf("expected internal error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "method=panic") {
+		t.Fatalf("expected log entry, got %q", buf.String())
+	}
+}
@@ -0,0 +1,158 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Tags of publication which ends RPC stream.
+const (
+	// RPCStreamStatusTag is a publication tag which ends RPCStream. Its value is
+	// RPCStreamStatusDone or RPCStreamStatusError.
+	RPCStreamStatusTag = "stream_status"
+	// RPCStreamStatusDone ends stream successfully, publication data is skipped.
+	RPCStreamStatusDone = "done"
+	// RPCStreamStatusError ends stream with error, publication data is an error
+	// message.
+	RPCStreamStatusError = "error"
+)
+
+// ErrRPCStreamFailed wraps error message of stream ended with
+// RPCStreamStatusError.
+var ErrRPCStreamFailed = errors.New("rpc stream failed")
+
+// rpcStreamReply is a reply of RPC which starts stream.
+type rpcStreamReply struct {
+	Channel string `json:"channel"`
+}
+
+// RPCStream receives publications of ephemeral channel returned by RPC, see
+// Client.StreamRPC. Not safe for concurrent Recv calls.
+type RPCStream struct {
+	client *Client
+	sub    *Subscription
+
+	mu     sync.Mutex
+	queue  [][]byte
+	err    error
+	ended  bool
+	notify chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// StreamRPC sends RPC which starts stream on server and returns RPCStream. Server
+// must reply with JSON object {"channel": "..."} with name of ephemeral channel,
+// publish stream items into it after client subscribed and finish stream with
+// publication tagged RPCStreamStatusTag. Channel is unsubscribed when stream
+// finished, Close does it earlier.
+func (c *Client) StreamRPC(ctx context.Context, method string, data []byte, opts ...RPCOption) (*RPCStream, error) {
+	res, err := c.RPC(ctx, method, data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var reply rpcStreamReply
+	if err := json.Unmarshal(res.Data, &reply); err != nil {
+		return nil, fmt.Errorf("error decoding stream reply: %w", err)
+	}
+	if reply.Channel == "" {
+		return nil, errors.New("stream reply has no channel")
+	}
+	sub, err := c.NewSubscription(reply.Channel)
+	if err != nil {
+		return nil, err
+	}
+	stream := &RPCStream{client: c, sub: sub, notify: make(chan struct{}, 1)}
+	sub.OnPublication(stream.handlePublication)
+	sub.OnUnsubscribed(func(e UnsubscribedEvent) {
+		stream.end(fmt.Errorf("%w: %s", ErrSubscriptionUnsubscribed, e.Reason))
+	})
+	if err := sub.Subscribe(); err != nil {
+		_ = c.RemoveSubscription(sub)
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (s *RPCStream) handlePublication(e PublicationEvent) {
+	switch e.Tags[RPCStreamStatusTag] {
+	case RPCStreamStatusDone:
+		s.end(ErrIteratorDone)
+		// Can't unsubscribe from event handler synchronously.
+		go func() { _ = s.Close() }()
+	case RPCStreamStatusError:
+		s.end(fmt.Errorf("%w: %s", ErrRPCStreamFailed, e.Data))
+		go func() { _ = s.Close() }()
+	default:
+		s.mu.Lock()
+		if !s.ended {
+			s.queue = append(s.queue, e.Data)
+		}
+		s.mu.Unlock()
+		s.wake()
+	}
+}
+
+// end finishes stream with error returned by Recv after queued items.
+func (s *RPCStream) end(err error) {
+	s.mu.Lock()
+	if !s.ended {
+		s.ended = true
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.wake()
+}
+
+func (s *RPCStream) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Recv returns data of the next stream item. Returns ErrIteratorDone when stream
+// finished, error wrapping ErrRPCStreamFailed when server failed stream.
+func (s *RPCStream) Recv(ctx context.Context) ([]byte, error) {
+	for {
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			data := s.queue[0]
+			s.queue[0] = nil
+			s.queue = s.queue[1:]
+			s.mu.Unlock()
+			return data, nil
+		}
+		if s.ended {
+			err := s.err
+			s.mu.Unlock()
+			return nil, err
+		}
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.notify:
+		}
+	}
+}
+
+// Close unsubscribes from stream channel. Recv returns ErrIteratorDone after
+// Close unless stream already ended.
+func (s *RPCStream) Close() error {
+	s.end(ErrIteratorDone)
+	s.closeOnce.Do(func() {
+		if s.sub.State() != SubStateUnsubscribed {
+			if err := s.sub.Unsubscribe(); err != nil {
+				s.closeErr = err
+				return
+			}
+		}
+		s.closeErr = s.client.RemoveSubscription(s.sub)
+	})
+	return s.closeErr
+}
@@ -0,0 +1,105 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startRPCStreamServer replies to "job" RPC with stream channel and publishes
+// items into it once subscribed. Stream ends with status tag.
+func startRPCStreamServer(t *testing.T, status string, unsubCh chan<- string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			var replies []string
+			switch {
+			case cmd.Connect != nil:
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id))
+			case cmd.Rpc != nil:
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"rpc":{"data":{"channel":"job:1"}}}`, cmd.Id))
+			case cmd.Subscribe != nil:
+				replies = append(replies,
+					fmt.Sprintf(`{"id":%d,"subscribe":{}}`, cmd.Id),
+					`{"push":{"channel":"job:1","pub":{"data":{"progress":50}}}}`,
+					`{"push":{"channel":"job:1","pub":{"data":{"progress":100}}}}`,
+					fmt.Sprintf(`{"push":{"channel":"job:1","pub":{"data":"failed","tags":{"stream_status":"%s"}}}}`, status),
+				)
+			case cmd.Unsubscribe != nil:
+				unsubCh <- cmd.Unsubscribe.Channel
+				replies = append(replies, fmt.Sprintf(`{"id":%d,"unsubscribe":{}}`, cmd.Id))
+			}
+			for _, reply := range replies {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestClient_StreamRPC(t *testing.T) {
+	for _, status := range []string{RPCStreamStatusDone, RPCStreamStatusError} {
+		t.Run(status, func(t *testing.T) {
+			unsubCh := make(chan string, 1)
+			client := NewJsonClient(startRPCStreamServer(t, status, unsubCh), Config{})
+			defer client.Close()
+			if err := client.Connect(); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			stream, err := client.StreamRPC(ctx, "job", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var items []string
+			for {
+				data, err := stream.Recv(ctx)
+				if err != nil {
+					if status == RPCStreamStatusDone && !errors.Is(err, ErrIteratorDone) {
+						t.Fatalf("expected ErrIteratorDone, got %v", err)
+					}
+					if status == RPCStreamStatusError && (!errors.Is(err, ErrRPCStreamFailed) || !strings.Contains(err.Error(), "failed")) {
+						t.Fatalf("expected ErrRPCStreamFailed, got %v", err)
+					}
+					break
+				}
+				items = append(items, string(data))
+			}
+			if strings.Join(items, ",") != `{"progress":50},{"progress":100}` {
+				t.Fatalf("unexpected items: %v", items)
+			}
+			select {
+			case channel := <-unsubCh:
+				if channel != "job:1" {
+					t.Fatalf("unexpected unsubscribe from %s", channel)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for unsubscribe")
+			}
+			waitFor(t, func() bool {
+				_, ok := client.GetSubscription("job:1")
+				return !ok
+			})
+		})
+	}
+}
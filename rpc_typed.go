@@ -0,0 +1,67 @@
+package centrifuge
+
+import (
+	"context"
+	"fmt"
+)
+
+// RPCCodec encodes and decodes typed RPC payloads. Any Codec is RPCCodec.
+type RPCCodec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// WithRPCCodec sets codec of RPCCall and RegisterRPC payloads.
+func WithRPCCodec(codec RPCCodec) RPCOption {
+	return func(options *RPCOptions) {
+		options.Codec = codec
+	}
+}
+
+func rpcCodec(opts []RPCOption, fallback RPCCodec) RPCCodec {
+	rpcOpts := RPCOptions{}
+	for _, opt := range opts {
+		opt(&rpcOpts)
+	}
+	if rpcOpts.Codec == nil {
+		return fallback
+	}
+	return rpcOpts.Codec
+}
+
+// RPCCall sends RPC with encoded req and decodes result into Resp. Payloads are
+// encoded with Config.Codec unless WithRPCCodec used. Server errors are returned
+// as *Error like from Client.RPC.
+func RPCCall[Req any, Resp any](ctx context.Context, c *Client, method string, req Req, opts ...RPCOption) (Resp, error) {
+	var resp Resp
+	codec := rpcCodec(opts, c.Codec())
+	data, err := codec.Marshal(req)
+	if err != nil {
+		return resp, fmt.Errorf("error encoding RPC request: %w", err)
+	}
+	res, err := c.RPC(ctx, method, data, opts...)
+	if err != nil {
+		return resp, err
+	}
+	if err := codec.Unmarshal(res.Data, &resp); err != nil {
+		return resp, fmt.Errorf("error decoding RPC result: %w", err)
+	}
+	return resp, nil
+}
+
+// RegisterRPC registers typed handler of server-initiated RPC method in mux.
+// Request which can't be decoded is replied with ServerRPCErrorBadRequest code.
+func RegisterRPC[Req any, Resp any](mux *RPCMux, method string, handler func(ctx context.Context, req Req) (Resp, error), opts ...RPCOption) {
+	codec := rpcCodec(opts, JSONCodec{})
+	mux.Handle(method, func(ctx context.Context, data []byte) ([]byte, error) {
+		var req Req
+		if err := codec.Unmarshal(data, &req); err != nil {
+			return nil, &Error{Code: ServerRPCErrorBadRequest, Message: "bad request"}
+		}
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return codec.Marshal(resp)
+	})
+}
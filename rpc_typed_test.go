@@ -0,0 +1,86 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type sumRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type sumResult struct {
+	Sum int `json:"sum"`
+}
+
+func TestRegisterRPC(t *testing.T) {
+	mux := NewRPCMux()
+	RegisterRPC(mux, "sum", func(_ context.Context, req sumRequest) (sumResult, error) {
+		if req.A < 0 {
+			return sumResult{}, &Error{Code: 1000, Message: "negative"}
+		}
+		return sumResult{Sum: req.A + req.B}, nil
+	})
+	res, err := mux.ServeRPC(context.Background(), "sum", []byte(`{"a":1,"b":2}`))
+	if err != nil || string(res) != `{"sum":3}` {
+		t.Fatalf("unexpected result: %s, %v", res, err)
+	}
+	var e *Error
+	if _, err := mux.ServeRPC(context.Background(), "sum", []byte(`{`)); !errors.As(err, &e) || e.Code != ServerRPCErrorBadRequest {
+		t.Fatalf("expected bad request error, got %v", err)
+	}
+	if _, err := mux.ServeRPC(context.Background(), "sum", []byte(`{"a":-1}`)); !errors.As(err, &e) || e.Code != 1000 {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+}
+
+// decimalCodec encodes ints as decimal strings.
+type decimalCodec struct{}
+
+func (decimalCodec) Marshal(v any) ([]byte, error) {
+	return []byte(strconv.Itoa(v.(int))), nil
+}
+
+func (decimalCodec) Unmarshal(data []byte, v any) error {
+	n, err := strconv.Atoi(string(data))
+	*(v.(*int)) = n
+	return err
+}
+
+func TestRegisterRPC_Codec(t *testing.T) {
+	mux := NewRPCMux()
+	RegisterRPC(mux, "double", func(_ context.Context, n int) (int, error) {
+		return n * 2, nil
+	}, WithRPCCodec(decimalCodec{}))
+	res, err := mux.ServeRPC(context.Background(), "double", []byte("21"))
+	if err != nil || string(res) != "42" {
+		t.Fatalf("unexpected result: %s, %v", res, err)
+	}
+}
+
+func TestRPCCall(t *testing.T) {
+	client := NewJsonClient(startRPCStreamServer(t, RPCStreamStatusDone, make(chan string, 1)), Config{})
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	type jobResult struct {
+		Channel string `json:"channel"`
+	}
+	res, err := RPCCall[sumRequest, jobResult](ctx, client, "job", sumRequest{A: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Channel != "job:1" {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+	if _, err := RPCCall[sumRequest, int](ctx, client, "job", sumRequest{}); err == nil {
+		t.Fatal("expected decode error")
+	}
+}
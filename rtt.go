@@ -0,0 +1,82 @@
+package centrifuge
+
+import (
+	"context"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+// RTT returns the last measured round trip time of ping command. Zero if not
+// measured yet for current connection. See Config.MeasureRTT.
+func (c *Client) RTT() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rtt
+}
+
+// Healthy actively checks connection: it sends ping command to server and waits
+// for reply. Unlike Client.State it detects connections which are broken but not
+// closed yet, so it's suitable for readiness probes. Returns ErrClientDisconnected
+// if client is not connected, ErrTimeout if server has not replied within
+// Config.ReadTimeout, or ctx error if ctx done before reply.
+func (c *Client) Healthy(ctx context.Context) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+	if !c.isConnected() {
+		return ErrClientDisconnected
+	}
+	errCh := make(chan error, 1)
+	if err := c.ping(func(_ time.Duration, err error) {
+		errCh <- err
+	}); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
+
+// ping sends ping command to server and calls fn with round trip time when reply
+// received. Error reply from server still measures round trip, so fn only gets
+// transport errors and timeouts.
+func (c *Client) ping(fn func(rtt time.Duration, err error)) error {
+	cmd := &protocol.Command{
+		Id:   c.nextCmdID(),
+		Ping: &protocol.PingRequest{},
+	}
+	started := time.Now()
+	return c.sendAsync(cmd, func(_ *protocol.Reply, err error) {
+		fn(time.Since(started), err)
+	})
+}
+
+// measureRTT sends ping command to server and measures time until reply.
+func (c *Client) measureRTT() {
+	_ = c.ping(func(rtt time.Duration, err error) {
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		if c.state != StateConnected {
+			c.mu.Unlock()
+			return
+		}
+		c.rtt = rtt
+		c.mu.Unlock()
+
+		var handler PingHandler
+		if c.events != nil && c.events.onPing != nil {
+			handler = c.events.onPing
+		}
+		if handler != nil {
+			c.runHandlerAsync(func() {
+				handler(PingEvent{RTT: rtt})
+			})
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package centrifuge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClient_MeasureRTT(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{MeasureRTT: true})
+	defer client.Close()
+	pings := make(chan PingEvent, 1)
+	client.OnPing(func(e PingEvent) {
+		pings <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-pings:
+		if e.RTT <= 0 || client.RTT() != e.RTT || client.Stats().RTT != e.RTT {
+			t.Fatalf("unexpected RTT: %v, %v", e.RTT, client.RTT())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for ping event")
+	}
+}
+
+func TestClient_Healthy(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Healthy(ctx); !errors.Is(err, ErrClientDisconnected) {
+		t.Fatalf("expected ErrClientDisconnected, got: %v", err)
+	}
+	connected := make(chan struct{}, 1)
+	client.OnConnected(func(ConnectedEvent) {
+		connected <- struct{}{}
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for connected event")
+	}
+	if err := client.Healthy(ctx); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	if err := client.Healthy(ctx); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got: %v", err)
+	}
+}
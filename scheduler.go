@@ -0,0 +1,218 @@
+package centrifuge
+
+import (
+	"sync"
+	"time"
+
+	"github.com/centrifugal/centrifuge-go/internal/lists"
+)
+
+// scheduler runs timed work of client – reconnect, server ping timeout, token
+// refresh, resubscribe and subscription token refresh – from one priority
+// queue ordered by deadline. Only
+// one Clock timer is armed for the earliest task, so all pending work can be
+// inspected and canceled together.
+type scheduler struct {
+	clock Clock
+	// spawn runs due tasks in goroutines registered by client, go statement is
+	// used if nil.
+	spawn func(name string, fn func())
+
+	mu    sync.Mutex
+	tasks *lists.PriorityQueue[scheduledEntry]
+	timer Timer
+	// timerAt is a deadline timer is armed for.
+	timerAt time.Time
+	// timerGen invalidates fired timers which were replaced.
+	timerGen uint64
+	seq      uint64
+	pending  int
+	stopped  bool
+}
+
+// scheduledEntry is a task in queue. Stopped or reset tasks leave stale entries
+// in queue which are skipped.
+type scheduledEntry struct {
+	task *scheduledTask
+	at   time.Time
+	// seq keeps order of tasks with the same deadline.
+	seq uint64
+	gen uint64
+}
+
+func (e scheduledEntry) less(o scheduledEntry) bool {
+	if e.at.Equal(o.at) {
+		return e.seq < o.seq
+	}
+	return e.at.Before(o.at)
+}
+
+// valid must be called with scheduler lock held.
+func (e scheduledEntry) valid() bool {
+	return e.task.active && e.task.gen == e.gen
+}
+
+// scheduledTask is Timer returned by scheduler.AfterFunc. As for time.AfterFunc
+// its channel is nil.
+type scheduledTask struct {
+	s  *scheduler
+	fn func()
+	// gen and active are protected by scheduler lock.
+	gen    uint64
+	active bool
+}
+
+func newScheduler(clock Clock, spawn func(name string, fn func())) *scheduler {
+	return &scheduler{
+		clock: clock,
+		spawn: spawn,
+		tasks: lists.NewPriorityQueue[scheduledEntry](scheduledEntry.less),
+	}
+}
+
+// AfterFunc schedules f to be called in its own goroutine after d. It's a no-op
+// after scheduler stopped.
+func (s *scheduler) AfterFunc(d time.Duration, f func()) Timer {
+	t := &scheduledTask{s: s, fn: f}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedule(t, d)
+	return t
+}
+
+// len returns the number of pending tasks.
+func (s *scheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// stop cancels all pending tasks, tasks scheduled after stop never run.
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	for {
+		e, ok := s.tasks.PopFront()
+		if !ok {
+			break
+		}
+		e.task.active = false
+	}
+	s.pending = 0
+	s.disarm()
+}
+
+// schedule must be called with lock held.
+func (s *scheduler) schedule(t *scheduledTask, d time.Duration) {
+	if s.stopped {
+		return
+	}
+	t.gen++
+	t.active = true
+	s.pending++
+	s.seq++
+	s.tasks.Push(scheduledEntry{task: t, at: s.clock.Now().Add(d), seq: s.seq, gen: t.gen})
+	s.arm()
+}
+
+// arm makes sure timer fires not later than the earliest task deadline. Must be
+// called with lock held.
+func (s *scheduler) arm() {
+	for {
+		e, ok := s.tasks.Peek()
+		if !ok {
+			s.disarm()
+			return
+		}
+		if !e.valid() {
+			s.tasks.PopFront()
+			continue
+		}
+		if s.timer != nil && !e.at.Before(s.timerAt) {
+			return
+		}
+		s.disarm()
+		gen := s.timerGen
+		s.timer = s.clock.AfterFunc(max(0, e.at.Sub(s.clock.Now())), func() {
+			s.fire(gen)
+		})
+		s.timerAt = e.at
+		return
+	}
+}
+
+// disarm must be called with lock held.
+func (s *scheduler) disarm() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.timerGen++
+}
+
+func (s *scheduler) fire(gen uint64) {
+	s.mu.Lock()
+	if gen != s.timerGen || s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.timer = nil
+	now := s.clock.Now()
+	var due []func()
+	for {
+		e, ok := s.tasks.Peek()
+		if !ok {
+			break
+		}
+		if !e.valid() {
+			s.tasks.PopFront()
+			continue
+		}
+		if e.at.After(now) {
+			break
+		}
+		s.tasks.PopFront()
+		e.task.active = false
+		s.pending--
+		due = append(due, e.task.fn)
+	}
+	s.arm()
+	s.mu.Unlock()
+	for _, fn := range due {
+		if s.spawn != nil {
+			s.spawn("scheduled_task", fn)
+		} else {
+			go fn()
+		}
+	}
+}
+
+func (t *scheduledTask) C() <-chan time.Time {
+	return nil
+}
+
+func (t *scheduledTask) Stop() bool {
+	s := t.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active := t.active
+	if active {
+		t.active = false
+		t.gen++
+		s.pending--
+	}
+	return active
+}
+
+func (t *scheduledTask) Reset(d time.Duration) bool {
+	s := t.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active := t.active
+	if active {
+		s.pending--
+	}
+	s.schedule(t, d)
+	return active
+}
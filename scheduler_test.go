@@ -0,0 +1,97 @@
+package centrifuge_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge-go"
+	"github.com/centrifugal/centrifuge-go/clocktest"
+)
+
+func TestScheduler_Order(t *testing.T) {
+	clock := clocktest.New()
+	s := centrifuge.NewScheduler(clock)
+	defer s.Stop()
+	fired := make(chan int, 3)
+	s.AfterFunc(3*time.Second, func() { fired <- 3 })
+	s.AfterFunc(time.Second, func() { fired <- 1 })
+	s.AfterFunc(2*time.Second, func() { fired <- 2 })
+	if s.Len() != 3 {
+		t.Fatalf("expected 3 pending tasks, got %d", s.Len())
+	}
+	// One clock timer is armed for all tasks.
+	if n := clock.ActiveTimers(); n != 1 {
+		t.Fatalf("expected 1 active clock timer, got %d", n)
+	}
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Second)
+		select {
+		case v := <-fired:
+			if v != i {
+				t.Fatalf("expected task %d, got %d", i, v)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("task %d not fired", i)
+		}
+	}
+	if s.Len() != 0 {
+		t.Fatalf("expected no pending tasks, got %d", s.Len())
+	}
+}
+
+func TestScheduler_StopReset(t *testing.T) {
+	clock := clocktest.New()
+	s := centrifuge.NewScheduler(clock)
+	defer s.Stop()
+	fired := make(chan int, 2)
+	stopped := s.AfterFunc(time.Second, func() { fired <- 1 })
+	reset := s.AfterFunc(time.Second, func() { fired <- 2 })
+	if !stopped.Stop() {
+		t.Fatal("expected Stop of pending task to return true")
+	}
+	if stopped.Stop() {
+		t.Fatal("expected second Stop to return false")
+	}
+	if !reset.Reset(5 * time.Second) {
+		t.Fatal("expected Reset of pending task to return true")
+	}
+	clock.Advance(time.Second)
+	select {
+	case v := <-fired:
+		t.Fatalf("unexpected task %d fired", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Advance(4 * time.Second)
+	select {
+	case v := <-fired:
+		if v != 2 {
+			t.Fatalf("expected task 2, got %d", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reset task not fired")
+	}
+}
+
+func TestScheduler_Stop(t *testing.T) {
+	clock := clocktest.New()
+	s := centrifuge.NewScheduler(clock)
+	fired := make(chan struct{}, 2)
+	task := s.AfterFunc(time.Second, func() { fired <- struct{}{} })
+	s.Stop()
+	s.AfterFunc(time.Second, func() { fired <- struct{}{} })
+	if s.Len() != 0 {
+		t.Fatalf("expected no pending tasks, got %d", s.Len())
+	}
+	if task.Stop() {
+		t.Fatal("expected task to be canceled by scheduler stop")
+	}
+	if n := clock.ActiveTimers(); n != 0 {
+		t.Fatalf("expected no active clock timers, got %d", n)
+	}
+	clock.Advance(time.Minute)
+	select {
+	case <-fired:
+		t.Fatal("task fired after scheduler stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,42 @@
+package centrifuge
+
+import (
+	"github.com/centrifugal/protocol"
+)
+
+// maxSendBatch limits the number of queued Send commands written in one frame.
+const maxSendBatch = 64
+
+// sendNoWait queues command of Send called with WithSendNoWait. Commands are
+// written by a separate goroutine, so caller never waits for slow connection.
+func (c *Client) sendNoWait(cmd *protocol.Command) {
+	c.sendQueueOnce.Do(func() {
+		c.spawn("send_queue", c.runSendQueue)
+	})
+	c.sendQueue.PushBack(cmd)
+}
+
+// runSendQueue writes queued Send commands in order. Commands queued meanwhile
+// are taken at once and written in one frame. Commands are dropped if client is
+// not connected in Config.ReadTimeout. Exits when client closed.
+func (c *Client) runSendQueue() {
+	for {
+		cmd, err := c.sendQueue.PopFrontCtx(c.closeCtx)
+		if err != nil {
+			return
+		}
+		cmds := []*protocol.Command{cmd}
+		c.sendQueue.DrainTo(func(cmd *protocol.Command) bool {
+			cmds = append(cmds, cmd)
+			return len(cmds) < maxSendBatch
+		})
+		errCh := make(chan error, 1)
+		c.onConnect(func(err error) {
+			errCh <- err
+		})
+		if err := <-errCh; err != nil {
+			continue
+		}
+		_ = c.sendMany(cmds)
+	}
+}
@@ -0,0 +1,104 @@
+package centrifuge
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+func TestClient_Send(t *testing.T) {
+	replyCh := make(chan ServerRPCReply, 2)
+	client := NewJsonClient(startServerRPCServer(t, nil, replyCh), Config{})
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	// Connecting, message is sent once connected.
+	if err := client.Send(ctx, []byte(`{"rpc_id":"1"}`), WithSendNoWait()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(ctx, []byte(`{"rpc_id":"2"}`)); err != nil {
+		t.Fatal(err)
+	}
+	received := make(map[string]bool)
+	for len(received) < 2 {
+		select {
+		case reply := <-replyCh:
+			received[reply.ID] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for messages, got %v", received)
+		}
+	}
+	if !received["1"] || !received["2"] {
+		t.Fatalf("unexpected messages: %v", received)
+	}
+}
+
+// blockingTransport blocks writes until unblock closed.
+type blockingTransport struct {
+	unblock chan struct{}
+	written chan []*protocol.Command
+}
+
+func (t *blockingTransport) Read() (*protocol.Reply, *disconnect, error) {
+	<-t.unblock
+	return nil, nil, io.EOF
+}
+
+func (t *blockingTransport) Write(cmd *protocol.Command, timeout time.Duration) error {
+	return t.WriteMany([]*protocol.Command{cmd}, timeout)
+}
+
+func (t *blockingTransport) WritePriority(cmd *protocol.Command, _ Priority, timeout time.Duration) error {
+	return t.WriteMany([]*protocol.Command{cmd}, timeout)
+}
+
+func (t *blockingTransport) WriteMany(cmds []*protocol.Command, _ time.Duration) error {
+	<-t.unblock
+	t.written <- cmds
+	return nil
+}
+
+func (t *blockingTransport) Close() error {
+	return nil
+}
+
+func TestClient_SendNoWait(t *testing.T) {
+	client := NewJsonClient("ws://localhost:9000/connection/websocket", Config{})
+	defer client.Close()
+	transport := &blockingTransport{unblock: make(chan struct{}), written: make(chan []*protocol.Command, 16)}
+	client.mu.Lock()
+	client.state = StateConnected
+	client.transport = transport
+	client.mu.Unlock()
+
+	// Socket is blocked, but Send returns immediately.
+	for i := 0; i < 3; i++ {
+		if err := client.Send(context.Background(), []byte(strconv.Itoa(i)), WithSendNoWait()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(transport.unblock)
+	var data []string
+	for len(data) < 3 {
+		select {
+		case cmds := <-transport.written:
+			for _, cmd := range cmds {
+				data = append(data, string(cmd.Send.Data))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for write, got %v", data)
+		}
+	}
+	if strings.Join(data, ",") != "0,1,2" {
+		t.Fatalf("unexpected order: %v", data)
+	}
+}
@@ -0,0 +1,107 @@
+package centrifuge
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+// ServerInfo describes server of the current connection as returned in connect
+// result.
+type ServerInfo struct {
+	// Version of server, empty if server does not expose it.
+	Version string
+	// Node is a name of server node, empty if server does not expose it.
+	Node string
+	// PingInterval is an interval of server pings, zero if server does not
+	// send pings.
+	PingInterval time.Duration
+	// Pong is true if server expects pong replies to pings.
+	Pong bool
+}
+
+func serverInfoFromProto(res *protocol.ConnectResult) ServerInfo {
+	return ServerInfo{
+		Version:      res.Version,
+		Node:         res.Node,
+		PingInterval: time.Duration(res.Ping) * time.Second,
+		Pong:         res.Pong,
+	}
+}
+
+// ServerInfo returns information about server of the last successful
+// connection. Zero value is returned if client never connected.
+func (c *Client) ServerInfo() ServerInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverInfo
+}
+
+// Feature is a server capability checked with Client.Supports.
+type Feature string
+
+const (
+	// FeatureDelta is a support of delta compression, see SubscriptionConfig.Delta.
+	// Available since Centrifugo v5.4.0.
+	FeatureDelta Feature = "delta"
+	// FeatureCacheRecovery is a support of cache recovery mode, see
+	// RecoveryModeCache. Available since Centrifugo v5.4.0.
+	FeatureCacheRecovery Feature = "cache_recovery"
+	// FeaturePong is a support of client pong replies to server pings.
+	FeaturePong Feature = "pong"
+)
+
+// featureVersions are minimal server versions of features.
+var featureVersions = map[Feature][3]int{
+	FeatureDelta:         {5, 4, 0},
+	FeatureCacheRecovery: {5, 4, 0},
+}
+
+// Supports reports whether server of the last successful connection supports
+// feature. Version-based features are only reported when server exposes its
+// version, so false means support is unknown and application should fall back
+// to behavior not requiring feature.
+func (c *Client) Supports(feature Feature) bool {
+	info := c.ServerInfo()
+	if feature == FeaturePong {
+		return info.Pong
+	}
+	minVersion, ok := featureVersions[feature]
+	if !ok {
+		return false
+	}
+	version, ok := parseVersion(info.Version)
+	if !ok {
+		return false
+	}
+	for i := range version {
+		if version[i] != minVersion[i] {
+			return version[i] > minVersion[i]
+		}
+	}
+	return true
+}
+
+// parseVersion parses semantic version like v5.4.1-rc.1, pre-release and build
+// suffixes are ignored.
+func parseVersion(s string) ([3]int, bool) {
+	var version [3]int
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(s, "-+ "); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if s == "" || len(parts) > 3 {
+		return version, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
@@ -0,0 +1,109 @@
+package centrifuge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+func startServerInfoServer(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			var cmd protocol.Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			if cmd.Connect == nil {
+				continue
+			}
+			reply := fmt.Sprintf(`{"id":%d,"connect":{"client":"c","version":"6.1.0","node":"n1","ping":25,"pong":true}}`, cmd.Id)
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    [3]int
+		ok      bool
+	}{
+		{"5.4.0", [3]int{5, 4, 0}, true},
+		{"v6.1.2-rc.1", [3]int{6, 1, 2}, true},
+		{"6", [3]int{6, 0, 0}, true},
+		{"", [3]int{}, false},
+		{"dev", [3]int{}, false},
+		{"1.2.3.4", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseVersion(tt.version)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseVersion(%q) = %v, %v, want %v, %v", tt.version, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestClient_Supports(t *testing.T) {
+	tests := []struct {
+		info    ServerInfo
+		feature Feature
+		want    bool
+	}{
+		{ServerInfo{Version: "5.4.0"}, FeatureDelta, true},
+		{ServerInfo{Version: "6.0.0"}, FeatureCacheRecovery, true},
+		{ServerInfo{Version: "5.3.9"}, FeatureDelta, false},
+		{ServerInfo{}, FeatureDelta, false},
+		{ServerInfo{Pong: true}, FeaturePong, true},
+		{ServerInfo{Version: "6.0.0"}, Feature("unknown"), false},
+	}
+	for _, tt := range tests {
+		client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+		client.serverInfo = tt.info
+		if got := client.Supports(tt.feature); got != tt.want {
+			t.Errorf("Supports(%s) with %+v = %v, want %v", tt.feature, tt.info, got, tt.want)
+		}
+		client.Close()
+	}
+}
+
+func TestClient_ServerInfo(t *testing.T) {
+	client := NewJsonClient(startServerInfoServer(t), Config{})
+	defer client.Close()
+	connected := make(chan ConnectedEvent, 1)
+	client.OnConnected(func(e ConnectedEvent) {
+		connected <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-connected:
+		if e.Version != "6.1.0" || e.Node != "n1" {
+			t.Fatalf("unexpected connected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for connected event")
+	}
+	info := client.ServerInfo()
+	if info != (ServerInfo{Version: "6.1.0", Node: "n1", PingInterval: 25 * time.Second, Pong: true}) {
+		t.Fatalf("unexpected server info %+v", info)
+	}
+	if !client.Supports(FeatureDelta) {
+		t.Fatal("expected delta support")
+	}
+}
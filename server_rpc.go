@@ -0,0 +1,125 @@
+package centrifuge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Error codes of ServerRPCError.
+const (
+	// ServerRPCErrorInternal is set when handler returned error which is not Error.
+	ServerRPCErrorInternal uint32 = 100
+	// ServerRPCErrorMethodNotFound is set when no handler registered for method.
+	ServerRPCErrorMethodNotFound uint32 = 104
+	// ServerRPCErrorBadRequest is set when request data can't be decoded.
+	ServerRPCErrorBadRequest uint32 = 107
+	// ServerRPCErrorTooManyRequests is set when Config.MaxConcurrentServerRPC
+	// handlers are already running.
+	ServerRPCErrorTooManyRequests uint32 = 111
+)
+
+const defaultMaxConcurrentServerRPC = 64
+
+// ServerRPCRequest is a request of RPC initiated by server. Server sends it as
+// async message with JSON object, so it's valid for both JSON and Protobuf
+// protocols. Messages which are not ServerRPCRequest are passed to OnMessage.
+type ServerRPCRequest struct {
+	// ID of request, copied to reply.
+	ID string `json:"rpc_id"`
+	// Method registered with Client.OnRPC.
+	Method string `json:"method"`
+	// Data of request.
+	Data []byte `json:"data,omitempty"`
+}
+
+// ServerRPCReply is a reply to ServerRPCRequest sent back to server with
+// Client.Send as JSON object.
+type ServerRPCReply struct {
+	// ID of request.
+	ID string `json:"rpc_id"`
+	// Data returned by handler.
+	Data []byte `json:"data,omitempty"`
+	// Error is set if handler failed.
+	Error *ServerRPCError `json:"error,omitempty"`
+}
+
+// ServerRPCError describes failed server-initiated RPC.
+type ServerRPCError struct {
+	Code    uint32 `json:"code"`
+	Message string `json:"message"`
+}
+
+// ServerRPCHandler handles server-initiated RPC. Return Error to set error code
+// of reply, other errors are sent with ServerRPCErrorInternal code.
+type ServerRPCHandler func(ctx context.Context, data []byte) ([]byte, error)
+
+// OnRPC registers handler of server-initiated RPC method in RPCMux of Client.
+// Handlers are called in separate goroutines, up to Config.MaxConcurrentServerRPC
+// at once, reply is sent once handler returned. Handler context is canceled when
+// Client is closed. Must be called before Connect.
+func (c *Client) OnRPC(method string, handler ServerRPCHandler) {
+	if c.events.rpcMux == nil {
+		c.events.rpcMux = NewRPCMux()
+	}
+	c.events.rpcMux.Handle(method, handler)
+}
+
+// decodeServerRPC returns server RPC request if message is one.
+func decodeServerRPC(data []byte) (ServerRPCRequest, bool) {
+	var req ServerRPCRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.ID == "" || req.Method == "" {
+		return ServerRPCRequest{}, false
+	}
+	return req, true
+}
+
+// handleServerRPC calls handler of request and sends reply.
+func (c *Client) handleServerRPC(req ServerRPCRequest) {
+	reply := ServerRPCReply{ID: req.ID}
+	res, err := callSafe(c, "OnRPC", func() ([]byte, error) {
+		return c.events.rpcMux.ServeRPC(c.closeCtx, req.Method, req.Data)
+	})
+	if err != nil {
+		reply.Error = serverRPCError(err)
+	} else {
+		reply.Data = res
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.ReadTimeout)
+	defer cancel()
+	c.sendServerRPCReply(ctx, req.Method, reply)
+}
+
+// rejectServerRPC replies with ServerRPCErrorTooManyRequests without waiting for
+// write, it's called from reader goroutine.
+func (c *Client) rejectServerRPC(req ServerRPCRequest) {
+	c.sendServerRPCReply(context.Background(), req.Method, ServerRPCReply{
+		ID:    req.ID,
+		Error: &ServerRPCError{Code: ServerRPCErrorTooManyRequests, Message: "too many requests"},
+	}, WithSendNoWait())
+}
+
+func (c *Client) sendServerRPCReply(ctx context.Context, method string, reply ServerRPCReply, opts ...SendOption) {
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+	if err := c.Send(ctx, data, opts...); err != nil {
+		c.log(LogLevelDebug, "error sending RPC reply", map[string]string{
+			"method": method,
+			"error":  err.Error(),
+		})
+	}
+}
+
+func serverRPCError(err error) *ServerRPCError {
+	var e *Error
+	if errors.As(err, &e) {
+		return &ServerRPCError{Code: e.Code, Message: e.Message}
+	}
+	var ev Error
+	if errors.As(err, &ev) {
+		return &ServerRPCError{Code: ev.Code, Message: ev.Message}
+	}
+	return &ServerRPCError{Code: ServerRPCErrorInternal, Message: err.Error()}
+}
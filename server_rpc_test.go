@@ -0,0 +1,160 @@
+package centrifuge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startServerRPCServer sends requests as async messages after connect and passes
+// replies sent by client to replyCh.
+func startServerRPCServer(t *testing.T, requests []ServerRPCRequest, replyCh chan<- ServerRPCReply) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			// Queued Send commands come in one frame.
+			for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+				var cmd protocol.Command
+				if err := json.Unmarshal(line, &cmd); err != nil {
+					t.Error(err)
+					return
+				}
+				switch {
+				case cmd.Connect != nil:
+					replies := []string{fmt.Sprintf(`{"id":%d,"connect":{"client":"c"}}`, cmd.Id)}
+					for _, req := range requests {
+						data, _ := json.Marshal(req)
+						replies = append(replies, fmt.Sprintf(`{"push":{"message":{"data":%s}}}`, data))
+					}
+					for _, reply := range replies {
+						if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+							return
+						}
+					}
+				case cmd.Send != nil:
+					var reply ServerRPCReply
+					if err := json.Unmarshal(cmd.Send.Data, &reply); err != nil {
+						t.Error(err)
+						return
+					}
+					replyCh <- reply
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestClient_OnRPC(t *testing.T) {
+	replyCh := make(chan ServerRPCReply, 4)
+	u := startServerRPCServer(t, []ServerRPCRequest{
+		{ID: "1", Method: "echo", Data: []byte("hello")},
+		{ID: "2", Method: "fail"},
+		{ID: "3", Method: "unknown"},
+		{ID: "4", Method: "internal"},
+	}, replyCh)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	client.OnRPC("echo", func(_ context.Context, data []byte) ([]byte, error) {
+		return data, nil
+	})
+	client.OnRPC("fail", func(context.Context, []byte) ([]byte, error) {
+		return nil, &Error{Code: 1000, Message: "custom"}
+	})
+	client.OnRPC("internal", func(context.Context, []byte) ([]byte, error) {
+		return nil, errors.New("boom")
+	})
+	client.OnMessage(func(e MessageEvent) {
+		t.Errorf("unexpected message: %s", e.Data)
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	replies := make(map[string]ServerRPCReply)
+	for len(replies) < 4 {
+		select {
+		case reply := <-replyCh:
+			replies[reply.ID] = reply
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for replies, got %v", replies)
+		}
+	}
+	if r := replies["1"]; string(r.Data) != "hello" || r.Error != nil {
+		t.Fatalf("unexpected echo reply: %#v", r)
+	}
+	if r := replies["2"]; r.Error == nil || r.Error.Code != 1000 || r.Error.Message != "custom" {
+		t.Fatalf("unexpected fail reply: %#v", r)
+	}
+	if r := replies["3"]; r.Error == nil || r.Error.Code != ServerRPCErrorMethodNotFound {
+		t.Fatalf("unexpected unknown method reply: %#v", r)
+	}
+	if r := replies["4"]; r.Error == nil || r.Error.Code != ServerRPCErrorInternal || r.Error.Message != "boom" {
+		t.Fatalf("unexpected internal reply: %#v", r)
+	}
+}
+
+func TestClient_OnRPCConcurrencyLimit(t *testing.T) {
+	replyCh := make(chan ServerRPCReply, 2)
+	u := startServerRPCServer(t, []ServerRPCRequest{
+		{ID: "1", Method: "block"},
+		{ID: "2", Method: "block"},
+	}, replyCh)
+	client := NewJsonClient(u, Config{MaxConcurrentServerRPC: 1})
+	defer client.Close()
+	canceled := make(chan struct{})
+	client.OnRPC("block", func(ctx context.Context, _ []byte) ([]byte, error) {
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reply := <-replyCh:
+		if reply.ID != "2" || reply.Error == nil || reply.Error.Code != ServerRPCErrorTooManyRequests {
+			t.Fatalf("unexpected reply: %#v", reply)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for reply")
+	}
+	// Handler context is canceled on close.
+	client.Close()
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler context not canceled")
+	}
+}
+
+func TestDecodeServerRPC(t *testing.T) {
+	if _, ok := decodeServerRPC([]byte(`{"text":"hi"}`)); ok {
+		t.Fatal("message without rpc_id must not be RPC")
+	}
+	if _, ok := decodeServerRPC([]byte(`not json`)); ok {
+		t.Fatal("non-JSON message must not be RPC")
+	}
+	req, ok := decodeServerRPC([]byte(`{"rpc_id":"1","method":"m","data":"aGk="}`))
+	if !ok || req.Method != "m" || string(req.Data) != "hi" {
+		t.Fatalf("unexpected request: %#v", req)
+	}
+}
@@ -0,0 +1,227 @@
+package centrifuge
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SpoolRecord is a publication kept in Spool.
+type SpoolRecord struct {
+	ID      uint64
+	Channel string
+	Data    []byte
+	// Time when publication was appended.
+	Time time.Time
+}
+
+// Spool persists offline queue, so queued publications survive process restarts.
+// Set it over OfflineQueueConfig.Spool.
+type Spool interface {
+	// Append stores publication and returns its ID. IDs must increase.
+	Append(channel string, data []byte) (uint64, error)
+	// Ack removes publication which was delivered or dropped from queue.
+	Ack(id uint64) error
+	// Load returns all stored publications in order of appending.
+	Load() ([]SpoolRecord, error)
+}
+
+// FileSpool is a Spool which keeps publications in append-only file. Every append
+// and ack is synced to disk before returning, so a crash never loses acknowledged
+// state. Record partially written during a crash is discarded upon open. File is
+// compacted when most of its records are acknowledged.
+type FileSpool struct {
+	mu     sync.Mutex
+	path   string
+	f      *os.File
+	nextID uint64
+	live   map[uint64]SpoolRecord
+	// garbage is a number of lines in file which belong to acknowledged records.
+	garbage int
+}
+
+var _ Spool = (*FileSpool)(nil)
+
+// minSpoolCompaction is a number of garbage lines after which file may be compacted.
+const minSpoolCompaction = 1024
+
+type spoolLine struct {
+	Op      string    `json:"op"`
+	ID      uint64    `json:"id"`
+	Channel string    `json:"channel,omitempty"`
+	Data    []byte    `json:"data,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+const (
+	spoolOpAppend = "append"
+	spoolOpAck    = "ack"
+)
+
+// NewFileSpool opens FileSpool at path, the file is created if not exists.
+func NewFileSpool(path string) (*FileSpool, error) {
+	s := &FileSpool{path: path, live: make(map[uint64]SpoolRecord)}
+	if err := s.read(); err != nil {
+		return nil, err
+	}
+	// Rewrite file on open to drop acknowledged records and a partially written
+	// tail, so new lines are appended to a clean file.
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSpool) read() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	// Every complete line ends with new line, the rest is a line partially
+	// written during a crash.
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	} else {
+		data = nil
+	}
+	for _, raw := range bytes.Split(data, []byte("\n")) {
+		if len(raw) == 0 {
+			continue
+		}
+		var line spoolLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return fmt.Errorf("corrupted spool file: %w", err)
+		}
+		switch line.Op {
+		case spoolOpAppend:
+			s.live[line.ID] = SpoolRecord{ID: line.ID, Channel: line.Channel, Data: line.Data, Time: line.Time}
+			if line.ID >= s.nextID {
+				s.nextID = line.ID + 1
+			}
+		case spoolOpAck:
+			delete(s.live, line.ID)
+		}
+	}
+	return nil
+}
+
+// compact rewrites file with live records only. File is replaced atomically.
+// Lock must be held outside or FileSpool not shared yet.
+func (s *FileSpool) compact() error {
+	err := writeFileAtomic(s.path, func(f io.Writer) error {
+		w := bufio.NewWriter(f)
+		for _, record := range s.records() {
+			if err := writeSpoolLine(w, spoolLine{Op: spoolOpAppend, ID: record.ID, Channel: record.Channel, Data: record.Data, Time: record.Time}); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	if s.f != nil {
+		_ = s.f.Close()
+	}
+	s.f, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	s.garbage = 0
+	return nil
+}
+
+func writeSpoolLine(w io.Writer, line spoolLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Lock must be held outside.
+func (s *FileSpool) write(line spoolLine) error {
+	if s.f == nil {
+		return errors.New("spool closed")
+	}
+	if err := writeSpoolLine(s.f, line); err != nil {
+		// Line may be written partially, rewrite file to keep it consistent.
+		_ = s.compact()
+		return err
+	}
+	return s.f.Sync()
+}
+
+// Lock must be held outside.
+func (s *FileSpool) records() []SpoolRecord {
+	records := make([]SpoolRecord, 0, len(s.live))
+	for _, record := range s.live {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+// Append writes publication to file.
+func (s *FileSpool) Append(channel string, data []byte) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data = append([]byte(nil), data...)
+	record := SpoolRecord{ID: s.nextID, Channel: channel, Data: data, Time: time.Now()}
+	if err := s.write(spoolLine{Op: spoolOpAppend, ID: record.ID, Channel: channel, Data: data, Time: record.Time}); err != nil {
+		return 0, err
+	}
+	s.nextID++
+	s.live[record.ID] = record
+	return record.ID, nil
+}
+
+// Ack marks publication removed in file.
+func (s *FileSpool) Ack(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.live[id]; !ok {
+		return nil
+	}
+	if err := s.write(spoolLine{Op: spoolOpAck, ID: id, Time: time.Now()}); err != nil {
+		return err
+	}
+	delete(s.live, id)
+	// Both append and ack lines of record are garbage now.
+	s.garbage += 2
+	if s.garbage >= minSpoolCompaction && s.garbage > 2*len(s.live) {
+		return s.compact()
+	}
+	return nil
+}
+
+// Load returns publications not acknowledged yet.
+func (s *FileSpool) Load() ([]SpoolRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records(), nil
+}
+
+// Close closes spool file.
+func (s *FileSpool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
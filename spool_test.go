@@ -0,0 +1,120 @@
+package centrifuge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSpool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool")
+	spool, err := NewFileSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range []string{"a", "b", "c"} {
+		if _, err := spool.Append(ch, []byte(ch)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := spool.Ack(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := spool.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate crash in the middle of writing a line.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"op":"append","id":3,"chan`); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	spool, err = NewFileSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = spool.Close() }()
+	records, err := spool.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Channel != "a" || records[1].Channel != "c" || string(records[1].Data) != "c" {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+	id, err := spool.Append("d", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 {
+		t.Fatalf("expected id 3, got %d", id)
+	}
+}
+
+func TestFileSpool_Compaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool")
+	spool, err := NewFileSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = spool.Close() }()
+	for i := 0; i < minSpoolCompaction; i++ {
+		id, err := spool.Append("test", []byte("data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := spool.Ack(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Fatalf("expected compacted file, size: %d", info.Size())
+	}
+}
+
+func TestClient_OfflineQueueSpool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool")
+	spool, err := NewFileSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+		OfflineQueue: &OfflineQueueConfig{Spool: spool},
+	})
+	for i := 0; i < 2; i++ {
+		if err := client.EnqueuePublish("test", []byte(`{}`), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.Close()
+	_ = spool.Close()
+
+	// Queued publications restored after restart and delivered.
+	spool, err = NewFileSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = spool.Close() }()
+	u, _ := startConnectServer(t, false)
+	client = NewJsonClient(u, Config{OfflineQueue: &OfflineQueueConfig{Spool: spool}})
+	defer client.Close()
+	if n := client.Stats().QueuedPublications; n != 2 {
+		t.Fatalf("expected 2 restored publications, got %d", n)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return client.Stats().QueuedPublications == 0 })
+	waitFor(t, func() bool {
+		records, _ := spool.Load()
+		return len(records) == 0
+	})
+}
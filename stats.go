@@ -0,0 +1,206 @@
+package centrifuge
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/centrifugal/centrifuge-go/internal/locks"
+	"github.com/centrifugal/protocol"
+)
+
+// Stats is a snapshot of Client internals returned by Client.Stats. Useful for
+// health endpoints and support bundles.
+type Stats struct {
+	State State
+	// ConnectedSince is a time when current connection was established. Zero if
+	// client is not connected.
+	ConnectedSince time.Time
+	// ClientID is a unique ID of current connection issued by server. Empty if
+	// client is not connected.
+	ClientID string
+	// Transport is a name of transport.
+	Transport string
+	// Protocol is a name of protocol format: json or protobuf.
+	Protocol string
+	// Subscriptions are client-side subscriptions not in unsubscribed state,
+	// sorted by channel.
+	Subscriptions []SubscriptionStats
+	// ServerSubscriptions are server-side subscriptions, sorted by channel.
+	ServerSubscriptions []SubscriptionStats
+	// PendingCommands is a number of commands waiting for reply.
+	PendingCommands int
+	// BytesSent is a number of bytes written to connections.
+	BytesSent uint64
+	// BytesReceived is a number of bytes read from connections.
+	BytesReceived uint64
+	// RTT is the last measured round trip time, see Config.MeasureRTT.
+	RTT time.Duration
+	// QueuedPublications is a number of publications in offline queue, see
+	// Config.OfflineQueue.
+	QueuedPublications int
+	// DroppedEvents is a number of events dropped from full event queue, see
+	// Config.EventQueuePolicy.
+	DroppedEvents uint64
+	// ClientLock is contention of client internal lock, see
+	// Config.MeasureLockContention.
+	ClientLock LockStats
+	// SubscriptionLock is contention of internal locks of all client-side
+	// subscriptions, see Config.MeasureLockContention.
+	SubscriptionLock LockStats
+}
+
+// LockStats describes contention of internal lock. Only write lock hold time is
+// measured.
+type LockStats struct {
+	// Acquisitions is the number of lock acquisitions.
+	Acquisitions uint64
+	// WaitTotal is a total time spent waiting for lock.
+	WaitTotal time.Duration
+	// MaxWait is the longest wait for lock.
+	MaxWait time.Duration
+	// HoldTotal is a total time lock was held.
+	HoldTotal time.Duration
+	// MaxHold is the longest time lock was held.
+	MaxHold time.Duration
+	// MaxHoldStack is a stack of the longest lock holder among sampled
+	// acquisitions.
+	MaxHoldStack string
+}
+
+func lockStatsFrom(stats *locks.Stats) LockStats {
+	if stats == nil {
+		return LockStats{}
+	}
+	s := stats.Snapshot()
+	return LockStats{
+		Acquisitions: s.Acquisitions,
+		WaitTotal:    s.WaitTotal,
+		MaxWait:      s.MaxWait,
+		HoldTotal:    s.HoldTotal,
+		MaxHold:      s.MaxHold,
+		MaxHoldStack: s.MaxHoldStack,
+	}
+}
+
+// SubscriptionStats describes subscription in Stats and is returned by
+// Subscription.Stats. Publication counters are only tracked for client-side
+// subscriptions.
+type SubscriptionStats struct {
+	Channel string
+	State   SubState
+	// StreamPosition is a position in channel stream of the last received
+	// publication.
+	StreamPosition StreamPosition
+	// Publications is a number of publications received.
+	Publications uint64
+	// PublicationBytes is a total size of received publication data.
+	PublicationBytes uint64
+	// ProcessedOffset is an offset of the last publication processed by
+	// OnPublication handler.
+	ProcessedOffset uint64
+	// Lag is an estimated number of publications received but not processed by
+	// OnPublication handler yet: StreamPosition.Offset minus ProcessedOffset.
+	// Only available for positioned and recoverable subscriptions.
+	Lag uint64
+}
+
+// Stats returns a snapshot of client state.
+func (c *Client) Stats() Stats {
+	subs := c.activeSubs()
+	subStats := make([]SubscriptionStats, 0, len(subs))
+	for _, s := range subs {
+		subStats = append(subStats, s.Stats())
+	}
+	sort.Slice(subStats, func(i, j int) bool { return subStats[i].Channel < subStats[j].Channel })
+
+	c.mu.RLock()
+	stats := Stats{
+		State:          c.state,
+		ConnectedSince: c.connectedAt,
+		ClientID:       c.clientID,
+		Transport:      "websocket",
+		Protocol:       string(c.protocolType),
+		Subscriptions:  subStats,
+		RTT:            c.rtt,
+	}
+	serverSubState := SubStateSubscribing
+	if c.state == StateConnected {
+		serverSubState = SubStateSubscribed
+	}
+	stats.ServerSubscriptions = make([]SubscriptionStats, 0, len(c.serverSubs))
+	for ch, sub := range c.serverSubs {
+		stats.ServerSubscriptions = append(stats.ServerSubscriptions, SubscriptionStats{
+			Channel:        ch,
+			State:          serverSubState,
+			StreamPosition: StreamPosition{Offset: sub.Offset, Epoch: sub.Epoch},
+		})
+	}
+	c.mu.RUnlock()
+	sort.Slice(stats.ServerSubscriptions, func(i, j int) bool {
+		return stats.ServerSubscriptions[i].Channel < stats.ServerSubscriptions[j].Channel
+	})
+
+	c.requestsMu.RLock()
+	stats.PendingCommands = len(c.requests)
+	c.requestsMu.RUnlock()
+
+	if c.offlineQueue != nil {
+		stats.QueuedPublications = c.offlineQueue.len()
+	}
+	stats.BytesSent = c.metrics.bytesSent.Load()
+	stats.BytesReceived = c.metrics.bytesReceived.Load()
+	stats.DroppedEvents = c.cbQueue.Dropped()
+	stats.ClientLock = lockStatsFrom(c.lockStats)
+	stats.SubscriptionLock = lockStatsFrom(c.subLockStats)
+	return stats
+}
+
+// Stats returns a snapshot of subscription state and publication counters.
+func (s *Subscription) Stats() SubscriptionStats {
+	s.mu.RLock()
+	stats := SubscriptionStats{
+		Channel:        s.Channel,
+		State:          s.state,
+		StreamPosition: StreamPosition{Offset: s.offset, Epoch: s.epoch},
+	}
+	s.mu.RUnlock()
+	stats.Publications = s.numPublications.Load()
+	stats.PublicationBytes = s.publicationBytes.Load()
+	stats.ProcessedOffset = s.processedOffset.Load()
+	if stats.StreamPosition.Offset > stats.ProcessedOffset {
+		stats.Lag = stats.StreamPosition.Offset - stats.ProcessedOffset
+	}
+	return stats
+}
+
+// countPublication accounts received publication in subscription stats.
+func (s *Subscription) countPublication(pub *protocol.Publication) {
+	s.numPublications.Add(1)
+	s.publicationBytes.Add(uint64(len(pub.Data)))
+}
+
+// markProcessed is called after publication with offset processed by handler.
+func (s *Subscription) markProcessed(offset uint64) {
+	if offset > 0 {
+		s.processedOffset.Store(offset)
+	}
+	s.savePosition(offset)
+}
+
+// statsMetrics counts bytes for Stats and passes all metrics to Config.Metrics.
+type statsMetrics struct {
+	Metrics
+	bytesSent     atomic.Uint64
+	bytesReceived atomic.Uint64
+}
+
+func (m *statsMetrics) AddBytesSent(n int) {
+	m.bytesSent.Add(uint64(n))
+	m.Metrics.AddBytesSent(n)
+}
+
+func (m *statsMetrics) AddBytesReceived(n int) {
+	m.bytesReceived.Add(uint64(n))
+	m.Metrics.AddBytesReceived(n)
+}
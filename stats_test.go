@@ -0,0 +1,122 @@
+package centrifuge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+)
+
+func TestClient_Stats(t *testing.T) {
+	u, _ := startConnectServer(t, false)
+	client := NewJsonClient(u, Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribed := make(chan struct{}, 1)
+	sub.OnSubscribed(func(SubscribedEvent) {
+		subscribed <- struct{}{}
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := client.Stats(); stats.State != StateDisconnected || !stats.ConnectedSince.IsZero() {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-subscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for subscribed event")
+	}
+	stats := client.Stats()
+	if stats.State != StateConnected || stats.ClientID != "c" || stats.ConnectedSince.IsZero() {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+	if stats.Transport != "websocket" || stats.Protocol != "json" || stats.BytesSent == 0 || stats.BytesReceived == 0 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+	if len(stats.Subscriptions) != 1 || stats.Subscriptions[0].Channel != "test" || stats.Subscriptions[0].State != SubStateSubscribed {
+		t.Fatalf("unexpected subscriptions: %#v", stats.Subscriptions)
+	}
+	if len(stats.ServerSubscriptions) != 1 || stats.ServerSubscriptions[0].Channel != "news" {
+		t.Fatalf("unexpected server subscriptions: %#v", stats.ServerSubscriptions)
+	}
+}
+
+func TestSubscription_Stats(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+	sub, err := client.NewSubscription("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	handled := make(chan struct{}, 2)
+	sub.OnPublication(func(PublicationEvent) {
+		<-release
+		handled <- struct{}{}
+	})
+	sub.mu.Lock()
+	sub.state = SubStateSubscribed
+	sub.mu.Unlock()
+
+	go sub.handlePublication(&protocol.Publication{Offset: 1, Data: []byte("abc")})
+	waitFor(t, func() bool { return sub.Stats().Publications == 1 })
+	go sub.handlePublication(&protocol.Publication{Offset: 2, Data: []byte("de")})
+	waitFor(t, func() bool { return sub.Stats().Publications == 2 })
+
+	stats := sub.Stats()
+	if stats.PublicationBytes != 5 || stats.StreamPosition.Offset != 2 || stats.ProcessedOffset != 0 || stats.Lag != 2 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+	close(release)
+	<-handled
+	<-handled
+	waitFor(t, func() bool { return sub.Stats().Lag == 0 })
+	if stats := sub.Stats(); stats.ProcessedOffset != 2 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClient_StatsLockContention(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{MeasureLockContention: true})
+	defer client.Close()
+	sub, err := client.NewSubscription("ch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = client.State()
+	_ = sub.State()
+	client.SetToken("token")
+
+	stats := client.Stats()
+	if stats.ClientLock.Acquisitions == 0 || stats.SubscriptionLock.Acquisitions == 0 {
+		t.Fatalf("expected lock acquisitions, got %+v, %+v", stats.ClientLock, stats.SubscriptionLock)
+	}
+	if stats.ClientLock.MaxHoldStack == "" {
+		t.Fatal("expected sampled holder stack")
+	}
+
+	plain := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer plain.Close()
+	_ = plain.State()
+	if stats := plain.Stats(); stats.ClientLock != (LockStats{}) {
+		t.Fatalf("expected no lock stats, got %+v", stats.ClientLock)
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/centrifugal/centrifuge-go/internal/locks"
 	"github.com/centrifugal/protocol"
 	fossil "github.com/shadowspore/fossil-delta"
 )
@@ -50,6 +50,63 @@ type SubscriptionConfig struct {
 	JoinLeave bool
 	// Delta allows to specify delta type for the subscription. By default, no delta is used.
 	Delta DeltaType
+	// RecoverViaHistory enables replay of missed publications from history when
+	// Recoverable subscription was not recovered by server after resubscribe.
+	// Replayed publications are passed to OnPublication with Replayed flag.
+	// Zero value means missed publications are not replayed.
+	RecoverViaHistory *HistoryRecoveryConfig
+	// DetectGaps enables strict offset continuity check of Positioned or
+	// Recoverable subscription. When publication offset is not the next one
+	// OnGapDetected event is emitted and subscription is re-synced: it's
+	// resubscribed recovering from the last received publication. Duplicate
+	// publications are dropped.
+	// Zero value means publications are passed as received.
+	DetectGaps bool
+	// ManualAck enables at-least-once processing with Config.PositionStore:
+	// position of publication is stored only after handler called
+	// PublicationEvent.Ack for it and all earlier publications. Publications
+	// not acknowledged before restart are recovered from history again.
+	// Zero value means position is stored once OnPublication handler returned.
+	ManualAck bool
+	// MaxRecoveredPublications is the maximum number of publications recovered by
+	// server to pass to OnPublication. If more publications were recovered
+	// recovery is aborted: publications are dropped, OnRecovering is called with
+	// Aborted flag and SubscribedEvent has Recovered false, so application should
+	// load fresh state instead.
+	// Zero value means no limit.
+	MaxRecoveredPublications int
+	// RecoveryMode defines which missed publications are passed to OnPublication
+	// after Recoverable subscription recovered, also applies to RecoverViaHistory.
+	// Zero value means RecoveryModeStream.
+	RecoveryMode RecoveryMode
+	// JoinLeaveWindow enables batching of join and leave messages: they are
+	// passed to OnJoinLeave handler in order of arrival at most once per window
+	// instead of OnJoin and OnLeave.
+	// Zero value means join and leave messages are passed one by one to OnJoin
+	// and OnLeave.
+	JoinLeaveWindow time.Duration
+	// WithoutSelf excludes connection of this Client from join and leave messages,
+	// Presence results and PresenceWatcher membership.
+	// Zero value means own connection is included.
+	WithoutSelf bool
+	// Codec encodes and decodes publication data of Subscription, see
+	// Subscription.Decode, Subscription.PublishValue and HistoryAs.
+	// Zero value means Config.Codec of Client.
+	Codec Codec
+	// Envelope wraps data published with Subscription.Publish into
+	// PublicationEnvelope with headers, content type, schema version and
+	// timestamp. Received envelopes are unwrapped, metadata is available in
+	// PublicationEvent.Envelope.
+	// Zero value means publication data is sent and received as is.
+	Envelope bool
+	// Compressor compresses data of publications published with Envelope when it
+	// exceeds CompressionThreshold. Received envelopes compressed with gzip or
+	// with Compressor are decompressed. Setting Compressor enables Envelope.
+	// Zero value means published data is not compressed.
+	Compressor Compressor
+	// CompressionThreshold is a minimal size of data to compress.
+	// Zero value means 1024 bytes.
+	CompressionThreshold int
 }
 
 func newSubscription(c *Client, channel string, config ...SubscriptionConfig) *Subscription {
@@ -59,8 +116,10 @@ func newSubscription(c *Client, channel string, config ...SubscriptionConfig) *S
 		state:               SubStateUnsubscribed,
 		events:              newSubscriptionEventHub(),
 		subFutures:          make(map[uint64]subFuture),
-		resubscribeStrategy: defaultBackoffReconnect,
+		resubscribeStrategy: newBackoff(c.config, c.jitterRand, defaultBackoffReconnect.MinDelay, defaultBackoffReconnect.MaxDelay),
 	}
+	s.mu.SetClass("subscription")
+	s.mu.SetStats(c.subLockStats)
 	if len(config) == 1 {
 		cfg := config[0]
 		s.token = cfg.Token
@@ -70,6 +129,24 @@ func newSubscription(c *Client, channel string, config ...SubscriptionConfig) *S
 		s.recoverable = cfg.Recoverable
 		s.joinLeave = cfg.JoinLeave
 		s.deltaType = cfg.Delta
+		s.historyRecovery = cfg.RecoverViaHistory
+		s.detectGaps = cfg.DetectGaps
+		s.maxRecovered = cfg.MaxRecoveredPublications
+		s.recoveryMode = cfg.RecoveryMode
+		s.withoutSelf = cfg.WithoutSelf
+		s.codec = cfg.Codec
+		s.envelope = cfg.Envelope || cfg.Compressor != nil
+		s.compressor = cfg.Compressor
+		s.compressionThreshold = cfg.CompressionThreshold
+		if s.compressionThreshold == 0 {
+			s.compressionThreshold = defaultCompressionThreshold
+		}
+		if cfg.JoinLeaveWindow > 0 {
+			s.joinLeaveBuffer = newJoinLeaveBuffer(s, cfg.JoinLeaveWindow)
+		}
+		if cfg.ManualAck {
+			s.acks = newAckTracker()
+		}
 	}
 	return s
 }
@@ -79,7 +156,7 @@ func newSubscription(c *Client, channel string, config ...SubscriptionConfig) *S
 type Subscription struct {
 	futureID uint64 // Keep atomic on top!
 
-	mu         sync.RWMutex
+	mu         locks.RWMutex
 	centrifuge *Client
 
 	// Channel for a subscription.
@@ -104,12 +181,36 @@ type Subscription struct {
 	resubscribeAttempts int
 	resubscribeStrategy reconnectStrategy
 
-	resubscribeTimer *time.Timer
-	refreshTimer     *time.Timer
+	resubscribeTimer Timer
+	refreshTimer     Timer
 
 	deltaType       DeltaType
 	deltaNegotiated bool
 	prevData        []byte
+
+	historyRecovery *HistoryRecoveryConfig
+	// replayBuffer keeps live publications received while missed ones are
+	// replayed from history, nil when replay is not in progress.
+	replayBuffer    []*protocol.Publication
+	detectGaps      bool
+	maxRecovered    int
+	recoveryMode    RecoveryMode
+	presenceWatcher *PresenceWatcher
+	joinLeaveBuffer *joinLeaveBuffer
+	withoutSelf     bool
+	acks            *ackTracker
+	codec           Codec
+	envelope        bool
+
+	compressor           Compressor
+	compressionThreshold int
+	// lostAt is a time when subscribed subscription moved to subscribing state.
+	lostAt time.Time
+
+	// Counters for Subscription.Stats.
+	numPublications  atomic.Uint64
+	publicationBytes atomic.Uint64
+	processedOffset  atomic.Uint64
 }
 
 func (s *Subscription) State() SubState {
@@ -141,7 +242,7 @@ func (s *Subscription) resolveSubFutures(err error) {
 }
 
 // Publish allows publishing data to the subscription channel.
-func (s *Subscription) Publish(ctx context.Context, data []byte) (PublishResult, error) {
+func (s *Subscription) Publish(ctx context.Context, data []byte, opts ...PublishOption) (PublishResult, error) {
 	s.mu.Lock()
 	if s.state == SubStateUnsubscribed {
 		s.mu.Unlock()
@@ -149,17 +250,62 @@ func (s *Subscription) Publish(ctx context.Context, data []byte) (PublishResult,
 	}
 	s.mu.Unlock()
 
-	resCh := make(chan PublishResult, 1)
-	errCh := make(chan error, 1)
-	s.publish(ctx, data, func(result PublishResult, err error) {
-		resCh <- result
-		errCh <- err
+	publishOpts := &PublishOptions{}
+	for _, opt := range opts {
+		opt(publishOpts)
+	}
+	if s.envelope {
+		var err error
+		data, err = s.wrapEnvelope(data, publishOpts)
+		if err != nil {
+			return PublishResult{}, err
+		}
+	}
+	return withRetry(ctx, publishOpts.Retry, func() (PublishResult, error) {
+		resCh := make(chan PublishResult, 1)
+		errCh := make(chan error, 1)
+		s.publish(ctx, data, publishOpts.Priority, func(result PublishResult, err error) {
+			resCh <- result
+			errCh <- err
+		})
+		select {
+		case <-ctx.Done():
+			return PublishResult{}, ctx.Err()
+		case res := <-resCh:
+			return res, <-errCh
+		}
 	})
-	select {
-	case <-ctx.Done():
-		return PublishResult{}, ctx.Err()
-	case res := <-resCh:
-		return res, <-errCh
+}
+
+type PublishOptions struct {
+	// Retry enables retrying of publish failed with retryable error.
+	Retry *RetryPolicy
+	// Priority of publish command, see Priority.
+	Priority Priority
+	// Headers of PublicationEnvelope, see WithHeaders.
+	Headers map[string]string
+	// SchemaVersion of PublicationEnvelope, see WithSchemaVersion.
+	SchemaVersion string
+	// ContentType of PublicationEnvelope, see WithContentType.
+	ContentType string
+}
+
+type PublishOption func(options *PublishOptions)
+
+// WithRetry retries publish failed with retryable error (timeout, connection
+// problem, temporary server error) according to policy. Upon failure RetryError
+// with all attempts is returned.
+func WithRetry(policy RetryPolicy) PublishOption {
+	return func(options *PublishOptions) {
+		options.Retry = &policy
+	}
+}
+
+// WithPriority sets priority of publish command. Commands with higher priority
+// are written first when connection is slow.
+func WithPriority(priority Priority) PublishOption {
+	return func(options *PublishOptions) {
+		options.Priority = priority
 	}
 }
 
@@ -167,6 +313,8 @@ type HistoryOptions struct {
 	Limit   int32
 	Since   *StreamPosition
 	Reverse bool
+	// TagFilter keeps only publications having all these tags, see WithTagFilter.
+	TagFilter map[string]string
 }
 
 type HistoryOption func(options *HistoryOptions)
@@ -189,6 +337,15 @@ func WithHistoryReverse(reverse bool) HistoryOption {
 	}
 }
 
+// WithTagFilter keeps only publications which have all tags with the same values.
+// Filtering is done on client side, so result may contain less publications than
+// requested limit.
+func WithTagFilter(tags map[string]string) HistoryOption {
+	return func(options *HistoryOptions) {
+		options.TagFilter = tags
+	}
+}
+
 // History allows extracting channel history. By default, it returns current stream top
 // position without publications. Use WithHistoryLimit with a value > 0 to make this func
 // to return publications.
@@ -207,6 +364,7 @@ func (s *Subscription) History(ctx context.Context, opts ...HistoryOption) (Hist
 	resCh := make(chan HistoryResult, 1)
 	errCh := make(chan error, 1)
 	s.history(ctx, *historyOpts, func(result HistoryResult, err error) {
+		result.Publications = filterByTags(result.Publications, historyOpts.TagFilter)
 		resCh <- result
 		errCh <- err
 	})
@@ -219,7 +377,7 @@ func (s *Subscription) History(ctx context.Context, opts ...HistoryOption) (Hist
 }
 
 // Presence allows extracting channel presence.
-func (s *Subscription) Presence(ctx context.Context) (PresenceResult, error) {
+func (s *Subscription) Presence(ctx context.Context, opts ...PresenceOption) (PresenceResult, error) {
 	s.mu.Lock()
 	if s.state == SubStateUnsubscribed {
 		s.mu.Unlock()
@@ -227,9 +385,16 @@ func (s *Subscription) Presence(ctx context.Context) (PresenceResult, error) {
 	}
 	s.mu.Unlock()
 
+	presenceOpts := PresenceOptions{WithoutSelf: s.withoutSelf}
+	for _, opt := range opts {
+		opt(&presenceOpts)
+	}
 	resCh := make(chan PresenceResult, 1)
 	errCh := make(chan error, 1)
 	s.presence(ctx, func(result PresenceResult, err error) {
+		if presenceOpts.WithoutSelf {
+			result = withoutClient(result, s.centrifuge.currentClientID())
+		}
 		resCh <- result
 		errCh <- err
 	})
@@ -292,7 +457,7 @@ func (s *Subscription) onSubscribe(fn func(err error)) {
 	}
 }
 
-func (s *Subscription) publish(ctx context.Context, data []byte, fn func(PublishResult, error)) {
+func (s *Subscription) publish(ctx context.Context, data []byte, priority Priority, fn func(PublishResult, error)) {
 	s.onSubscribe(func(err error) {
 		select {
 		case <-ctx.Done():
@@ -304,7 +469,7 @@ func (s *Subscription) publish(ctx context.Context, data []byte, fn func(Publish
 			fn(PublishResult{}, err)
 			return
 		}
-		s.centrifuge.publish(ctx, s.Channel, data, fn)
+		s.centrifuge.publish(ctx, s.Channel, data, priority, fn)
 	})
 }
 
@@ -367,6 +532,7 @@ func (s *Subscription) Unsubscribe() error {
 
 func (s *Subscription) unsubscribe(code uint32, reason string, sendUnsubscribe bool) {
 	s.moveToUnsubscribed(code, reason)
+	s.flushPositions()
 	if sendUnsubscribe {
 		s.centrifuge.unsubscribe(s.Channel, func(result UnsubscribeResult, err error) {
 			if err != nil {
@@ -403,7 +569,7 @@ func (s *Subscription) Subscribe() error {
 	if !s.centrifuge.isConnected() {
 		return nil
 	}
-	s.resubscribe()
+	s.resubscribe(nil)
 	return nil
 }
 
@@ -419,6 +585,7 @@ func (s *Subscription) moveToUnsubscribed(code uint32, reason string) {
 
 	needEvent := s.state != SubStateUnsubscribed
 	s.state = SubStateUnsubscribed
+	s.replayBuffer = nil
 	s.mu.Unlock()
 
 	if needEvent && s.events != nil && s.events.onUnsubscribe != nil {
@@ -442,7 +609,11 @@ func (s *Subscription) moveToSubscribing(code uint32, reason string) {
 		s.refreshTimer.Stop()
 	}
 	needEvent := s.state != SubStateSubscribing
+	if s.state == SubStateSubscribed {
+		s.lostAt = time.Now()
+	}
 	s.state = SubStateSubscribing
+	s.replayBuffer = nil
 	s.mu.Unlock()
 
 	if needEvent && s.events != nil && s.events.onSubscribing != nil {
@@ -464,7 +635,7 @@ func (s *Subscription) moveToSubscribed(res *protocol.SubscribeResult) {
 	}
 	s.state = SubStateSubscribed
 	if res.Expires {
-		s.scheduleSubRefresh(res.Ttl)
+		s.scheduleSubRefresh(s.centrifuge.tokenRefreshDelay(res.Ttl, s.token))
 	}
 	if res.Recoverable {
 		s.recover = true
@@ -474,16 +645,37 @@ func (s *Subscription) moveToSubscribed(res *protocol.SubscribeResult) {
 		s.resubscribeTimer.Stop()
 	}
 	s.resolveSubFutures(nil)
+	if s.presenceWatcher != nil {
+		s.presenceWatcher.requestSync()
+	}
+	replaySince, replayLimit, replay := s.historyReplayLocked(res)
+	if replay {
+		s.replayBuffer = []*protocol.Publication{}
+	}
+	pubs := s.recoveredPublicationsLocked(res)
+	aborted := s.recoveryAbortedLocked(pubs)
+	if aborted {
+		pubs = nil
+	}
 	s.offset = res.Offset
 	s.epoch = res.Epoch
+	if len(pubs) == 0 && !replay {
+		s.processedOffset.Store(res.Offset)
+	}
 	s.deltaNegotiated = res.Delta
 	s.mu.Unlock()
+	if len(pubs) == 0 && !replay && (s.acks == nil || !s.acks.hasPending()) {
+		s.storePosition(StreamPosition{Offset: res.Offset, Epoch: res.Epoch})
+	}
+	if aborted {
+		s.emitRecoveryAborted(len(res.Publications))
+	}
 
 	if s.events != nil && s.events.onSubscribed != nil {
 		handler := s.events.onSubscribed
 		ev := SubscribedEvent{
 			Data:          res.GetData(),
-			Recovered:     res.GetRecovered(),
+			Recovered:     res.GetRecovered() && !aborted,
 			WasRecovering: res.GetWasRecovering(),
 			Recoverable:   res.GetRecoverable(),
 			Positioned:    res.GetPositioned(),
@@ -499,11 +691,11 @@ func (s *Subscription) moveToSubscribed(res *protocol.SubscribeResult) {
 		})
 	}
 
-	if len(res.Publications) > 0 {
-		s.centrifuge.runHandlerSync(func() {
-			pubs := res.Publications
+	if len(pubs) > 0 {
+		s.centrifuge.runDataHandler(func() {
+			progress := s.newRecoveryProgress(len(pubs))
 			for i := 0; i < len(pubs); i++ {
-				pub := res.Publications[i]
+				pub := pubs[i]
 				s.mu.Lock()
 				if s.state != SubStateSubscribed {
 					s.mu.Unlock()
@@ -512,19 +704,28 @@ func (s *Subscription) moveToSubscribed(res *protocol.SubscribeResult) {
 				if pub.Offset > 0 {
 					s.offset = pub.Offset
 				}
-				publicationEvent := PublicationEvent{Publication: pubFromProto(pub)}
+				s.countPublication(pub)
+				publicationEvent := s.publicationEventLocked(pub)
 				publicationEvent = s.applyDeltaLocked(pub, publicationEvent)
 				s.mu.Unlock()
+				publicationEvent = s.unwrapEnvelope(publicationEvent)
 				var handler PublicationHandler
 				if s.events != nil && s.events.onPublication != nil {
 					handler = s.events.onPublication
 				}
 				if handler != nil {
+					publicationEvent.QueuedFor = s.centrifuge.queuedFor
 					handler(publicationEvent)
 				}
+				s.markProcessed(pub.Offset)
+				progress.advance()
 			}
 		})
 	}
+
+	if replay {
+		go s.replayHistory(replaySince, replayLimit, res.Offset)
+	}
 }
 
 func (s *Subscription) applyDeltaLocked(pub *protocol.Publication, event PublicationEvent) PublicationEvent {
@@ -574,14 +775,14 @@ func (s *Subscription) applyDeltaLocked(pub *protocol.Publication, event Publica
 func (s *Subscription) scheduleResubscribe() {
 	delay := s.resubscribeStrategy.timeBeforeNextAttempt(s.resubscribeAttempts)
 	s.resubscribeAttempts++
-	s.resubscribeTimer = time.AfterFunc(delay, func() {
+	s.resubscribeTimer = s.centrifuge.scheduler.AfterFunc(delay, func() {
 		s.mu.Lock()
 		if s.state != SubStateSubscribing {
 			s.mu.Unlock()
 			return
 		}
 		s.mu.Unlock()
-		s.resubscribe()
+		s.resubscribe(nil)
 	})
 }
 
@@ -602,6 +803,9 @@ func (s *Subscription) subscribeError(err error) {
 
 	var serverError *Error
 	if errors.As(err, &serverError) {
+		if isTokenRejectedError(err) {
+			s.centrifuge.invalidateToken(s.Channel)
+		}
 		if serverError.Code == 109 { // Token expired.
 			s.mu.Lock()
 			s.token = ""
@@ -640,45 +844,92 @@ func (s *Subscription) handlePublication(pub *protocol.Publication) {
 		s.mu.Unlock()
 		return
 	}
+	if s.replayBuffer != nil {
+		// Delivered after replayed publications.
+		s.replayBuffer = append(s.replayBuffer, pub)
+		s.mu.Unlock()
+		return
+	}
+	s.deliverPublicationLocked(pub)
+}
+
+// deliverPublicationLocked passes publication to OnPublication handler. Lock must
+// be held outside, it's released inside.
+func (s *Subscription) deliverPublicationLocked(pub *protocol.Publication) {
+	if s.detectGaps && pub.Offset > 0 && s.offset > 0 && pub.Offset != s.offset+1 {
+		expected := s.offset + 1
+		s.mu.Unlock()
+		if pub.Offset > expected {
+			s.handleGap(expected, pub.Offset)
+		}
+		// Duplicate publications are dropped.
+		return
+	}
 	if pub.Offset > 0 {
 		s.offset = pub.Offset
 	}
-	publicationEvent := PublicationEvent{Publication: pubFromProto(pub)}
+	s.countPublication(pub)
+	publicationEvent := s.publicationEventLocked(pub)
 	publicationEvent = s.applyDeltaLocked(pub, publicationEvent)
 	s.mu.Unlock()
+	// Decompression may be slow, so envelope is unwrapped without lock.
+	publicationEvent = s.unwrapEnvelope(publicationEvent)
 
 	var handler PublicationHandler
 	if s.events != nil && s.events.onPublication != nil {
 		handler = s.events.onPublication
 	}
 	if handler == nil {
+		s.markProcessed(pub.Offset)
 		return
 	}
-	s.centrifuge.runHandlerSync(func() {
+	s.centrifuge.runDataHandler(func() {
+		publicationEvent.QueuedFor = s.centrifuge.queuedFor
 		handler(publicationEvent)
+		s.markProcessed(pub.Offset)
 	})
 }
 
 func (s *Subscription) handleJoin(info *protocol.ClientInfo) {
+	if s.isSelf(info.GetClient()) {
+		return
+	}
+	if w := s.watcher(); w != nil {
+		w.join(infoFromProto(info))
+	}
+	if s.joinLeaveBuffer != nil {
+		s.joinLeaveBuffer.add(JoinLeaveEvent{ClientInfo: infoFromProto(info)})
+		return
+	}
 	var handler JoinHandler
 	if s.events != nil && s.events.onJoin != nil {
 		handler = s.events.onJoin
 	}
 	if handler != nil {
-		s.centrifuge.runHandlerSync(func() {
-			handler(JoinEvent{ClientInfo: infoFromProto(info)})
+		s.centrifuge.runDataHandler(func() {
+			handler(JoinEvent{ClientInfo: infoFromProto(info), QueuedFor: s.centrifuge.queuedFor})
 		})
 	}
 }
 
 func (s *Subscription) handleLeave(info *protocol.ClientInfo) {
+	if s.isSelf(info.GetClient()) {
+		return
+	}
+	if w := s.watcher(); w != nil {
+		w.leave(infoFromProto(info))
+	}
+	if s.joinLeaveBuffer != nil {
+		s.joinLeaveBuffer.add(JoinLeaveEvent{ClientInfo: infoFromProto(info), Leave: true})
+		return
+	}
 	var handler LeaveHandler
 	if s.events != nil && s.events.onLeave != nil {
 		handler = s.events.onLeave
 	}
 	if handler != nil {
-		s.centrifuge.runHandlerSync(func() {
-			handler(LeaveEvent{ClientInfo: infoFromProto(info)})
+		s.centrifuge.runDataHandler(func() {
+			handler(LeaveEvent{ClientInfo: infoFromProto(info), QueuedFor: s.centrifuge.queuedFor})
 		})
 	}
 }
@@ -688,11 +939,13 @@ func (s *Subscription) handleUnsubscribe(unsubscribe *protocol.Unsubscribe) {
 		s.moveToUnsubscribed(unsubscribe.Code, unsubscribe.Reason)
 	} else {
 		s.moveToSubscribing(unsubscribe.Code, unsubscribe.Reason)
-		s.resubscribe()
+		s.resubscribe(nil)
 	}
 }
 
-func (s *Subscription) resubscribe() {
+// resubscribe sends subscribe command to a server. If batch is not nil then command
+// is only added to it, caller is responsible for sending the batch.
+func (s *Subscription) resubscribe(batch *commandBatch) {
 	s.mu.Lock()
 	if s.state != SubStateSubscribing {
 		s.mu.Unlock()
@@ -701,7 +954,7 @@ func (s *Subscription) resubscribe() {
 	token := s.token
 	s.mu.Unlock()
 
-	if token == "" && s.getToken != nil {
+	if token == "" && (s.getToken != nil || s.centrifuge.config.TokenProvider != nil) {
 		var err error
 		token, err = s.getSubscriptionToken(s.Channel)
 		if err != nil {
@@ -736,7 +989,7 @@ func (s *Subscription) resubscribe() {
 		sp.Epoch = s.epoch
 	}
 
-	err := s.centrifuge.sendSubscribe(s.Channel, s.data, isRecover, sp, token, s.positioned, s.recoverable, s.joinLeave, s.deltaType, func(res *protocol.SubscribeResult, err error) {
+	err := s.centrifuge.sendSubscribe(s.Channel, s.data, isRecover, sp, token, s.positioned, s.recoverable, s.joinLeave, s.deltaType, batch, func(res *protocol.SubscribeResult, err error) {
 		if err != nil {
 			s.subscribeError(err)
 			return
@@ -749,22 +1002,29 @@ func (s *Subscription) resubscribe() {
 }
 
 func (s *Subscription) getSubscriptionToken(channel string) (string, error) {
+	ev := SubscriptionTokenEvent{
+		Channel: channel,
+	}
 	handler := s.getToken
 	if handler != nil {
-		ev := SubscriptionTokenEvent{
-			Channel: channel,
-		}
-		return handler(ev)
+		return callSafe(s.centrifuge, "SubscriptionConfig.GetToken", func() (string, error) {
+			return handler(ev)
+		})
+	}
+	if s.centrifuge.config.TokenProvider != nil {
+		return callSafe(s.centrifuge, "TokenProvider", func() (string, error) {
+			return s.centrifuge.config.TokenProvider.SubscriptionToken(ev)
+		})
 	}
 	return "", errors.New("GetToken must be set to get subscription token")
 }
 
 // Lock must be held outside.
-func (s *Subscription) scheduleSubRefresh(ttl uint32) {
+func (s *Subscription) scheduleSubRefresh(delay time.Duration) {
 	if s.state != SubStateSubscribed {
 		return
 	}
-	s.refreshTimer = time.AfterFunc(time.Duration(ttl)*time.Second, func() {
+	s.refreshTimer = s.centrifuge.scheduler.AfterFunc(delay, func() {
 		s.mu.Lock()
 		if s.state != SubStateSubscribed {
 			s.mu.Unlock()
@@ -772,6 +1032,8 @@ func (s *Subscription) scheduleSubRefresh(ttl uint32) {
 		}
 		s.mu.Unlock()
 
+		// Current token is about to expire – make sure we get a new one.
+		s.centrifuge.invalidateToken(s.Channel)
 		token, err := s.getSubscriptionToken(s.Channel)
 		if err != nil {
 			if errors.Is(err, ErrUnauthorized) {
@@ -781,7 +1043,7 @@ func (s *Subscription) scheduleSubRefresh(ttl uint32) {
 			s.mu.Lock()
 			defer s.mu.Unlock()
 			s.emitError(SubscriptionRefreshError{Err: err})
-			s.scheduleSubRefresh(10)
+			s.scheduleSubRefresh(10 * time.Second)
 			return
 		}
 		if token == "" {
@@ -797,7 +1059,7 @@ func (s *Subscription) scheduleSubRefresh(ttl uint32) {
 					if serverError.Temporary {
 						s.mu.Lock()
 						defer s.mu.Unlock()
-						s.scheduleSubRefresh(10)
+						s.scheduleSubRefresh(10 * time.Second)
 						return
 					} else {
 						s.unsubscribe(serverError.Code, serverError.Message, true)
@@ -806,13 +1068,13 @@ func (s *Subscription) scheduleSubRefresh(ttl uint32) {
 				} else {
 					s.mu.Lock()
 					defer s.mu.Unlock()
-					s.scheduleSubRefresh(10)
+					s.scheduleSubRefresh(10 * time.Second)
 					return
 				}
 			}
 			if result.Expires {
 				s.mu.Lock()
-				s.scheduleSubRefresh(result.Ttl)
+				s.scheduleSubRefresh(s.centrifuge.tokenRefreshDelay(result.Ttl, token))
 				s.mu.Unlock()
 			}
 		})
@@ -1,5 +1,9 @@
 package centrifuge
 
+import (
+	"time"
+)
+
 // SubscribedEvent is an event context passed
 // to subscribe success callback.
 type SubscribedEvent struct {
@@ -32,16 +36,88 @@ type UnsubscribedEvent struct {
 // LeaveEvent has info about user who left channel.
 type LeaveEvent struct {
 	ClientInfo
+	// QueuedFor is the time event waited in event queue.
+	QueuedFor time.Duration
 }
 
 // JoinEvent has info about user who joined channel.
 type JoinEvent struct {
 	ClientInfo
+	// QueuedFor is the time event waited in event queue.
+	QueuedFor time.Duration
 }
 
 // PublicationEvent has info about received channel Publication.
 type PublicationEvent struct {
 	Publication
+	// StreamPosition of publication, set if subscription is Positioned or
+	// Recoverable.
+	StreamPosition *StreamPosition
+	// Replayed is true for publications loaded from history after subscription
+	// was not recovered by server, see SubscriptionConfig.RecoverViaHistory.
+	// Replayed publications may arrive after newer live publications.
+	Replayed bool
+	// Envelope contains metadata of publication published in PublicationEnvelope,
+	// its Data is moved to Publication.Data. Nil if SubscriptionConfig.Envelope
+	// is not set or publication is not an envelope.
+	Envelope *PublicationEnvelope
+	// QueuedFor is the time event waited for previous handlers to finish. Large
+	// values mean handler processes stale data due to event backlog.
+	QueuedFor time.Duration
+
+	ack func()
+}
+
+// GapDetectedEvent is passed to OnGapDetected callback when publication offset is
+// not the next one. See SubscriptionConfig.DetectGaps.
+type GapDetectedEvent struct {
+	// Expected is the offset of the next publication.
+	Expected uint64
+	// Received is the offset of received publication.
+	Received uint64
+	// Epoch of stream.
+	Epoch string
+}
+
+// RecoveringEvent reports progress of passing publications recovered after
+// resubscribe to OnPublication handler. Emitted every 100 publications and once
+// all recovered publications were passed.
+type RecoveringEvent struct {
+	// Recovered is the number of publications passed to handler so far.
+	Recovered int
+	// Total is the approximate number of publications to recover.
+	Total int
+	// Elapsed is the time passed since recovered publications started to be
+	// processed.
+	Elapsed time.Duration
+	// Aborted is true if recovered publications were dropped since there were more
+	// than SubscriptionConfig.MaxRecoveredPublications.
+	Aborted bool
+}
+
+// JoinLeaveEvent is a join or leave message passed to OnJoinLeave callback in
+// batch. See SubscriptionConfig.JoinLeaveWindow.
+type JoinLeaveEvent struct {
+	ClientInfo
+	// Leave is true for leave message and false for join message.
+	Leave bool
+}
+
+// PresenceChangedEvent is passed to OnPresenceChanged callback of PresenceWatcher
+// when channel membership changed.
+type PresenceChangedEvent struct {
+	// Joined clients.
+	Joined []ClientInfo
+	// Left clients.
+	Left []ClientInfo
+	// Changed clients which info changed, found upon reconciliation.
+	Changed []ClientInfo
+}
+
+// PresenceStaleEvent is passed to OnPresenceStale callback of PresenceWatcher with
+// clients not confirmed within PresenceWatchOptions.StaleAfter.
+type PresenceStaleEvent struct {
+	Clients []ClientInfo
 }
 
 // PublicationHandler is a function to handle messages published in
@@ -54,6 +130,21 @@ type JoinHandler func(JoinEvent)
 // LeaveHandler is a function to handle leave messages.
 type LeaveHandler func(LeaveEvent)
 
+// GapDetectedHandler is a function to handle gap detected event.
+type GapDetectedHandler func(GapDetectedEvent)
+
+// RecoveringHandler is a function to handle recovering progress event.
+type RecoveringHandler func(RecoveringEvent)
+
+// JoinLeaveHandler is a function to handle batch of join and leave messages.
+type JoinLeaveHandler func([]JoinLeaveEvent)
+
+// PresenceChangedHandler is a function to handle presence changed event.
+type PresenceChangedHandler func(PresenceChangedEvent)
+
+// PresenceStaleHandler is a function to handle presence stale event.
+type PresenceStaleHandler func(PresenceStaleEvent)
+
 // UnsubscribedHandler is a function to handle unsubscribe event.
 type UnsubscribedHandler func(UnsubscribedEvent)
 
@@ -76,6 +167,12 @@ type subscriptionEventHub struct {
 	onPublication PublicationHandler
 	onJoin        JoinHandler
 	onLeave       LeaveHandler
+	onGapDetected GapDetectedHandler
+	onRecovering  RecoveringHandler
+
+	onPresenceChanged PresenceChangedHandler
+	onJoinLeave       JoinLeaveHandler
+	onPresenceStale   PresenceStaleHandler
 }
 
 // newSubscriptionEventHub initializes new subscriptionEventHub.
@@ -117,3 +214,33 @@ func (s *Subscription) OnJoin(handler JoinHandler) {
 func (s *Subscription) OnLeave(handler LeaveHandler) {
 	s.events.onLeave = handler
 }
+
+// OnGapDetected allows setting GapDetectedHandler to SubEventHandler. Called only
+// if SubscriptionConfig.DetectGaps enabled.
+func (s *Subscription) OnGapDetected(handler GapDetectedHandler) {
+	s.events.onGapDetected = handler
+}
+
+// OnRecovering allows setting RecoveringHandler to SubEventHandler. Called from
+// the same goroutine as OnPublication while recovered publications are processed.
+func (s *Subscription) OnRecovering(handler RecoveringHandler) {
+	s.events.onRecovering = handler
+}
+
+// OnPresenceChanged allows setting PresenceChangedHandler to SubEventHandler.
+// Called only if PresenceWatcher started with WatchPresence.
+func (s *Subscription) OnPresenceChanged(handler PresenceChangedHandler) {
+	s.events.onPresenceChanged = handler
+}
+
+// OnJoinLeave allows setting JoinLeaveHandler to SubEventHandler. Called only if
+// SubscriptionConfig.JoinLeaveWindow set.
+func (s *Subscription) OnJoinLeave(handler JoinLeaveHandler) {
+	s.events.onJoinLeave = handler
+}
+
+// OnPresenceStale allows setting PresenceStaleHandler to SubEventHandler. Called
+// only if PresenceWatcher started with WithPresenceStaleAfter option.
+func (s *Subscription) OnPresenceStale(handler PresenceStaleHandler) {
+	s.events.onPresenceStale = handler
+}
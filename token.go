@@ -0,0 +1,149 @@
+package centrifuge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// minTokenRefreshDelay protects from refreshing token in a busy loop when token
+// is already expired according to local clock.
+const minTokenRefreshDelay = time.Second
+
+// tokenExpiresAt extracts expiration time from JWT exp claim. Signature is not
+// verified – this is only used as a hint for scheduling refresh. Returns false if
+// token is not a JWT or has no exp claim.
+func tokenExpiresAt(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(claims.Exp), 0), true
+}
+
+// tokenRefreshDelay returns how long to wait before refreshing token which expires
+// in ttl seconds according to a server. With Config.TokenRefreshAhead set, exp
+// claim of token is used instead if it comes earlier, and TokenRefreshAhead is
+// then subtracted. Without it server TTL is used as is.
+func (c *Client) tokenRefreshDelay(ttl uint32, token string) time.Duration {
+	delay := time.Duration(ttl) * time.Second
+	if c.config.TokenRefreshAhead <= 0 {
+		return delay
+	}
+	if exp, ok := tokenExpiresAt(token); ok {
+		if untilExp := time.Until(exp); untilExp < delay {
+			delay = untilExp
+		}
+	}
+	delay -= c.config.TokenRefreshAhead
+	if delay < minTokenRefreshDelay {
+		delay = minTokenRefreshDelay
+	}
+	return delay
+}
+
+// invalidateToken notifies TokenProvider that token must not be reused. Channel is
+// empty for connection token.
+func (c *Client) invalidateToken(channel string) {
+	if c.config.TokenProvider != nil {
+		func() {
+			defer c.recoverCallback("TokenProvider")
+			c.config.TokenProvider.InvalidateToken(TokenInvalidateEvent{Channel: channel})
+		}()
+	}
+}
+
+// rejectToken invalidates token rejected by a server. Stored connection credentials
+// are cleared too – unlike the proactive refresh, where stored token is still valid
+// until a new one is saved.
+func (c *Client) rejectToken(channel string) {
+	c.invalidateToken(channel)
+	if channel == "" && c.config.CredentialStore != nil {
+		if err := c.config.CredentialStore.Save(Credentials{}); err != nil {
+			c.handleError(CredentialStoreError{err})
+		}
+	}
+}
+
+// loadStoredToken returns connection token from Config.CredentialStore if it's not
+// expired.
+func (c *Client) loadStoredToken() (string, bool) {
+	creds, err := c.config.CredentialStore.Load()
+	if err != nil {
+		c.handleError(CredentialStoreError{err})
+		return "", false
+	}
+	if creds.Token == "" {
+		return "", false
+	}
+	if !creds.ExpiresAt.IsZero() && !time.Now().Add(c.config.TokenRefreshAhead).Before(creds.ExpiresAt) {
+		return "", false
+	}
+	return creds.Token, true
+}
+
+// saveToken saves connection token to Config.CredentialStore (if set).
+func (c *Client) saveToken(token string) {
+	if c.config.CredentialStore == nil {
+		return
+	}
+	creds := Credentials{Token: token}
+	if exp, ok := tokenExpiresAt(token); ok {
+		creds.ExpiresAt = exp
+	}
+	if err := c.config.CredentialStore.Save(creds); err != nil {
+		c.handleError(CredentialStoreError{err})
+	}
+}
+
+// applyTokenTransport puts connection token to WebSocket Upgrade request according
+// to Config.TokenTransport. Returns endpoint to dial.
+func (c *Client) applyTokenTransport(endpoint string, token string, wsConfig *websocketConfig) string {
+	switch c.config.TokenTransport {
+	case TokenTransportHeader:
+		wsConfig.Header = wsConfig.Header.Clone()
+		wsConfig.Header.Set("Authorization", "Bearer "+token)
+	case TokenTransportSubprotocol:
+		wsConfig.Subprotocols = []string{TokenSubprotocolPrefix + token}
+	case TokenTransportQuery:
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			// Dial will fail with proper error.
+			return endpoint
+		}
+		q := u.Query()
+		q.Set("token", token)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return endpoint
+}
+
+// authRequired checks whether disconnect code means that server rejected
+// connection credentials, so application should re-authenticate user. Also returns
+// whether reconnecting with new credentials makes sense: it does not when user was
+// authenticated but has no permission to connect.
+func authRequired(code uint32) (bool, bool) {
+	switch code {
+	case disconnectedUnauthorized, 101, 3500:
+		// Client-side unauthorized (ErrUnauthorized returned from GetToken), server
+		// unauthorized error, server disconnect with invalid token.
+		return true, true
+	case 103, 3507:
+		// Server permission denied error and disconnect.
+		return true, false
+	}
+	return false, false
+}
@@ -0,0 +1,129 @@
+package centrifuge
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// TokenProvider is an alternative to Config.GetToken and SubscriptionConfig.GetToken
+// functions which allows managing all tokens of a Client in one place. Set it over
+// Config.TokenProvider. Methods may be called concurrently from different goroutines.
+type TokenProvider interface {
+	// ConnectionToken returns connection token.
+	ConnectionToken(ConnectionTokenEvent) (string, error)
+	// SubscriptionToken returns subscription token for a channel. Only called for
+	// subscriptions without SubscriptionConfig.GetToken set.
+	SubscriptionToken(SubscriptionTokenEvent) (string, error)
+	// InvalidateToken is called by SDK when previously returned token must not be
+	// returned again: it's about to be refreshed, expired or rejected by a server.
+	InvalidateToken(TokenInvalidateEvent)
+}
+
+// TokenInvalidateEvent is passed to TokenProvider.InvalidateToken.
+type TokenInvalidateEvent struct {
+	// Channel is a channel of subscription token. Empty for connection token.
+	Channel string
+}
+
+// CachingTokenProvider is a TokenProvider which caches tokens returned by wrapped
+// functions until they are invalidated by SDK or expire according to JWT exp claim.
+// Concurrent requests for the same token result into a single call of the wrapped
+// function.
+type CachingTokenProvider struct {
+	getConnectionToken   func(ConnectionTokenEvent) (string, error)
+	getSubscriptionToken func(SubscriptionTokenEvent) (string, error)
+
+	mu       sync.Mutex
+	tokens   map[string]string
+	inflight map[string]*tokenCall
+}
+
+type tokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+var _ TokenProvider = (*CachingTokenProvider)(nil)
+
+// NewCachingTokenProvider creates CachingTokenProvider. Any of functions may be nil
+// if corresponding tokens are not used.
+func NewCachingTokenProvider(
+	getConnectionToken func(ConnectionTokenEvent) (string, error),
+	getSubscriptionToken func(SubscriptionTokenEvent) (string, error),
+) *CachingTokenProvider {
+	return &CachingTokenProvider{
+		getConnectionToken:   getConnectionToken,
+		getSubscriptionToken: getSubscriptionToken,
+		tokens:               make(map[string]string),
+		inflight:             make(map[string]*tokenCall),
+	}
+}
+
+// ConnectionToken returns cached connection token or gets a new one.
+func (p *CachingTokenProvider) ConnectionToken(event ConnectionTokenEvent) (string, error) {
+	if p.getConnectionToken == nil {
+		return "", errors.New("connection token function not set")
+	}
+	return p.get("", func() (string, error) {
+		return p.getConnectionToken(event)
+	})
+}
+
+// SubscriptionToken returns cached subscription token or gets a new one.
+func (p *CachingTokenProvider) SubscriptionToken(event SubscriptionTokenEvent) (string, error) {
+	if p.getSubscriptionToken == nil {
+		return "", errors.New("subscription token function not set")
+	}
+	return p.get(event.Channel, func() (string, error) {
+		return p.getSubscriptionToken(event)
+	})
+}
+
+// InvalidateToken removes token from cache.
+func (p *CachingTokenProvider) InvalidateToken(event TokenInvalidateEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.tokens, event.Channel)
+}
+
+func (p *CachingTokenProvider) get(key string, fn func() (string, error)) (string, error) {
+	p.mu.Lock()
+	if token, ok := p.tokens[key]; ok {
+		if exp, ok := tokenExpiresAt(token); !ok || time.Now().Before(exp) {
+			p.mu.Unlock()
+			return token, nil
+		}
+		delete(p.tokens, key)
+	}
+	if call, ok := p.inflight[key]; ok {
+		p.mu.Unlock()
+		<-call.done
+		return call.token, call.err
+	}
+	call := &tokenCall{done: make(chan struct{})}
+	p.inflight[key] = call
+	p.mu.Unlock()
+
+	call.token, call.err = fn()
+
+	p.mu.Lock()
+	delete(p.inflight, key)
+	if call.err == nil && call.token != "" {
+		p.tokens[key] = call.token
+	}
+	p.mu.Unlock()
+	close(call.done)
+	return call.token, call.err
+}
+
+// isTokenRejectedError checks whether server rejected token: unauthorized (101),
+// permission denied (103) or token expired (109).
+func isTokenRejectedError(err error) bool {
+	var serverError *Error
+	if errors.As(err, &serverError) {
+		return serverError.Code == 101 || serverError.Code == 103 || serverError.Code == 109
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package centrifuge
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachingTokenProvider_Cache(t *testing.T) {
+	var numCalls int64
+	p := NewCachingTokenProvider(func(ConnectionTokenEvent) (string, error) {
+		return "token" + strconv.FormatInt(atomic.AddInt64(&numCalls, 1), 10), nil
+	}, nil)
+	token, err := p.ConnectionToken(ConnectionTokenEvent{})
+	if err != nil || token != "token1" {
+		t.Fatalf("unexpected token: %s, %v", token, err)
+	}
+	token, _ = p.ConnectionToken(ConnectionTokenEvent{})
+	if token != "token1" {
+		t.Fatalf("expected cached token, got %s", token)
+	}
+	p.InvalidateToken(TokenInvalidateEvent{})
+	token, _ = p.ConnectionToken(ConnectionTokenEvent{})
+	if token != "token2" {
+		t.Fatalf("expected new token after invalidation, got %s", token)
+	}
+	if _, err := p.SubscriptionToken(SubscriptionTokenEvent{Channel: "test"}); err == nil {
+		t.Fatal("expected error for unset subscription token function")
+	}
+}
+
+func TestCachingTokenProvider_Expired(t *testing.T) {
+	var numCalls int64
+	p := NewCachingTokenProvider(nil, func(e SubscriptionTokenEvent) (string, error) {
+		atomic.AddInt64(&numCalls, 1)
+		return testJWT(fmt.Sprintf(`{"channel":%q,"exp":%d}`, e.Channel, time.Now().Add(-time.Minute).Unix())), nil
+	})
+	_, _ = p.SubscriptionToken(SubscriptionTokenEvent{Channel: "test"})
+	_, _ = p.SubscriptionToken(SubscriptionTokenEvent{Channel: "test"})
+	if atomic.LoadInt64(&numCalls) != 2 {
+		t.Fatalf("expected expired token to be requested again, calls: %d", numCalls)
+	}
+}
+
+func TestCachingTokenProvider_SingleFlight(t *testing.T) {
+	var numCalls int64
+	release := make(chan struct{})
+	p := NewCachingTokenProvider(func(ConnectionTokenEvent) (string, error) {
+		atomic.AddInt64(&numCalls, 1)
+		<-release
+		return "token", nil
+	}, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := p.ConnectionToken(ConnectionTokenEvent{})
+			if err != nil || token != "token" {
+				t.Errorf("unexpected token: %s, %v", token, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if atomic.LoadInt64(&numCalls) != 1 {
+		t.Fatalf("expected single call, got %d", numCalls)
+	}
+}
+
+func TestIsTokenRejectedError(t *testing.T) {
+	if !isTokenRejectedError(&Error{Code: 109}) {
+		t.Fatal("expected token expired error to be rejected")
+	}
+	if isTokenRejectedError(&Error{Code: 100}) {
+		t.Fatal("internal error is not a token rejection")
+	}
+	if isTokenRejectedError(ErrTimeout) {
+		t.Fatal("timeout is not a token rejection")
+	}
+}
@@ -0,0 +1,142 @@
+package centrifuge
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func testJWT(claims string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(claims))
+	return header + "." + payload + ".signature"
+}
+
+func TestTokenExpiresAt(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	expiresAt, ok := tokenExpiresAt(testJWT(fmt.Sprintf(`{"sub":"42","exp":%d}`, exp)))
+	if !ok {
+		t.Fatal("expected exp to be extracted")
+	}
+	if expiresAt.Unix() != exp {
+		t.Fatalf("expected %d, got %d", exp, expiresAt.Unix())
+	}
+	if _, ok := tokenExpiresAt(testJWT(`{"sub":"42"}`)); ok {
+		t.Fatal("expected no exp")
+	}
+	if _, ok := tokenExpiresAt("opaque-token"); ok {
+		t.Fatal("expected no exp for non-JWT token")
+	}
+}
+
+func TestTokenRefreshDelay(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+		TokenRefreshAhead: 10 * time.Second,
+	})
+	defer client.Close()
+
+	delay := client.tokenRefreshDelay(60, "opaque-token")
+	if delay != 50*time.Second {
+		t.Fatalf("expected 50s, got %s", delay)
+	}
+
+	// JWT exp comes earlier than server TTL.
+	token := testJWT(fmt.Sprintf(`{"exp":%d}`, time.Now().Add(30*time.Second).Unix()))
+	delay = client.tokenRefreshDelay(60, token)
+	if delay > 20*time.Second || delay < 18*time.Second {
+		t.Fatalf("unexpected delay: %s", delay)
+	}
+
+	delay = client.tokenRefreshDelay(5, "opaque-token")
+	if delay != minTokenRefreshDelay {
+		t.Fatalf("expected minimal delay, got %s", delay)
+	}
+}
+
+func TestTokenRefreshDelay_NoRefreshAhead(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+	// Server TTL is used as is: exp claim and minimal delay are not applied.
+	token := testJWT(fmt.Sprintf(`{"exp":%d}`, time.Now().Add(30*time.Second).Unix()))
+	if delay := client.tokenRefreshDelay(60, token); delay != 60*time.Second {
+		t.Fatalf("expected 60s, got %s", delay)
+	}
+	if delay := client.tokenRefreshDelay(0, "opaque-token"); delay != 0 {
+		t.Fatalf("expected zero delay, got %s", delay)
+	}
+}
+
+func TestRefreshFailurePolicy(t *testing.T) {
+	testCases := []struct {
+		policy RefreshFailurePolicy
+		state  State
+	}{
+		{RefreshFailureRetry, StateConnected},
+		{RefreshFailureDisconnect, StateDisconnected},
+		{RefreshFailureClose, StateClosed},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+				GetToken: func(ConnectionTokenEvent) (string, error) {
+					return "", errors.New("boom")
+				},
+				RefreshFailurePolicy: tc.policy,
+				RefreshRetryMinDelay: time.Hour,
+			})
+			defer client.Close()
+			client.mu.Lock()
+			client.state = StateConnected
+			client.mu.Unlock()
+
+			events := make(chan RefreshErrorEvent, 2)
+			client.OnRefreshError(func(e RefreshErrorEvent) {
+				events <- e
+			})
+			numAttempts := 1
+			if tc.policy == RefreshFailureRetry {
+				numAttempts = 2
+			}
+			for i := 0; i < numAttempts; i++ {
+				client.sendRefresh()
+			}
+			for attempt := 1; attempt <= numAttempts; attempt++ {
+				e := <-events
+				if e.Attempt != attempt || e.Policy != tc.policy || e.Error == nil {
+					t.Fatalf("unexpected event: %#v", e)
+				}
+			}
+			deadline := time.Now().Add(time.Second)
+			for client.State() != tc.state {
+				if time.Now().After(deadline) {
+					t.Fatalf("expected state %s, got %s", tc.state, client.State())
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if tc.policy == RefreshFailureRetry {
+				client.mu.RLock()
+				scheduled := client.refreshTimer != nil
+				client.mu.RUnlock()
+				if !scheduled {
+					t.Fatal("expected refresh retry scheduled")
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshRetryDelay(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{
+		RefreshRetryMinDelay: time.Second,
+		RefreshRetryMaxDelay: 4 * time.Second,
+	})
+	defer client.Close()
+	for attempt := 1; attempt < 10; attempt++ {
+		delay := client.refreshRetryDelay(attempt)
+		if delay < time.Second || delay > 4*time.Second {
+			t.Fatalf("delay out of bounds for attempt %d: %s", attempt, delay)
+		}
+	}
+}
@@ -13,6 +13,13 @@ type transport interface {
 	// Write should write Command to connection with specified write timeout.
 	// It should not be thread-safe as we will call it from one goroutine.
 	Write(cmd *protocol.Command, timeout time.Duration) error
+	// WritePriority is like Write but commands waiting to be written are ordered
+	// by priority.
+	WritePriority(cmd *protocol.Command, priority Priority, timeout time.Duration) error
+	// WriteMany should write several Commands to connection in one frame with
+	// specified write timeout.
+	// It should not be thread-safe as we will call it from one goroutine.
+	WriteMany(cmds []*protocol.Command, timeout time.Duration) error
 	// Close should close connection and do all cleanups required.
 	// It must be safe to call Close several times and concurrently with Read
 	// and Write methods.
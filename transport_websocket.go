@@ -1,6 +1,7 @@
 package centrifuge
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -27,11 +28,20 @@ func extractDisconnectWebsocket(err error) *disconnect {
 				code := uint32(closeErr.Code)
 				reason := closeErr.Text
 				reconnect := code < 3500 || code >= 5000 || (code >= 4000 && code < 4500)
+				var cause DisconnectCause
 				if code < 3000 {
 					switch code {
 					case websocket.CloseMessageTooBig:
 						code = disconnectMessageSizeLimit
+						cause = DisconnectCauseMessageSizeLimit
 					default:
+						if code == websocket.CloseAbnormalClosure {
+							cause = DisconnectCauseNetworkError
+						} else {
+							// Centrifuge servers use codes >= 3000, so this is most
+							// probably a close from proxy.
+							cause = DisconnectCauseProxyClose
+						}
 						// We expose codes defined by Centrifuge protocol, hiding
 						// details about transport-specific error codes. We may have extra
 						// optional transportCode field in the future.
@@ -42,6 +52,7 @@ func extractDisconnectWebsocket(err error) *disconnect {
 					Code:      code,
 					Reason:    reason,
 					Reconnect: reconnect,
+					Cause:     cause,
 				}
 			}
 		}
@@ -51,6 +62,7 @@ func extractDisconnectWebsocket(err error) *disconnect {
 
 type websocketTransport struct {
 	mu             sync.Mutex
+	writer         writeScheduler
 	conn           *websocket.Conn
 	protocolType   protocol.Type
 	commandEncoder protocol.CommandEncoder
@@ -59,6 +71,24 @@ type websocketTransport struct {
 	disconnect     *disconnect
 	closed         bool
 	closeCh        chan struct{}
+
+	// batchMu protects batch collecting commands to write in one frame, see
+	// writeBatched.
+	batchMu sync.Mutex
+	batch   *writeBatch
+	// flushMu serializes batch writes.
+	flushMu sync.Mutex
+}
+
+// writeBatch is a group of commands written in one frame.
+type writeBatch struct {
+	cmds []*protocol.Command
+	// priority is the highest priority of batch commands.
+	priority Priority
+	// full is closed when batch reached max size.
+	full chan struct{}
+	done chan struct{}
+	err  error
 }
 
 // websocketConfig configures Websocket transport.
@@ -93,6 +123,26 @@ type websocketConfig struct {
 
 	// Header specifies custom HTTP Header to send.
 	Header http.Header
+
+	// Subprotocols are requested in addition to protocol-specific one.
+	Subprotocols []string
+
+	// Metrics counts bytes sent and received, may be nil.
+	Metrics Metrics
+
+	// ProtocolDump writes frames sent and received, may be nil.
+	ProtocolDump *protocolDumper
+
+	// MaxBatchSize enables coalescing of concurrently written commands into one
+	// frame if greater than 1.
+	MaxBatchSize int
+	// MaxBatchDelay is how long to wait for more commands before writing a batch.
+	MaxBatchDelay time.Duration
+	// Watchdog tracks progress of writes, nil if disabled.
+	Watchdog *watchdog
+	// Spawn runs reader goroutine registered by client, go statement is used
+	// if nil.
+	Spawn func(name string, fn func())
 }
 
 func newWebsocketTransport(url string, protocolType protocol.Type, config websocketConfig) (transport, error) {
@@ -114,10 +164,17 @@ func newWebsocketTransport(url string, protocolType protocol.Type, config websoc
 	if protocolType == protocol.TypeProtobuf {
 		dialer.Subprotocols = []string{"centrifuge-protobuf"}
 	}
+	dialer.Subprotocols = append(dialer.Subprotocols, config.Subprotocols...)
 
 	conn, resp, err := dialer.Dial(url, wsHeaders)
 	if err != nil {
-		return nil, fmt.Errorf("error dial: %v", err)
+		err = fmt.Errorf("error dial: %v", err)
+		if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				return nil, &retryAfterError{err: err, retryAfter: retryAfter}
+			}
+		}
+		return nil, err
 	}
 	if resp.StatusCode != http.StatusSwitchingProtocols {
 		return nil, fmt.Errorf("wrong status code while connecting to server: %d", resp.StatusCode)
@@ -131,7 +188,11 @@ func newWebsocketTransport(url string, protocolType protocol.Type, config websoc
 		commandEncoder: newCommandEncoder(protocolType),
 		protocolType:   protocolType,
 	}
-	go t.reader()
+	if config.Spawn != nil {
+		config.Spawn("transport_reader", t.reader)
+	} else {
+		go t.reader()
+	}
 	return t, nil
 }
 
@@ -152,49 +213,208 @@ func (t *websocketTransport) reader() {
 	defer close(t.replyCh)
 
 	for {
-		_, data, err := t.conn.ReadMessage()
+		var buf *bytes.Buffer
+		var data []byte
+		var err error
+		if t.protocolType == protocol.TypeProtobuf {
+			// Protobuf decoder copies bytes fields into Reply, so it's safe to reuse
+			// read buffer as soon as frame is decoded.
+			buf = getBuffer()
+			err = t.readMessage(buf)
+			data = buf.Bytes()
+		} else {
+			_, data, err = t.conn.ReadMessage()
+		}
 		if err != nil {
+			if buf != nil {
+				putBuffer(buf)
+			}
 			disconnect := extractDisconnectWebsocket(err)
 			t.disconnect = disconnect
 			return
 		}
 		//println("<----", strings.Trim(string(data), "\n"))
-	loop:
-		for {
-			decoder := newReplyDecoder(t.protocolType, data)
-			for {
-				reply, err := decoder.Decode()
-				if err != nil {
-					if err == io.EOF {
-						break loop
-					}
-					t.disconnect = &disconnect{Code: disconnectBadProtocol, Reason: "decode error", Reconnect: false}
-					return
-				}
-				select {
-				case <-t.closeCh:
-					return
-				case t.replyCh <- reply:
-					// Send is blocking here, but slow client will be disconnected
-					// eventually with `no ping` reason – so we will exit from this
-					// goroutine.
-				}
+		if t.config.Metrics != nil {
+			t.config.Metrics.AddBytesReceived(len(data))
+		}
+		if t.config.ProtocolDump != nil {
+			t.config.ProtocolDump.dumpIn(data)
+		}
+		ok := t.decodeReplies(data)
+		if buf != nil {
+			putBuffer(buf)
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+func (t *websocketTransport) readMessage(buf *bytes.Buffer) error {
+	_, r, err := t.conn.NextReader()
+	if err != nil {
+		return err
+	}
+	_, err = buf.ReadFrom(r)
+	return err
+}
+
+// decodeReplies decodes all replies in data and passes them to reader. Returns
+// false if reader goroutine must exit.
+func (t *websocketTransport) decodeReplies(data []byte) bool {
+	decoder := newReplyDecoder(t.protocolType, data)
+	for {
+		reply, err := decoder.Decode()
+		if err != nil {
+			if err == io.EOF {
+				return true
 			}
+			t.disconnect = &disconnect{Code: disconnectBadProtocol, Reason: "decode error", Reconnect: false}
+			return false
+		}
+		select {
+		case <-t.closeCh:
+			return false
+		case t.replyCh <- reply:
+			// Send is blocking here, but slow client will be disconnected
+			// eventually with `no ping` reason – so we will exit from this
+			// goroutine.
 		}
 	}
 }
 
 func (t *websocketTransport) Write(cmd *protocol.Command, timeout time.Duration) error {
+	return t.WritePriority(cmd, commandPriority(cmd), timeout)
+}
+
+func (t *websocketTransport) WritePriority(cmd *protocol.Command, priority Priority, timeout time.Duration) error {
+	if t.config.MaxBatchSize > 1 {
+		return t.writeBatched(cmd, priority, timeout)
+	}
+	return t.writeOne(cmd, priority, timeout)
+}
+
+func (t *websocketTransport) writeOne(cmd *protocol.Command, priority Priority, timeout time.Duration) error {
+	if t.protocolType == protocol.TypeProtobuf {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err := encodeProtobufCommand(buf, cmd); err != nil {
+			return err
+		}
+		// WriteMessage copies data into connection write buffer so buf can be
+		// reused right after it returns.
+		return t.writeData(buf.Bytes(), priority, timeout, cmd)
+	}
 	data, err := t.commandEncoder.Encode(cmd)
 	if err != nil {
 		return err
 	}
-	return t.writeData(data, timeout)
+	return t.writeData(data, priority, timeout, cmd)
 }
 
-func (t *websocketTransport) writeData(data []byte, timeout time.Duration) error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+// writeBatched adds cmd to current batch and waits until batch written. The
+// first writer of batch waits for MaxBatchDelay or until batch is full and
+// then writes it. Commands issued while previous batch is being written are
+// collected into the next one, so under load frames contain many commands even
+// without delay.
+func (t *websocketTransport) writeBatched(cmd *protocol.Command, priority Priority, timeout time.Duration) error {
+	t.batchMu.Lock()
+	b := t.batch
+	leader := b == nil
+	if leader {
+		b = &writeBatch{priority: priority, full: make(chan struct{}), done: make(chan struct{})}
+		t.batch = b
+	}
+	b.cmds = append(b.cmds, cmd)
+	if priority.lane() < b.priority.lane() {
+		b.priority = priority
+	}
+	if len(b.cmds) >= t.config.MaxBatchSize {
+		// Next commands go to a new batch.
+		t.batch = nil
+		close(b.full)
+	}
+	t.batchMu.Unlock()
+
+	if !leader {
+		<-b.done
+		return b.err
+	}
+	if t.config.MaxBatchDelay > 0 {
+		timer := time.NewTimer(t.config.MaxBatchDelay)
+		select {
+		case <-timer.C:
+		case <-b.full:
+			timer.Stop()
+		case <-t.closeCh:
+			timer.Stop()
+		}
+	}
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+	// Stop collecting commands into batch only when previous batch written.
+	t.batchMu.Lock()
+	if t.batch == b {
+		t.batch = nil
+	}
+	t.batchMu.Unlock()
+	if len(b.cmds) == 1 {
+		b.err = t.writeOne(b.cmds[0], b.priority, timeout)
+	} else {
+		b.err = t.writeMany(b.cmds, b.priority, timeout)
+	}
+	close(b.done)
+	return b.err
+}
+
+func (t *websocketTransport) WriteMany(cmds []*protocol.Command, timeout time.Duration) error {
+	priority := PriorityBulk
+	for _, cmd := range cmds {
+		if p := commandPriority(cmd); p.lane() < priority.lane() {
+			priority = p
+		}
+	}
+	return t.writeMany(cmds, priority, timeout)
+}
+
+func (t *websocketTransport) writeMany(cmds []*protocol.Command, priority Priority, timeout time.Duration) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeCommands(buf, t.protocolType, t.commandEncoder, cmds); err != nil {
+		return err
+	}
+	return t.writeData(buf.Bytes(), priority, timeout, cmds...)
+}
+
+// encodeCommands encodes cmds into one frame.
+func encodeCommands(buf *bytes.Buffer, protocolType protocol.Type, encoder protocol.CommandEncoder, cmds []*protocol.Command) error {
+	for _, cmd := range cmds {
+		if protocolType == protocol.TypeProtobuf {
+			if err := encodeProtobufCommand(buf, cmd); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := encoder.Encode(cmd)
+		if err != nil {
+			return err
+		}
+		// JSON commands in one frame are separated by new line.
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(bytes.TrimRight(data, "\n"))
+	}
+	return nil
+}
+
+// writeData writes frame with cmds encoded into data. Concurrent writers are
+// ordered by priority.
+func (t *websocketTransport) writeData(data []byte, priority Priority, timeout time.Duration, cmds ...*protocol.Command) error {
+	t.writer.acquire(priority)
+	defer t.writer.release()
+	t.config.Watchdog.begin(componentWriter)
+	defer t.config.Watchdog.end(componentWriter)
 	if timeout > 0 {
 		_ = t.conn.SetWriteDeadline(time.Now().Add(timeout))
 	}
@@ -208,6 +428,12 @@ func (t *websocketTransport) writeData(data []byte, timeout time.Duration) error
 	if timeout > 0 {
 		_ = t.conn.SetWriteDeadline(time.Time{})
 	}
+	if err == nil && t.config.Metrics != nil {
+		t.config.Metrics.AddBytesSent(len(data))
+	}
+	if err == nil && t.config.ProtocolDump != nil {
+		t.config.ProtocolDump.dumpOut(data, cmds)
+	}
 	return err
 }
 
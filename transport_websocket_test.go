@@ -0,0 +1,117 @@
+package centrifuge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// startFrameServer starts WebSocket server which passes every received frame to frames
+// channel.
+func startFrameServer(t *testing.T, frames chan<- []byte) string {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{"centrifuge-protobuf"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			frames <- data
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func testWriteMany(t *testing.T, protocolType protocol.Type) []byte {
+	frames := make(chan []byte, 1)
+	u := startFrameServer(t, frames)
+	tr, err := newWebsocketTransport(u, protocolType, websocketConfig{HandshakeTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tr.Close() }()
+	cmds := []*protocol.Command{
+		{Id: 1, Subscribe: &protocol.SubscribeRequest{Channel: "a"}},
+		{Id: 2, Subscribe: &protocol.SubscribeRequest{Channel: "b"}},
+	}
+	if err := tr.WriteMany(cmds, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-frames:
+		return data
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for frame")
+	}
+	return nil
+}
+
+func checkWriteManyCommands(t *testing.T, protocolType protocol.Type, data []byte) {
+	t.Helper()
+	cmds, err := DecodeProtocolCommands(protocolType, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 2 || cmds[0].Id != 1 || cmds[1].Id != 2 {
+		t.Fatalf("unexpected commands: %v", cmds)
+	}
+}
+
+func TestWebsocketTransport_WriteManyJSON(t *testing.T) {
+	data := testWriteMany(t, protocol.TypeJSON)
+	checkWriteManyCommands(t, protocol.TypeJSON, data)
+}
+
+func TestWebsocketTransport_WriteManyProtobuf(t *testing.T) {
+	data := testWriteMany(t, protocol.TypeProtobuf)
+	checkWriteManyCommands(t, protocol.TypeProtobuf, data)
+}
+
+func TestWebsocketTransport_WriteBatched(t *testing.T) {
+	frames := make(chan []byte, 10)
+	u := startFrameServer(t, frames)
+	tr, err := newWebsocketTransport(u, protocol.TypeJSON, websocketConfig{
+		HandshakeTimeout: time.Second,
+		MaxBatchSize:     3,
+		MaxBatchDelay:    time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tr.Close() }()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := tr.Write(&protocol.Command{Id: uint32(i)}, time.Second); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	select {
+	case data := <-frames:
+		// Full batch is written without waiting for MaxBatchDelay.
+		if n := strings.Count(string(data), "\n") + 1; n != 3 {
+			t.Fatalf("expected 3 commands in frame, got %d: %s", n, data)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("timeout waiting for frame")
+	}
+}
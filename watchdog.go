@@ -0,0 +1,122 @@
+package centrifuge
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	componentReader = iota
+	componentWriter
+	componentDispatcher
+	numComponents
+)
+
+var componentNames = [numComponents]string{"reader", "writer", "dispatcher"}
+
+// maxGoroutineDumpSize limits goroutine dump of StalledEvent.
+const maxGoroutineDumpSize = 1 << 20
+
+// watchdog detects internal goroutines stuck in one work item. Goroutines mark
+// start and end of work items, watchdog periodically checks how long current
+// items take. All methods are no-op on nil watchdog.
+type watchdog struct {
+	client    *Client
+	timeout   time.Duration
+	busySince [numComponents]atomic.Int64
+	progress  [numComponents]atomic.Uint64
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+func newWatchdog(c *Client, timeout time.Duration) *watchdog {
+	return &watchdog{client: c, timeout: timeout, closeCh: make(chan struct{})}
+}
+
+func (w *watchdog) begin(component int) {
+	if w == nil {
+		return
+	}
+	w.busySince[component].Store(time.Now().UnixNano())
+}
+
+func (w *watchdog) end(component int) {
+	if w == nil {
+		return
+	}
+	w.busySince[component].Store(0)
+	w.progress[component].Add(1)
+}
+
+func (w *watchdog) stop() {
+	if w == nil {
+		return
+	}
+	w.closeOnce.Do(func() {
+		close(w.closeCh)
+	})
+}
+
+func (w *watchdog) run() {
+	ticker := time.NewTicker(w.timeout / 4)
+	defer ticker.Stop()
+	// Progress value at the moment stall was reported, so every stall is
+	// reported once.
+	var reported [numComponents]uint64
+	var stalled [numComponents]bool
+	for {
+		select {
+		case <-w.closeCh:
+			return
+		case now := <-ticker.C:
+			for i := range numComponents {
+				since := w.busySince[i].Load()
+				progress := w.progress[i].Load()
+				if stalled[i] && progress == reported[i] {
+					continue
+				}
+				stalled[i] = false
+				if since == 0 {
+					continue
+				}
+				duration := now.Sub(time.Unix(0, since))
+				if duration < w.timeout {
+					continue
+				}
+				stalled[i] = true
+				reported[i] = progress
+				w.report(StalledEvent{Component: componentNames[i], Duration: duration, Goroutines: goroutineDump()})
+			}
+		}
+	}
+}
+
+func (w *watchdog) report(event StalledEvent) {
+	c := w.client
+	if c.logLevelEnabled(LogLevelDebug) {
+		c.log(LogLevelDebug, "client goroutine stalled", map[string]string{
+			"component": event.Component,
+			"duration":  event.Duration.String(),
+		})
+	}
+	if c.events != nil && c.events.onStalled != nil {
+		handler := c.events.onStalled
+		c.spawn("stalled_handler", func() {
+			defer c.recoverCallback("OnStalled")
+			handler(event)
+		})
+	}
+}
+
+func goroutineDump() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxGoroutineDumpSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
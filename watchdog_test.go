@@ -0,0 +1,83 @@
+package centrifuge
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestClient_OnStalled(t *testing.T) {
+	client := NewJsonClient(startServerInfoServer(t), Config{StallTimeout: 100 * time.Millisecond})
+	defer client.Close()
+	unblock := make(chan struct{})
+	client.OnConnected(func(_ ConnectedEvent) {
+		<-unblock
+	})
+	stalled := make(chan StalledEvent, 8)
+	client.OnStalled(func(e StalledEvent) {
+		stalled <- e
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-stalled:
+			if e.Component != "dispatcher" {
+				continue
+			}
+			if e.Duration < 100*time.Millisecond {
+				t.Fatalf("unexpected stall duration %s", e.Duration)
+			}
+			if !bytes.Contains(e.Goroutines, []byte("goroutine")) {
+				t.Fatal("expected goroutine dump")
+			}
+			close(unblock)
+			return
+		case <-timeout:
+			close(unblock)
+			t.Fatal("timeout waiting for stalled event")
+		}
+	}
+}
+
+func TestWatchdog_ReportsStallOnce(t *testing.T) {
+	client := NewJsonClient("ws://localhost:8000/connection/websocket", Config{})
+	defer client.Close()
+	stalled := make(chan StalledEvent, 8)
+	client.OnStalled(func(e StalledEvent) {
+		stalled <- e
+	})
+	w := newWatchdog(client, 40*time.Millisecond)
+	go w.run()
+	defer w.stop()
+
+	w.begin(componentWriter)
+	select {
+	case e := <-stalled:
+		if e.Component != "writer" {
+			t.Fatalf("unexpected component %s", e.Component)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for stalled event")
+	}
+	select {
+	case e := <-stalled:
+		t.Fatalf("stall reported twice: %+v", e)
+	case <-time.After(200 * time.Millisecond):
+	}
+	w.end(componentWriter)
+	w.begin(componentWriter)
+	select {
+	case <-stalled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for new stall reported")
+	}
+	w.end(componentWriter)
+
+	var nilWatchdog *watchdog
+	nilWatchdog.begin(componentReader)
+	nilWatchdog.end(componentReader)
+	nilWatchdog.stop()
+}